@@ -3,6 +3,25 @@ package ast
 // Batch represents a T-SQL batch of statements.
 type Batch struct {
 	Statements []Statement `json:"Statements,omitempty"`
+	// Annotations holds the query annotations of annotated statements. It
+	// is not part of the ScriptDOM-compatible JSON output.
+	Annotations map[Statement]*QueryAnnotation `json:"-"`
+	// Sources holds the source text of the statements, as written, if the
+	// batch was parsed with parser.Options.Sources. It is not part of the
+	// ScriptDOM-compatible JSON output.
+	Sources map[Statement]string `json:"-"`
 }
 
 func (*Batch) node() {}
+
+// Annotation returns the query annotation of a statement in the batch, or
+// nil if it has none.
+func (b *Batch) Annotation(stmt Statement) *QueryAnnotation {
+	return b.Annotations[stmt]
+}
+
+// Source returns the source text of a statement in the batch, or "" if it
+// is not known.
+func (b *Batch) Source(stmt Statement) string {
+	return b.Sources[stmt]
+}
@@ -50,3 +50,5 @@ func (o *OnOffAtomicBlockOption) atomicBlockOption() {}
 type StatementList struct {
 	Statements []Statement `json:"Statements,omitempty"`
 }
+
+func (s *StatementList) node() {}
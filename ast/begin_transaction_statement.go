@@ -6,6 +6,8 @@ type BeginTransactionStatement struct {
 	Distributed    bool                         `json:"Distributed"`
 	MarkDefined    bool                         `json:"MarkDefined"`
 	MarkDescription ScalarExpression            `json:"MarkDescription,omitempty"`
+	// Pos is the position of the BEGIN keyword in the source, if known.
+	Pos Position `json:"-"`
 }
 
 func (b *BeginTransactionStatement) node()      {}
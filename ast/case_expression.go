@@ -16,6 +16,8 @@ type SearchedWhenClause struct {
 	ThenExpression ScalarExpression
 }
 
+func (s *SearchedWhenClause) node() {}
+
 // SimpleCaseExpression represents a CASE expression WHEN value THEN result END.
 type SimpleCaseExpression struct {
 	InputExpression ScalarExpression
@@ -32,3 +34,5 @@ type SimpleWhenClause struct {
 	WhenExpression ScalarExpression
 	ThenExpression ScalarExpression
 }
+
+func (s *SimpleWhenClause) node() {}
@@ -4,6 +4,8 @@ package ast
 type CommitTransactionStatement struct {
 	Name                    *IdentifierOrValueExpression `json:"Name,omitempty"`
 	DelayedDurabilityOption string                       `json:"DelayedDurabilityOption,omitempty"`
+	// Pos is the position of the COMMIT keyword in the source, if known.
+	Pos Position `json:"-"`
 }
 
 func (c *CommitTransactionStatement) node()      {}
@@ -16,6 +16,8 @@ type DeclareVariableElement struct {
 	Nullable     *NullableConstraintDefinition `json:"Nullable,omitempty"`
 }
 
+func (d *DeclareVariableElement) node() {}
+
 // DeclareTableVariableStatement represents a DECLARE @var TABLE statement.
 type DeclareTableVariableStatement struct {
 	Body *DeclareTableVariableBody `json:"Body,omitempty"`
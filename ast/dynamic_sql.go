@@ -0,0 +1,23 @@
+package ast
+
+// DynamicSQL is the SQL text that an EXECUTE statement runs, either as a
+// string list, EXEC ('...'), or through sp_executesql. It is only set when
+// the parser is asked to parse dynamic SQL.
+type DynamicSQL struct {
+	// Constant reports whether the text is made of string literals alone.
+	// A concatenation that includes variables or other expressions is not
+	// constant, and its text is unknown.
+	Constant bool
+	// Text is the SQL text if it is constant.
+	Text string
+	// Script is the parsed text, or nil if the text is not constant or does
+	// not parse.
+	Script *Script
+	// Err is the error from parsing a constant text.
+	Err error
+	// Parameters are the parameters declared by the @params argument of
+	// sp_executesql, if it is constant.
+	Parameters []*ProcedureParameter
+}
+
+func (d *DynamicSQL) node() {}
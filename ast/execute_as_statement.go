@@ -15,3 +15,5 @@ type ExecuteContext struct {
 	Kind      string           // Caller, Login, User, Self, Owner
 	Principal ScalarExpression // The principal (login or user name)
 }
+
+func (e *ExecuteContext) node() {}
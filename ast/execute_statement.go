@@ -4,6 +4,8 @@ package ast
 type ExecuteStatement struct {
 	ExecuteSpecification *ExecuteSpecification `json:"ExecuteSpecification,omitempty"`
 	Options              []ExecuteOptionType   `json:"Options,omitempty"`
+	// Dynamic is the SQL text the statement runs, if it runs a string.
+	Dynamic *DynamicSQL `json:"-"`
 }
 
 func (e *ExecuteStatement) node()      {}
@@ -72,6 +74,8 @@ type ExecuteSpecification struct {
 	ExecutableEntity ExecutableEntity   `json:"ExecutableEntity,omitempty"`
 }
 
+func (e *ExecuteSpecification) node() {}
+
 // ExecutableEntity is an interface for executable entities.
 type ExecutableEntity interface {
 	executableEntity()
@@ -84,6 +88,7 @@ type ExecutableProcedureReference struct {
 	AdHocDataSource    *AdHocDataSource        `json:"AdHocDataSource,omitempty"`
 }
 
+func (e *ExecutableProcedureReference) node()             {}
 func (e *ExecutableProcedureReference) executableEntity() {}
 
 // ExecutableStringList represents an EXECUTE with a string expression list.
@@ -93,6 +98,7 @@ type ExecutableStringList struct {
 	Parameters []*ExecuteParameter `json:"Parameters,omitempty"`
 }
 
+func (e *ExecutableStringList) node()             {}
 func (e *ExecutableStringList) executableEntity() {}
 
 // ProcedureReferenceName holds either a variable or a procedure reference.
@@ -101,12 +107,16 @@ type ProcedureReferenceName struct {
 	ProcedureReference *ProcedureReference `json:"ProcedureReference,omitempty"`
 }
 
+func (p *ProcedureReferenceName) node() {}
+
 // ProcedureReference references a stored procedure by name.
 type ProcedureReference struct {
 	Name   *SchemaObjectName `json:"Name,omitempty"`
 	Number *IntegerLiteral   `json:"Number,omitempty"`
 }
 
+func (p *ProcedureReference) node() {}
+
 // ExecuteParameter represents a parameter to an EXEC call.
 type ExecuteParameter struct {
 	ParameterValue ScalarExpression   `json:"ParameterValue,omitempty"`
@@ -114,6 +124,8 @@ type ExecuteParameter struct {
 	IsOutput       bool               `json:"IsOutput"`
 }
 
+func (e *ExecuteParameter) node() {}
+
 // AdHocDataSource represents an OPENDATASOURCE or OPENROWSET call for ad-hoc data access.
 type AdHocDataSource struct {
 	ProviderName *StringLiteral `json:"ProviderName,omitempty"`
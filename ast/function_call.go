@@ -10,6 +10,7 @@ type MultiPartIdentifierCallTarget struct {
 	MultiPartIdentifier *MultiPartIdentifier
 }
 
+func (*MultiPartIdentifierCallTarget) node()       {}
 func (*MultiPartIdentifierCallTarget) callTarget() {}
 
 // ExpressionCallTarget represents an expression call target.
@@ -17,6 +18,7 @@ type ExpressionCallTarget struct {
 	Expression ScalarExpression
 }
 
+func (*ExpressionCallTarget) node()       {}
 func (*ExpressionCallTarget) callTarget() {}
 
 // UserDefinedTypeCallTarget represents a user-defined type call target.
@@ -24,6 +26,7 @@ type UserDefinedTypeCallTarget struct {
 	SchemaObjectName *SchemaObjectName
 }
 
+func (*UserDefinedTypeCallTarget) node()       {}
 func (*UserDefinedTypeCallTarget) callTarget() {}
 
 // OverClause represents an OVER clause for window functions.
@@ -34,6 +37,8 @@ type OverClause struct {
 	WindowFrameClause *WindowFrameClause `json:"WindowFrameClause,omitempty"`
 }
 
+func (o *OverClause) node() {}
+
 // WindowFrameClause represents ROWS/RANGE frame specification in OVER clause
 type WindowFrameClause struct {
 	WindowFrameType string           // "Rows", "Range"
@@ -4,6 +4,8 @@ package ast
 type Identifier struct {
 	Value     string `json:"Value,omitempty"`
 	QuoteType string `json:"QuoteType,omitempty"`
+	// Pos is the position of the identifier in the source, if known.
+	Pos Position `json:"-"`
 }
 
 func (*Identifier) node() {}
@@ -29,6 +29,8 @@ type OutputClause struct {
 	SelectColumns []SelectElement `json:"SelectColumns,omitempty"`
 }
 
+func (o *OutputClause) node() {}
+
 // OutputIntoClause represents an OUTPUT INTO clause.
 type OutputIntoClause struct {
 	SelectColumns    []SelectElement              `json:"SelectColumns,omitempty"`
@@ -36,6 +38,8 @@ type OutputIntoClause struct {
 	IntoTableColumns []*ColumnReferenceExpression `json:"IntoTableColumns,omitempty"`
 }
 
+func (o *OutputIntoClause) node() {}
+
 // InsertSource is an interface for INSERT sources.
 type InsertSource interface {
 	insertSource()
@@ -47,6 +51,7 @@ type ValuesInsertSource struct {
 	RowValues       []*RowValue `json:"RowValues,omitempty"`
 }
 
+func (v *ValuesInsertSource) node()         {}
 func (v *ValuesInsertSource) insertSource() {}
 
 // RowValue represents a row of values.
@@ -54,11 +59,14 @@ type RowValue struct {
 	ColumnValues []ScalarExpression `json:"ColumnValues,omitempty"`
 }
 
+func (r *RowValue) node() {}
+
 // SelectInsertSource represents INSERT ... SELECT.
 type SelectInsertSource struct {
 	Select QueryExpression `json:"Select,omitempty"`
 }
 
+func (s *SelectInsertSource) node()         {}
 func (s *SelectInsertSource) insertSource() {}
 
 // ExecuteInsertSource represents INSERT ... EXEC.
@@ -66,4 +74,5 @@ type ExecuteInsertSource struct {
 	Execute *ExecuteSpecification `json:"Execute,omitempty"`
 }
 
+func (e *ExecuteInsertSource) node()         {}
 func (e *ExecuteInsertSource) insertSource() {}
@@ -0,0 +1,17 @@
+package ast
+
+// QueryAnnotation is a sqlc-style query annotation, a comment such as
+// "-- name: GetUser :one" on the line above a statement.
+type QueryAnnotation struct {
+	// Name is the query name, e.g. GetUser.
+	Name string
+	// Command is the command kind without its colon, e.g. one. It is empty
+	// if the annotation does not give one.
+	Command string
+	// Pos is the position of the comment.
+	Pos Position
+	// Text is the source text of the annotated statement, and TextPos the
+	// position at which it starts.
+	Text    string
+	TextPos Position
+}
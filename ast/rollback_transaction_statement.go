@@ -3,6 +3,8 @@ package ast
 // RollbackTransactionStatement represents a ROLLBACK [TRAN|TRANSACTION] statement.
 type RollbackTransactionStatement struct {
 	Name *IdentifierOrValueExpression `json:"Name,omitempty"`
+	// Pos is the position of the ROLLBACK keyword in the source, if known.
+	Pos Position `json:"-"`
 }
 
 func (r *RollbackTransactionStatement) node()      {}
@@ -3,6 +3,8 @@ package ast
 // SaveTransactionStatement represents a SAVE [TRAN|TRANSACTION] statement.
 type SaveTransactionStatement struct {
 	Name *IdentifierOrValueExpression `json:"Name,omitempty"`
+	// Pos is the position of the SAVE keyword in the source, if known.
+	Pos Position `json:"-"`
 }
 
 func (s *SaveTransactionStatement) node()      {}
@@ -21,6 +21,8 @@ type CursorDefinition struct {
 	Select  *SelectStatement `json:"Select,omitempty"`
 }
 
+func (c *CursorDefinition) node() {}
+
 // CursorOption represents a cursor option like SCROLL or DYNAMIC.
 type CursorOption struct {
 	OptionKind string `json:"OptionKind,omitempty"`
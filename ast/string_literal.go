@@ -2,10 +2,11 @@ package ast
 
 // StringLiteral represents a string literal.
 type StringLiteral struct {
-	LiteralType   string `json:"LiteralType,omitempty"`
-	IsNational    bool   `json:"IsNational,omitempty"`
-	IsLargeObject bool   `json:"IsLargeObject,omitempty"`
-	Value         string `json:"Value,omitempty"`
+	LiteralType   string      `json:"LiteralType,omitempty"`
+	IsNational    bool        `json:"IsNational,omitempty"`
+	IsLargeObject bool        `json:"IsLargeObject,omitempty"`
+	Value         string      `json:"Value,omitempty"`
+	Collation     *Identifier `json:"Collation,omitempty"`
 }
 
 func (*StringLiteral) node()             {}
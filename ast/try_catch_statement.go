@@ -4,6 +4,10 @@ package ast
 type TryCatchStatement struct {
 	TryStatements   *StatementList `json:"TryStatements,omitempty"`
 	CatchStatements *StatementList `json:"CatchStatements,omitempty"`
+	// Pos is the position of BEGIN TRY in the source, if known.
+	Pos Position `json:"-"`
+	// CatchPos is the position of BEGIN CATCH, if known.
+	CatchPos Position `json:"-"`
 }
 
 func (t *TryCatchStatement) node()      {}
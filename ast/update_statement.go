@@ -37,6 +37,7 @@ type AssignmentSetClause struct {
 	AssignmentKind string                    `json:"AssignmentKind,omitempty"`
 }
 
+func (a *AssignmentSetClause) node()      {}
 func (a *AssignmentSetClause) setClause() {}
 
 // FunctionCallSetClause represents a mutator function call in UPDATE SET.
@@ -44,4 +45,5 @@ type FunctionCallSetClause struct {
 	MutatorFunction *FunctionCall `json:"MutatorFunction,omitempty"`
 }
 
+func (f *FunctionCallSetClause) node()      {}
 func (f *FunctionCallSetClause) setClause() {}
@@ -3,6 +3,8 @@ package ast
 // VariableReference represents a reference to a variable (e.g., @var).
 type VariableReference struct {
 	Name string `json:"Name,omitempty"`
+	// Pos is the position of the variable in the source, if known.
+	Pos Position `json:"-"`
 }
 
 func (*VariableReference) node()             {}
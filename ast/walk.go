@@ -0,0 +1,81 @@
+package ast
+
+import "reflect"
+
+// Visitor is implemented by callers of Walk. Visit is invoked for each node
+// encountered by Walk. If the result visitor w is not nil, Walk visits each of
+// the children of node with w, followed by a call of w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses an AST in depth-first order, visiting children in the order
+// their fields are declared. Values that do not implement Node (such as
+// option structs) are not passed to the visitor, but their children are.
+func Walk(v Visitor, node Node) {
+	if isNil(node) {
+		return
+	}
+	if v = v.Visit(node); v == nil {
+		return
+	}
+	walkValue(v, reflect.Indirect(reflect.ValueOf(node)))
+	v.Visit(nil)
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses an AST in depth-first order: It starts by calling
+// f(node); node must not be nil. If f returns true, Inspect invokes f
+// recursively for each of the non-nil children of node, followed by a
+// call of f(nil).
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
+
+var nodeType = reflect.TypeOf((*Node)(nil)).Elem()
+
+func walkValue(v Visitor, rv reflect.Value) {
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return
+		}
+		if rv.Type().Implements(nodeType) {
+			Walk(v, rv.Interface().(Node))
+			return
+		}
+		if rv.Kind() == reflect.Interface && rv.Elem().Type().Implements(nodeType) {
+			Walk(v, rv.Elem().Interface().(Node))
+			return
+		}
+		walkValue(v, rv.Elem())
+	case reflect.Struct:
+		t := rv.Type()
+		for i := 0; i < rv.NumField(); i++ {
+			if !t.Field(i).IsExported() {
+				continue
+			}
+			walkValue(v, rv.Field(i))
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			walkValue(v, rv.Index(i))
+		}
+	}
+}
+
+func isNil(node Node) bool {
+	if node == nil {
+		return true
+	}
+	rv := reflect.ValueOf(node)
+	return rv.Kind() == reflect.Pointer && rv.IsNil()
+}
@@ -0,0 +1,317 @@
+// Package binder resolves column references to the table sources that
+// provide them.
+//
+// Every ColumnReferenceExpression in a query is bound to a source in scope:
+// a table, view or function named in a FROM clause (through its alias, if
+// any), a derived table, a CTE, an APPLY operand, the inserted and deleted
+// tables of an OUTPUT clause, or the inserted and deleted pseudo-tables of a
+// trigger. When a catalog is supplied, named sources have known columns and
+// references are also resolved to catalog columns. References that are
+// ambiguous or that no source in scope provides are reported with their
+// position.
+package binder
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sqlc-dev/teesql/ast"
+	"github.com/sqlc-dev/teesql/catalog"
+)
+
+// Source kinds.
+const (
+	SourceTable    = "Table"
+	SourceView     = "View"
+	SourceFunction = "Function"
+	SourceDerived  = "Derived"
+	SourceCTE      = "CTE"
+	SourceVariable = "Variable"
+	SourceInserted = "Inserted"
+	SourceDeleted  = "Deleted"
+	// SourceUnknown is a named object that is not in the catalog, or a
+	// table-valued construct such as OPENROWSET whose columns are unknown.
+	SourceUnknown = "Unknown"
+)
+
+// Source is a table source in scope of a column reference.
+type Source struct {
+	Kind string
+	// Name is the name the source is referred to by: its alias, or the
+	// object name.
+	Name string
+	// Object is the name of a named object as written, e.g. "dbo.Orders".
+	// For the inserted and deleted tables it names the modified table.
+	Object string
+	// Columns are the source's columns, or nil if they are not known.
+	Columns []*catalog.Column
+	// Reference is the table reference that introduced the source. It is
+	// nil for the inserted and deleted tables.
+	Reference ast.TableReference
+	// Nullable is set for sources on the inner side of an outer join or
+	// OUTER APPLY, whose columns are null in unmatched rows.
+	Nullable bool
+
+	object  catalog.ObjectName
+	aliased bool
+}
+
+// Column returns the named column of the source, or nil.
+func (s *Source) Column(name string) *catalog.Column {
+	for _, c := range s.Columns {
+		if strings.EqualFold(c.Name, name) {
+			return c
+		}
+	}
+	return nil
+}
+
+// Binding is a column reference resolved to a source.
+type Binding struct {
+	Ref    *ast.ColumnReferenceExpression
+	Source *Source
+	// Column is the source column, or nil if the source's columns are not
+	// known.
+	Column *catalog.Column
+}
+
+// Error is a column reference that could not be bound.
+type Error struct {
+	Pos     ast.Position
+	Ref     *ast.ColumnReferenceExpression
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Pos.String() + ": " + e.Message
+}
+
+// Result holds the bindings of a script.
+type Result struct {
+	Bindings []*Binding
+	Errors   []*Error
+
+	refs    map[*ast.ColumnReferenceExpression]*Binding
+	exprs   map[*catalog.Column]ast.ScalarExpression
+	origins map[*catalog.Column]*Source
+	queries map[ast.QueryExpression][]*catalog.Column
+	outputs map[*ast.OutputClause][]*catalog.Column
+}
+
+// Lookup returns the binding of a column reference, or nil if it was not
+// bound.
+func (r *Result) Lookup(ref *ast.ColumnReferenceExpression) *Binding {
+	return r.refs[ref]
+}
+
+// Expression returns the select list expression that computes an output
+// column of a query, derived table or CTE, or nil.
+func (r *Result) Expression(col *catalog.Column) ast.ScalarExpression {
+	return r.exprs[col]
+}
+
+// Origin returns the source a star-expanded output column was taken from,
+// or nil.
+func (r *Result) Origin(col *catalog.Column) *Source {
+	return r.origins[col]
+}
+
+// Output returns the columns of a bound OUTPUT clause, or nil if they are
+// not known.
+func (r *Result) Output(oc *ast.OutputClause) []*catalog.Column {
+	return r.outputs[oc]
+}
+
+// Columns returns the output columns of a bound query expression, or nil if
+// they are not known. The columns of a UNION are those of its first query.
+func (r *Result) Columns(q ast.QueryExpression) []*catalog.Column {
+	return r.queries[q]
+}
+
+// Bind resolves the column references of every statement in the script. cat
+// may be nil, in which case only derived tables, CTEs and tables created by
+// the script have known columns.
+func Bind(script *ast.Script, cat *catalog.Catalog) *Result {
+	b := newBinder(cat)
+	if script != nil {
+		b.walk(script)
+	}
+	return b.result
+}
+
+// BindStatement resolves the column references of a single statement.
+func BindStatement(stmt ast.Statement, cat *catalog.Catalog) *Result {
+	b := newBinder(cat)
+	if stmt != nil {
+		b.walk(stmt)
+	}
+	return b.result
+}
+
+type binder struct {
+	cat    *catalog.Catalog
+	result *Result
+	// local holds tables and table variables declared by the script, keyed
+	// by lower-case name.
+	local map[string]*catalog.Table
+	// trigger is the table of the trigger whose body is being bound.
+	trigger *catalog.Table
+	// skip holds references that are not columns, such as DATEADD's
+	// datepart argument.
+	skip map[*ast.ColumnReferenceExpression]bool
+}
+
+func newBinder(cat *catalog.Catalog) *binder {
+	return &binder{
+		cat: cat,
+		result: &Result{
+			refs:    map[*ast.ColumnReferenceExpression]*Binding{},
+			exprs:   map[*catalog.Column]ast.ScalarExpression{},
+			origins: map[*catalog.Column]*Source{},
+			queries: map[ast.QueryExpression][]*catalog.Column{},
+			outputs: map[*ast.OutputClause][]*catalog.Column{},
+		},
+		local: map[string]*catalog.Table{},
+		skip:  map[*ast.ColumnReferenceExpression]bool{},
+	}
+}
+
+// walk binds every statement under node.
+func (b *binder) walk(node ast.Node) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch s := n.(type) {
+		case *ast.CreateTableStatement:
+			b.declareTable(s)
+			return false
+		case *ast.DeclareTableVariableStatement:
+			if s.Body != nil {
+				b.declareTableVariable(s.Body)
+			}
+			return false
+		case *ast.SelectStatement:
+			b.selectStatement(s)
+			return false
+		case *ast.InsertStatement:
+			b.insert(s)
+			return false
+		case *ast.UpdateStatement:
+			b.update(s)
+			return false
+		case *ast.DeleteStatement:
+			b.delete(s)
+			return false
+		case *ast.MergeStatement:
+			b.merge(s)
+			return false
+		case *ast.CreateTriggerStatement:
+			b.triggerBody(s.TriggerObject, s.StatementList)
+			return false
+		case *ast.AlterTriggerStatement:
+			b.triggerBody(s.TriggerObject, s.StatementList)
+			return false
+		case *ast.CreateOrAlterTriggerStatement:
+			b.triggerBody(s.TriggerObject, s.StatementList)
+			return false
+		case ast.QueryExpression:
+			// Subqueries in IF, WHILE, SET and similar statements.
+			b.query(s, nil)
+			return false
+		case *ast.ColumnReferenceExpression:
+			// Column lists of DDL statements, not bound.
+			return false
+		}
+		return true
+	})
+}
+
+func (b *binder) triggerBody(obj *ast.TriggerObject, body *ast.StatementList) {
+	if body == nil {
+		return
+	}
+	prev := b.trigger
+	b.trigger = nil
+	if obj != nil && obj.Name != nil {
+		if t := b.table(obj.Name); t != nil {
+			b.trigger = t
+		} else {
+			// Unknown columns, but inserted and deleted still resolve.
+			b.trigger = &catalog.Table{Name: catalog.NameOf(obj.Name).Name}
+		}
+	}
+	b.walk(body)
+	b.trigger = prev
+}
+
+func localKey(name *ast.SchemaObjectName) string {
+	n := catalog.NameOf(name)
+	if strings.HasPrefix(n.Name, "#") {
+		return strings.ToLower(n.Name)
+	}
+	if n.Schema == "" {
+		n.Schema = catalog.DefaultSchema
+	}
+	return strings.ToLower(n.String())
+}
+
+func (b *binder) declareTable(s *ast.CreateTableStatement) {
+	t := catalog.NewTable(catalog.NameOf(s.SchemaObjectName), s.Definition)
+	if s.SelectStatement != nil {
+		// CREATE TABLE AS SELECT: the columns are the query's outputs.
+		t.Columns = b.renamed(b.selectStatement(s.SelectStatement), identifierValues(s.CtasColumns))
+	}
+	b.local[localKey(s.SchemaObjectName)] = t
+}
+
+func (b *binder) declareTableVariable(body *ast.DeclareTableVariableBody) {
+	if body.VariableName == nil {
+		return
+	}
+	name := body.VariableName.Value
+	b.local[strings.ToLower(name)] = catalog.NewTable(catalog.ObjectName{Name: name}, body.Definition)
+}
+
+// table returns the table with the given name, declared by the script or
+// in the catalog, or nil.
+func (b *binder) table(name *ast.SchemaObjectName) *catalog.Table {
+	if t, ok := b.local[localKey(name)]; ok {
+		return t
+	}
+	if b.cat != nil {
+		return b.cat.Table(catalog.NameOf(name))
+	}
+	return nil
+}
+
+func (b *binder) bind(ref *ast.ColumnReferenceExpression, src *Source, col *catalog.Column) {
+	binding := &Binding{Ref: ref, Source: src, Column: col}
+	b.result.Bindings = append(b.result.Bindings, binding)
+	b.result.refs[ref] = binding
+}
+
+func (b *binder) errorf(ref *ast.ColumnReferenceExpression, format string, args ...interface{}) {
+	b.result.Errors = append(b.result.Errors, &Error{
+		Pos:     refPos(ref),
+		Ref:     ref,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func refPos(ref *ast.ColumnReferenceExpression) ast.Position {
+	if ref.MultiPartIdentifier == nil {
+		return ast.Position{}
+	}
+	return qualifierPos(ref.MultiPartIdentifier)
+}
+
+func refParts(ref *ast.ColumnReferenceExpression) []string {
+	if ref.MultiPartIdentifier == nil {
+		return nil
+	}
+	parts := make([]string, len(ref.MultiPartIdentifier.Identifiers))
+	for i, id := range ref.MultiPartIdentifier.Identifiers {
+		if id != nil {
+			parts[i] = id.Value
+		}
+	}
+	return parts
+}
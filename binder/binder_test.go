@@ -0,0 +1,235 @@
+package binder
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sqlc-dev/teesql/ast"
+	"github.com/sqlc-dev/teesql/catalog"
+	"github.com/sqlc-dev/teesql/parser"
+)
+
+const schemaSQL = `
+CREATE TABLE dbo.Customers (Id int NOT NULL PRIMARY KEY, Name nvarchar(100) NOT NULL, Email nvarchar(256) NULL);
+CREATE TABLE dbo.Orders (Id int NOT NULL PRIMARY KEY, CustomerId int NOT NULL, Amount money NOT NULL, PlacedAt datetime2 NOT NULL);
+CREATE TABLE dbo.Audit (OrderId int NOT NULL, Action nvarchar(10) NOT NULL);
+GO
+CREATE VIEW dbo.BigOrders AS SELECT o.Id, o.Amount FROM dbo.Orders o WHERE o.Amount > 100;
+GO
+CREATE FUNCTION dbo.OrdersOf (@customer int) RETURNS TABLE AS RETURN SELECT Id, Amount FROM dbo.Orders WHERE CustomerId = @customer;
+GO
+CREATE SYNONYM dbo.Clients FOR dbo.Customers;
+`
+
+func parse(t *testing.T, sql string) *ast.Script {
+	t.Helper()
+	script, err := parser.Parse(context.Background(), strings.NewReader(sql))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	return script
+}
+
+func TestBind(t *testing.T) {
+	cat, err := catalog.Build(parse(t, schemaSQL))
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	tests := []struct {
+		name string
+		sql  string
+		want []string
+	}{
+		{
+			name: "aliases and join",
+			sql:  "SELECT c.Name, Amount FROM dbo.Customers c JOIN dbo.Orders o ON o.CustomerId = c.Id",
+			want: []string{
+				"o.CustomerId -> Table o.CustomerId",
+				"c.Id -> Table c.Id",
+				"c.Name -> Table c.Name",
+				"Amount -> Table o.Amount",
+			},
+		},
+		{
+			name: "ambiguous and invalid columns",
+			sql:  "SELECT Id,\n  Nope, o.Missing, x.Id FROM dbo.Customers c JOIN dbo.Orders o ON o.CustomerId = c.Id",
+			want: []string{
+				"o.CustomerId -> Table o.CustomerId",
+				"c.Id -> Table c.Id",
+				`1:8: ambiguous column name "Id"`,
+				`2:3: invalid column name "Nope"`,
+				`2:9: invalid column name "Missing"`,
+				`2:20: multi-part identifier "x.Id" could not be bound`,
+			},
+		},
+		{
+			name: "schema-qualified reference",
+			sql:  "SELECT dbo.Orders.Amount FROM dbo.Orders",
+			want: []string{"dbo.Orders.Amount -> Table Orders.Amount"},
+		},
+		{
+			name: "cte and derived table",
+			sql: `WITH totals (CustomerId, Total) AS (SELECT CustomerId, SUM(Amount) FROM dbo.Orders GROUP BY CustomerId)
+SELECT d.Name, t.Total FROM totals t JOIN (SELECT Id, Name AS Name FROM dbo.Customers) d ON d.Id = t.CustomerId`,
+			want: []string{
+				"CustomerId -> Table Orders.CustomerId",
+				"CustomerId -> Table Orders.CustomerId",
+				"Amount -> Table Orders.Amount",
+				"Id -> Table Customers.Id",
+				"Name -> Table Customers.Name",
+				"d.Id -> Derived d.Id",
+				"t.CustomerId -> CTE t.CustomerId",
+				"d.Name -> Derived d.Name",
+				"t.Total -> CTE t.Total",
+			},
+		},
+		{
+			name: "recursive cte",
+			sql: `WITH n AS (SELECT 1 AS i UNION ALL SELECT i + 1 FROM n WHERE i < 10)
+SELECT i FROM n`,
+			want: []string{
+				"i -> CTE n.i",
+				"i -> CTE n.i",
+				"i -> CTE n.i",
+			},
+		},
+		{
+			name: "correlated subquery and apply",
+			sql: `SELECT c.Name, x.Amount FROM dbo.Customers c
+CROSS APPLY (SELECT TOP 1 Amount FROM dbo.Orders WHERE CustomerId = c.Id ORDER BY PlacedAt DESC) x
+WHERE EXISTS (SELECT 1 FROM dbo.Orders o2 WHERE o2.CustomerId = c.Id)`,
+			want: []string{
+				"CustomerId -> Table Orders.CustomerId",
+				"c.Id -> Table c.Id",
+				"Amount -> Table Orders.Amount",
+				"PlacedAt -> Table Orders.PlacedAt",
+				"o2.CustomerId -> Table o2.CustomerId",
+				"c.Id -> Table c.Id",
+				"c.Name -> Table c.Name",
+				"x.Amount -> Derived x.Amount",
+			},
+		},
+		{
+			name: "view, function, synonym and unknown table",
+			sql: `SELECT b.Amount, f.Id, s.Email, u.Whatever FROM dbo.BigOrders b
+CROSS APPLY dbo.OrdersOf(b.Id) f, dbo.Clients s, dbo.Unknown u`,
+			want: []string{
+				"b.Id -> View b.Id",
+				"b.Amount -> View b.Amount",
+				"f.Id -> Function f.Id",
+				"s.Email -> Table s.Email",
+				"u.Whatever -> Unknown u",
+			},
+		},
+		{
+			name: "datepart and order by alias",
+			sql:  "SELECT DATEADD(day, 1, PlacedAt) AS Due FROM dbo.Orders ORDER BY Due, Id",
+			want: []string{
+				"PlacedAt -> Table Orders.PlacedAt",
+				"Id -> Table Orders.Id",
+			},
+		},
+		{
+			name: "insert with output",
+			sql:  "INSERT INTO dbo.Audit (OrderId, Action) OUTPUT inserted.OrderId, deleted.Action SELECT Id, N'new' FROM dbo.Orders",
+			want: []string{
+				"OrderId -> Table Audit.OrderId",
+				"Action -> Table Audit.Action",
+				"Id -> Table Orders.Id",
+				"inserted.OrderId -> Inserted inserted.OrderId",
+				`1:66: multi-part identifier "deleted.Action" could not be bound`,
+			},
+		},
+		{
+			name: "update through from alias",
+			sql:  "UPDATE o SET Amount = o.Amount * 2 OUTPUT deleted.Amount FROM dbo.Orders o JOIN dbo.Customers c ON c.Id = o.CustomerId WHERE c.Name = N'x'",
+			want: []string{
+				"c.Id -> Table c.Id",
+				"o.CustomerId -> Table o.CustomerId",
+				"Amount -> Table o.Amount",
+				"o.Amount -> Table o.Amount",
+				"c.Name -> Table c.Name",
+				"deleted.Amount -> Deleted deleted.Amount",
+			},
+		},
+		{
+			name: "delete",
+			sql:  "DELETE FROM dbo.Orders WHERE Total > 0",
+			want: []string{`1:30: invalid column name "Total"`},
+		},
+		{
+			name: "merge",
+			sql: `MERGE dbo.Customers AS t USING (SELECT Id, Name FROM #staging) AS s ON t.Id = s.Id
+WHEN MATCHED THEN UPDATE SET Name = s.Name
+WHEN NOT MATCHED THEN INSERT (Id, Name) VALUES (s.Id, s.Name)
+OUTPUT $action, inserted.Id;`,
+			want: []string{
+				"Id -> Unknown #staging",
+				"Name -> Unknown #staging",
+				"t.Id -> Table t.Id",
+				"s.Id -> Derived s.Id",
+				"Name -> Table t.Name",
+				"s.Name -> Derived s.Name",
+				"Id -> Table t.Id",
+				"Name -> Table t.Name",
+				"s.Id -> Derived s.Id",
+				"s.Name -> Derived s.Name",
+				"inserted.Id -> Inserted inserted.Id",
+			},
+		},
+		{
+			name: "script tables and variables",
+			sql: `CREATE TABLE #t (a int, b int);
+DECLARE @v TABLE (k int);
+SELECT a INTO #u FROM #t;
+SELECT u.a, v.k, u.b FROM #u u, @v v;`,
+			want: []string{
+				"a -> Table #t.a",
+				"u.a -> Table u.a",
+				"v.k -> Variable v.k",
+				`4:18: invalid column name "b"`,
+			},
+		},
+		{
+			name: "trigger pseudo-tables",
+			sql: `CREATE TRIGGER dbo.trOrders ON dbo.Orders AFTER UPDATE AS
+INSERT INTO dbo.Audit (OrderId, Action) SELECT i.Id, N'upd' FROM inserted i JOIN deleted d ON d.Id = i.Id WHERE i.Amount <> d.Bogus`,
+			want: []string{
+				"OrderId -> Table Audit.OrderId",
+				"Action -> Table Audit.Action",
+				"d.Id -> Deleted d.Id",
+				"i.Id -> Inserted i.Id",
+				"i.Amount -> Inserted i.Amount",
+				"i.Id -> Inserted i.Id",
+				`2:125: invalid column name "Bogus"`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Bind(parse(t, tt.sql), cat)
+			got := format(result)
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("bindings mismatch:\ngot:\n%s\n\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+// format renders the bindings, then the errors, in the order they were
+// found.
+func format(r *Result) []string {
+	var out []string
+	for _, b := range r.Bindings {
+		line := strings.Join(refParts(b.Ref), ".") + " -> " + b.Source.Kind + " " + b.Source.Name
+		if b.Column != nil {
+			line += "." + b.Column.Name
+		}
+		out = append(out, line)
+	}
+	for _, e := range r.Errors {
+		out = append(out, e.Error())
+	}
+	return out
+}
@@ -0,0 +1,377 @@
+package binder
+
+import (
+	"strings"
+
+	"github.com/sqlc-dev/teesql/ast"
+	"github.com/sqlc-dev/teesql/catalog"
+	"github.com/sqlc-dev/teesql/internal/astutil"
+)
+
+// scope is the set of table sources visible to an expression. Unqualified
+// names resolve in the innermost scope that provides them.
+type scope struct {
+	parent  *scope
+	sources []*Source
+	ctes    []*Source
+}
+
+func newScope(parent *scope, sources ...*Source) *scope {
+	return &scope{parent: parent, sources: sources}
+}
+
+func (sc *scope) add(src *Source) {
+	sc.sources = append(sc.sources, src)
+}
+
+func (sc *scope) cte(name string) *Source {
+	for s := sc; s != nil; s = s.parent {
+		for _, c := range s.ctes {
+			if strings.EqualFold(c.Name, name) {
+				return c
+			}
+		}
+	}
+	return nil
+}
+
+// matches reports whether a column qualifier refers to the source. A single
+// part matches the source's name; longer qualifiers match the schema and
+// name of an unaliased object.
+func (s *Source) matches(qualifier []string) bool {
+	if len(qualifier) == 1 {
+		return strings.EqualFold(s.Name, qualifier[0])
+	}
+	if s.aliased || s.object.Name == "" {
+		return false
+	}
+	schema := s.object.Schema
+	if schema == "" {
+		schema = catalog.DefaultSchema
+	}
+	n := len(qualifier)
+	return strings.EqualFold(qualifier[n-2], schema) && strings.EqualFold(qualifier[n-1], s.object.Name)
+}
+
+// resolve binds a column reference to a source in scope.
+func (b *binder) resolve(ref *ast.ColumnReferenceExpression, sc *scope) {
+	if b.skip[ref] || ref.ColumnType != "" && ref.ColumnType != "Regular" {
+		return
+	}
+	parts := refParts(ref)
+	if len(parts) == 0 {
+		return
+	}
+	name, qualifier := parts[len(parts)-1], parts[:len(parts)-1]
+	if len(qualifier) > 0 {
+		for s := sc; s != nil; s = s.parent {
+			for _, src := range s.sources {
+				if !src.matches(qualifier) {
+					continue
+				}
+				if src.Columns == nil {
+					b.bind(ref, src, nil)
+				} else if col := src.Column(name); col != nil {
+					b.bind(ref, src, col)
+				} else {
+					b.errorf(ref, "invalid column name %q", name)
+				}
+				return
+			}
+		}
+		b.errorf(ref, "multi-part identifier %q could not be bound", strings.Join(parts, "."))
+		return
+	}
+	for s := sc; s != nil; s = s.parent {
+		var found, unknown []*Source
+		for _, src := range s.sources {
+			if src.Columns == nil {
+				unknown = append(unknown, src)
+			} else if src.Column(name) != nil {
+				found = append(found, src)
+			}
+		}
+		switch {
+		case len(found) > 1:
+			b.errorf(ref, "ambiguous column name %q", name)
+			return
+		case len(found) == 1:
+			b.bind(ref, found[0], found[0].Column(name))
+			return
+		case len(unknown) == 1:
+			b.bind(ref, unknown[0], nil)
+			return
+		case len(unknown) > 1:
+			// Any of the sources may provide the column.
+			return
+		}
+	}
+	b.errorf(ref, "invalid column name %q", name)
+}
+
+// expr binds the column references in an expression. Subqueries are bound
+// in their own scope nested in sc.
+func (b *binder) expr(node ast.Node, sc *scope) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.ColumnReferenceExpression:
+			b.resolve(n, sc)
+			return false
+		case ast.QueryExpression:
+			b.query(n, sc)
+			return false
+		case *ast.FunctionCall:
+			b.skipDatepart(n)
+		}
+		return true
+	})
+}
+
+func (b *binder) skipDatepart(f *ast.FunctionCall) {
+	if f.CallTarget != nil || f.FunctionName == nil || len(f.Parameters) == 0 {
+		return
+	}
+	if !astutil.TakesDatepart(f.FunctionName.Value) {
+		return
+	}
+	if ref, ok := f.Parameters[0].(*ast.ColumnReferenceExpression); ok {
+		b.skip[ref] = true
+	}
+}
+
+// from adds the sources of a FROM clause to sc.
+func (b *binder) from(clause *ast.FromClause, sc *scope) {
+	if clause == nil {
+		return
+	}
+	for _, ref := range clause.TableReferences {
+		b.tableReference(ref, sc, sc.parent)
+	}
+}
+
+// tableReference adds the sources of ref to sc. outer is the scope visible
+// to expressions inside ref, such as derived table queries and function
+// arguments; for the right-hand side of APPLY it is sc itself.
+func (b *binder) tableReference(ref ast.TableReference, sc, outer *scope) {
+	switch r := ref.(type) {
+	case *ast.NamedTableReference:
+		sc.add(b.namedSource(r, sc))
+	case *ast.QueryDerivedTable:
+		cols := b.query(r.QueryExpression, outer)
+		sc.add(&Source{
+			Kind:      SourceDerived,
+			Name:      identifierValue(r.Alias),
+			Columns:   b.renamed(cols, identifierValues(r.Columns)),
+			Reference: r,
+			aliased:   true,
+		})
+	case *ast.InlineDerivedTable:
+		for _, row := range r.RowValues {
+			if row != nil {
+				b.expr(row, outer)
+			}
+		}
+		sc.add(&Source{
+			Kind:      SourceDerived,
+			Name:      identifierValue(r.Alias),
+			Columns:   b.renamed(nil, identifierValues(r.Columns)),
+			Reference: r,
+			aliased:   true,
+		})
+	case *ast.SchemaObjectFunctionTableReference:
+		for _, p := range r.Parameters {
+			b.expr(p, outer)
+		}
+		n := catalog.NameOf(r.SchemaObject)
+		src := &Source{Kind: SourceUnknown, Name: n.Name, Object: n.String(), Reference: r, object: n}
+		if b.cat != nil {
+			if f := b.cat.Function(n); f != nil && f.Kind != catalog.ScalarFunction {
+				src.Kind = SourceFunction
+				src.Columns = f.Columns
+			}
+		}
+		src.Columns = b.renamed(src.Columns, identifierValues(r.Columns))
+		alias(src, r.Alias)
+		sc.add(src)
+	case *ast.VariableTableReference:
+		src := &Source{Kind: SourceVariable, Reference: r}
+		if r.Variable != nil {
+			src.Name = r.Variable.Name
+			src.Object = r.Variable.Name
+			if t, ok := b.local[strings.ToLower(r.Variable.Name)]; ok {
+				src.Columns = t.Columns
+			}
+		}
+		alias(src, r.Alias)
+		sc.add(src)
+	case *ast.OpenJsonTableReference:
+		b.expr(r.Variable, outer)
+		b.expr(r.RowPattern, outer)
+		src := &Source{Kind: SourceFunction, Name: "OPENJSON", Object: "OPENJSON", Reference: r}
+		if len(r.SchemaDeclarationItems) == 0 {
+			src.Columns = []*catalog.Column{
+				{Name: "key", Type: catalog.Type{Name: "nvarchar", Length: 4000}},
+				{Name: "value", Type: catalog.Type{Name: "nvarchar", Length: catalog.MaxLength}, Nullable: true},
+				{Name: "type", Type: catalog.Type{Name: "int"}},
+			}
+		}
+		for _, item := range r.SchemaDeclarationItems {
+			if item == nil || item.ColumnDefinition == nil {
+				continue
+			}
+			def := item.ColumnDefinition
+			src.Columns = append(src.Columns, &catalog.Column{
+				Name:     identifierValue(def.ColumnIdentifier),
+				Type:     catalog.TypeOf(def.DataType),
+				Nullable: true,
+			})
+		}
+		alias(src, r.Alias)
+		sc.add(src)
+	case *ast.QualifiedJoin:
+		first := len(sc.sources)
+		b.tableReference(r.FirstTableReference, sc, outer)
+		second := len(sc.sources)
+		b.tableReference(r.SecondTableReference, sc, outer)
+		b.expr(r.SearchCondition, sc)
+		switch r.QualifiedJoinType {
+		case "LeftOuter":
+			nullable(sc.sources[second:])
+		case "RightOuter":
+			nullable(sc.sources[first:second])
+		case "FullOuter":
+			nullable(sc.sources[first:])
+		}
+	case *ast.UnqualifiedJoin:
+		b.tableReference(r.FirstTableReference, sc, outer)
+		second := len(sc.sources)
+		switch r.UnqualifiedJoinType {
+		case "CrossApply":
+			b.tableReference(r.SecondTableReference, sc, sc)
+		case "OuterApply":
+			b.tableReference(r.SecondTableReference, sc, sc)
+			nullable(sc.sources[second:])
+		default:
+			b.tableReference(r.SecondTableReference, sc, outer)
+		}
+	case *ast.JoinParenthesisTableReference:
+		b.tableReference(r.Join, sc, outer)
+	case nil:
+	default:
+		// Rowset functions and other sources whose columns are not known
+		// are still visible by alias.
+		sc.add(&Source{Kind: SourceUnknown, Name: identifierValue(astutil.Alias(ref)), Reference: ref, aliased: true})
+	}
+}
+
+// namedSource returns the source for a named table reference: a CTE, a
+// trigger's inserted or deleted table, a table declared by the script, or a
+// table, view or synonym in the catalog.
+func (b *binder) namedSource(r *ast.NamedTableReference, sc *scope) *Source {
+	n := catalog.NameOf(r.SchemaObject)
+	src := &Source{Kind: SourceUnknown, Name: n.Name, Object: n.String(), Reference: r, object: n}
+	defer alias(src, r.Alias)
+	if n.Schema == "" {
+		if c := sc.cte(n.Name); c != nil {
+			src.Kind = SourceCTE
+			src.Columns = c.Columns
+			src.object = catalog.ObjectName{}
+			return src
+		}
+		if b.trigger != nil {
+			kind := ""
+			switch strings.ToLower(n.Name) {
+			case "inserted":
+				kind = SourceInserted
+			case "deleted":
+				kind = SourceDeleted
+			}
+			if kind != "" {
+				src.Kind = kind
+				src.Columns = b.trigger.Columns
+				src.object = catalog.ObjectName{Schema: b.trigger.Schema, Name: b.trigger.Name}
+				src.Object = src.object.String()
+				return src
+			}
+		}
+	}
+	if t := b.table(r.SchemaObject); t != nil {
+		src.Kind = SourceTable
+		src.Columns = t.Columns
+		return src
+	}
+	if b.cat == nil {
+		return src
+	}
+	if syn := b.cat.Synonym(n); syn != nil {
+		n = catalog.NameOf(syn.Target)
+	}
+	if t := b.cat.Table(n); t != nil {
+		src.Kind = SourceTable
+		src.Columns = t.Columns
+	} else if v := b.cat.View(n); v != nil {
+		src.Kind = SourceView
+		src.Columns = v.Columns
+		if v.Column("*") != nil {
+			// SELECT * over a source that was not in the catalog.
+			src.Columns = nil
+		}
+	}
+	return src
+}
+
+func nullable(sources []*Source) {
+	for _, src := range sources {
+		src.Nullable = true
+	}
+}
+
+func alias(src *Source, id *ast.Identifier) {
+	if id != nil {
+		src.Name = id.Value
+		src.aliased = true
+	}
+}
+
+// renamed returns cols with the given names applied in order. When the
+// columns are unknown but names are given, the names are known and the
+// types are not.
+func (b *binder) renamed(cols []*catalog.Column, names []string) []*catalog.Column {
+	if cols == nil {
+		if len(names) == 0 {
+			return nil
+		}
+		cols = make([]*catalog.Column, len(names))
+		for i := range cols {
+			cols[i] = &catalog.Column{Nullable: true}
+		}
+	}
+	out := make([]*catalog.Column, len(cols))
+	for i, c := range cols {
+		if i < len(names) && names[i] != "" && names[i] != c.Name {
+			copied := *c
+			copied.Name = names[i]
+			if e, ok := b.result.exprs[c]; ok {
+				b.result.exprs[&copied] = e
+			}
+			c = &copied
+		}
+		out[i] = c
+	}
+	return out
+}
+
+func identifierValue(id *ast.Identifier) string {
+	if id == nil {
+		return ""
+	}
+	return id.Value
+}
+
+func identifierValues(ids []*ast.Identifier) []string {
+	out := make([]string, len(ids))
+	for i, id := range ids {
+		out[i] = identifierValue(id)
+	}
+	return out
+}
@@ -0,0 +1,424 @@
+package binder
+
+import (
+	"strings"
+
+	"github.com/sqlc-dev/teesql/ast"
+	"github.com/sqlc-dev/teesql/catalog"
+)
+
+// selectStatement binds a SELECT statement and returns its output columns,
+// or nil if they are not known. SELECT INTO declares the new table.
+func (b *binder) selectStatement(s *ast.SelectStatement) []*catalog.Column {
+	sc := b.withCtes(s.WithCtesAndXmlNamespaces)
+	cols := b.query(s.QueryExpression, sc)
+	if s.Into != nil {
+		n := catalog.NameOf(s.Into)
+		b.local[localKey(s.Into)] = &catalog.Table{Schema: n.Schema, Name: n.Name, Columns: cols}
+	}
+	return cols
+}
+
+// withCtes returns a scope in which the CTEs of w are defined. The anchor
+// of a recursive CTE defines its columns before the recursive member is
+// bound.
+func (b *binder) withCtes(w *ast.WithCtesAndXmlNamespaces) *scope {
+	sc := newScope(nil)
+	if w == nil {
+		return sc
+	}
+	for _, def := range w.CommonTableExpressions {
+		if def == nil || def.ExpressionName == nil {
+			continue
+		}
+		src := &Source{Kind: SourceCTE, Name: def.ExpressionName.Value, aliased: true}
+		sc.ctes = append(sc.ctes, src)
+		names := identifierValues(def.Columns)
+		if union, ok := def.QueryExpression.(*ast.BinaryQueryExpression); ok {
+			src.Columns = b.renamed(b.query(union.FirstQueryExpression, sc), names)
+			b.query(union.SecondQueryExpression, sc)
+			continue
+		}
+		src.Columns = b.renamed(b.query(def.QueryExpression, sc), names)
+	}
+	return sc
+}
+
+// query binds a query expression in a scope nested in parent and returns
+// its output columns, or nil if they are not known.
+func (b *binder) query(q ast.QueryExpression, parent *scope) []*catalog.Column {
+	cols := b.queryColumns(q, parent)
+	if cols != nil {
+		b.result.queries[q] = cols
+	}
+	return cols
+}
+
+func (b *binder) queryColumns(q ast.QueryExpression, parent *scope) []*catalog.Column {
+	switch q := q.(type) {
+	case *ast.QuerySpecification:
+		return b.querySpecification(q, parent)
+	case *ast.QueryParenthesisExpression:
+		return b.query(q.QueryExpression, parent)
+	case *ast.BinaryQueryExpression:
+		cols := b.query(q.FirstQueryExpression, parent)
+		b.query(q.SecondQueryExpression, parent)
+		if q.OrderByClause != nil {
+			outputs := &Source{Kind: SourceDerived, Columns: cols}
+			b.orderBy(q.OrderByClause, newScope(parent, outputs), names(cols))
+		}
+		return cols
+	}
+	return nil
+}
+
+func (b *binder) querySpecification(q *ast.QuerySpecification, parent *scope) []*catalog.Column {
+	sc := newScope(parent)
+	b.from(q.FromClause, sc)
+	if q.TopRowFilter != nil {
+		b.expr(q.TopRowFilter, sc)
+	}
+	if q.WhereClause != nil {
+		b.expr(q.WhereClause, sc)
+	}
+	if q.GroupByClause != nil {
+		b.expr(q.GroupByClause, sc)
+	}
+	if q.HavingClause != nil {
+		b.expr(q.HavingClause, sc)
+	}
+	if q.WindowClause != nil {
+		b.expr(q.WindowClause, sc)
+	}
+	cols, known := b.selectElements(q.SelectElements, sc)
+	var aliases []string
+	for _, elem := range q.SelectElements {
+		if e, ok := elem.(*ast.SelectScalarExpression); ok && e.ColumnName != nil {
+			aliases = append(aliases, columnAlias(e.ColumnName))
+		}
+	}
+	if q.OrderByClause != nil {
+		b.orderBy(q.OrderByClause, sc, aliases)
+	}
+	if q.OffsetClause != nil {
+		b.expr(q.OffsetClause, sc)
+	}
+	if !known {
+		return nil
+	}
+	return cols
+}
+
+// orderBy binds an ORDER BY clause. Unqualified names of output columns
+// refer to the select list rather than to a source.
+func (b *binder) orderBy(clause *ast.OrderByClause, sc *scope, outputs []string) {
+	for _, elem := range clause.OrderByElements {
+		if elem == nil {
+			continue
+		}
+		if ref, ok := elem.Expression.(*ast.ColumnReferenceExpression); ok {
+			if parts := refParts(ref); len(parts) == 1 && containsFold(outputs, parts[0]) {
+				continue
+			}
+		}
+		b.expr(elem.Expression, sc)
+	}
+}
+
+// selectElements binds a select list and returns its output columns. known
+// is false if a star expands a source whose columns are not known.
+func (b *binder) selectElements(elems []ast.SelectElement, sc *scope) (cols []*catalog.Column, known bool) {
+	known = true
+	for _, elem := range elems {
+		switch e := elem.(type) {
+		case *ast.SelectScalarExpression:
+			b.expr(e.Expression, sc)
+			cols = append(cols, b.outputColumn(e))
+		case *ast.SelectStarExpression:
+			expanded, ok := b.star(e, sc)
+			cols = append(cols, expanded...)
+			known = known && ok
+		case *ast.SelectSetVariable:
+			b.expr(e.Expression, sc)
+		}
+	}
+	return cols, known
+}
+
+// outputColumn returns the column produced by a select list expression. A
+// plain column keeps the type and nullability of the column it reads.
+func (b *binder) outputColumn(e *ast.SelectScalarExpression) *catalog.Column {
+	col := &catalog.Column{Nullable: true}
+	if ref, ok := e.Expression.(*ast.ColumnReferenceExpression); ok {
+		if binding := b.result.Lookup(ref); binding != nil && binding.Column != nil {
+			copied := *binding.Column
+			copied.Nullable = copied.Nullable || binding.Source.Nullable
+			col = &copied
+		} else if parts := refParts(ref); len(parts) > 0 {
+			col.Name = parts[len(parts)-1]
+		} else if ref.ColumnType == "PseudoColumnAction" {
+			col.Name = "$action"
+		}
+	}
+	if e.ColumnName != nil {
+		col.Name = columnAlias(e.ColumnName)
+	}
+	b.result.exprs[col] = e.Expression
+	return col
+}
+
+// star expands a select list star. ok is false if a matching source's
+// columns are not known.
+func (b *binder) star(e *ast.SelectStarExpression, sc *scope) (cols []*catalog.Column, ok bool) {
+	var qualifier []string
+	if e.Qualifier != nil {
+		for _, id := range e.Qualifier.Identifiers {
+			qualifier = append(qualifier, identifierValue(id))
+		}
+	}
+	ok = true
+	matched := false
+	for _, src := range sc.sources {
+		if len(qualifier) > 0 && !src.matches(qualifier) {
+			continue
+		}
+		matched = true
+		if src.Columns == nil {
+			ok = false
+		}
+		for _, col := range src.Columns {
+			if src.Nullable && !col.Nullable {
+				copied := *col
+				copied.Nullable = true
+				if e, ok := b.result.exprs[col]; ok {
+					b.result.exprs[&copied] = e
+				}
+				col = &copied
+			}
+			b.result.origins[col] = src
+			cols = append(cols, col)
+		}
+	}
+	if !matched && len(qualifier) > 0 {
+		b.result.Errors = append(b.result.Errors, &Error{
+			Pos:     qualifierPos(e.Qualifier),
+			Message: "multi-part identifier \"" + strings.Join(qualifier, ".") + "\" could not be bound",
+		})
+		return nil, false
+	}
+	return cols, ok
+}
+
+func (b *binder) insert(s *ast.InsertStatement) {
+	spec := s.InsertSpecification
+	if spec == nil {
+		return
+	}
+	sc := b.withCtes(s.WithCtesAndXmlNamespaces)
+	if spec.TopRowFilter != nil {
+		b.expr(spec.TopRowFilter, sc)
+	}
+	target := b.target(spec.Target, sc)
+	ts := newScope(nil, target)
+	for _, col := range spec.Columns {
+		b.resolve(col, ts)
+	}
+	switch src := spec.InsertSource.(type) {
+	case *ast.ValuesInsertSource:
+		for _, row := range src.RowValues {
+			if row != nil {
+				b.expr(row, sc)
+			}
+		}
+	case *ast.SelectInsertSource:
+		b.query(src.Select, sc)
+	}
+	b.output(spec.OutputClause, spec.OutputIntoClause, sc, target, SourceInserted)
+}
+
+func (b *binder) update(s *ast.UpdateStatement) {
+	spec := s.UpdateSpecification
+	if spec == nil {
+		return
+	}
+	sc := b.withCtes(s.WithCtesAndXmlNamespaces)
+	if spec.TopRowFilter != nil {
+		b.expr(spec.TopRowFilter, sc)
+	}
+	fs, target := b.modificationScope(spec.Target, spec.FromClause, sc)
+	b.setClauses(spec.SetClauses, target, fs)
+	if spec.WhereClause != nil {
+		b.expr(spec.WhereClause, fs)
+	}
+	b.output(spec.OutputClause, spec.OutputIntoClause, fs, target, SourceInserted, SourceDeleted)
+}
+
+func (b *binder) delete(s *ast.DeleteStatement) {
+	spec := s.DeleteSpecification
+	if spec == nil {
+		return
+	}
+	sc := b.withCtes(s.WithCtesAndXmlNamespaces)
+	if spec.TopRowFilter != nil {
+		b.expr(spec.TopRowFilter, sc)
+	}
+	fs, target := b.modificationScope(spec.Target, spec.FromClause, sc)
+	if spec.WhereClause != nil {
+		b.expr(spec.WhereClause, fs)
+	}
+	b.output(spec.OutputClause, spec.OutputIntoClause, fs, target, SourceDeleted)
+}
+
+func (b *binder) merge(s *ast.MergeStatement) {
+	spec := s.MergeSpecification
+	if spec == nil {
+		return
+	}
+	sc := b.withCtes(s.WithCtesAndXmlNamespaces)
+	if spec.TopRowFilter != nil {
+		b.expr(spec.TopRowFilter, sc)
+	}
+	target := b.target(spec.Target, sc)
+	alias(target, spec.TableAlias)
+	ms := newScope(sc, target)
+	b.tableReference(spec.TableReference, ms, sc)
+	b.expr(spec.SearchCondition, ms)
+	ts := newScope(nil, target)
+	for _, clause := range spec.ActionClauses {
+		if clause == nil {
+			continue
+		}
+		b.expr(clause.SearchCondition, ms)
+		switch a := clause.Action.(type) {
+		case *ast.UpdateMergeAction:
+			b.setClauses(a.SetClauses, target, ms)
+		case *ast.InsertMergeAction:
+			for _, col := range a.Columns {
+				b.resolve(col, ts)
+			}
+			if values, ok := a.Source.(*ast.ValuesInsertSource); ok {
+				for _, row := range values.RowValues {
+					if row != nil {
+						b.expr(row, ms)
+					}
+				}
+			}
+		}
+	}
+	b.output(spec.OutputClause, nil, ms, target, SourceInserted, SourceDeleted)
+}
+
+// target returns the source for the target of an INSERT or MERGE.
+func (b *binder) target(ref ast.TableReference, sc *scope) *Source {
+	ts := newScope(sc)
+	b.tableReference(ref, ts, sc)
+	if len(ts.sources) == 0 {
+		return &Source{Kind: SourceUnknown, Reference: ref}
+	}
+	return ts.sources[0]
+}
+
+// modificationScope returns the scope of an UPDATE or DELETE and its
+// target. A target named by an alias of the FROM clause resolves to that
+// source; otherwise the target is added to the FROM clause's sources.
+func (b *binder) modificationScope(ref ast.TableReference, from *ast.FromClause, sc *scope) (*scope, *Source) {
+	fs := newScope(sc)
+	b.from(from, fs)
+	if named, ok := ref.(*ast.NamedTableReference); ok && named.Alias == nil && named.SchemaObject != nil {
+		n := catalog.NameOf(named.SchemaObject)
+		for _, src := range fs.sources {
+			if n.Schema == "" && strings.EqualFold(src.Name, n.Name) || !src.aliased && src.matches([]string{schemaOf(n), n.Name}) {
+				return fs, src
+			}
+		}
+	}
+	target := b.target(ref, sc)
+	fs.add(target)
+	return fs, target
+}
+
+func schemaOf(n catalog.ObjectName) string {
+	if n.Schema == "" {
+		return catalog.DefaultSchema
+	}
+	return n.Schema
+}
+
+// setClauses binds the SET clauses of an UPDATE or MERGE. Assigned columns
+// belong to the target; values may read any source in sc.
+func (b *binder) setClauses(clauses []ast.SetClause, target *Source, sc *scope) {
+	ts := newScope(nil, target)
+	for _, clause := range clauses {
+		switch c := clause.(type) {
+		case *ast.AssignmentSetClause:
+			if c.Column != nil {
+				b.resolve(c.Column, ts)
+			}
+			b.expr(c.NewValue, sc)
+		case *ast.FunctionCallSetClause:
+			if c.MutatorFunction != nil {
+				for _, p := range c.MutatorFunction.Parameters {
+					b.expr(p, sc)
+				}
+			}
+		}
+	}
+}
+
+// output binds OUTPUT and OUTPUT INTO clauses, in which the inserted and
+// deleted tables have the target's columns.
+func (b *binder) output(oc *ast.OutputClause, into *ast.OutputIntoClause, sc *scope, target *Source, kinds ...string) {
+	if oc == nil && into == nil {
+		return
+	}
+	os := newScope(sc)
+	for _, kind := range kinds {
+		os.add(&Source{Kind: kind, Name: strings.ToLower(kind), Columns: target.Columns, Object: target.Object, object: target.object, aliased: true})
+	}
+	if oc != nil {
+		if cols, known := b.selectElements(oc.SelectColumns, os); known {
+			b.result.outputs[oc] = cols
+		}
+	}
+	if into != nil {
+		b.selectElements(into.SelectColumns, os)
+		it := newScope(nil)
+		b.tableReference(into.IntoTable, it, nil)
+		for _, col := range into.IntoTableColumns {
+			b.resolve(col, it)
+		}
+	}
+}
+
+func columnAlias(v *ast.IdentifierOrValueExpression) string {
+	if v.Identifier != nil {
+		return v.Identifier.Value
+	}
+	return v.Value
+}
+
+func names(cols []*catalog.Column) []string {
+	out := make([]string, len(cols))
+	for i, c := range cols {
+		out[i] = c.Name
+	}
+	return out
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func qualifierPos(mpi *ast.MultiPartIdentifier) ast.Position {
+	for _, id := range mpi.Identifiers {
+		if id != nil && id.Pos.IsValid() {
+			return id.Pos
+		}
+	}
+	return ast.Position{}
+}
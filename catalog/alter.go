@@ -0,0 +1,533 @@
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/sqlc-dev/teesql/ast"
+)
+
+func (c *Catalog) alterTableAdd(s *ast.AlterTableAddTableElementStatement) error {
+	name := c.qualify(NameOf(s.SchemaObjectName))
+	t := c.Table(name)
+	if t == nil {
+		return notFoundError("ALTER TABLE: table", name)
+	}
+	if s.Definition == nil {
+		return nil
+	}
+	var errs []error
+	for _, cd := range s.Definition.ColumnDefinitions {
+		col := identifierValue(cd.ColumnIdentifier)
+		if t.Column(col) != nil {
+			errs = append(errs, existsError("ALTER TABLE ADD: column", columnObject(name, col)))
+			continue
+		}
+		addColumn(t, cd)
+	}
+	for _, tc := range s.Definition.TableConstraints {
+		if err := checkConstraintName(t, name, constraintName(tc)); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if d, ok := tc.(*ast.DefaultConstraintDefinition); ok && d.Column != nil && t.Column(d.Column.Value) == nil {
+			errs = append(errs, notFoundError("ALTER TABLE ADD DEFAULT: column", columnObject(name, d.Column.Value)))
+			continue
+		}
+		addConstraint(t, tc, nil)
+	}
+	for _, ix := range s.Definition.Indexes {
+		if ix.Name != nil && t.Index(ix.Name.Value) != nil {
+			errs = append(errs, existsError("ALTER TABLE ADD: index "+ix.Name.Value+" on", name))
+			continue
+		}
+		t.Indexes = append(t.Indexes, indexDefinition(ix))
+	}
+	return errors.Join(errs...)
+}
+
+func constraintName(tc ast.TableConstraint) string {
+	switch c := tc.(type) {
+	case *ast.UniqueConstraintDefinition:
+		return identifierValue(c.ConstraintIdentifier)
+	case *ast.ForeignKeyConstraintDefinition:
+		return identifierValue(c.ConstraintIdentifier)
+	case *ast.CheckConstraintDefinition:
+		return identifierValue(c.ConstraintIdentifier)
+	case *ast.DefaultConstraintDefinition:
+		return identifierValue(c.ConstraintIdentifier)
+	}
+	return ""
+}
+
+// checkConstraintName reports an error if t already has a constraint or
+// default named name.
+func checkConstraintName(t *Table, table ObjectName, name string) error {
+	if name == "" {
+		return nil
+	}
+	if t.Constraint(name) != nil || t.defaultNamed(name) != nil {
+		return existsError("ALTER TABLE ADD: constraint "+name+" on", table)
+	}
+	return nil
+}
+
+func (t *Table) defaultNamed(name string) *Column {
+	for _, col := range t.Columns {
+		if col.Default != nil && col.Default.Name != "" && strings.EqualFold(col.Default.Name, name) {
+			return col
+		}
+	}
+	return nil
+}
+
+// columnObject returns the name of a column for use in error messages.
+func columnObject(table ObjectName, column string) ObjectName {
+	return ObjectName{Schema: table.String(), Name: column}
+}
+
+func (c *Catalog) alterColumn(s *ast.AlterTableAlterColumnStatement) error {
+	name := c.qualify(NameOf(s.SchemaObjectName))
+	t := c.Table(name)
+	if t == nil {
+		return notFoundError("ALTER COLUMN: table", name)
+	}
+	colName := identifierValue(s.ColumnIdentifier)
+	col := t.Column(colName)
+	if col == nil {
+		return notFoundError("ALTER COLUMN: column", columnObject(name, colName))
+	}
+	if col.Computed != nil && s.DataType != nil {
+		return fmt.Errorf("ALTER COLUMN: column %s is computed", columnObject(name, col.Name))
+	}
+	switch s.AlterTableAlterColumnOption {
+	case "AddRowGuidCol":
+		col.RowGuidCol = true
+		return nil
+	case "DropRowGuidCol":
+		col.RowGuidCol = false
+		return nil
+	case "AddHidden", "DropHidden":
+		col.Hidden = s.AlterTableAlterColumnOption == "AddHidden"
+		return nil
+	case "AddMaskingFunction":
+		col.Mask = maskingFunction(s.MaskingFunction)
+		return nil
+	case "DropMaskingFunction":
+		col.Mask = ""
+		return nil
+	}
+	if s.DataType == nil {
+		return nil
+	}
+	col.Type = TypeOf(s.DataType)
+	col.Collation = identifierValue(s.Collation)
+	// The column is encrypted as the new definition says, and decrypted if
+	// it says nothing.
+	col.Encryption = encryption(s.Encryption)
+	// Without NULL or NOT NULL, ALTER COLUMN makes the column nullable.
+	col.Nullable = s.AlterTableAlterColumnOption != "NotNull"
+	return nil
+}
+
+func (c *Catalog) alterTableDrop(s *ast.AlterTableDropTableElementStatement) error {
+	name := c.qualify(NameOf(s.SchemaObjectName))
+	t := c.Table(name)
+	if t == nil {
+		return notFoundError("ALTER TABLE DROP: table", name)
+	}
+	var errs []error
+	for _, elem := range s.AlterTableDropTableElements {
+		if err := dropTableElement(t, name, elem); err != nil && !(elem.IsIfExists && errors.Is(err, ErrNotFound)) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func dropTableElement(t *Table, table ObjectName, elem *ast.AlterTableDropTableElement) error {
+	elemName := identifierValue(elem.Name)
+	switch elem.TableElementType {
+	case "Column":
+		col := t.Column(elemName)
+		if col == nil {
+			return notFoundError("ALTER TABLE DROP COLUMN: column", columnObject(table, elemName))
+		}
+		// SQL Server refuses to drop a column that named constraints or
+		// indexes depend on. Unnamed ones are assumed to have been dropped
+		// by dynamic SQL the model cannot follow, and go with the column.
+		for _, cons := range t.Constraints {
+			if cons.Name != "" && containsFold(cons.Columns, col.Name) {
+				return fmt.Errorf("ALTER TABLE DROP COLUMN: constraint %s depends on column %s", cons.Name, columnObject(table, col.Name))
+			}
+		}
+		for _, ix := range t.Indexes {
+			if ix.Name != "" && ix.references(col.Name) {
+				return fmt.Errorf("ALTER TABLE DROP COLUMN: index %s depends on column %s", ix.Name, columnObject(table, col.Name))
+			}
+		}
+		t.Constraints = filter(t.Constraints, func(cons *Constraint) bool { return !containsFold(cons.Columns, col.Name) })
+		t.Indexes = filter(t.Indexes, func(ix *Index) bool { return !ix.references(col.Name) })
+		t.Columns = filter(t.Columns, func(c *Column) bool { return c != col })
+	case "Index":
+		if t.Index(elemName) == nil {
+			return notFoundError("ALTER TABLE DROP INDEX: index "+elemName+" on", table)
+		}
+		t.Indexes = filter(t.Indexes, func(ix *Index) bool { return !strings.EqualFold(ix.Name, elemName) })
+	case "Period":
+		// PERIOD FOR SYSTEM_TIME is not modelled.
+	default:
+		// CONSTRAINT, or a name without a keyword.
+		if col := t.defaultNamed(elemName); col != nil {
+			col.Default = nil
+			return nil
+		}
+		if t.Constraint(elemName) == nil {
+			return notFoundError("ALTER TABLE DROP CONSTRAINT: constraint "+elemName+" on", table)
+		}
+		t.Constraints = filter(t.Constraints, func(cons *Constraint) bool { return !strings.EqualFold(cons.Name, elemName) })
+	}
+	return nil
+}
+
+func (ix *Index) references(column string) bool {
+	for _, col := range ix.Columns {
+		if strings.EqualFold(col.Name, column) {
+			return true
+		}
+	}
+	return containsFold(ix.Include, column)
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// filter returns the elements of list for which keep returns true. It
+// reuses the backing array of list.
+func filter[T any](list []T, keep func(T) bool) []T {
+	out := list[:0]
+	for _, v := range list {
+		if keep(v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func (c *Catalog) alterSequence(s *ast.AlterSequenceStatement) error {
+	name := c.qualify(NameOf(s.Name))
+	q := c.Sequence(name)
+	if q == nil {
+		return notFoundError("ALTER SEQUENCE: sequence", name)
+	}
+	q.setOptions(s.SequenceOptions)
+	return nil
+}
+
+// alterSchema handles ALTER SCHEMA ... TRANSFER.
+func (c *Catalog) alterSchema(s *ast.AlterSchemaStatement) error {
+	if s.Name == nil || s.ObjectName == nil {
+		return nil
+	}
+	to := c.Schema(s.Name.Value)
+	if to == nil {
+		return notFoundError("ALTER SCHEMA: schema", ObjectName{Name: s.Name.Value})
+	}
+	name := c.qualify(NameOf(s.ObjectName))
+	from := c.Schema(name.Schema)
+	if s.ObjectKind == "Type" {
+		if from == nil || from.Type(name.Name) == nil {
+			return notFoundError("ALTER SCHEMA TRANSFER: type", name)
+		}
+		if to.Type(name.Name) != nil {
+			return existsError("ALTER SCHEMA TRANSFER: type", ObjectName{Schema: to.Name, Name: name.Name})
+		}
+		udt := from.Type(name.Name)
+		from.Types = filter(from.Types, func(t *UserDefinedType) bool { return t != udt })
+		udt.Schema = to.Name
+		if udt.Table != nil {
+			udt.Table.Schema = to.Name
+		}
+		to.Types = append(to.Types, udt)
+		return nil
+	}
+	if from == nil || !from.exists(name.Name) {
+		return notFoundError("ALTER SCHEMA TRANSFER: object", name)
+	}
+	if to.exists(name.Name) {
+		return existsError("ALTER SCHEMA TRANSFER: object", ObjectName{Schema: to.Name, Name: name.Name})
+	}
+	c.moveObject(from, name.Name, to, name.Name)
+	return nil
+}
+
+// moveObject moves the object named oldName in from to newName in to. The
+// schemas may be the same. Foreign keys referencing a moved table are
+// updated.
+func (c *Catalog) moveObject(from *Schema, oldName string, to *Schema, newName string) {
+	if t := from.Table(oldName); t != nil {
+		old := ObjectName{Schema: t.Schema, Name: t.Name}
+		from.Tables = filter(from.Tables, func(x *Table) bool { return x != t })
+		t.Schema, t.Name = to.Name, newName
+		to.Tables = append(to.Tables, t)
+		c.eachForeignKey(func(_ *Table, fk *Constraint) {
+			if c.sameObject(fk.References, old) {
+				fk.References = ObjectName{Schema: t.Schema, Name: t.Name}
+			}
+		})
+	}
+	if v := from.View(oldName); v != nil {
+		from.Views = filter(from.Views, func(x *View) bool { return x != v })
+		v.Schema, v.Name = to.Name, newName
+		to.Views = append(to.Views, v)
+	}
+	if f := from.Function(oldName); f != nil {
+		from.Functions = filter(from.Functions, func(x *Function) bool { return x != f })
+		f.Schema, f.Name = to.Name, newName
+		to.Functions = append(to.Functions, f)
+	}
+	if p := from.Procedure(oldName); p != nil {
+		from.Procedures = filter(from.Procedures, func(x *Procedure) bool { return x != p })
+		p.Schema, p.Name = to.Name, newName
+		to.Procedures = append(to.Procedures, p)
+	}
+	if q := from.Sequence(oldName); q != nil {
+		from.Sequences = filter(from.Sequences, func(x *Sequence) bool { return x != q })
+		q.Schema, q.Name = to.Name, newName
+		to.Sequences = append(to.Sequences, q)
+	}
+	if y := from.Synonym(oldName); y != nil {
+		from.Synonyms = filter(from.Synonyms, func(x *Synonym) bool { return x != y })
+		y.Schema, y.Name = to.Name, newName
+		to.Synonyms = append(to.Synonyms, y)
+	}
+}
+
+func (c *Catalog) eachForeignKey(f func(t *Table, fk *Constraint)) {
+	for _, s := range c.Schemas {
+		for _, t := range s.Tables {
+			for _, cons := range t.Constraints {
+				if cons.Kind == ForeignKey {
+					f(t, cons)
+				}
+			}
+		}
+	}
+}
+
+// sameObject reports whether two names refer to the same object.
+func (c *Catalog) sameObject(a, b ObjectName) bool {
+	a, b = c.qualify(a), c.qualify(b)
+	return strings.EqualFold(a.Schema, b.Schema) && strings.EqualFold(a.Name, b.Name)
+}
+
+// renameObject handles the Azure Synapse RENAME OBJECT statement.
+func (c *Catalog) renameObject(s *ast.RenameEntityStatement) error {
+	if s.RenameEntityType != "Object" || s.NewName == nil {
+		return nil
+	}
+	return c.rename(c.qualify(NameOf(s.OldName)), s.NewName.Value)
+}
+
+// rename renames the object name to newName within its schema.
+func (c *Catalog) rename(name ObjectName, newName string) error {
+	schema := c.Schema(name.Schema)
+	if schema == nil || !schema.exists(name.Name) {
+		return notFoundError("rename: object", name)
+	}
+	if !strings.EqualFold(name.Name, newName) && schema.exists(newName) {
+		return existsError("rename: object", ObjectName{Schema: schema.Name, Name: newName})
+	}
+	c.moveObject(schema, name.Name, schema, newName)
+	return nil
+}
+
+// spRename handles EXEC sp_rename, reporting whether stmt is such a call.
+// The parameters must be string literals.
+func (c *Catalog) spRename(exec *ast.ExecuteStatement) (bool, error) {
+	if exec.ExecuteSpecification == nil {
+		return false, nil
+	}
+	ref, ok := exec.ExecuteSpecification.ExecutableEntity.(*ast.ExecutableProcedureReference)
+	if !ok || ref.ProcedureReference == nil || ref.ProcedureReference.ProcedureReference == nil {
+		return false, nil
+	}
+	proc := NameOf(ref.ProcedureReference.ProcedureReference.Name)
+	if !strings.EqualFold(proc.Name, "sp_rename") {
+		return false, nil
+	}
+	// Positional and named arguments: @objname, @newname, @objtype.
+	args := make([]string, 3)
+	names := []string{"@objname", "@newname", "@objtype"}
+	for i, p := range ref.Parameters {
+		lit, ok := p.ParameterValue.(*ast.StringLiteral)
+		if !ok {
+			return true, fmt.Errorf("sp_rename: argument %d is not a string literal", i+1)
+		}
+		pos := i
+		if p.Variable != nil {
+			pos = -1
+			for j, n := range names {
+				if strings.EqualFold(p.Variable.Name, n) {
+					pos = j
+				}
+			}
+		}
+		if pos >= 0 && pos < len(args) {
+			args[pos] = lit.Value
+		}
+	}
+	if args[0] == "" || args[1] == "" {
+		return true, fmt.Errorf("sp_rename: missing @objname or @newname")
+	}
+	return true, c.spRenameArgs(args[0], args[1], args[2])
+}
+
+func (c *Catalog) spRenameArgs(objname, newname, objtype string) error {
+	parts := splitName(objname)
+	switch strings.ToUpper(objtype) {
+	case "COLUMN":
+		if len(parts) < 2 {
+			return fmt.Errorf("sp_rename: %q is not a column name", objname)
+		}
+		table := c.qualify(ObjectName{Name: parts[len(parts)-2]})
+		if len(parts) > 2 {
+			table.Schema = parts[len(parts)-3]
+		}
+		return c.renameColumn(table, parts[len(parts)-1], newname)
+	case "INDEX":
+		if len(parts) < 2 {
+			return fmt.Errorf("sp_rename: %q is not an index name", objname)
+		}
+		table := c.qualify(ObjectName{Name: parts[len(parts)-2]})
+		if len(parts) > 2 {
+			table.Schema = parts[len(parts)-3]
+		}
+		t := c.Table(table)
+		if t == nil {
+			return notFoundError("sp_rename: table", table)
+		}
+		ix := t.Index(parts[len(parts)-1])
+		if ix == nil {
+			return notFoundError("sp_rename: index "+parts[len(parts)-1]+" on", table)
+		}
+		ix.Name = newname
+		return nil
+	case "", "OBJECT":
+		name := c.qualify(ObjectName{Name: parts[len(parts)-1]})
+		if len(parts) > 1 {
+			name.Schema = parts[len(parts)-2]
+		}
+		// Constraints share the object namespace of their schema.
+		if s := c.Schema(name.Schema); s != nil && !s.exists(name.Name) {
+			for _, t := range s.Tables {
+				if cons := t.Constraint(name.Name); cons != nil {
+					cons.Name = newname
+					return nil
+				}
+				if col := t.defaultNamed(name.Name); col != nil {
+					col.Default.Name = newname
+					return nil
+				}
+			}
+		}
+		return c.rename(name, newname)
+	case "USERDATATYPE":
+		name := c.qualify(ObjectName{Name: parts[len(parts)-1]})
+		if len(parts) > 1 {
+			name.Schema = parts[len(parts)-2]
+		}
+		udt := c.Type(name)
+		if udt == nil {
+			return notFoundError("sp_rename: type", name)
+		}
+		udt.Name = newname
+		if udt.Table != nil {
+			udt.Table.Name = newname
+		}
+		return nil
+	}
+	// DATABASE and STATISTICS renames do not affect the model.
+	return nil
+}
+
+// renameColumn renames a column and the references to it from the table's
+// constraints and indexes and from foreign keys of other tables.
+func (c *Catalog) renameColumn(table ObjectName, oldName, newName string) error {
+	t := c.Table(table)
+	if t == nil {
+		return notFoundError("sp_rename: table", table)
+	}
+	col := t.Column(oldName)
+	if col == nil {
+		return notFoundError("sp_rename: column", columnObject(table, oldName))
+	}
+	if !strings.EqualFold(oldName, newName) && t.Column(newName) != nil {
+		return existsError("sp_rename: column", columnObject(table, newName))
+	}
+	col.Name = newName
+	for _, cons := range t.Constraints {
+		replaceFold(cons.Columns, oldName, newName)
+	}
+	for _, ix := range t.Indexes {
+		for i := range ix.Columns {
+			if strings.EqualFold(ix.Columns[i].Name, oldName) {
+				ix.Columns[i].Name = newName
+			}
+		}
+		replaceFold(ix.Include, oldName, newName)
+	}
+	c.eachForeignKey(func(_ *Table, fk *Constraint) {
+		if c.sameObject(fk.References, table) {
+			replaceFold(fk.ReferencedColumns, oldName, newName)
+		}
+	})
+	return nil
+}
+
+func replaceFold(list []string, old, new string) {
+	for i, v := range list {
+		if strings.EqualFold(v, old) {
+			list[i] = new
+		}
+	}
+}
+
+// splitName splits a multi-part name such as "dbo.[Order Lines].Id" into
+// its parts, removing brackets and quotes.
+func splitName(name string) []string {
+	var parts []string
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		switch ch := name[i]; ch {
+		case '[':
+			for i++; i < len(name); i++ {
+				if name[i] == ']' {
+					if i+1 < len(name) && name[i+1] == ']' {
+						i++
+					} else {
+						break
+					}
+				}
+				b.WriteByte(name[i])
+			}
+		case '"':
+			for i++; i < len(name) && name[i] != '"'; i++ {
+				b.WriteByte(name[i])
+			}
+		case '.':
+			parts = append(parts, b.String())
+			b.Reset()
+		default:
+			b.WriteByte(ch)
+		}
+	}
+	return append(parts, b.String())
+}
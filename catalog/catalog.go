@@ -0,0 +1,496 @@
+// Package catalog builds an in-memory model of a SQL Server database schema
+// from DDL scripts.
+//
+// A Catalog is populated by applying parsed statements in order. It records
+// schemas, tables (with columns, constraints and indexes), views, functions,
+// procedures, sequences, synonyms and user-defined types. Besides CREATE
+// statements, ALTER, DROP, sp_rename and RENAME OBJECT are replayed, so a
+// folder of migration scripts yields the final schema. Names are matched
+// case-insensitively, as under SQL Server's default collation.
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/sqlc-dev/teesql/ast"
+)
+
+// DefaultSchema is the schema used for object names without a schema.
+const DefaultSchema = "dbo"
+
+// ObjectName is a schema-qualified object name.
+type ObjectName struct {
+	Schema string `json:"Schema,omitempty"`
+	Name   string `json:"Name"`
+}
+
+// NameOf returns the schema and object parts of a multi-part name. Server and
+// database parts are ignored.
+func NameOf(name *ast.SchemaObjectName) ObjectName {
+	if name == nil {
+		return ObjectName{}
+	}
+	var n ObjectName
+	if name.BaseIdentifier != nil {
+		n.Name = name.BaseIdentifier.Value
+	}
+	if name.SchemaIdentifier != nil {
+		n.Schema = name.SchemaIdentifier.Value
+	}
+	return n
+}
+
+// String returns the name in schema.name form.
+func (n ObjectName) String() string {
+	if n.Schema == "" {
+		return n.Name
+	}
+	return n.Schema + "." + n.Name
+}
+
+// Catalog is the schema model of a database.
+type Catalog struct {
+	// DefaultSchema is the schema assumed for unqualified names.
+	DefaultSchema string    `json:"DefaultSchema"`
+	Schemas       []*Schema `json:"Schemas,omitempty"`
+}
+
+// Schema holds the objects of one database schema.
+type Schema struct {
+	Name       string             `json:"Name"`
+	Owner      string             `json:"Owner,omitempty"`
+	Tables     []*Table           `json:"Tables,omitempty"`
+	Views      []*View            `json:"Views,omitempty"`
+	Functions  []*Function        `json:"Functions,omitempty"`
+	Procedures []*Procedure       `json:"Procedures,omitempty"`
+	Sequences  []*Sequence        `json:"Sequences,omitempty"`
+	Synonyms   []*Synonym         `json:"Synonyms,omitempty"`
+	Types      []*UserDefinedType `json:"Types,omitempty"`
+	// SecurityPolicies are the row-level security policies in the schema.
+	SecurityPolicies []*SecurityPolicy `json:"SecurityPolicies,omitempty"`
+
+	// implicit is set for schemas created by a qualified object name rather
+	// than by CREATE SCHEMA.
+	implicit bool
+}
+
+// New returns an empty catalog containing the dbo schema.
+func New() *Catalog {
+	c := &Catalog{DefaultSchema: DefaultSchema}
+	c.Schemas = append(c.Schemas, &Schema{Name: DefaultSchema})
+	return c
+}
+
+// Build returns a catalog populated from the given scripts, applied in order.
+func Build(scripts ...*ast.Script) (*Catalog, error) {
+	c := New()
+	var errs []error
+	for _, script := range scripts {
+		if err := c.Update(script); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return c, errors.Join(errs...)
+}
+
+// Schema returns the named schema, or nil.
+func (c *Catalog) Schema(name string) *Schema {
+	if name == "" {
+		name = c.DefaultSchema
+	}
+	for _, s := range c.Schemas {
+		if strings.EqualFold(s.Name, name) {
+			return s
+		}
+	}
+	return nil
+}
+
+func (c *Catalog) schemaFor(name ObjectName) *Schema {
+	s := c.Schema(name.Schema)
+	if s == nil {
+		// Scripts frequently omit CREATE SCHEMA; create it implicitly.
+		s = &Schema{Name: name.Schema, implicit: true}
+		c.Schemas = append(c.Schemas, s)
+	}
+	return s
+}
+
+// qualify fills in the default schema.
+func (c *Catalog) qualify(name ObjectName) ObjectName {
+	if name.Schema == "" {
+		name.Schema = c.DefaultSchema
+	}
+	return name
+}
+
+// Table returns the named table, or nil.
+func (c *Catalog) Table(name ObjectName) *Table {
+	if s := c.Schema(name.Schema); s != nil {
+		return s.Table(name.Name)
+	}
+	return nil
+}
+
+// View returns the named view, or nil.
+func (c *Catalog) View(name ObjectName) *View {
+	if s := c.Schema(name.Schema); s != nil {
+		return s.View(name.Name)
+	}
+	return nil
+}
+
+// Function returns the named function, or nil.
+func (c *Catalog) Function(name ObjectName) *Function {
+	if s := c.Schema(name.Schema); s != nil {
+		return s.Function(name.Name)
+	}
+	return nil
+}
+
+// Procedure returns the named procedure, or nil.
+func (c *Catalog) Procedure(name ObjectName) *Procedure {
+	if s := c.Schema(name.Schema); s != nil {
+		return s.Procedure(name.Name)
+	}
+	return nil
+}
+
+// Sequence returns the named sequence, or nil.
+func (c *Catalog) Sequence(name ObjectName) *Sequence {
+	if s := c.Schema(name.Schema); s != nil {
+		return s.Sequence(name.Name)
+	}
+	return nil
+}
+
+// Synonym returns the named synonym, or nil.
+func (c *Catalog) Synonym(name ObjectName) *Synonym {
+	if s := c.Schema(name.Schema); s != nil {
+		return s.Synonym(name.Name)
+	}
+	return nil
+}
+
+// Type returns the named user-defined type, or nil.
+func (c *Catalog) Type(name ObjectName) *UserDefinedType {
+	if s := c.Schema(name.Schema); s != nil {
+		return s.Type(name.Name)
+	}
+	return nil
+}
+
+// ResolveType resolves a user-defined alias type to its base system type.
+// Other types are returned unchanged.
+func (c *Catalog) ResolveType(t Type) Type {
+	if !t.IsUserDefined() {
+		return t
+	}
+	udt := c.Type(ObjectName{Schema: t.Schema, Name: t.Name})
+	if udt == nil || udt.BaseType == nil {
+		return t
+	}
+	base := *udt.BaseType
+	base.Alias = ObjectName{Schema: udt.Schema, Name: udt.Name}.String()
+	return base
+}
+
+// Table returns the named table, or nil.
+func (s *Schema) Table(name string) *Table {
+	for _, t := range s.Tables {
+		if strings.EqualFold(t.Name, name) {
+			return t
+		}
+	}
+	return nil
+}
+
+// View returns the named view, or nil.
+func (s *Schema) View(name string) *View {
+	for _, v := range s.Views {
+		if strings.EqualFold(v.Name, name) {
+			return v
+		}
+	}
+	return nil
+}
+
+// Function returns the named function, or nil.
+func (s *Schema) Function(name string) *Function {
+	for _, f := range s.Functions {
+		if strings.EqualFold(f.Name, name) {
+			return f
+		}
+	}
+	return nil
+}
+
+// Procedure returns the named procedure, or nil.
+func (s *Schema) Procedure(name string) *Procedure {
+	for _, p := range s.Procedures {
+		if strings.EqualFold(p.Name, name) {
+			return p
+		}
+	}
+	return nil
+}
+
+// Sequence returns the named sequence, or nil.
+func (s *Schema) Sequence(name string) *Sequence {
+	for _, q := range s.Sequences {
+		if strings.EqualFold(q.Name, name) {
+			return q
+		}
+	}
+	return nil
+}
+
+// Synonym returns the named synonym, or nil.
+func (s *Schema) Synonym(name string) *Synonym {
+	for _, y := range s.Synonyms {
+		if strings.EqualFold(y.Name, name) {
+			return y
+		}
+	}
+	return nil
+}
+
+// Type returns the named user-defined type, or nil.
+func (s *Schema) Type(name string) *UserDefinedType {
+	for _, t := range s.Types {
+		if strings.EqualFold(t.Name, name) {
+			return t
+		}
+	}
+	return nil
+}
+
+// exists reports whether the schema has an object of any kind named name.
+// Types live in a separate namespace.
+func (s *Schema) exists(name string) bool {
+	return s.Table(name) != nil || s.View(name) != nil || s.Function(name) != nil ||
+		s.Procedure(name) != nil || s.Sequence(name) != nil || s.Synonym(name) != nil ||
+		s.SecurityPolicy(name) != nil
+}
+
+// Update applies every statement of the script in order. Statements that do
+// not define or change schema objects are ignored. All errors are returned, joined;
+// statements after a failing one are still applied.
+func (c *Catalog) Update(script *ast.Script) error {
+	if script == nil {
+		return nil
+	}
+	var errs []error
+	for _, batch := range script.Batches {
+		for _, stmt := range batch.Statements {
+			if err := c.apply(stmt, false); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			c.setSource(stmt, batch.Source(stmt))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// setSource records the source text of the view or procedure that stmt
+// has just defined.
+func (c *Catalog) setSource(stmt ast.Statement, source string) {
+	var name *ast.SchemaObjectName
+	switch s := stmt.(type) {
+	case *ast.CreateViewStatement:
+		name = s.SchemaObjectName
+	case *ast.CreateOrAlterViewStatement:
+		name = s.SchemaObjectName
+	case *ast.AlterViewStatement:
+		name = s.SchemaObjectName
+	default:
+		if p := NewProcedure(stmt); p != nil {
+			if p := c.Procedure(c.qualify(ObjectName{Schema: p.Schema, Name: p.Name})); p != nil {
+				p.Source = source
+			}
+		}
+		return
+	}
+	if v := c.View(c.qualify(NameOf(name))); v != nil {
+		v.Source = source
+	}
+}
+
+// Apply applies a single statement to the catalog.
+func (c *Catalog) Apply(stmt ast.Statement) error {
+	return c.apply(stmt, false)
+}
+
+// apply applies stmt. Statements guarded by an IF, in either branch, are
+// assumed to run, but missing or conflicting objects are not reported for
+// them since the guard usually tests for existence.
+func (c *Catalog) apply(stmt ast.Statement, guarded bool) error {
+	var err error
+	switch s := stmt.(type) {
+	case *ast.IfStatement:
+		err := c.apply(s.ThenStatement, true)
+		if s.ElseStatement == nil {
+			return err
+		}
+		return errors.Join(err, c.apply(s.ElseStatement, true))
+	case *ast.BeginEndBlockStatement:
+		if s.StatementList == nil {
+			return nil
+		}
+		var errs []error
+		for _, inner := range s.StatementList.Statements {
+			if err := c.apply(inner, guarded); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	case *ast.CreateSchemaStatement:
+		err = c.createSchema(s)
+	case *ast.CreateTableStatement:
+		err = c.createTable(s)
+	case *ast.CreateIndexStatement:
+		err = c.createIndex(s)
+	case *ast.CreateColumnStoreIndexStatement:
+		err = c.createColumnStoreIndex(s)
+	case *ast.CreateViewStatement:
+		err = c.createView(s.SchemaObjectName, s.Columns, s.SelectStatement, s.ViewOptions, false)
+	case *ast.CreateOrAlterViewStatement:
+		err = c.createView(s.SchemaObjectName, s.Columns, s.SelectStatement, s.ViewOptions, true)
+	case *ast.CreateProcedureStatement:
+		err = c.createProcedure(s, false)
+	case *ast.CreateOrAlterProcedureStatement:
+		err = c.createProcedure(s, true)
+	case *ast.CreateFunctionStatement:
+		err = c.createFunction(s.Name, s.Parameters, s.ReturnType, s.StatementList, false)
+	case *ast.CreateOrAlterFunctionStatement:
+		err = c.createFunction(s.Name, s.Parameters, s.ReturnType, s.StatementList, true)
+	case *ast.CreateSequenceStatement:
+		err = c.createSequence(s)
+	case *ast.CreateSynonymStatement:
+		err = c.createSynonym(s)
+	case *ast.CreateTypeUddtStatement:
+		err = c.createAliasType(s)
+	case *ast.CreateTypeTableStatement:
+		err = c.createTableType(s)
+	case *ast.AlterTableAddTableElementStatement:
+		err = c.alterTableAdd(s)
+	case *ast.AlterTableAlterColumnStatement:
+		err = c.alterColumn(s)
+	case *ast.AlterTableDropTableElementStatement:
+		err = c.alterTableDrop(s)
+	case *ast.AlterViewStatement:
+		if err = mustExist(c, "ALTER VIEW: view", s.SchemaObjectName, c.View); err == nil {
+			err = c.createView(s.SchemaObjectName, s.Columns, s.SelectStatement, s.ViewOptions, true)
+		}
+	case *ast.AlterProcedureStatement:
+		if s.ProcedureReference == nil {
+			break
+		}
+		if err = mustExist(c, "ALTER PROCEDURE: procedure", s.ProcedureReference.Name, c.Procedure); err == nil {
+			err = c.createProcedure(s, true)
+		}
+	case *ast.AlterFunctionStatement:
+		if err = mustExist(c, "ALTER FUNCTION: function", s.Name, c.Function); err == nil {
+			err = c.createFunction(s.Name, s.Parameters, s.ReturnType, s.StatementList, true)
+		}
+	case *ast.AlterSequenceStatement:
+		err = c.alterSequence(s)
+	case *ast.AlterSchemaStatement:
+		err = c.alterSchema(s)
+	case *ast.CreateSecurityPolicyStatement:
+		err = c.createSecurityPolicy(s)
+	case *ast.AlterSecurityPolicyStatement:
+		err = c.alterSecurityPolicy(s)
+	case *ast.RenameEntityStatement:
+		err = c.renameObject(s)
+	case *ast.ExecuteStatement:
+		_, err = c.spRename(s)
+	default:
+		err = c.drop(stmt)
+	}
+	if guarded && (errors.Is(err, ErrExists) || errors.Is(err, ErrNotFound)) {
+		return nil
+	}
+	return err
+}
+
+// ErrExists is wrapped by errors returned when a statement creates an object
+// that already exists.
+var ErrExists = errors.New("already exists")
+
+// ErrNotFound is wrapped by errors returned when a statement refers to an
+// object that does not exist.
+var ErrNotFound = errors.New("does not exist")
+
+// mustExist reports an error if lookup does not find the named object.
+func mustExist[T any](c *Catalog, kind string, n *ast.SchemaObjectName, lookup func(ObjectName) *T) error {
+	if lookup(c.qualify(NameOf(n))) == nil {
+		return notFoundError(kind, c.qualify(NameOf(n)))
+	}
+	return nil
+}
+
+func existsError(kind string, name ObjectName) error {
+	return fmt.Errorf("%s %s %w", kind, name, ErrExists)
+}
+
+func notFoundError(kind string, name ObjectName) error {
+	return fmt.Errorf("%s %s %w", kind, name, ErrNotFound)
+}
+
+func (c *Catalog) createSchema(s *ast.CreateSchemaStatement) error {
+	if s.Name == nil {
+		return nil
+	}
+	schema := c.Schema(s.Name.Value)
+	switch {
+	case schema == nil:
+		schema = &Schema{Name: s.Name.Value}
+		c.Schemas = append(c.Schemas, schema)
+	case !schema.implicit:
+		return fmt.Errorf("schema %s %w", s.Name.Value, ErrExists)
+	}
+	// A schema first seen as the qualifier of another object is completed
+	// by its CREATE SCHEMA.
+	schema.implicit = false
+	if s.Owner != nil {
+		schema.Owner = s.Owner.Value
+	}
+	if s.StatementList == nil {
+		return nil
+	}
+	var errs []error
+	for _, stmt := range s.StatementList.Statements {
+		if err := c.apply(stmt, false); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func identifierValue(id *ast.Identifier) string {
+	if id == nil {
+		return ""
+	}
+	return id.Value
+}
+
+func identifierValues(ids []*ast.Identifier) []string {
+	out := make([]string, len(ids))
+	for i, id := range ids {
+		out[i] = identifierValue(id)
+	}
+	return out
+}
+
+// columnName returns the last part of a column reference.
+func columnName(ref *ast.ColumnReferenceExpression) string {
+	if ref == nil || ref.MultiPartIdentifier == nil || len(ref.MultiPartIdentifier.Identifiers) == 0 {
+		return ""
+	}
+	ids := ref.MultiPartIdentifier.Identifiers
+	return identifierValue(ids[len(ids)-1])
+}
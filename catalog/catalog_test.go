@@ -0,0 +1,510 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/sqlc-dev/teesql/ast"
+	"github.com/sqlc-dev/teesql/parser"
+)
+
+func parse(t *testing.T, sql string) *ast.Script {
+	t.Helper()
+	script, err := parser.Parse(context.Background(), strings.NewReader(sql))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	return script
+}
+
+const schemaSQL = `
+CREATE SCHEMA sales AUTHORIZATION dbo;
+GO
+CREATE TYPE dbo.Email FROM nvarchar(256) NOT NULL;
+CREATE TYPE dbo.IdList AS TABLE (Id int NOT NULL PRIMARY KEY);
+GO
+CREATE TABLE sales.Customers (
+	Id int IDENTITY(100, 5) NOT NULL,
+	Email dbo.Email,
+	Name nvarchar(max) NULL,
+	Balance decimal(10, 2) CONSTRAINT DF_Balance DEFAULT 0,
+	Created datetime2 NOT NULL DEFAULT SYSUTCDATETIME(),
+	Version rowversion,
+	Label AS UPPER(Name) PERSISTED,
+	CONSTRAINT PK_Customers PRIMARY KEY CLUSTERED (Id),
+	CONSTRAINT CK_Balance CHECK (Balance >= 0)
+);
+CREATE TABLE sales.Orders (
+	Id bigint NOT NULL PRIMARY KEY NONCLUSTERED,
+	CustomerId int NOT NULL REFERENCES sales.Customers (Id) ON DELETE CASCADE,
+	Total float(24),
+	Code char,
+	INDEX IX_Orders_Customer (CustomerId DESC)
+);
+CREATE UNIQUE INDEX UX_Customers_Email ON sales.Customers (Email) INCLUDE (Name) WHERE Email IS NOT NULL;
+GO
+CREATE VIEW sales.CustomerOrders AS
+SELECT c.Id AS CustomerId, c.Email, o.Total, COUNT(*) OVER () AS Cnt
+FROM sales.Customers c JOIN sales.Orders o ON o.CustomerId = c.Id;
+GO
+CREATE PROCEDURE sales.GetCustomer @Id int, @Name sysname = NULL, @Count int OUTPUT, @Ids dbo.IdList READONLY
+AS SELECT * FROM sales.Customers WHERE Id = @Id;
+GO
+CREATE FUNCTION dbo.Add1 (@x int) RETURNS int AS BEGIN RETURN @x + 1 END;
+GO
+CREATE FUNCTION dbo.Recent (@n int) RETURNS TABLE AS RETURN SELECT TOP (@n) * FROM sales.Orders;
+GO
+CREATE SEQUENCE dbo.OrderNumbers AS int START WITH 1000 INCREMENT BY 10 CYCLE;
+CREATE SYNONYM dbo.Clients FOR sales.Customers;
+`
+
+func TestBuild(t *testing.T) {
+	c, err := Build(parse(t, schemaSQL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := c.Schema("SALES"); s == nil || s.Owner != "dbo" {
+		t.Fatalf("schema sales = %+v", s)
+	}
+
+	customers := c.Table(ObjectName{Schema: "sales", Name: "customers"})
+	if customers == nil {
+		t.Fatal("sales.Customers not found")
+	}
+	cols := map[string]string{}
+	for _, col := range customers.Columns {
+		desc := col.Type.String()
+		if !col.Nullable {
+			desc += " not null"
+		}
+		if col.Identity != nil {
+			desc += " identity"
+		}
+		if col.Default != nil {
+			desc += " default"
+		}
+		if col.Computed != nil {
+			desc += " computed"
+		}
+		cols[col.Name] = desc
+	}
+	wantCols := map[string]string{
+		"Id":      "int not null identity",
+		"Email":   "dbo.Email",
+		"Name":    "nvarchar(max)",
+		"Balance": "decimal(10,2) default",
+		"Created": "datetime2 not null default",
+		"Version": "rowversion not null",
+		"Label":   " computed",
+	}
+	for name, want := range wantCols {
+		if cols[name] != want {
+			t.Errorf("column %s = %q, want %q", name, cols[name], want)
+		}
+	}
+	if id := customers.Column("id").Identity; id.Seed != 100 || id.Increment != 5 {
+		t.Errorf("identity = %+v", id)
+	}
+	if got := c.ResolveType(customers.Column("Email").Type); got.String() != "nvarchar(256)" || got.Alias != "dbo.Email" {
+		t.Errorf("resolved Email type = %s (%s)", got, got.Alias)
+	}
+	if pk := customers.PrimaryKey(); pk == nil || pk.Name != "PK_Customers" || !pk.Clustered || pk.Columns[0] != "Id" {
+		t.Errorf("primary key = %+v", pk)
+	}
+	if ck := customers.Constraint("ck_balance"); ck == nil || ck.Kind != Check || ck.Check == nil {
+		t.Errorf("check constraint = %+v", ck)
+	}
+	if ix := customers.Index("UX_Customers_Email"); ix == nil || !ix.Unique || ix.Filter == nil || ix.Include[0] != "Name" {
+		t.Errorf("index = %+v", ix)
+	}
+
+	orders := c.Table(ObjectName{Schema: "sales", Name: "Orders"})
+	if pk := orders.PrimaryKey(); pk == nil || pk.Clustered || pk.Columns[0] != "Id" {
+		t.Errorf("orders primary key = %+v", pk)
+	}
+	var fk *Constraint
+	for _, cons := range orders.Constraints {
+		if cons.Kind == ForeignKey {
+			fk = cons
+		}
+	}
+	if fk == nil || fk.Columns[0] != "CustomerId" || fk.References.String() != "sales.Customers" || fk.OnDelete != "Cascade" {
+		t.Errorf("foreign key = %+v", fk)
+	}
+	if got := orders.Column("Total").Type.String(); got != "real" {
+		t.Errorf("float(24) = %s", got)
+	}
+	if got := orders.Column("Code").Type.String(); got != "char(1)" {
+		t.Errorf("char = %s", got)
+	}
+	if ix := orders.Index("IX_Orders_Customer"); ix == nil || !ix.Columns[0].Descending {
+		t.Errorf("inline index = %+v", ix)
+	}
+
+	view := c.View(ObjectName{Schema: "sales", Name: "CustomerOrders"})
+	if view == nil {
+		t.Fatal("view not found")
+	}
+	var viewCols []string
+	for _, col := range view.Columns {
+		viewCols = append(viewCols, col.Name+" "+col.Type.String())
+	}
+	if got, want := strings.Join(viewCols, ", "), "CustomerId int, Email dbo.Email, Total real, Cnt "; got != want {
+		t.Errorf("view columns = %q, want %q", got, want)
+	}
+
+	proc := c.Procedure(ObjectName{Schema: "sales", Name: "GetCustomer"})
+	if proc == nil || len(proc.Parameters) != 4 {
+		t.Fatalf("procedure = %+v", proc)
+	}
+	if p := proc.Parameters[1]; p.Type.String() != "nvarchar(128)" || !p.HasDefault() {
+		t.Errorf("@Name = %+v", p)
+	}
+	if p := proc.Parameters[2]; !p.Output {
+		t.Errorf("@Count = %+v", p)
+	}
+	if p := proc.Parameters[3]; !p.ReadOnly || c.Type(ObjectName{Schema: p.Type.Schema, Name: p.Type.Name}).Table == nil {
+		t.Errorf("@Ids = %+v", p)
+	}
+
+	if f := c.Function(ObjectName{Name: "Add1"}); f == nil || f.Kind != ScalarFunction || f.ReturnType.String() != "int" {
+		t.Errorf("dbo.Add1 = %+v", f)
+	}
+	if f := c.Function(ObjectName{Name: "Recent"}); f == nil || f.Kind != InlineTableFunction || len(f.Columns) != 4 {
+		t.Errorf("dbo.Recent = %+v", f)
+	}
+	if q := c.Sequence(ObjectName{Name: "OrderNumbers"}); q == nil || q.Type.Name != "int" || *q.Start != 1000 || *q.Increment != 10 || !q.Cycle {
+		t.Errorf("sequence = %+v", q)
+	}
+	if s := c.Synonym(ObjectName{Schema: "dbo", Name: "Clients"}); s == nil || NameOf(s.Target).String() != "sales.Customers" {
+		t.Errorf("synonym = %+v", s)
+	}
+}
+
+func TestBuildErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want string
+	}{
+		{
+			name: "duplicate table",
+			sql:  "CREATE TABLE t (a int); CREATE TABLE T (b int);",
+			want: "table dbo.T already exists",
+		},
+		{
+			name: "index on missing table",
+			sql:  "CREATE INDEX ix ON dbo.missing (a);",
+			want: "table dbo.missing does not exist",
+		},
+		{
+			name: "guarded duplicate",
+			sql: `CREATE TABLE t (a int);
+IF OBJECT_ID('t') IS NULL BEGIN CREATE TABLE t (a int) END`,
+		},
+		{
+			name: "create or alter",
+			sql:  "CREATE VIEW v AS SELECT 1 AS x; GO\nCREATE OR ALTER VIEW v AS SELECT 2 AS y;",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Build(parse(t, tt.sql))
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.want {
+				t.Fatalf("error = %v, want %q", err, tt.want)
+			}
+			if !errors.Is(err, ErrExists) && !errors.Is(err, ErrNotFound) {
+				t.Errorf("error %v does not wrap a sentinel", err)
+			}
+		})
+	}
+}
+
+func TestReplay(t *testing.T) {
+	c, err := Build(parse(t, `
+CREATE TABLE dbo.Users (Id int NOT NULL, Name varchar(50), Legacy int, CONSTRAINT PK_Users PRIMARY KEY (Id));
+CREATE TABLE dbo.Posts (Id int PRIMARY KEY, UserId int CONSTRAINT FK_Posts_Users REFERENCES dbo.Users (Id));
+CREATE INDEX IX_Users_Name ON dbo.Users (Name);
+CREATE VIEW dbo.Old AS SELECT 1 AS x;
+GO
+ALTER TABLE dbo.Users ADD Email nvarchar(256) NOT NULL CONSTRAINT DF_Email DEFAULT '', Active bit;
+ALTER TABLE dbo.Users ALTER COLUMN Name nvarchar(100) NOT NULL;
+ALTER TABLE dbo.Users DROP COLUMN Legacy;
+ALTER TABLE dbo.Users DROP CONSTRAINT DF_Email;
+EXEC sp_rename 'dbo.Users.Name', 'DisplayName', 'COLUMN';
+EXEC sp_rename 'dbo.Users.IX_Users_Name', 'IX_Users_DisplayName', 'INDEX';
+EXEC sp_rename @objname = N'dbo.Users.Id', @newname = N'UserId', @objtype = N'COLUMN';
+EXEC sp_rename 'dbo.Users', 'Accounts';
+DROP VIEW dbo.Old;
+DROP VIEW IF EXISTS dbo.Missing;
+IF OBJECT_ID('dbo.Gone') IS NOT NULL DROP TABLE dbo.Gone;
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Table(ObjectName{Name: "Users"}) != nil || c.View(ObjectName{Name: "Old"}) != nil {
+		t.Error("renamed or dropped objects still present")
+	}
+	accounts := c.Table(ObjectName{Name: "Accounts"})
+	if accounts == nil {
+		t.Fatal("dbo.Accounts not found")
+	}
+	var cols []string
+	for _, col := range accounts.Columns {
+		desc := col.Name + " " + col.Type.String()
+		if !col.Nullable {
+			desc += " not null"
+		}
+		if col.Default != nil {
+			desc += " default"
+		}
+		cols = append(cols, desc)
+	}
+	want := "UserId int not null, DisplayName nvarchar(100) not null, Email nvarchar(256) not null, Active bit"
+	if got := strings.Join(cols, ", "); got != want {
+		t.Errorf("columns = %q, want %q", got, want)
+	}
+	if pk := accounts.PrimaryKey(); pk == nil || pk.Columns[0] != "UserId" {
+		t.Errorf("primary key = %+v", pk)
+	}
+	if ix := accounts.Index("IX_Users_DisplayName"); ix == nil || ix.Columns[0].Name != "DisplayName" {
+		t.Errorf("index = %+v", ix)
+	}
+	fk := c.Table(ObjectName{Name: "Posts"}).Constraint("FK_Posts_Users")
+	if fk.References.String() != "dbo.Accounts" || fk.ReferencedColumns[0] != "UserId" {
+		t.Errorf("foreign key = %+v", fk)
+	}
+}
+
+func TestIfElse(t *testing.T) {
+	c, err := Build(parse(t, `
+IF OBJECT_ID('dbo.Users') IS NULL
+	CREATE TABLE dbo.Users (Id int NOT NULL)
+ELSE
+	ALTER TABLE dbo.Users ADD Email nvarchar(256) NULL;
+IF OBJECT_ID('dbo.Orders') IS NOT NULL
+	DROP TABLE dbo.Orders
+ELSE BEGIN
+	CREATE TABLE dbo.Orders (Id int NOT NULL);
+	CREATE INDEX IX_Orders_Id ON dbo.Orders (Id);
+END
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := c.Table(ObjectName{Name: "Users"})
+	if users == nil || users.Column("Email") == nil {
+		t.Errorf("dbo.Users = %+v, want the column added by ELSE", users)
+	}
+	orders := c.Table(ObjectName{Name: "Orders"})
+	if orders == nil || orders.Index("IX_Orders_Id") == nil {
+		t.Errorf("dbo.Orders = %+v, want the table and index created by ELSE", orders)
+	}
+}
+
+func TestSecurityPolicy(t *testing.T) {
+	c, err := Build(parse(t, `
+CREATE TABLE dbo.Orders (Id int, TenantId int, Email varchar(100) MASKED WITH (FUNCTION = 'email()'),
+	Card char(16) ENCRYPTED WITH (COLUMN_ENCRYPTION_KEY = CEK1, ENCRYPTION_TYPE = Deterministic, ALGORITHM = 'AEAD_AES_256_CBC_HMAC_SHA_256'),
+	Phone varchar(20));
+ALTER TABLE dbo.Orders ALTER COLUMN Phone ADD MASKED WITH (FUNCTION = 'partial(0,"XXX",4)');
+ALTER TABLE dbo.Orders ALTER COLUMN Email DROP MASKED;
+CREATE SECURITY POLICY Security.TenantPolicy
+	ADD FILTER PREDICATE Security.fn_tenant(TenantId) ON dbo.Orders,
+	ADD BLOCK PREDICATE Security.fn_tenant(TenantId) ON dbo.Orders AFTER INSERT
+	WITH (STATE = OFF);
+ALTER SECURITY POLICY Security.TenantPolicy
+	ALTER FILTER PREDICATE Security.fn_tenant2(TenantId) ON dbo.Orders,
+	DROP BLOCK PREDICATE ON dbo.Orders AFTER INSERT
+	WITH (STATE = ON);
+CREATE SECURITY POLICY Gone ADD FILTER PREDICATE dbo.fn(Id) ON dbo.Orders;
+DROP SECURITY POLICY Gone;
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	orders := c.Table(ObjectName{Name: "Orders"})
+	if col := orders.Column("Email"); col.Mask != "" {
+		t.Errorf("Email mask = %q", col.Mask)
+	}
+	if col := orders.Column("Phone"); col.Mask != `partial(0,"XXX",4)` {
+		t.Errorf("Phone mask = %q", col.Mask)
+	}
+	if enc := orders.Column("Card").Encryption; enc == nil || enc.Key != "CEK1" || enc.Type != "Deterministic" || enc.Algorithm != "AEAD_AES_256_CBC_HMAC_SHA_256" {
+		t.Errorf("Card encryption = %+v", enc)
+	}
+	if c.SecurityPolicy(ObjectName{Name: "Gone"}) != nil {
+		t.Error("dropped policy still present")
+	}
+	p := c.SecurityPolicy(ObjectName{Schema: "Security", Name: "TenantPolicy"})
+	if p == nil {
+		t.Fatal("Security.TenantPolicy not found")
+	}
+	if !p.Enabled || len(p.Predicates) != 1 {
+		t.Fatalf("policy = %+v", p)
+	}
+	if pred := p.Predicates[0]; pred.Kind != FilterPredicate || pred.Function.String() != "Security.fn_tenant2" || pred.Table.String() != "dbo.Orders" {
+		t.Errorf("predicate = %+v", pred)
+	}
+}
+
+func TestReplayErrors(t *testing.T) {
+	const setup = `CREATE TABLE t (a int CONSTRAINT CK_a CHECK (a > 0), b int);
+CREATE TABLE r (id int, t_b int CONSTRAINT FK_r_t REFERENCES t (b));
+GO
+`
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{"ALTER TABLE t ALTER COLUMN c int", "ALTER COLUMN: column dbo.t.c does not exist"},
+		{"ALTER TABLE missing ADD c int", "ALTER TABLE: table dbo.missing does not exist"},
+		{"ALTER TABLE t ADD a int", "ALTER TABLE ADD: column dbo.t.a already exists"},
+		{"ALTER TABLE t DROP COLUMN a", "ALTER TABLE DROP COLUMN: constraint CK_a depends on column dbo.t.a"},
+		{"ALTER TABLE t DROP CONSTRAINT CK_x", "ALTER TABLE DROP CONSTRAINT: constraint CK_x on dbo.t does not exist"},
+		{"ALTER TABLE t DROP CONSTRAINT IF EXISTS CK_x", ""},
+		{"DROP TABLE t", "DROP TABLE: table dbo.t is referenced by foreign key FK_r_t"},
+		{"DROP PROCEDURE p", "DROP PROCEDURE: procedure dbo.p does not exist"},
+		{"EXEC sp_rename 't.z', 'y', 'COLUMN'", "sp_rename: column dbo.t.z does not exist"},
+		{"EXEC sp_rename 't', 'r'", "rename: object dbo.r already exists"},
+		{"ALTER VIEW v AS SELECT 1 AS x", "ALTER VIEW: view dbo.v does not exist"},
+		{"ALTER SECURITY POLICY p WITH (STATE = OFF)", "ALTER SECURITY POLICY: security policy dbo.p does not exist"},
+		{"CREATE SECURITY POLICY p ADD FILTER PREDICATE dbo.fn(a) ON t, ADD FILTER PREDICATE dbo.fn(b) ON t", "CREATE SECURITY POLICY: filter predicate on dbo.t in security policy dbo.p already exists"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			_, err := Build(parse(t, setup+tt.sql))
+			got := ""
+			if err != nil {
+				got = err.Error()
+			}
+			if got != tt.want {
+				t.Errorf("error = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNaturalLess(t *testing.T) {
+	paths := []string{"m/V10__c.sql", "m/V2__b.sql", "m/V1__a.sql", "m/V1__a/x.sql"}
+	sort.Slice(paths, func(i, j int) bool { return naturalLess(paths[i], paths[j]) })
+	if got, want := strings.Join(paths, " "), "m/V1__a.sql m/V1__a/x.sql m/V2__b.sql m/V10__c.sql"; got != want {
+		t.Errorf("order = %s, want %s", got, want)
+	}
+}
+
+func TestNewProcedure(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{
+			sql: `CREATE PROCEDURE sales.Find @Id int, @Name nvarchar(50) = N'x', @Total money = NULL OUTPUT, @Ids dbo.IdList READONLY
+WITH RECOMPILE, EXECUTE AS 'auditor' AS SELECT 1`,
+			want: `sales.Find EXECUTE AS auditor RECOMPILE
+@Id int
+@Name nvarchar(50) = N'x'
+@Total money = NULL OUTPUT
+@Ids dbo.IdList READONLY table(Id)`,
+		},
+		{
+			sql: `CREATE OR ALTER PROCEDURE Quick (@a int NOT NULL) WITH NATIVE_COMPILATION, SCHEMABINDING, EXECUTE AS OWNER
+AS BEGIN ATOMIC WITH (TRANSACTION ISOLATION LEVEL = SNAPSHOT, LANGUAGE = N'us_english') SELECT @a END`,
+			want: `Quick EXECUTE AS OWNER NATIVE_COMPILATION SCHEMABINDING
+@a int NOT NULL`,
+		},
+		{
+			sql: "ALTER PROC dbo.OpenAll @c CURSOR VARYING OUTPUT WITH ENCRYPTION, EXECUTE AS CALLER AS RETURN",
+			want: `dbo.OpenAll EXECUTE AS CALLER ENCRYPTION
+@c cursor VARYING OUTPUT`,
+		},
+		{
+			sql:  "CREATE TABLE t (a int)",
+			want: "<nil>",
+		},
+	}
+	c, err := Build(parse(t, schemaSQL))
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			p := NewProcedure(parse(t, tt.sql).Batches[0].Statements[0])
+			if p == nil {
+				if tt.want != "<nil>" {
+					t.Fatalf("NewProcedure = nil")
+				}
+				return
+			}
+			header := ObjectName{Schema: p.Schema, Name: p.Name}.String()
+			if p.ExecuteAs != "" {
+				header += " EXECUTE AS " + p.ExecuteAs
+			}
+			for _, opt := range []struct {
+				set  bool
+				name string
+			}{
+				{p.Recompile, "RECOMPILE"},
+				{p.Encryption, "ENCRYPTION"},
+				{p.NativeCompilation, "NATIVE_COMPILATION"},
+				{p.SchemaBinding, "SCHEMABINDING"},
+			} {
+				if opt.set {
+					header += " " + opt.name
+				}
+			}
+			lines := []string{header}
+			for _, param := range p.Parameters {
+				line := param.Name + " " + param.Type.String()
+				if param.Varying {
+					line += " VARYING"
+				}
+				if param.NotNull {
+					line += " NOT NULL"
+				}
+				if param.HasDefault() {
+					line += " = " + defaultSQL(param.Default)
+				}
+				if param.Output {
+					line += " OUTPUT"
+				}
+				if param.ReadOnly {
+					line += " READONLY"
+				}
+				if tt := c.TableType(param); tt != nil {
+					var cols []string
+					for _, col := range tt.Table.Columns {
+						cols = append(cols, col.Name)
+					}
+					line += " table(" + strings.Join(cols, ", ") + ")"
+				}
+				lines = append(lines, line)
+			}
+			if got := strings.Join(lines, "\n"); got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func defaultSQL(e ast.ScalarExpression) string {
+	switch e := e.(type) {
+	case *ast.StringLiteral:
+		if e.IsNational {
+			return "N'" + e.Value + "'"
+		}
+		return "'" + e.Value + "'"
+	case *ast.NullLiteral:
+		return "NULL"
+	}
+	return "?"
+}
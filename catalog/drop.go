@@ -0,0 +1,148 @@
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/sqlc-dev/teesql/ast"
+)
+
+// dropObjects drops each named object of the given kind. remove deletes the
+// object from its schema, reporting whether it was found.
+func (c *Catalog) dropObjects(kind string, names []*ast.SchemaObjectName, ifExists bool, remove func(s *Schema, name string) bool) error {
+	var errs []error
+	for _, n := range names {
+		name := c.qualify(NameOf(n))
+		s := c.Schema(name.Schema)
+		if s == nil || !remove(s, name.Name) {
+			if !ifExists {
+				errs = append(errs, notFoundError("DROP "+strings.ToUpper(kind)+": "+kind, name))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// removeNamed deletes the element of *list whose name matches, reporting
+// whether one was found.
+func removeNamed[T any](list *[]T, name string, nameOf func(T) string) bool {
+	for i, v := range *list {
+		if strings.EqualFold(nameOf(v), name) {
+			*list = append((*list)[:i], (*list)[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Catalog) drop(stmt ast.Statement) error {
+	switch s := stmt.(type) {
+	case *ast.DropTableStatement:
+		var errs []error
+		for _, n := range s.Objects {
+			if err := c.checkReferenced(c.qualify(NameOf(n))); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if len(errs) > 0 {
+			return errors.Join(errs...)
+		}
+		return c.dropObjects("table", s.Objects, s.IsIfExists, func(sc *Schema, name string) bool {
+			return removeNamed(&sc.Tables, name, func(t *Table) string { return t.Name })
+		})
+	case *ast.DropViewStatement:
+		return c.dropObjects("view", s.Objects, s.IsIfExists, func(sc *Schema, name string) bool {
+			return removeNamed(&sc.Views, name, func(v *View) string { return v.Name })
+		})
+	case *ast.DropProcedureStatement:
+		return c.dropObjects("procedure", s.Objects, s.IsIfExists, func(sc *Schema, name string) bool {
+			return removeNamed(&sc.Procedures, name, func(p *Procedure) string { return p.Name })
+		})
+	case *ast.DropFunctionStatement:
+		return c.dropObjects("function", s.Objects, s.IsIfExists, func(sc *Schema, name string) bool {
+			return removeNamed(&sc.Functions, name, func(f *Function) string { return f.Name })
+		})
+	case *ast.DropSequenceStatement:
+		return c.dropObjects("sequence", s.Objects, s.IsIfExists, func(sc *Schema, name string) bool {
+			return removeNamed(&sc.Sequences, name, func(q *Sequence) string { return q.Name })
+		})
+	case *ast.DropSynonymStatement:
+		return c.dropObjects("synonym", s.Objects, s.IsIfExists, func(sc *Schema, name string) bool {
+			return removeNamed(&sc.Synonyms, name, func(y *Synonym) string { return y.Name })
+		})
+	case *ast.DropTypeStatement:
+		return c.dropObjects("type", []*ast.SchemaObjectName{s.Name}, s.IsIfExists, func(sc *Schema, name string) bool {
+			return removeNamed(&sc.Types, name, func(t *UserDefinedType) string { return t.Name })
+		})
+	case *ast.DropSecurityPolicyStatement:
+		return c.dropObjects("security policy", s.Objects, s.IsIfExists, func(sc *Schema, name string) bool {
+			return removeNamed(&sc.SecurityPolicies, name, func(p *SecurityPolicy) string { return p.Name })
+		})
+	case *ast.DropIndexStatement:
+		return c.dropIndex(s)
+	case *ast.DropSchemaStatement:
+		return c.dropSchema(s)
+	}
+	return nil
+}
+
+// checkReferenced reports an error if a foreign key of another table
+// references the table.
+func (c *Catalog) checkReferenced(table ObjectName) error {
+	var err error
+	c.eachForeignKey(func(t *Table, fk *Constraint) {
+		if err == nil && c.sameObject(fk.References, table) && !c.sameObject(ObjectName{Schema: t.Schema, Name: t.Name}, table) {
+			name := fk.Name
+			if name == "" {
+				name = "on " + ObjectName{Schema: t.Schema, Name: t.Name}.String()
+			}
+			err = fmt.Errorf("DROP TABLE: table %s is referenced by foreign key %s", table, name)
+		}
+	})
+	return err
+}
+
+func (c *Catalog) dropIndex(s *ast.DropIndexStatement) error {
+	var errs []error
+	for _, clause := range s.DropIndexClauses {
+		var table ObjectName
+		var index string
+		switch {
+		case clause.LegacyIndex != nil:
+			// DROP INDEX table.index
+			legacy := clause.LegacyIndex
+			index = identifierValue(legacy.BaseIdentifier)
+			table.Name = identifierValue(legacy.SchemaIdentifier)
+			table.Schema = identifierValue(legacy.DatabaseIdentifier)
+		default:
+			index = identifierValue(clause.Index)
+			table = NameOf(clause.Object)
+		}
+		table = c.qualify(table)
+		t := c.Table(table)
+		if t == nil || !removeNamed(&t.Indexes, index, func(ix *Index) string { return ix.Name }) {
+			if !s.IsIfExists {
+				errs = append(errs, notFoundError("DROP INDEX: index "+index+" on", table))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (c *Catalog) dropSchema(s *ast.DropSchemaStatement) error {
+	name := NameOf(s.Schema)
+	schema := c.Schema(name.Name)
+	if schema == nil || name.Name == "" {
+		if s.IsIfExists {
+			return nil
+		}
+		return notFoundError("DROP SCHEMA: schema", name)
+	}
+	if len(schema.Tables)+len(schema.Views)+len(schema.Functions)+len(schema.Procedures)+
+		len(schema.Sequences)+len(schema.Synonyms)+len(schema.Types)+len(schema.SecurityPolicies) > 0 {
+		return errors.New("DROP SCHEMA: schema " + schema.Name + " is not empty")
+	}
+	removeNamed(&c.Schemas, schema.Name, func(s *Schema) string { return s.Name })
+	return nil
+}
@@ -0,0 +1,118 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sqlc-dev/teesql/ast"
+	"github.com/sqlc-dev/teesql/parser"
+)
+
+// ParseDir parses every .sql file under dir, recursively, ordered by path.
+// Runs of digits compare numerically, so V2__b.sql sorts before V10__a.sql.
+// The source text of the statements is kept, for the views and procedures
+// of a catalog built from the scripts.
+func ParseDir(ctx context.Context, dir string) ([]*ast.Script, error) {
+	paths, err := SQLFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	scripts := make([]*ast.Script, 0, len(paths))
+	for _, path := range paths {
+		script, err := parseFile(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+		scripts = append(scripts, script)
+	}
+	return scripts, nil
+}
+
+// LoadDir builds a catalog from the .sql files under dir, applied in the
+// order described for ParseDir. Errors are prefixed with the file name.
+func LoadDir(ctx context.Context, dir string) (*Catalog, error) {
+	paths, err := SQLFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	c := New()
+	var errs []error
+	for _, path := range paths {
+		script, err := parseFile(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.Update(script); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+		}
+	}
+	return c, errors.Join(errs...)
+}
+
+// SQLFiles returns the paths of the .sql files under dir, recursively, in
+// the order described for ParseDir.
+func SQLFiles(dir string) ([]string, error) {
+	var paths []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.EqualFold(filepath.Ext(path), ".sql") {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	sort.Slice(paths, func(i, j int) bool { return naturalLess(paths[i], paths[j]) })
+	return paths, err
+}
+
+// naturalLess compares strings, treating runs of digits as numbers.
+func naturalLess(a, b string) bool {
+	for a != "" && b != "" {
+		da, db := digitPrefix(a), digitPrefix(b)
+		if da > 0 && db > 0 {
+			na := strings.TrimLeft(a[:da], "0")
+			nb := strings.TrimLeft(b[:db], "0")
+			if len(na) != len(nb) {
+				return len(na) < len(nb)
+			}
+			if na != nb {
+				return na < nb
+			}
+			a, b = a[da:], b[db:]
+			continue
+		}
+		if a[0] != b[0] {
+			return a[0] < b[0]
+		}
+		a, b = a[1:], b[1:]
+	}
+	return len(a) < len(b)
+}
+
+func digitPrefix(s string) int {
+	n := 0
+	for n < len(s) && s[n] >= '0' && s[n] <= '9' {
+		n++
+	}
+	return n
+}
+
+func parseFile(ctx context.Context, path string) (*ast.Script, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	script, err := parser.ParseWithOptions(ctx, f, parser.Options{Sources: true})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return script, nil
+}
@@ -0,0 +1,541 @@
+package catalog
+
+import (
+	"strings"
+
+	"github.com/sqlc-dev/teesql/ast"
+)
+
+// View is a view.
+type View struct {
+	Schema string `json:"Schema"`
+	Name   string `json:"Name"`
+	// Columns are the view's output columns. Only names are known unless the
+	// view selects plain columns of tables in the catalog.
+	Columns       []*Column            `json:"Columns,omitempty"`
+	SchemaBinding bool                 `json:"SchemaBinding,omitempty"`
+	Definition    *ast.SelectStatement `json:"-"`
+	// Source is the text of the statement that defined the view, as
+	// written, or empty if the view was not defined from a script parsed
+	// with parser.Options.Sources.
+	Source string `json:"-"`
+}
+
+// Column returns the named column, or nil.
+func (v *View) Column(name string) *Column {
+	for _, c := range v.Columns {
+		if strings.EqualFold(c.Name, name) {
+			return c
+		}
+	}
+	return nil
+}
+
+// Parameter is a procedure or function parameter.
+type Parameter struct {
+	// Name includes the leading @.
+	Name string `json:"Name"`
+	// Type is the declared type; see Catalog.ResolveType.
+	Type Type `json:"Type"`
+	// Default is the parameter's default value, or nil if it has none.
+	Default  ast.ScalarExpression `json:"-"`
+	Output   bool                 `json:"Output,omitempty"`
+	ReadOnly bool                 `json:"ReadOnly,omitempty"`
+	// Varying is set for CURSOR VARYING parameters.
+	Varying bool `json:"Varying,omitempty"`
+	// NotNull is set for parameters of natively compiled modules declared
+	// NOT NULL.
+	NotNull bool `json:"NotNull,omitempty"`
+}
+
+// HasDefault reports whether the parameter may be omitted by callers.
+func (p *Parameter) HasDefault() bool {
+	return p.Default != nil
+}
+
+// Procedure is a stored procedure.
+type Procedure struct {
+	Schema     string       `json:"Schema"`
+	Name       string       `json:"Name"`
+	Parameters []*Parameter `json:"Parameters,omitempty"`
+	// ExecuteAs is the EXECUTE AS option: CALLER, SELF, OWNER or a user
+	// name. It is empty if the procedure has none.
+	ExecuteAs         string             `json:"ExecuteAs,omitempty"`
+	Recompile         bool               `json:"Recompile,omitempty"`
+	Encryption        bool               `json:"Encryption,omitempty"`
+	NativeCompilation bool               `json:"NativeCompilation,omitempty"`
+	SchemaBinding     bool               `json:"SchemaBinding,omitempty"`
+	Body              *ast.StatementList `json:"-"`
+	// Source is the text of the statement that defined the procedure, as
+	// written, or empty if it was not defined from a script parsed with
+	// parser.Options.Sources.
+	Source string `json:"-"`
+}
+
+// NewProcedure returns the procedure that a CREATE PROCEDURE, CREATE OR
+// ALTER PROCEDURE or ALTER PROCEDURE statement defines, or nil for other
+// statements. The procedure is not added to a catalog, and its schema is
+// empty unless the statement names one.
+func NewProcedure(stmt ast.Statement) *Procedure {
+	var (
+		ref    *ast.ProcedureReference
+		params []*ast.ProcedureParameter
+		opts   []ast.ProcedureOptionBase
+		body   *ast.StatementList
+	)
+	switch s := stmt.(type) {
+	case *ast.CreateProcedureStatement:
+		ref, params, opts, body = s.ProcedureReference, s.Parameters, s.Options, s.StatementList
+	case *ast.CreateOrAlterProcedureStatement:
+		ref, params, opts, body = s.ProcedureReference, s.Parameters, s.Options, s.StatementList
+	case *ast.AlterProcedureStatement:
+		ref, params, opts, body = s.ProcedureReference, s.Parameters, s.Options, s.StatementList
+	default:
+		return nil
+	}
+	if ref == nil {
+		return nil
+	}
+	name := NameOf(ref.Name)
+	p := &Procedure{
+		Schema:     name.Schema,
+		Name:       name.Name,
+		Parameters: parameters(params),
+		Body:       body,
+	}
+	for _, opt := range opts {
+		switch o := opt.(type) {
+		case *ast.ProcedureOption:
+			switch o.OptionKind {
+			case "Recompile":
+				p.Recompile = true
+			case "Encryption":
+				p.Encryption = true
+			case "NativeCompilation":
+				p.NativeCompilation = true
+			case "SchemaBinding":
+				p.SchemaBinding = true
+			}
+		case *ast.ExecuteAsProcedureOption:
+			p.ExecuteAs = executeAs(o.ExecuteAs)
+		}
+	}
+	return p
+}
+
+// TableType returns the table type of a table-valued parameter, or nil if
+// the parameter's type is not a table type in the catalog.
+func (c *Catalog) TableType(p *Parameter) *UserDefinedType {
+	if !p.Type.IsUserDefined() {
+		return nil
+	}
+	t := c.Type(ObjectName{Schema: p.Type.Schema, Name: p.Type.Name})
+	if t == nil || t.Table == nil {
+		return nil
+	}
+	return t
+}
+
+func executeAs(e *ast.ExecuteAsClause) string {
+	if e == nil {
+		return ""
+	}
+	if e.ExecuteAsOption == "String" {
+		if e.Literal != nil {
+			return e.Literal.Value
+		}
+		return ""
+	}
+	return strings.ToUpper(e.ExecuteAsOption)
+}
+
+// Function kinds.
+const (
+	ScalarFunction      = "Scalar"
+	InlineTableFunction = "InlineTable"
+	TableFunction       = "Table"
+)
+
+// Function is a user-defined function.
+type Function struct {
+	Schema     string       `json:"Schema"`
+	Name       string       `json:"Name"`
+	Kind       string       `json:"Kind"`
+	Parameters []*Parameter `json:"Parameters,omitempty"`
+	// ReturnType is the return type of a scalar function.
+	ReturnType Type `json:"ReturnType"`
+	// Columns are the columns returned by a table-valued function.
+	Columns []*Column `json:"Columns,omitempty"`
+	// Query is the body of an inline table-valued function.
+	Query *ast.SelectStatement `json:"-"`
+	// Body is the body of a scalar or multi-statement function.
+	Body *ast.StatementList `json:"-"`
+}
+
+// Sequence is a sequence object. Options that were not specified are nil.
+type Sequence struct {
+	Schema    string `json:"Schema"`
+	Name      string `json:"Name"`
+	Type      Type   `json:"Type"`
+	Start     *int64 `json:"Start,omitempty"`
+	Increment *int64 `json:"Increment,omitempty"`
+	MinValue  *int64 `json:"MinValue,omitempty"`
+	MaxValue  *int64 `json:"MaxValue,omitempty"`
+	Cycle     bool   `json:"Cycle,omitempty"`
+}
+
+// Synonym is an alternative name for another object.
+type Synonym struct {
+	Schema string `json:"Schema"`
+	Name   string `json:"Name"`
+	// Target is the object the synonym refers to. It may name an object in
+	// another database or on another server.
+	Target *ast.SchemaObjectName `json:"-"`
+}
+
+// UserDefinedType is an alias type or a table type.
+type UserDefinedType struct {
+	Schema string `json:"Schema"`
+	Name   string `json:"Name"`
+	// BaseType is set for alias types (CREATE TYPE ... FROM).
+	BaseType *Type `json:"BaseType,omitempty"`
+	Nullable bool  `json:"Nullable,omitempty"`
+	// Table is set for table types (CREATE TYPE ... AS TABLE).
+	Table *Table `json:"Table,omitempty"`
+}
+
+func (c *Catalog) createView(n *ast.SchemaObjectName, columns []*ast.Identifier, sel *ast.SelectStatement, opts []ast.ViewOption, replace bool) error {
+	name := c.qualify(NameOf(n))
+	schema := c.schemaFor(name)
+	v := &View{Schema: schema.Name, Name: name.Name, Definition: sel}
+	for _, opt := range opts {
+		if o, ok := opt.(*ast.ViewStatementOption); ok && o.OptionKind == "SchemaBinding" {
+			v.SchemaBinding = true
+		}
+	}
+	if sel != nil {
+		v.Columns = selectColumns(c, columns, sel)
+	}
+	if existing := schema.View(name.Name); existing != nil && replace {
+		*existing = *v
+		return nil
+	}
+	if schema.exists(name.Name) {
+		return existsError("view", name)
+	}
+	schema.Views = append(schema.Views, v)
+	return nil
+}
+
+func parameters(params []*ast.ProcedureParameter) []*Parameter {
+	out := make([]*Parameter, 0, len(params))
+	for _, p := range params {
+		out = append(out, &Parameter{
+			Name:     identifierValue(p.VariableName),
+			Type:     TypeOf(p.DataType),
+			Default:  p.Value,
+			Output:   p.Modifier == "Output",
+			ReadOnly: p.Modifier == "ReadOnly",
+			Varying:  p.IsVarying,
+			NotNull:  p.Nullable != nil && !p.Nullable.Nullable,
+		})
+	}
+	return out
+}
+
+func (c *Catalog) createProcedure(stmt ast.Statement, replace bool) error {
+	p := NewProcedure(stmt)
+	if p == nil {
+		return nil
+	}
+	name := c.qualify(ObjectName{Schema: p.Schema, Name: p.Name})
+	schema := c.schemaFor(name)
+	p.Schema = schema.Name
+	if existing := schema.Procedure(name.Name); existing != nil && replace {
+		*existing = *p
+		return nil
+	}
+	if schema.exists(name.Name) {
+		return existsError("procedure", name)
+	}
+	schema.Procedures = append(schema.Procedures, p)
+	return nil
+}
+
+func (c *Catalog) createFunction(n *ast.SchemaObjectName, params []*ast.ProcedureParameter, ret ast.FunctionReturnType, body *ast.StatementList, replace bool) error {
+	name := c.qualify(NameOf(n))
+	schema := c.schemaFor(name)
+	f := &Function{
+		Schema:     schema.Name,
+		Name:       name.Name,
+		Parameters: parameters(params),
+		Body:       body,
+	}
+	switch r := ret.(type) {
+	case *ast.ScalarFunctionReturnType:
+		f.Kind = ScalarFunction
+		f.ReturnType = TypeOf(r.DataType)
+	case *ast.SelectFunctionReturnType:
+		f.Kind = InlineTableFunction
+		f.Query = r.SelectStatement
+		if r.SelectStatement != nil {
+			f.Columns = selectColumns(c, nil, r.SelectStatement)
+		}
+	case *ast.TableValuedFunctionReturnType:
+		f.Kind = TableFunction
+		if r.DeclareTableVariableBody != nil && r.DeclareTableVariableBody.Definition != nil {
+			t := &Table{}
+			tableDefinition(t, r.DeclareTableVariableBody.Definition)
+			f.Columns = t.Columns
+		}
+	}
+	if existing := schema.Function(name.Name); existing != nil && replace {
+		*existing = *f
+		return nil
+	}
+	if schema.exists(name.Name) {
+		return existsError("function", name)
+	}
+	schema.Functions = append(schema.Functions, f)
+	return nil
+}
+
+func (c *Catalog) createSequence(s *ast.CreateSequenceStatement) error {
+	name := c.qualify(NameOf(s.Name))
+	schema := c.schemaFor(name)
+	if schema.exists(name.Name) {
+		return existsError("sequence", name)
+	}
+	// Sequences are bigint unless declared otherwise.
+	q := &Sequence{Schema: schema.Name, Name: name.Name, Type: Type{Name: "bigint"}}
+	q.setOptions(s.SequenceOptions)
+	schema.Sequences = append(schema.Sequences, q)
+	return nil
+}
+
+// setOptions applies CREATE or ALTER SEQUENCE options. RESTART WITH sets the
+// start value.
+func (q *Sequence) setOptions(opts []interface{}) {
+	for _, opt := range opts {
+		switch o := opt.(type) {
+		case *ast.DataTypeSequenceOption:
+			q.Type = TypeOf(o.DataType)
+		case *ast.ScalarExpressionSequenceOption:
+			if o.NoValue {
+				continue
+			}
+			n, ok := intValue(o.OptionValue)
+			if !ok {
+				continue
+			}
+			switch o.OptionKind {
+			case "Start", "Restart":
+				q.Start = &n
+			case "Increment":
+				q.Increment = &n
+			case "MinValue":
+				q.MinValue = &n
+			case "MaxValue":
+				q.MaxValue = &n
+			}
+		case *ast.SequenceOption:
+			switch o.OptionKind {
+			case "Cycle":
+				q.Cycle = !o.NoValue
+			case "MinValue":
+				q.MinValue = nil
+			case "MaxValue":
+				q.MaxValue = nil
+			}
+		}
+	}
+}
+
+func (c *Catalog) createSynonym(s *ast.CreateSynonymStatement) error {
+	name := c.qualify(NameOf(s.Name))
+	schema := c.schemaFor(name)
+	if schema.exists(name.Name) {
+		return existsError("synonym", name)
+	}
+	schema.Synonyms = append(schema.Synonyms, &Synonym{Schema: schema.Name, Name: name.Name, Target: s.ForName})
+	return nil
+}
+
+func (c *Catalog) createAliasType(s *ast.CreateTypeUddtStatement) error {
+	name := c.qualify(NameOf(s.Name))
+	schema := c.schemaFor(name)
+	if schema.Type(name.Name) != nil {
+		return existsError("type", name)
+	}
+	base := TypeOf(s.DataType)
+	udt := &UserDefinedType{Schema: schema.Name, Name: name.Name, BaseType: &base, Nullable: true}
+	if s.NullableConstraint != nil {
+		udt.Nullable = s.NullableConstraint.Nullable
+	}
+	schema.Types = append(schema.Types, udt)
+	return nil
+}
+
+func (c *Catalog) createTableType(s *ast.CreateTypeTableStatement) error {
+	name := c.qualify(NameOf(s.Name))
+	schema := c.schemaFor(name)
+	if schema.Type(name.Name) != nil {
+		return existsError("type", name)
+	}
+	t := &Table{Schema: schema.Name, Name: name.Name}
+	if s.Definition != nil {
+		tableDefinition(t, s.Definition)
+	}
+	schema.Types = append(schema.Types, &UserDefinedType{Schema: schema.Name, Name: name.Name, Table: t})
+	return nil
+}
+
+// selectColumns returns the output columns of a view or CTAS query. Names are
+// taken from names when given. Columns that select a column of a table in
+// the catalog copy its type and nullability.
+func selectColumns(c *Catalog, names []*ast.Identifier, sel *ast.SelectStatement) []*Column {
+	spec := firstQuerySpecification(sel.QueryExpression)
+	if spec == nil {
+		return nil
+	}
+	tables := fromTables(c, spec.FromClause)
+	var cols []*Column
+	for _, elem := range spec.SelectElements {
+		switch e := elem.(type) {
+		case *ast.SelectScalarExpression:
+			col := &Column{Nullable: true}
+			if ref, ok := e.Expression.(*ast.ColumnReferenceExpression); ok {
+				if src := lookupColumn(tables, ref); src != nil {
+					col.Type = src.Type
+					col.Nullable = src.Nullable
+				}
+				col.Name = columnName(ref)
+			}
+			if e.ColumnName != nil {
+				if e.ColumnName.Identifier != nil {
+					col.Name = e.ColumnName.Identifier.Value
+				} else {
+					col.Name = e.ColumnName.Value
+				}
+			}
+			cols = append(cols, col)
+		case *ast.SelectStarExpression:
+			var qualifier string
+			if e.Qualifier != nil && len(e.Qualifier.Identifiers) > 0 {
+				qualifier = identifierValue(e.Qualifier.Identifiers[len(e.Qualifier.Identifiers)-1])
+			}
+			expanded := false
+			for _, t := range tables {
+				if qualifier != "" && !strings.EqualFold(qualifier, t.alias) {
+					continue
+				}
+				for _, src := range t.table.Columns {
+					copied := *src
+					cols = append(cols, &copied)
+				}
+				expanded = true
+			}
+			if !expanded {
+				cols = append(cols, &Column{Name: "*", Nullable: true})
+			}
+		}
+	}
+	for i, n := range names {
+		if i < len(cols) {
+			cols[i].Name = n.Value
+		}
+	}
+	return cols
+}
+
+func firstQuerySpecification(q ast.QueryExpression) *ast.QuerySpecification {
+	for {
+		switch e := q.(type) {
+		case *ast.QuerySpecification:
+			return e
+		case *ast.QueryParenthesisExpression:
+			q = e.QueryExpression
+		case *ast.BinaryQueryExpression:
+			// The first query of a set operation names the columns.
+			q = e.FirstQueryExpression
+		default:
+			return nil
+		}
+	}
+}
+
+type aliasedTable struct {
+	alias string
+	table *Table
+}
+
+// fromTables returns the catalog tables referenced by name in a FROM clause.
+// Nullable sides of outer joins are not taken into account.
+func fromTables(c *Catalog, from *ast.FromClause) []aliasedTable {
+	if from == nil {
+		return nil
+	}
+	var out []aliasedTable
+	var visit func(ref ast.TableReference)
+	visit = func(ref ast.TableReference) {
+		switch r := ref.(type) {
+		case *ast.NamedTableReference:
+			t := c.Table(NameOf(r.SchemaObject))
+			if t == nil {
+				if v := c.View(NameOf(r.SchemaObject)); v != nil {
+					t = &Table{Schema: v.Schema, Name: v.Name, Columns: v.Columns}
+				}
+			}
+			if t == nil {
+				return
+			}
+			alias := t.Name
+			if r.Alias != nil {
+				alias = r.Alias.Value
+			}
+			out = append(out, aliasedTable{alias: alias, table: t})
+		case *ast.QualifiedJoin:
+			visit(r.FirstTableReference)
+			visit(r.SecondTableReference)
+		case *ast.UnqualifiedJoin:
+			visit(r.FirstTableReference)
+			visit(r.SecondTableReference)
+		case *ast.JoinParenthesisTableReference:
+			visit(r.Join)
+		}
+	}
+	for _, ref := range from.TableReferences {
+		visit(ref)
+	}
+	return out
+}
+
+// lookupColumn finds the table column a column reference refers to. An
+// unqualified name matches only if exactly one table has such a column.
+func lookupColumn(tables []aliasedTable, ref *ast.ColumnReferenceExpression) *Column {
+	if ref.MultiPartIdentifier == nil {
+		return nil
+	}
+	ids := ref.MultiPartIdentifier.Identifiers
+	if len(ids) == 0 {
+		return nil
+	}
+	name := identifierValue(ids[len(ids)-1])
+	var qualifier string
+	if len(ids) > 1 {
+		qualifier = identifierValue(ids[len(ids)-2])
+	}
+	var found *Column
+	for _, t := range tables {
+		if qualifier != "" && !strings.EqualFold(qualifier, t.alias) {
+			continue
+		}
+		if col := t.table.Column(name); col != nil {
+			if found != nil {
+				return nil
+			}
+			found = col
+		}
+	}
+	return found
+}
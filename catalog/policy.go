@@ -0,0 +1,175 @@
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/sqlc-dev/teesql/ast"
+)
+
+// Security predicate kinds.
+const (
+	FilterPredicate = "Filter"
+	BlockPredicate  = "Block"
+)
+
+// SecurityPolicy is a row-level security policy.
+type SecurityPolicy struct {
+	Schema string `json:"Schema"`
+	Name   string `json:"Name"`
+	// Enabled is the STATE option; policies are enabled unless created or
+	// altered WITH (STATE = OFF).
+	Enabled           bool                 `json:"Enabled"`
+	SchemaBinding     bool                 `json:"SchemaBinding"`
+	NotForReplication bool                 `json:"NotForReplication,omitempty"`
+	Predicates        []*SecurityPredicate `json:"Predicates,omitempty"`
+}
+
+// SecurityPredicate is a filter or block predicate of a security policy.
+type SecurityPredicate struct {
+	// Kind is FilterPredicate or BlockPredicate.
+	Kind string `json:"Kind"`
+	// Function is the inline table-valued function that decides access.
+	Function ObjectName        `json:"Function"`
+	Call     *ast.FunctionCall `json:"-"`
+	Table    ObjectName        `json:"Table"`
+	// Operation is All, or the operation a block predicate applies to:
+	// AfterInsert, AfterUpdate, BeforeUpdate or BeforeDelete.
+	Operation string `json:"Operation"`
+}
+
+// SecurityPolicy returns the named security policy, or nil.
+func (c *Catalog) SecurityPolicy(name ObjectName) *SecurityPolicy {
+	if s := c.Schema(name.Schema); s != nil {
+		return s.SecurityPolicy(name.Name)
+	}
+	return nil
+}
+
+// SecurityPolicy returns the named security policy, or nil.
+func (s *Schema) SecurityPolicy(name string) *SecurityPolicy {
+	for _, p := range s.SecurityPolicies {
+		if strings.EqualFold(p.Name, name) {
+			return p
+		}
+	}
+	return nil
+}
+
+func (c *Catalog) createSecurityPolicy(s *ast.CreateSecurityPolicyStatement) error {
+	name := c.qualify(NameOf(s.Name))
+	schema := c.schemaFor(name)
+	if schema.exists(name.Name) {
+		return existsError("security policy", name)
+	}
+	p := &SecurityPolicy{
+		Schema:            schema.Name,
+		Name:              name.Name,
+		Enabled:           true,
+		SchemaBinding:     true,
+		NotForReplication: s.NotForReplication,
+	}
+	p.setOptions(s.SecurityPolicyOptions)
+	err := c.alterPredicates("CREATE SECURITY POLICY", p, s.SecurityPredicateActions)
+	schema.SecurityPolicies = append(schema.SecurityPolicies, p)
+	return err
+}
+
+func (c *Catalog) alterSecurityPolicy(s *ast.AlterSecurityPolicyStatement) error {
+	name := c.qualify(NameOf(s.Name))
+	p := c.SecurityPolicy(name)
+	if p == nil {
+		return notFoundError("ALTER SECURITY POLICY: security policy", name)
+	}
+	if s.NotForReplicationModified {
+		p.NotForReplication = s.NotForReplication
+	}
+	p.setOptions(s.SecurityPolicyOptions)
+	return c.alterPredicates("ALTER SECURITY POLICY", p, s.SecurityPredicateActions)
+}
+
+func (p *SecurityPolicy) setOptions(opts []*ast.SecurityPolicyOption) {
+	for _, o := range opts {
+		switch o.OptionKind {
+		case "State":
+			p.Enabled = o.OptionState == "On"
+		case "SchemaBinding":
+			p.SchemaBinding = o.OptionState == "On"
+		}
+	}
+}
+
+// alterPredicates adds, drops and replaces the predicates of a policy. A
+// table has at most one filter predicate, and one block predicate per
+// operation, in a policy.
+func (c *Catalog) alterPredicates(stmt string, p *SecurityPolicy, actions []*ast.SecurityPredicateAction) error {
+	var errs []error
+	for _, a := range actions {
+		pred := &SecurityPredicate{
+			Kind:      a.SecurityPredicateType,
+			Table:     c.qualify(NameOf(a.TargetObjectName)),
+			Operation: a.SecurityPredicateOperation,
+		}
+		if pred.Operation == "" {
+			pred.Operation = "All"
+		}
+		if a.FunctionCall != nil {
+			pred.Call = a.FunctionCall
+			pred.Function = c.qualify(functionName(a.FunctionCall))
+		}
+		i := p.predicate(pred)
+		switch a.ActionType {
+		case "Create":
+			if i >= 0 {
+				errs = append(errs, fmt.Errorf("%s: %s %w", stmt, pred.describe(p), ErrExists))
+				continue
+			}
+			p.Predicates = append(p.Predicates, pred)
+		case "Alter":
+			if i < 0 {
+				errs = append(errs, fmt.Errorf("%s: %s %w", stmt, pred.describe(p), ErrNotFound))
+				continue
+			}
+			p.Predicates[i] = pred
+		case "Drop":
+			if i < 0 {
+				errs = append(errs, fmt.Errorf("%s: %s %w", stmt, pred.describe(p), ErrNotFound))
+				continue
+			}
+			p.Predicates = append(p.Predicates[:i], p.Predicates[i+1:]...)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// predicate returns the index of the policy's predicate of the same kind
+// on the same table and operation, or -1.
+func (p *SecurityPolicy) predicate(pred *SecurityPredicate) int {
+	for i, q := range p.Predicates {
+		if q.Kind == pred.Kind && strings.EqualFold(q.Table.String(), pred.Table.String()) && q.Operation == pred.Operation {
+			return i
+		}
+	}
+	return -1
+}
+
+func (pred *SecurityPredicate) describe(p *SecurityPolicy) string {
+	s := strings.ToLower(pred.Kind) + " predicate on " + pred.Table.String()
+	if pred.Operation != "All" {
+		s += " " + pred.Operation
+	}
+	return s + " in security policy " + ObjectName{Schema: p.Schema, Name: p.Name}.String()
+}
+
+// functionName returns the name of a called function, with the schema
+// given by its call target.
+func functionName(call *ast.FunctionCall) ObjectName {
+	name := ObjectName{Name: identifierValue(call.FunctionName)}
+	if t, ok := call.CallTarget.(*ast.MultiPartIdentifierCallTarget); ok && t.MultiPartIdentifier != nil {
+		if ids := t.MultiPartIdentifier.Identifiers; len(ids) > 0 {
+			name.Schema = identifierValue(ids[len(ids)-1])
+		}
+	}
+	return name
+}
@@ -0,0 +1,462 @@
+package catalog
+
+import (
+	"strings"
+
+	"github.com/sqlc-dev/teesql/ast"
+)
+
+// Constraint kinds.
+const (
+	PrimaryKey = "PrimaryKey"
+	Unique     = "Unique"
+	ForeignKey = "ForeignKey"
+	Check      = "Check"
+)
+
+// Table is a table, or the definition of a table type.
+type Table struct {
+	Schema      string        `json:"Schema"`
+	Name        string        `json:"Name"`
+	Columns     []*Column     `json:"Columns,omitempty"`
+	Constraints []*Constraint `json:"Constraints,omitempty"`
+	Indexes     []*Index      `json:"Indexes,omitempty"`
+}
+
+// Column is a table column.
+type Column struct {
+	Name string `json:"Name"`
+	// Type is the declared type, which may name a user-defined alias type
+	// (see Catalog.ResolveType). It is zero for computed columns.
+	Type     Type `json:"Type"`
+	Nullable bool `json:"Nullable"`
+	// Default is the column's DEFAULT constraint, if any.
+	Default *Default `json:"Default,omitempty"`
+	// Identity is set for IDENTITY columns.
+	Identity *Identity `json:"Identity,omitempty"`
+	// Computed is the expression of a computed column.
+	Computed   ast.ScalarExpression `json:"-"`
+	Persisted  bool                 `json:"Persisted,omitempty"`
+	Collation  string               `json:"Collation,omitempty"`
+	RowGuidCol bool                 `json:"RowGuidCol,omitempty"`
+	Hidden     bool                 `json:"Hidden,omitempty"`
+	// GeneratedAlways is RowStart or RowEnd for system-versioning period
+	// columns, as in ast.ColumnDefinition.
+	GeneratedAlways string `json:"GeneratedAlways,omitempty"`
+	// Mask is the dynamic data masking function of a masked column, such
+	// as "email()", or empty.
+	Mask string `json:"Mask,omitempty"`
+	// Encryption is set for columns encrypted with Always Encrypted.
+	Encryption *Encryption `json:"Encryption,omitempty"`
+}
+
+// Encryption is the Always Encrypted definition of a column.
+type Encryption struct {
+	// Key is the name of the column encryption key.
+	Key string `json:"Key"`
+	// Type is Deterministic or Randomized.
+	Type      string `json:"Type"`
+	Algorithm string `json:"Algorithm,omitempty"`
+}
+
+// Default is a DEFAULT constraint.
+type Default struct {
+	Name       string               `json:"Name,omitempty"`
+	Expression ast.ScalarExpression `json:"-"`
+}
+
+// Identity holds the seed and increment of an IDENTITY column.
+type Identity struct {
+	Seed      int64 `json:"Seed"`
+	Increment int64 `json:"Increment"`
+}
+
+// Constraint is a PRIMARY KEY, UNIQUE, FOREIGN KEY or CHECK constraint.
+type Constraint struct {
+	// Name is the constraint name, or empty for system-named constraints.
+	Name    string   `json:"Name,omitempty"`
+	Kind    string   `json:"Kind"`
+	Columns []string `json:"Columns,omitempty"`
+	// Clustered is set for clustered PRIMARY KEY and UNIQUE constraints.
+	Clustered bool `json:"Clustered,omitempty"`
+	// References, ReferencedColumns, OnDelete and OnUpdate describe a
+	// FOREIGN KEY. The actions are as in ast.ForeignKeyConstraintDefinition.
+	References        ObjectName `json:"References,omitempty"`
+	ReferencedColumns []string   `json:"ReferencedColumns,omitempty"`
+	OnDelete          string     `json:"OnDelete,omitempty"`
+	OnUpdate          string     `json:"OnUpdate,omitempty"`
+	// Check is the condition of a CHECK constraint.
+	Check ast.BooleanExpression `json:"-"`
+}
+
+// Index is an index on a table. Indexes backing PRIMARY KEY and UNIQUE
+// constraints are not listed.
+type Index struct {
+	Name        string        `json:"Name,omitempty"`
+	Columns     []IndexColumn `json:"Columns,omitempty"`
+	Include     []string      `json:"Include,omitempty"`
+	Unique      bool          `json:"Unique,omitempty"`
+	Clustered   bool          `json:"Clustered,omitempty"`
+	Columnstore bool          `json:"Columnstore,omitempty"`
+	// Filter is the WHERE clause of a filtered index.
+	Filter ast.BooleanExpression `json:"-"`
+}
+
+// IndexColumn is a key column of an index.
+type IndexColumn struct {
+	Name       string `json:"Name"`
+	Descending bool   `json:"Descending,omitempty"`
+}
+
+// Column returns the named column, or nil.
+func (t *Table) Column(name string) *Column {
+	for _, c := range t.Columns {
+		if strings.EqualFold(c.Name, name) {
+			return c
+		}
+	}
+	return nil
+}
+
+// Constraint returns the named constraint, or nil.
+func (t *Table) Constraint(name string) *Constraint {
+	for _, c := range t.Constraints {
+		if c.Name != "" && strings.EqualFold(c.Name, name) {
+			return c
+		}
+	}
+	return nil
+}
+
+// Index returns the named index, or nil.
+func (t *Table) Index(name string) *Index {
+	for _, ix := range t.Indexes {
+		if ix.Name != "" && strings.EqualFold(ix.Name, name) {
+			return ix
+		}
+	}
+	return nil
+}
+
+// PrimaryKey returns the table's primary key constraint, or nil.
+func (t *Table) PrimaryKey() *Constraint {
+	for _, c := range t.Constraints {
+		if c.Kind == PrimaryKey {
+			return c
+		}
+	}
+	return nil
+}
+
+func (c *Catalog) createTable(s *ast.CreateTableStatement) error {
+	name := c.qualify(NameOf(s.SchemaObjectName))
+	schema := c.schemaFor(name)
+	if schema.exists(name.Name) {
+		return existsError("table", name)
+	}
+	t := &Table{Schema: schema.Name, Name: name.Name}
+	switch {
+	case s.Definition != nil:
+		tableDefinition(t, s.Definition)
+	case s.SelectStatement != nil:
+		// CREATE TABLE AS SELECT: only the column names are known.
+		for _, col := range selectColumns(c, s.CtasColumns, s.SelectStatement) {
+			t.Columns = append(t.Columns, &Column{Name: col.Name, Nullable: true})
+		}
+	}
+	schema.Tables = append(schema.Tables, t)
+	return nil
+}
+
+// NewTable returns the table described by a table definition, such as the
+// body of a DECLARE @t TABLE statement. The table is not added to a catalog.
+func NewTable(name ObjectName, def *ast.TableDefinition) *Table {
+	t := &Table{Schema: name.Schema, Name: name.Name}
+	if def != nil {
+		tableDefinition(t, def)
+	}
+	return t
+}
+
+// tableDefinition adds the columns, constraints and indexes of def to t.
+func tableDefinition(t *Table, def *ast.TableDefinition) {
+	for _, cd := range def.ColumnDefinitions {
+		addColumn(t, cd)
+	}
+	for _, tc := range def.TableConstraints {
+		addConstraint(t, tc, nil)
+	}
+	for _, ix := range def.Indexes {
+		t.Indexes = append(t.Indexes, indexDefinition(ix))
+	}
+}
+
+func addColumn(t *Table, cd *ast.ColumnDefinition) *Column {
+	col := &Column{
+		Name:            identifierValue(cd.ColumnIdentifier),
+		Nullable:        true,
+		Computed:        cd.ComputedColumnExpression,
+		Persisted:       cd.IsPersisted,
+		Collation:       identifierValue(cd.Collation),
+		RowGuidCol:      cd.IsRowGuidCol,
+		Hidden:          cd.IsHidden,
+		GeneratedAlways: cd.GeneratedAlways,
+	}
+	if cd.DataType != nil {
+		col.Type = TypeOf(cd.DataType)
+	}
+	if cd.IsMasked {
+		col.Mask = maskingFunction(cd.MaskingFunction)
+	}
+	col.Encryption = encryption(cd.Encryption)
+	switch col.Type.Name {
+	case "rowversion", "timestamp":
+		col.Nullable = false
+	}
+	if cd.GeneratedAlways != "" {
+		col.Nullable = false
+	}
+	if cd.DefaultConstraint != nil {
+		col.Default = &Default{
+			Name:       identifierValue(cd.DefaultConstraint.ConstraintIdentifier),
+			Expression: cd.DefaultConstraint.Expression,
+		}
+	}
+	if cd.IdentityOptions != nil {
+		col.Identity = identity(cd.IdentityOptions)
+		col.Nullable = false
+	}
+	if cd.Nullable != nil {
+		col.Nullable = cd.Nullable.Nullable
+	}
+	t.Columns = append(t.Columns, col)
+	for _, cons := range cd.Constraints {
+		if n, ok := cons.(*ast.NullableConstraintDefinition); ok {
+			col.Nullable = n.Nullable
+			continue
+		}
+		if tc, ok := cons.(ast.TableConstraint); ok {
+			addConstraint(t, tc, col)
+		}
+	}
+	if cd.Index != nil {
+		ix := indexDefinition(cd.Index)
+		if len(ix.Columns) == 0 {
+			ix.Columns = []IndexColumn{{Name: col.Name}}
+		}
+		t.Indexes = append(t.Indexes, ix)
+	}
+	return col
+}
+
+// maskingFunction returns the text of a MASKED WITH (FUNCTION = '...')
+// clause.
+func maskingFunction(e ast.ScalarExpression) string {
+	if lit, ok := e.(*ast.StringLiteral); ok {
+		return lit.Value
+	}
+	return ""
+}
+
+func encryption(def *ast.ColumnEncryptionDefinition) *Encryption {
+	if def == nil {
+		return nil
+	}
+	enc := &Encryption{}
+	for _, p := range def.Parameters {
+		switch p := p.(type) {
+		case *ast.ColumnEncryptionKeyNameParameter:
+			enc.Key = identifierValue(p.Name)
+		case *ast.ColumnEncryptionTypeParameter:
+			enc.Type = p.EncryptionType
+		case *ast.ColumnEncryptionAlgorithmParameter:
+			if lit, ok := p.EncryptionAlgorithm.(*ast.StringLiteral); ok {
+				enc.Algorithm = lit.Value
+			}
+		}
+	}
+	return enc
+}
+
+func identity(opts *ast.IdentityOptions) *Identity {
+	id := &Identity{Seed: 1, Increment: 1}
+	if n, ok := intValue(opts.IdentitySeed); ok {
+		id.Seed = n
+	}
+	if n, ok := intValue(opts.IdentityIncrement); ok {
+		id.Increment = n
+	}
+	return id
+}
+
+// addConstraint adds a table constraint to t. col is the column a column
+// constraint was declared on, or nil for table constraints.
+func addConstraint(t *Table, tc ast.TableConstraint, col *Column) *Constraint {
+	var cons *Constraint
+	switch c := tc.(type) {
+	case *ast.UniqueConstraintDefinition:
+		cons = &Constraint{
+			Name:      identifierValue(c.ConstraintIdentifier),
+			Kind:      Unique,
+			Clustered: c.Clustered,
+		}
+		if c.IsPrimaryKey {
+			cons.Kind = PrimaryKey
+			// Primary keys are clustered unless declared NONCLUSTERED or
+			// the table already has a clustered index.
+			if c.IndexType == nil || c.IndexType.IndexTypeKind == "" {
+				cons.Clustered = !t.hasClustered()
+			}
+		}
+		for _, cs := range c.Columns {
+			cons.Columns = append(cons.Columns, columnName(cs.Column))
+		}
+		if len(cons.Columns) == 0 && col != nil {
+			cons.Columns = []string{col.Name}
+		}
+		if cons.Kind == PrimaryKey {
+			for _, name := range cons.Columns {
+				if pk := t.Column(name); pk != nil {
+					pk.Nullable = false
+				}
+			}
+		}
+	case *ast.ForeignKeyConstraintDefinition:
+		cons = &Constraint{
+			Name:              identifierValue(c.ConstraintIdentifier),
+			Kind:              ForeignKey,
+			Columns:           identifierValues(c.Columns),
+			References:        NameOf(c.ReferenceTableName),
+			ReferencedColumns: identifierValues(c.ReferencedColumns),
+			OnDelete:          referentialAction(c.DeleteAction),
+			OnUpdate:          referentialAction(c.UpdateAction),
+		}
+		if len(cons.Columns) == 0 && col != nil {
+			cons.Columns = []string{col.Name}
+		}
+	case *ast.CheckConstraintDefinition:
+		cons = &Constraint{
+			Name:  identifierValue(c.ConstraintIdentifier),
+			Kind:  Check,
+			Check: c.CheckCondition,
+		}
+		if col != nil {
+			cons.Columns = []string{col.Name}
+		}
+	case *ast.DefaultConstraintDefinition:
+		// Table-level DEFAULT ... FOR column.
+		target := col
+		if c.Column != nil {
+			target = t.Column(c.Column.Value)
+		}
+		if target != nil {
+			target.Default = &Default{
+				Name:       identifierValue(c.ConstraintIdentifier),
+				Expression: c.Expression,
+			}
+		}
+		return nil
+	default:
+		return nil
+	}
+	t.Constraints = append(t.Constraints, cons)
+	return cons
+}
+
+// referentialAction returns the action, or empty when it was not specified.
+func referentialAction(action string) string {
+	if action == "NotSpecified" {
+		return ""
+	}
+	return action
+}
+
+func (t *Table) hasClustered() bool {
+	for _, c := range t.Constraints {
+		if c.Clustered {
+			return true
+		}
+	}
+	for _, ix := range t.Indexes {
+		if ix.Clustered {
+			return true
+		}
+	}
+	return false
+}
+
+func indexDefinition(def *ast.IndexDefinition) *Index {
+	ix := &Index{
+		Name:   identifierValue(def.Name),
+		Unique: def.Unique,
+		Filter: def.FilterPredicate,
+	}
+	if def.IndexType != nil {
+		kind := def.IndexType.IndexTypeKind
+		ix.Clustered = strings.HasPrefix(kind, "Clustered")
+		ix.Columnstore = strings.HasSuffix(kind, "ColumnStore")
+	}
+	ix.Columns = indexColumns(def.Columns)
+	for _, inc := range def.IncludeColumns {
+		ix.Include = append(ix.Include, columnName(inc))
+	}
+	return ix
+}
+
+func indexColumns(cols []*ast.ColumnWithSortOrder) []IndexColumn {
+	var out []IndexColumn
+	for _, cs := range cols {
+		out = append(out, IndexColumn{
+			Name:       columnName(cs.Column),
+			Descending: cs.SortOrder == ast.SortOrderDescending,
+		})
+	}
+	return out
+}
+
+func (c *Catalog) createIndex(s *ast.CreateIndexStatement) error {
+	name := c.qualify(NameOf(s.OnName))
+	t := c.Table(name)
+	if t == nil {
+		return notFoundError("table", name)
+	}
+	if s.Name != nil && t.Index(s.Name.Value) != nil {
+		return existsError("index "+s.Name.Value+" on", name)
+	}
+	ix := &Index{
+		Name:    identifierValue(s.Name),
+		Unique:  s.Unique,
+		Columns: indexColumns(s.Columns),
+		Filter:  s.FilterPredicate,
+	}
+	if s.Clustered != nil {
+		ix.Clustered = *s.Clustered
+	}
+	for _, inc := range s.IncludeColumns {
+		ix.Include = append(ix.Include, columnName(inc))
+	}
+	t.Indexes = append(t.Indexes, ix)
+	return nil
+}
+
+func (c *Catalog) createColumnStoreIndex(s *ast.CreateColumnStoreIndexStatement) error {
+	name := c.qualify(NameOf(s.OnName))
+	t := c.Table(name)
+	if t == nil {
+		return notFoundError("table", name)
+	}
+	if s.Name != nil && t.Index(s.Name.Value) != nil {
+		return existsError("index "+s.Name.Value+" on", name)
+	}
+	ix := &Index{
+		Name:        identifierValue(s.Name),
+		Clustered:   s.Clustered,
+		Columnstore: true,
+		Filter:      s.FilterClause,
+	}
+	for _, col := range s.Columns {
+		ix.Columns = append(ix.Columns, IndexColumn{Name: columnName(col)})
+	}
+	t.Indexes = append(t.Indexes, ix)
+	return nil
+}
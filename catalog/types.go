@@ -0,0 +1,218 @@
+package catalog
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/sqlc-dev/teesql/ast"
+)
+
+// MaxLength is the Length of a (n)varchar(max) or varbinary(max) type.
+const MaxLength = -1
+
+// Type is a SQL Server data type.
+type Type struct {
+	// Schema is set for user-defined types that have not been resolved to a
+	// system type.
+	Schema string `json:"Schema,omitempty"`
+	// Name is the lower-case name of a system type, e.g. "nvarchar", or the
+	// name of a user-defined type as written.
+	Name string `json:"Name,omitempty"`
+	// Length is the declared length of character and binary types, or
+	// MaxLength.
+	Length int `json:"Length,omitempty"`
+	// Precision is the precision of decimal, numeric and float types.
+	Precision int `json:"Precision,omitempty"`
+	// Scale is the scale of decimal and numeric types, and the fractional
+	// seconds precision of time, datetime2 and datetimeoffset.
+	Scale int `json:"Scale,omitempty"`
+	// Alias is the schema-qualified name of the user-defined alias type
+	// this type was resolved from, if any.
+	Alias string `json:"Alias,omitempty"`
+}
+
+// IsZero reports whether the type is unknown.
+func (t Type) IsZero() bool {
+	return t.Name == ""
+}
+
+// IsUserDefined reports whether t names a user-defined type that has not
+// been resolved to a system type.
+func (t Type) IsUserDefined() bool {
+	return t.Name != "" && !IsSystemType(t.Name)
+}
+
+// String returns the type as it would be written in T-SQL.
+func (t Type) String() string {
+	name := t.Name
+	if t.Schema != "" {
+		name = t.Schema + "." + name
+	}
+	switch t.Name {
+	case "char", "varchar", "nchar", "nvarchar", "binary", "varbinary":
+		if t.Length == MaxLength {
+			return name + "(max)"
+		}
+		if t.Length > 0 {
+			return name + "(" + strconv.Itoa(t.Length) + ")"
+		}
+	case "decimal", "numeric":
+		return name + "(" + strconv.Itoa(t.Precision) + "," + strconv.Itoa(t.Scale) + ")"
+	case "float":
+		if t.Precision != 0 && t.Precision != 53 {
+			return name + "(" + strconv.Itoa(t.Precision) + ")"
+		}
+	case "time", "datetime2", "datetimeoffset":
+		if t.Scale != 7 {
+			return name + "(" + strconv.Itoa(t.Scale) + ")"
+		}
+	}
+	return name
+}
+
+// systemTypes maps SqlDataTypeOption values to system type names.
+var systemTypes = map[string]string{
+	"bigint":           "bigint",
+	"int":              "int",
+	"smallint":         "smallint",
+	"tinyint":          "tinyint",
+	"bit":              "bit",
+	"decimal":          "decimal",
+	"numeric":          "numeric",
+	"money":            "money",
+	"smallmoney":       "smallmoney",
+	"float":            "float",
+	"real":             "real",
+	"datetime":         "datetime",
+	"datetime2":        "datetime2",
+	"datetimeoffset":   "datetimeoffset",
+	"smalldatetime":    "smalldatetime",
+	"date":             "date",
+	"time":             "time",
+	"char":             "char",
+	"varchar":          "varchar",
+	"text":             "text",
+	"nchar":            "nchar",
+	"nvarchar":         "nvarchar",
+	"ntext":            "ntext",
+	"binary":           "binary",
+	"varbinary":        "varbinary",
+	"image":            "image",
+	"cursor":           "cursor",
+	"sql_variant":      "sql_variant",
+	"table":            "table",
+	"uniqueidentifier": "uniqueidentifier",
+	"xml":              "xml",
+	"json":             "json",
+	"rowversion":       "rowversion",
+	"timestamp":        "timestamp",
+	"vector":           "vector",
+	"hierarchyid":      "hierarchyid",
+	"geometry":         "geometry",
+	"geography":        "geography",
+}
+
+// IsSystemType reports whether name is a SQL Server system type.
+func IsSystemType(name string) bool {
+	_, ok := systemTypes[strings.ToLower(name)]
+	return ok
+}
+
+// TypeOf converts a data type reference to a Type, applying SQL Server's
+// defaults for omitted lengths, precisions and scales as they apply in
+// column and parameter declarations.
+func TypeOf(ref ast.DataTypeReference) Type {
+	switch r := ref.(type) {
+	case *ast.SqlDataTypeReference:
+		name, ok := systemTypes[strings.ToLower(r.SqlDataTypeOption)]
+		if !ok {
+			name = strings.ToLower(r.SqlDataTypeOption)
+		}
+		return withParameters(Type{Name: name}, r.Parameters)
+	case *ast.XmlDataTypeReference:
+		return Type{Name: "xml"}
+	case *ast.UserDataTypeReference:
+		if r.Name == nil || r.Name.BaseIdentifier == nil {
+			return Type{}
+		}
+		base := r.Name.BaseIdentifier.Value
+		if r.Name.SchemaIdentifier == nil {
+			if strings.EqualFold(base, "sysname") {
+				return Type{Name: "nvarchar", Length: 128, Alias: "sysname"}
+			}
+			if name, ok := systemTypes[strings.ToLower(base)]; ok {
+				return withParameters(Type{Name: name}, r.Parameters)
+			}
+		}
+		t := Type{Name: base}
+		if r.Name.SchemaIdentifier != nil {
+			t.Schema = r.Name.SchemaIdentifier.Value
+		}
+		return t
+	}
+	return Type{}
+}
+
+func withParameters(t Type, params []ast.ScalarExpression) Type {
+	switch t.Name {
+	case "char", "varchar", "nchar", "nvarchar", "binary", "varbinary":
+		t.Length = 1
+		if len(params) > 0 {
+			if _, ok := params[0].(*ast.MaxLiteral); ok {
+				t.Length = MaxLength
+			} else if n, ok := intValue(params[0]); ok {
+				t.Length = int(n)
+			}
+		}
+	case "decimal", "numeric":
+		t.Precision = 18
+		if len(params) > 0 {
+			if n, ok := intValue(params[0]); ok {
+				t.Precision = int(n)
+			}
+		}
+		if len(params) > 1 {
+			if n, ok := intValue(params[1]); ok {
+				t.Scale = int(n)
+			}
+		}
+	case "float":
+		t.Precision = 53
+		if len(params) > 0 {
+			if n, ok := intValue(params[0]); ok && n <= 24 {
+				return Type{Name: "real", Precision: 24}
+			}
+		}
+	case "real":
+		t.Precision = 24
+	case "time", "datetime2", "datetimeoffset":
+		t.Scale = 7
+		if len(params) > 0 {
+			if n, ok := intValue(params[0]); ok {
+				t.Scale = int(n)
+			}
+		}
+	}
+	return t
+}
+
+// intValue returns the value of an integer literal, allowing a leading sign.
+func intValue(expr ast.ScalarExpression) (int64, bool) {
+	switch e := expr.(type) {
+	case *ast.IntegerLiteral:
+		n, err := strconv.ParseInt(e.Value, 10, 64)
+		return n, err == nil
+	case *ast.NumericLiteral:
+		n, err := strconv.ParseInt(e.Value, 10, 64)
+		return n, err == nil
+	case *ast.ParenthesisExpression:
+		return intValue(e.Expression)
+	case *ast.UnaryExpression:
+		n, ok := intValue(e.Expression)
+		if e.UnaryExpressionType == "Negative" {
+			n = -n
+		}
+		return n, ok
+	}
+	return 0, false
+}
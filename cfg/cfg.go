@@ -0,0 +1,300 @@
+// Package cfg builds control-flow graphs over T-SQL statement lists, such
+// as the body of a procedure or the statements of a batch.
+//
+// The graph has a node for each statement that runs on its own. IF and
+// WHILE statements are represented by a node that evaluates the predicate,
+// with true and false edges to the branches; BEGIN...END blocks and
+// TRY...CATCH statements have no node of their own, their statements are
+// linked in sequence. BREAK, CONTINUE, GOTO and RETURN jump to the end of
+// the loop, the loop predicate, the label and the exit.
+//
+// Errors are modeled by error edges. Inside BEGIN TRY, any statement may
+// fail and has an error edge to the start of the CATCH block; THROW and
+// RAISERROR with a severity of 11 or more have only that edge. Outside of
+// TRY, THROW ends the batch and has an error edge to the exit, while
+// RAISERROR, as in SQL Server, continues with the next statement.
+package cfg
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/sqlc-dev/teesql/ast"
+)
+
+// Node kinds.
+const (
+	Entry     = "Entry"
+	Exit      = "Exit"
+	Statement = "Statement"
+	// Condition nodes evaluate the predicate of an IF or WHILE statement.
+	Condition = "Condition"
+)
+
+// Edge kinds.
+const (
+	Next  = ""
+	True  = "true"
+	False = "false"
+	Error = "error"
+)
+
+// Graph is a control-flow graph.
+type Graph struct {
+	Entry *Node
+	Exit  *Node
+	// Nodes holds every node in the order the statements appear, starting
+	// with the entry and ending with the exit.
+	Nodes []*Node
+}
+
+// Node is a node of the graph.
+type Node struct {
+	// ID is the index of the node in Graph.Nodes.
+	ID   int
+	Kind string
+	// Stmt is the statement the node runs, or, for condition nodes, the IF
+	// or WHILE statement. It is nil for the entry and exit.
+	Stmt  ast.Statement
+	Succs []*Edge
+	Preds []*Edge
+}
+
+// Edge is an edge of the graph.
+type Edge struct {
+	From *Node
+	To   *Node
+	Kind string
+}
+
+func (n *Node) String() string {
+	switch n.Kind {
+	case Entry:
+		return "entry"
+	case Exit:
+		return "exit"
+	}
+	return fmt.Sprintf("%d: %s", n.ID, label(n))
+}
+
+// pending is an edge whose target is not yet known.
+type pending struct {
+	from *Node
+	kind string
+}
+
+type loop struct {
+	cond   *Node
+	breaks []pending
+}
+
+type builder struct {
+	g     *Graph
+	loops []*loop
+	// catches holds, for each enclosing TRY block, the error edges into its
+	// CATCH block.
+	catches [][]pending
+	labels  map[string]*Node
+	gotos   []*Node
+	errs    []error
+}
+
+// New returns the control-flow graph of a list of statements. A GOTO to a
+// label that is not among the statements is reported as an error; the
+// graph is still returned, with no edge out of the GOTO.
+func New(stmts []ast.Statement) (*Graph, error) {
+	b := &builder{g: &Graph{}, labels: map[string]*Node{}}
+	b.g.Entry = b.node(Entry, nil, nil)
+	out := b.list(stmts, []pending{{from: b.g.Entry}})
+	b.g.Exit = b.node(Exit, nil, out)
+	for _, n := range b.gotos {
+		name := strings.ToLower(identifierValue(n.Stmt.(*ast.GoToStatement).LabelName))
+		if target := b.labels[name]; target != nil {
+			link(n, target, Next)
+		} else {
+			b.errs = append(b.errs, fmt.Errorf("%s: label %s is not defined", label(n), name))
+		}
+	}
+	// Exits are collected while building; link them now so the exit keeps
+	// its place as the last node.
+	for _, n := range b.g.Nodes {
+		switch n.Stmt.(type) {
+		case *ast.ReturnStatement:
+			link(n, b.g.Exit, Next)
+		case *ast.ThrowStatement:
+			if len(n.Succs) == 0 {
+				link(n, b.g.Exit, Error)
+			}
+		}
+	}
+	return b.g, errors.Join(b.errs...)
+}
+
+func link(from, to *Node, kind string) {
+	e := &Edge{From: from, To: to, Kind: kind}
+	from.Succs = append(from.Succs, e)
+	to.Preds = append(to.Preds, e)
+}
+
+// node adds a node entered by the pending edges in.
+func (b *builder) node(kind string, stmt ast.Statement, in []pending) *Node {
+	n := &Node{ID: len(b.g.Nodes), Kind: kind, Stmt: stmt}
+	b.g.Nodes = append(b.g.Nodes, n)
+	for _, p := range in {
+		link(p.from, n, p.kind)
+	}
+	if stmt != nil && len(b.catches) > 0 && mayFail(stmt) {
+		top := len(b.catches) - 1
+		b.catches[top] = append(b.catches[top], pending{from: n, kind: Error})
+	}
+	return n
+}
+
+// mayFail reports whether a statement can raise an error.
+func mayFail(stmt ast.Statement) bool {
+	switch stmt.(type) {
+	case *ast.BreakStatement, *ast.ContinueStatement, *ast.GoToStatement, *ast.LabelStatement:
+		return false
+	}
+	return true
+}
+
+func (b *builder) list(stmts []ast.Statement, in []pending) []pending {
+	for _, stmt := range stmts {
+		in = b.stmt(stmt, in)
+	}
+	return in
+}
+
+// stmt adds the nodes of stmt, entered by the pending edges in, and returns
+// the edges that leave it for the next statement.
+func (b *builder) stmt(stmt ast.Statement, in []pending) []pending {
+	switch s := stmt.(type) {
+	case *ast.BeginEndBlockStatement:
+		return b.list(statements(s.StatementList), in)
+	case *ast.BeginEndAtomicBlockStatement:
+		return b.list(statements(s.StatementList), in)
+	case *ast.IfStatement:
+		n := b.node(Condition, s, in)
+		out := b.stmt(s.ThenStatement, []pending{{from: n, kind: True}})
+		if s.ElseStatement != nil {
+			return append(out, b.stmt(s.ElseStatement, []pending{{from: n, kind: False}})...)
+		}
+		return append(out, pending{from: n, kind: False})
+	case *ast.WhileStatement:
+		n := b.node(Condition, s, in)
+		l := &loop{cond: n}
+		b.loops = append(b.loops, l)
+		for _, p := range b.stmt(s.Statement, []pending{{from: n, kind: True}}) {
+			link(p.from, n, p.kind)
+		}
+		b.loops = b.loops[:len(b.loops)-1]
+		return append(l.breaks, pending{from: n, kind: False})
+	case *ast.TryCatchStatement:
+		b.catches = append(b.catches, nil)
+		out := b.list(statements(s.TryStatements), in)
+		caught := b.catches[len(b.catches)-1]
+		b.catches = b.catches[:len(b.catches)-1]
+		return append(out, b.list(statements(s.CatchStatements), caught)...)
+	case *ast.BreakStatement:
+		n := b.node(Statement, s, in)
+		if len(b.loops) > 0 {
+			l := b.loops[len(b.loops)-1]
+			l.breaks = append(l.breaks, pending{from: n})
+		}
+		return nil
+	case *ast.ContinueStatement:
+		n := b.node(Statement, s, in)
+		if len(b.loops) > 0 {
+			link(n, b.loops[len(b.loops)-1].cond, Next)
+		}
+		return nil
+	case *ast.LabelStatement:
+		n := b.node(Statement, s, in)
+		name := strings.ToLower(strings.TrimSuffix(s.Value, ":"))
+		if b.labels[name] != nil {
+			b.errs = append(b.errs, fmt.Errorf("label %s is defined more than once", name))
+		} else {
+			b.labels[name] = n
+		}
+		return []pending{{from: n}}
+	case *ast.GoToStatement:
+		b.gotos = append(b.gotos, b.node(Statement, s, in))
+		return nil
+	case *ast.ReturnStatement:
+		b.node(Statement, s, in)
+		return nil
+	case *ast.ThrowStatement:
+		b.node(Statement, s, in)
+		return nil
+	case *ast.RaiseErrorStatement:
+		n := b.node(Statement, s, in)
+		if len(b.catches) > 0 && Severity(s) >= 11 {
+			return nil
+		}
+		return []pending{{from: n}}
+	case nil:
+		return in
+	}
+	return []pending{{from: b.node(Statement, stmt, in)}}
+}
+
+// Severity returns the severity of a RAISERROR, or -1 if it is not a
+// constant.
+func Severity(s *ast.RaiseErrorStatement) int {
+	lit, ok := s.SecondParameter.(*ast.IntegerLiteral)
+	if !ok {
+		return -1
+	}
+	n, err := strconv.Atoi(lit.Value)
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+func statements(list *ast.StatementList) []ast.Statement {
+	if list == nil {
+		return nil
+	}
+	return list.Statements
+}
+
+func identifierValue(id *ast.Identifier) string {
+	if id == nil {
+		return ""
+	}
+	return id.Value
+}
+
+// Reachable returns the nodes reachable from the entry.
+func (g *Graph) Reachable() map[*Node]bool {
+	seen := map[*Node]bool{g.Entry: true}
+	stack := []*Node{g.Entry}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, e := range n.Succs {
+			if !seen[e.To] {
+				seen[e.To] = true
+				stack = append(stack, e.To)
+			}
+		}
+	}
+	return seen
+}
+
+// Unreachable returns the statement and condition nodes that no path from
+// the entry reaches, in statement order.
+func (g *Graph) Unreachable() []*Node {
+	reachable := g.Reachable()
+	var nodes []*Node
+	for _, n := range g.Nodes {
+		if n.Stmt != nil && !reachable[n] {
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes
+}
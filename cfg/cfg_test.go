@@ -0,0 +1,204 @@
+package cfg
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sqlc-dev/teesql/parser"
+)
+
+// edges describes the edges of g, one per line, as "from -> to", with the
+// kind of the edge in brackets.
+func edges(g *Graph) string {
+	var lines []string
+	for _, n := range g.Nodes {
+		for _, e := range n.Succs {
+			s := label(e.From) + " -> " + label(e.To)
+			if e.Kind != Next {
+				s += " [" + e.Kind + "]"
+			}
+			lines = append(lines, s)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name        string
+		sql         string
+		edges       string
+		unreachable string
+		err         string
+	}{
+		{
+			name: "if",
+			sql: `IF @a = 1 BEGIN PRINT 'one'; PRINT 'uno'; END ELSE PRINT 'other';
+IF @b = 1 PRINT 'b';`,
+			edges: `entry -> IF @a = 1
+IF @a = 1 -> PRINT 'one' [true]
+IF @a = 1 -> PRINT 'other' [false]
+PRINT 'one' -> PRINT 'uno'
+PRINT 'uno' -> IF @b = 1
+PRINT 'other' -> IF @b = 1
+IF @b = 1 -> PRINT 'b' [true]
+IF @b = 1 -> exit [false]
+PRINT 'b' -> exit`,
+		},
+		{
+			name: "while",
+			sql: `WHILE @i < 10
+BEGIN
+	IF @i = 5 BREAK;
+	SET @i += 1;
+	IF @i = 2 CONTINUE;
+	PRINT @i;
+END
+PRINT 'done';`,
+			edges: `entry -> WHILE @i < 10
+WHILE @i < 10 -> IF @i = 5 [true]
+WHILE @i < 10 -> PRINT 'done' [false]
+IF @i = 5 -> BREAK [true]
+IF @i = 5 -> SET @i += 1 [false]
+BREAK -> PRINT 'done'
+SET @i += 1 -> IF @i = 2
+IF @i = 2 -> CONTINUE [true]
+IF @i = 2 -> PRINT @i [false]
+CONTINUE -> WHILE @i < 10
+PRINT @i -> WHILE @i < 10
+PRINT 'done' -> exit`,
+		},
+		{
+			name: "try catch",
+			sql: `BEGIN TRY
+	INSERT INTO t VALUES (1);
+	RAISERROR('warning', 10, 1);
+	RAISERROR('failed', 16, 1);
+	PRINT 'not reached';
+END TRY
+BEGIN CATCH
+	PRINT 'caught';
+END CATCH
+RAISERROR('continues', 16, 1);
+THROW 50000, 'failed', 1;`,
+			edges: `entry -> INSERT INTO t VALUES (1)
+INSERT INTO t VALUES (1) -> RAISERROR('warning', 10, 1)
+INSERT INTO t VALUES (1) -> PRINT 'caught' [error]
+RAISERROR('warning', 10, 1) -> RAISERROR('failed', 16, 1)
+RAISERROR('warning', 10, 1) -> PRINT 'caught' [error]
+RAISERROR('failed', 16, 1) -> PRINT 'caught' [error]
+PRINT 'not reached' -> PRINT 'caught' [error]
+PRINT 'not reached' -> RAISERROR('continues', 16, 1)
+PRINT 'caught' -> RAISERROR('continues', 16, 1)
+RAISERROR('continues', 16, 1) -> THROW 50000, 'failed', 1
+THROW 50000, 'failed', 1 -> exit [error]`,
+			unreachable: "PRINT 'not reached'",
+		},
+		{
+			name: "nested try",
+			sql: `BEGIN TRY
+	BEGIN TRY
+		EXEC dbo.p;
+	END TRY
+	BEGIN CATCH
+		THROW;
+	END CATCH
+END TRY
+BEGIN CATCH
+	RETURN 1;
+END CATCH`,
+			edges: `entry -> EXEC dbo.p
+EXEC dbo.p -> THROW [error]
+EXEC dbo.p -> exit
+THROW -> RETURN 1 [error]
+RETURN 1 -> exit`,
+		},
+		{
+			name: "goto and return",
+			sql: `IF @a IS NULL GOTO fail;
+RETURN 0;
+PRINT 'dead';
+fail:
+RETURN 1;`,
+			edges: `entry -> IF @a IS NULL
+IF @a IS NULL -> GOTO fail [true]
+IF @a IS NULL -> RETURN 0 [false]
+GOTO fail -> fail:
+RETURN 0 -> exit
+PRINT 'dead' -> fail:
+fail: -> RETURN 1
+RETURN 1 -> exit`,
+			unreachable: "PRINT 'dead'",
+		},
+		{
+			name:  "missing label",
+			sql:   `GOTO nowhere;`,
+			edges: `entry -> GOTO nowhere`,
+			err:   "GOTO nowhere: label nowhere is not defined",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script, err := parser.Parse(context.Background(), strings.NewReader(tt.sql))
+			if err != nil {
+				t.Fatal(err)
+			}
+			g, err := New(script.Batches[0].Statements)
+			if got := errString(err); got != tt.err {
+				t.Errorf("error = %q, want %q", got, tt.err)
+			}
+			if got := edges(g); got != tt.edges {
+				t.Errorf("got edges:\n%s\nwant:\n%s", got, tt.edges)
+			}
+			var unreachable []string
+			for _, n := range g.Unreachable() {
+				unreachable = append(unreachable, label(n))
+			}
+			if got := strings.Join(unreachable, ", "); got != tt.unreachable {
+				t.Errorf("unreachable = %q, want %q", got, tt.unreachable)
+			}
+		})
+	}
+}
+
+func TestWriteDOT(t *testing.T) {
+	script, err := parser.Parse(context.Background(), strings.NewReader(`BEGIN TRY IF @a = 1 PRINT 'a'; END TRY BEGIN CATCH THROW; END CATCH`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := New(script.Batches[0].Statements)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	if err := g.WriteDOT(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := `digraph cfg {
+	node [shape=box];
+	n0 [label="entry", shape=oval];
+	n1 [label="IF @a = 1", shape=diamond];
+	n2 [label="PRINT 'a'"];
+	n3 [label="THROW"];
+	n4 [label="exit", shape=oval];
+	n0 -> n1;
+	n1 -> n2 [label=true];
+	n1 -> n3 [label=error, style=dashed];
+	n1 -> n4 [label=false];
+	n2 -> n3 [label=error, style=dashed];
+	n2 -> n4;
+	n3 -> n4 [label=error, style=dashed];
+}
+`
+	if b.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", b.String(), want)
+	}
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,73 @@
+package cfg
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/sqlc-dev/teesql/ast"
+	"github.com/sqlc-dev/teesql/format"
+)
+
+// maxLabel is the length at which node labels are cut.
+const maxLabel = 40
+
+// WriteDOT writes the graph in the Graphviz DOT language. Nodes are labeled
+// with the first line of their statement; error edges are dashed.
+func (g *Graph) WriteDOT(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("digraph cfg {\n\tnode [shape=box];\n")
+	for _, n := range g.Nodes {
+		shape := ""
+		switch n.Kind {
+		case Entry, Exit:
+			shape = ", shape=oval"
+		case Condition:
+			shape = ", shape=diamond"
+		}
+		fmt.Fprintf(&b, "\tn%d [label=%s%s];\n", n.ID, strconv.Quote(label(n)), shape)
+	}
+	for _, n := range g.Nodes {
+		for _, e := range n.Succs {
+			attrs := ""
+			switch e.Kind {
+			case True, False:
+				attrs = " [label=" + e.Kind + "]"
+			case Error:
+				attrs = " [label=error, style=dashed]"
+			}
+			fmt.Fprintf(&b, "\tn%d -> n%d%s;\n", e.From.ID, e.To.ID, attrs)
+		}
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// label returns a short description of the node's statement.
+func label(n *Node) string {
+	var s string
+	switch stmt := n.Stmt.(type) {
+	case nil:
+		return strings.ToLower(n.Kind)
+	case *ast.IfStatement:
+		s = "IF " + format.Node(stmt.Predicate)
+	case *ast.WhileStatement:
+		s = "WHILE " + format.Node(stmt.Predicate)
+	case *ast.GoToStatement:
+		s = "GOTO " + identifierValue(stmt.LabelName)
+	case *ast.LabelStatement:
+		s = stmt.Value
+	default:
+		s = format.Node(stmt)
+	}
+	s, _, cut := strings.Cut(s, "\n")
+	if r := []rune(s); len(r) > maxLabel {
+		s, cut = string(r[:maxLabel]), true
+	}
+	if cut {
+		s += " ..."
+	}
+	return s
+}
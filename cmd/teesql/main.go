@@ -0,0 +1,352 @@
+// Command teesql works with T-SQL schemas and queries.
+//
+// Usage:
+//
+//	teesql gen -schema DIR -queries FILE [-package NAME] [-out FILE] [-procedures]
+//	teesql check [-json] PATH...
+//	teesql risk [-schema DIR] [-json] PATH...
+//	teesql diff [-json] [-out FILE] FROM TO
+//	teesql deps [-format order|dot|mermaid] DIR
+//	teesql impact [-dir DIR] [-json] OBJECT
+//
+// The gen command writes a Go data-access package for the annotated queries
+// in FILE, typed against the schema that the .sql files under DIR define.
+//
+// The check command reports stored procedures in the given files, or the
+// .sql files under the given directories, that run dynamic SQL built from
+// their parameters or from table data without sanitizing it. It exits with
+// status 1 if it finds any.
+//
+// The risk command classifies the statements of the migration scripts in
+// the given files or directories as causing data loss, blocking or being
+// non-idempotent. With -schema, ALTER COLUMN statements are checked for
+// narrowing against the schema the .sql files under DIR define. It exits
+// with status 1 if any statement is at risk.
+//
+// The diff command compares the schemas that the .sql files under the
+// directories FROM and TO define, and writes the T-SQL migration script
+// from one to the other, or with -json, the list of changes.
+//
+// The deps command builds the dependency graph of the objects that the .sql
+// files under DIR define, and writes them in an order they can be deployed
+// in, followed by the foreign keys to add once both their tables exist, or
+// the graph in the Graphviz DOT or Mermaid format. It exits with status 1
+// if the objects depend on each other in a cycle that deferring foreign
+// keys and the references procedures resolve when they run does not break.
+//
+// The impact command lists the views, procedures, functions and triggers
+// defined by the .sql files under DIR, by default the current directory,
+// that read, write, execute or call OBJECT, directly or through other
+// objects, including from constant dynamic SQL. OBJECT is a name such as
+// dbo.Orders, or a column such as dbo.Orders.Total to find what dropping
+// or changing the column breaks.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/sqlc-dev/teesql/ast"
+	"github.com/sqlc-dev/teesql/catalog"
+	"github.com/sqlc-dev/teesql/codegen"
+	"github.com/sqlc-dev/teesql/deps"
+	"github.com/sqlc-dev/teesql/impact"
+	"github.com/sqlc-dev/teesql/injection"
+	"github.com/sqlc-dev/teesql/migration"
+	"github.com/sqlc-dev/teesql/parser"
+	"github.com/sqlc-dev/teesql/schemadiff"
+)
+
+var commands = map[string]func(ctx context.Context, args []string) error{
+	"gen":    gen,
+	"check":  check,
+	"risk":   risk,
+	"diff":   diff,
+	"deps":   dependencies,
+	"impact": impacts,
+}
+
+func main() {
+	if len(os.Args) < 2 || commands[os.Args[1]] == nil {
+		fmt.Fprintln(os.Stderr, "usage: teesql gen|check|risk|diff|deps|impact [flags]")
+		os.Exit(2)
+	}
+	if err := commands[os.Args[1]](context.Background(), os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "teesql %s: %v\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
+
+func gen(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("gen", flag.ExitOnError)
+	schema := fs.String("schema", "", "directory of DDL `files`")
+	queries := fs.String("queries", "", "annotated query `file`")
+	pkg := fs.String("package", "db", "package `name` of the generated code")
+	out := fs.String("out", "", "output `file` (default standard output)")
+	procs := fs.Bool("procedures", false, "generate wrappers for the schema's stored procedures")
+	fs.Parse(args)
+	if *schema == "" {
+		return fmt.Errorf("-schema is required")
+	}
+	cat, err := catalog.LoadDir(ctx, *schema)
+	if err != nil {
+		return err
+	}
+	var script *ast.Script
+	if *queries != "" {
+		script, err = parseFile(ctx, *queries, parser.Options{})
+		if err != nil {
+			return err
+		}
+	}
+	src, err := codegen.Generate(cat, script, codegen.Options{Package: *pkg, Procedures: *procs})
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+	return os.WriteFile(*out, src, 0o644)
+}
+
+func check(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "write the findings as JSON")
+	fs.Parse(args)
+	paths, err := sqlFiles(fs.Args())
+	if err != nil {
+		return err
+	}
+	type result struct {
+		File string `json:"File"`
+		*injection.Finding
+	}
+	results := []result{}
+	for _, path := range paths {
+		script, err := parseFile(ctx, path, parser.Options{})
+		if err != nil {
+			return err
+		}
+		for _, f := range injection.Check(script) {
+			results = append(results, result{path, f})
+		}
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(results); err != nil {
+			return err
+		}
+	} else {
+		for _, r := range results {
+			fmt.Printf("%s:%s\n", r.File, r.Finding)
+		}
+	}
+	if len(results) > 0 {
+		return fmt.Errorf("%d SQL injection risks found", len(results))
+	}
+	return nil
+}
+
+func risk(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("risk", flag.ExitOnError)
+	schema := fs.String("schema", "", "directory of DDL `files` defining the schema before the migration")
+	asJSON := fs.Bool("json", false, "write the reports as JSON")
+	fs.Parse(args)
+	var opts migration.Options
+	if *schema != "" {
+		scripts, err := catalog.ParseDir(ctx, *schema)
+		if err != nil {
+			return err
+		}
+		opts.Schema = scripts
+	}
+	paths, err := sqlFiles(fs.Args())
+	if err != nil {
+		return err
+	}
+	type result struct {
+		File string `json:"File"`
+		*migration.Report
+	}
+	results := []result{}
+	risks := 0
+	for _, path := range paths {
+		script, err := parseFile(ctx, path, parser.Options{})
+		if err != nil {
+			return err
+		}
+		report := migration.Check(script, opts)
+		results = append(results, result{path, report})
+		// The schema grows with each migration, so later files are checked
+		// against the earlier ones.
+		opts.Schema = append(opts.Schema, script)
+		for _, s := range report.Risky() {
+			risks += len(s.Risks)
+		}
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(results); err != nil {
+			return err
+		}
+	} else {
+		for _, r := range results {
+			for _, s := range r.Risky() {
+				for _, risk := range s.Risks {
+					fmt.Printf("%s:%s: %s\n", r.File, s.Pos, risk)
+				}
+			}
+		}
+	}
+	if risks > 0 {
+		return fmt.Errorf("%d migration risks found", risks)
+	}
+	return nil
+}
+
+func diff(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "write the changes as JSON instead of a script")
+	out := fs.String("out", "", "output `file` (default standard output)")
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		return fmt.Errorf("usage: teesql diff [-json] [-out FILE] FROM TO")
+	}
+	from, err := catalog.LoadDir(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	to, err := catalog.LoadDir(ctx, fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	d := schemadiff.Compare(from, to)
+	src := []byte(d.Script())
+	if *asJSON {
+		src, err = json.MarshalIndent(d, "", "  ")
+		if err != nil {
+			return err
+		}
+		src = append(src, '\n')
+	}
+	if *out == "" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+	return os.WriteFile(*out, src, 0o644)
+}
+
+func dependencies(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("deps", flag.ExitOnError)
+	format := fs.String("format", "order", "output `format`: order, dot or mermaid")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: teesql deps [-format order|dot|mermaid] DIR")
+	}
+	scripts, err := catalog.ParseDir(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	g := deps.Build(scripts...)
+	switch *format {
+	case "order":
+		order, err := g.Order()
+		for _, n := range order {
+			fmt.Printf("%s %s\n", n.Kind, n)
+		}
+		for _, e := range g.DeferredForeignKeys() {
+			fmt.Printf("deferred foreign key %s -> %s\n", e.From, e.To)
+		}
+		return err
+	case "dot":
+		if err := g.WriteDOT(os.Stdout); err != nil {
+			return err
+		}
+	case "mermaid":
+		if err := g.WriteMermaid(os.Stdout); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	if cycles := g.Cycles(); len(cycles) > 0 {
+		return &deps.CycleError{Cycles: cycles}
+	}
+	return nil
+}
+
+func impacts(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("impact", flag.ExitOnError)
+	dir := fs.String("dir", ".", "directory of DDL `files`")
+	asJSON := fs.Bool("json", false, "write the impacts as JSON")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: teesql impact [-dir DIR] [-json] OBJECT")
+	}
+	paths, err := sqlFiles([]string{*dir})
+	if err != nil {
+		return err
+	}
+	var scripts []*ast.Script
+	for _, path := range paths {
+		script, err := parseFile(ctx, path, parser.Options{DynamicSQL: true})
+		if err != nil {
+			return err
+		}
+		scripts = append(scripts, script)
+	}
+	found, err := impact.Analyze(fs.Arg(0), scripts...)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(found)
+	}
+	for _, i := range found {
+		fmt.Println(i)
+	}
+	return nil
+}
+
+// sqlFiles returns the files named by args, with each directory replaced by
+// the .sql files under it, in the order catalog.ParseDir reads them.
+func sqlFiles(args []string) ([]string, error) {
+	var paths []string
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			paths = append(paths, arg)
+			continue
+		}
+		files, err := catalog.SQLFiles(arg)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, files...)
+	}
+	return paths, nil
+}
+
+func parseFile(ctx context.Context, path string, opts parser.Options) (*ast.Script, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	script, err := parser.ParseWithOptions(ctx, f, opts)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return script, nil
+}
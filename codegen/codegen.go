@@ -0,0 +1,565 @@
+// Package codegen generates a typed Go data-access package from sqlc-style
+// annotated queries and a schema.
+//
+// The generated code uses database/sql with go-mssqldb's parameter style:
+// parameters are written @name in the query text and passed with
+// sql.Named. Each annotated query becomes a method of a Queries type, with
+// structs for its parameters and result rows, and each stored procedure in
+// the schema can be given a wrapper that calls it by name.
+package codegen
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"go/format"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sqlc-dev/teesql/ast"
+	"github.com/sqlc-dev/teesql/catalog"
+	"github.com/sqlc-dev/teesql/parser"
+	"github.com/sqlc-dev/teesql/sqlc"
+	"github.com/sqlc-dev/teesql/typecheck"
+)
+
+// Options configure the generated package.
+type Options struct {
+	// Package is the package name. It defaults to db.
+	Package string
+	// Procedures adds a wrapper for every stored procedure in the catalog.
+	Procedures bool
+}
+
+// Generate returns the source of a Go package with a method for each
+// annotated query in queries, which may be nil, and for the catalog's
+// procedures if opts.Procedures is set.
+func Generate(cat *catalog.Catalog, queries *ast.Script, opts Options) ([]byte, error) {
+	if opts.Package == "" {
+		opts.Package = "db"
+	}
+	g := &generator{cat: cat, imports: map[string]bool{"context": true, "database/sql": true}, tvps: map[string]*tvp{}}
+	var errs []error
+	if queries != nil {
+		f := sqlc.Read(queries)
+		for _, e := range f.Errors {
+			errs = append(errs, e)
+		}
+		if len(errs) > 0 {
+			return nil, errors.Join(errs...)
+		}
+		for _, q := range f.Queries {
+			m, err := g.query(q)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: query %s: %w", q.Pos, q.Name, err))
+				continue
+			}
+			g.methods = append(g.methods, m)
+		}
+	}
+	if opts.Procedures {
+		for _, s := range cat.Schemas {
+			for _, p := range s.Procedures {
+				g.methods = append(g.methods, g.procedure(p))
+			}
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	src := g.render(opts.Package)
+	out, err := format.Source(src)
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %w\n%s", err, src)
+	}
+	return out, nil
+}
+
+// Result shapes of a generated method.
+const (
+	shapeOne     = "one"     // a single row
+	shapeMany    = "many"    // a slice of rows
+	shapeExec    = "exec"    // no rows
+	shapeRows    = "rows"    // the number of rows affected
+	shapeResult  = "result"  // the sql.Result
+	shapeRawRows = "rawrows" // *sql.Rows, for result sets not known statically
+)
+
+// method is a generated query or procedure method.
+type method struct {
+	name      string
+	doc       string
+	constName string
+	sql       string
+	shape     string
+	// params are the method's parameters. A method with more than one
+	// parameter, or with output parameters, takes a params struct.
+	params []*param
+	// columns are the columns of the rows returned.
+	columns []*field
+	// pointer is set when the params struct is passed by pointer, so that
+	// output parameters can be stored in it.
+	pointer bool
+}
+
+type param struct {
+	// sqlName is the parameter name without the @.
+	sqlName string
+	field   string
+	arg     string
+	typ     goType
+	slice   bool
+	output  bool
+	// tvp is the table type of a table-valued parameter.
+	tvp *tvp
+}
+
+type field struct {
+	name string
+	typ  goType
+}
+
+// tvp is a table type passed as a table-valued parameter.
+type tvp struct {
+	sqlName string
+	goName  string
+	fields  []*field
+}
+
+type generator struct {
+	cat     *catalog.Catalog
+	methods []*method
+	imports map[string]bool
+	tvps    map[string]*tvp
+}
+
+// query builds the method for an annotated query. Macros are replaced by
+// parameters, and the resulting statement is type checked to find the
+// parameter and column types.
+func (g *generator) query(q *sqlc.Query) (*method, error) {
+	m := &method{name: exported(q.Name), constName: unexported(q.Name), sql: strings.TrimSuffix(strings.TrimSpace(q.SQL()), ";")}
+	switch q.Command {
+	case sqlc.CommandOne:
+		m.shape = shapeOne
+	case sqlc.CommandMany:
+		m.shape = shapeMany
+	case sqlc.CommandExec:
+		m.shape = shapeExec
+	case sqlc.CommandExecRows:
+		m.shape = shapeRows
+	case sqlc.CommandExecResult:
+		m.shape = shapeResult
+	default:
+		return nil, fmt.Errorf("command :%s is not supported for SQL Server", q.Command)
+	}
+	script, err := parser.Parse(context.Background(), strings.NewReader(m.sql))
+	if err != nil {
+		return nil, err
+	}
+	if len(script.Batches) != 1 || len(script.Batches[0].Statements) != 1 {
+		return nil, errors.New("query must be a single statement")
+	}
+	stmt := script.Batches[0].Statements[0]
+	info := typecheck.CheckStatement(stmt, g.cat)
+	kinds := map[string]string{}
+	for _, mac := range q.Macros {
+		kinds[strings.ToLower(mac.Name)] = mac.Kind
+	}
+	for _, p := range info.Parameters(stmt).Params {
+		name := strings.TrimPrefix(p.Name, "@")
+		kind := kinds[strings.ToLower(name)]
+		typ := columnType(p.Type)
+		if kind == sqlc.MacroNarg && !p.Type.IsZero() {
+			typ = typeOf(p.Type.Type, true)
+		}
+		if kind == sqlc.MacroSlice {
+			typ = columnType(typecheck.Type{Type: p.Type.Type})
+		}
+		m.params = append(m.params, &param{sqlName: name, typ: typ, slice: kind == sqlc.MacroSlice})
+	}
+	if m.shape == shapeOne || m.shape == shapeMany {
+		cols := info.StatementColumns(stmt)
+		if cols == nil {
+			return nil, errors.New("result columns could not be determined")
+		}
+		m.columns = resultFields(cols)
+	}
+	g.nameParams(m)
+	return m, nil
+}
+
+// procedure builds the wrapper method for a stored procedure.
+func (g *generator) procedure(p *catalog.Procedure) *method {
+	qualified := p.Schema + "." + p.Name
+	base := p.Name
+	if !strings.EqualFold(p.Schema, catalog.DefaultSchema) {
+		base = p.Schema + "_" + p.Name
+	}
+	m := &method{name: exported(base), constName: unexported(base), sql: qualified}
+	for _, cp := range p.Parameters {
+		prm := &param{sqlName: strings.TrimPrefix(cp.Name, "@"), output: cp.Output}
+		if t := g.cat.TableType(cp); t != nil {
+			prm.tvp = g.tableType(t)
+			prm.typ = goType{name: "[]" + prm.tvp.goName}
+		} else {
+			prm.typ = typeOf(g.cat.ResolveType(cp.Type), cp.Output || cp.HasDefault())
+		}
+		if cp.Output {
+			m.pointer = true
+		}
+		m.params = append(m.params, prm)
+	}
+	results := typecheck.CatalogProcedureResults(g.cat, p)
+	switch {
+	case len(results) == 0:
+		m.shape = shapeExec
+		m.doc = fmt.Sprintf("%s executes %s.", m.name, qualified)
+	case len(results) == 1 && !results[0].Unknown && results[0].Columns != nil:
+		m.shape = shapeMany
+		m.doc = fmt.Sprintf("%s executes %s and returns the rows of its result set.", m.name, qualified)
+		m.columns = resultFields(results[0].Columns)
+	default:
+		m.shape = shapeRawRows
+		m.doc = fmt.Sprintf("%s executes %s. Its result sets are not known\n// statically, so the rows are returned for the caller to read.", m.name, qualified)
+	}
+	if m.pointer {
+		m.doc += "\n// Output parameters are stored in arg when the call completes."
+	}
+	g.nameParams(m)
+	return m
+}
+
+// tableType returns the Go struct for a table type, declaring it once.
+func (g *generator) tableType(t *catalog.UserDefinedType) *tvp {
+	qualified := t.Schema + "." + t.Name
+	if v, ok := g.tvps[strings.ToLower(qualified)]; ok {
+		return v
+	}
+	base := t.Name
+	if !strings.EqualFold(t.Schema, catalog.DefaultSchema) {
+		base = t.Schema + "_" + t.Name
+	}
+	v := &tvp{sqlName: qualified, goName: exported(base)}
+	var names []string
+	for _, c := range t.Table.Columns {
+		names = append(names, c.Name)
+	}
+	for i, name := range fieldNames(names) {
+		c := t.Table.Columns[i]
+		v.fields = append(v.fields, &field{name: name, typ: typeOf(g.cat.ResolveType(c.Type), c.Nullable)})
+	}
+	g.tvps[strings.ToLower(qualified)] = v
+	return v
+}
+
+// nameParams assigns the Go names of a method's parameters.
+func (g *generator) nameParams(m *method) {
+	names := make([]string, len(m.params))
+	for i, p := range m.params {
+		names[i] = p.sqlName
+	}
+	for i, name := range fieldNames(names) {
+		m.params[i].field = name
+		m.params[i].arg = unexported(m.params[i].sqlName)
+	}
+}
+
+// usesStruct reports whether a method takes its parameters as a struct.
+func (m *method) usesStruct() bool {
+	return len(m.params) > 1 || m.pointer || (len(m.params) == 1 && m.params[0].output)
+}
+
+// resultFields returns the row struct fields for result columns. Unnamed
+// columns are named by position.
+func resultFields(cols []typecheck.ResultColumn) []*field {
+	names := make([]string, len(cols))
+	for i, c := range cols {
+		names[i] = c.Name
+		if c.Unnamed {
+			names[i] = "column_" + strconv.Itoa(i+1)
+		}
+	}
+	fields := make([]*field, len(cols))
+	for i, name := range fieldNames(names) {
+		fields[i] = &field{name: name, typ: columnType(cols[i].Type)}
+	}
+	return fields
+}
+
+// fieldNames returns distinct exported Go names for SQL names.
+func fieldNames(names []string) []string {
+	out := make([]string, len(names))
+	seen := map[string]int{}
+	for i, name := range names {
+		s := exported(name)
+		seen[s]++
+		if n := seen[s]; n > 1 {
+			s += strconv.Itoa(n)
+		}
+		out[i] = s
+	}
+	return out
+}
+
+func (g *generator) use(t goType) string {
+	if t.imp != "" {
+		g.imports[t.imp] = true
+	}
+	return t.name
+}
+
+func (g *generator) render(pkg string) []byte {
+	var body bytes.Buffer
+	w := func(format string, args ...any) { fmt.Fprintf(&body, format, args...) }
+	slices := false
+
+	tvpNames := make([]string, 0, len(g.tvps))
+	for k := range g.tvps {
+		tvpNames = append(tvpNames, k)
+	}
+	sort.Strings(tvpNames)
+	for _, k := range tvpNames {
+		v := g.tvps[k]
+		w("// %s is a row of the table type %s.\n", v.goName, v.sqlName)
+		w("type %s struct {\n", v.goName)
+		for _, f := range v.fields {
+			w("%s %s\n", f.name, g.use(f.typ))
+		}
+		w("}\n\n")
+	}
+
+	for _, m := range g.methods {
+		w("const %s = %s\n\n", m.constName, quote(m.sql))
+
+		paramsType := m.name + "Params"
+		if m.usesStruct() {
+			w("type %s struct {\n", paramsType)
+			for _, p := range m.params {
+				typ := g.use(p.typ)
+				if p.slice {
+					typ = "[]" + typ
+				}
+				if p.output {
+					w("%s %s // OUTPUT\n", p.field, typ)
+				} else {
+					w("%s %s\n", p.field, typ)
+				}
+			}
+			w("}\n\n")
+		}
+
+		rowType := ""
+		switch {
+		case len(m.columns) == 1:
+			rowType = g.use(m.columns[0].typ)
+		case len(m.columns) > 1:
+			rowType = m.name + "Row"
+			w("type %s struct {\n", rowType)
+			for _, f := range m.columns {
+				w("%s %s\n", f.name, g.use(f.typ))
+			}
+			w("}\n\n")
+		}
+
+		var sig []string
+		var values []string
+		for _, p := range m.params {
+			value := p.arg
+			if m.usesStruct() {
+				value = "arg." + p.field
+			} else {
+				typ := g.use(p.typ)
+				if p.slice {
+					typ = "[]" + typ
+				}
+				sig = append(sig, p.arg+" "+typ)
+			}
+			switch {
+			case p.slice:
+				continue
+			case p.output:
+				value = "sql.Out{Dest: &" + value + "}"
+			case p.tvp != nil:
+				g.imports[mssqlImport] = true
+				value = "mssql.TVP{TypeName: " + strconv.Quote(p.tvp.sqlName) + ", Value: " + value + "}"
+			}
+			values = append(values, "sql.Named("+strconv.Quote(p.sqlName)+", "+value+")")
+		}
+		if m.usesStruct() {
+			if m.pointer {
+				sig = []string{"arg *" + paramsType}
+			} else {
+				sig = []string{"arg " + paramsType}
+			}
+		}
+
+		var results string
+		switch m.shape {
+		case shapeOne:
+			results = "(" + rowType + ", error)"
+		case shapeMany:
+			results = "([]" + rowType + ", error)"
+		case shapeExec:
+			results = "error"
+		case shapeRows:
+			results = "(int64, error)"
+		case shapeResult:
+			results = "(sql.Result, error)"
+		case shapeRawRows:
+			results = "(*sql.Rows, error)"
+		}
+		if m.doc != "" {
+			w("// %s\n", m.doc)
+		}
+		w("func (q *Queries) %s(%s) %s {\n", m.name, strings.Join(append([]string{"ctx context.Context"}, sig...), ", "), results)
+
+		query := m.constName
+		args := strings.Join(values, ", ")
+		hasSlice := false
+		for _, p := range m.params {
+			hasSlice = hasSlice || p.slice
+		}
+		if hasSlice {
+			slices = true
+			w("query := %s\n", m.constName)
+			w("args := []any{%s}\n", args)
+			for _, p := range m.params {
+				if p.slice {
+					value := p.arg
+					if m.usesStruct() {
+						value = "arg." + p.field
+					}
+					w("query, args = expandSlice(query, %s, %s, args)\n", strconv.Quote(p.sqlName), value)
+				}
+			}
+			query, args = "query", "args..."
+		}
+		call := query
+		if args != "" {
+			call += ", " + args
+		}
+
+		scan := func(v string) string {
+			if len(m.columns) == 1 {
+				return "&" + v
+			}
+			var dests []string
+			for _, f := range m.columns {
+				dests = append(dests, "&"+v+"."+f.name)
+			}
+			return strings.Join(dests, ", ")
+		}
+		switch m.shape {
+		case shapeOne:
+			w("row := q.db.QueryRowContext(ctx, %s)\n", call)
+			w("var i %s\n", rowType)
+			w("err := row.Scan(%s)\n", scan("i"))
+			w("return i, err\n")
+		case shapeMany:
+			w("rows, err := q.db.QueryContext(ctx, %s)\n", call)
+			w("if err != nil {\nreturn nil, err\n}\n")
+			w("defer rows.Close()\n")
+			w("var items []%s\n", rowType)
+			w("for rows.Next() {\n")
+			w("var i %s\n", rowType)
+			w("if err := rows.Scan(%s); err != nil {\nreturn nil, err\n}\n", scan("i"))
+			w("items = append(items, i)\n")
+			w("}\n")
+			w("if err := rows.Err(); err != nil {\nreturn nil, err\n}\n")
+			w("return items, nil\n")
+		case shapeExec:
+			w("_, err := q.db.ExecContext(ctx, %s)\n", call)
+			w("return err\n")
+		case shapeRows:
+			w("result, err := q.db.ExecContext(ctx, %s)\n", call)
+			w("if err != nil {\nreturn 0, err\n}\n")
+			w("return result.RowsAffected()\n")
+		case shapeResult:
+			w("return q.db.ExecContext(ctx, %s)\n", call)
+		case shapeRawRows:
+			w("return q.db.QueryContext(ctx, %s)\n", call)
+		}
+		w("}\n\n")
+	}
+
+	if slices {
+		g.imports["strings"] = true
+		g.imports["strconv"] = true
+		w(expandSlice)
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by teesql gen. DO NOT EDIT.\n\npackage %s\n\n", pkg)
+	imports := make([]string, 0, len(g.imports))
+	for imp := range g.imports {
+		imports = append(imports, imp)
+	}
+	sort.Strings(imports)
+	out.WriteString("import (\n")
+	for _, imp := range imports {
+		if imp == mssqlImport {
+			continue
+		}
+		fmt.Fprintf(&out, "%q\n", imp)
+	}
+	if g.imports[mssqlImport] {
+		fmt.Fprintf(&out, "\nmssql %q\n", mssqlImport)
+	}
+	out.WriteString(")\n\n")
+	out.WriteString(header)
+	out.Write(body.Bytes())
+	return out.Bytes()
+}
+
+// quote returns s as a Go string literal, raw if possible.
+func quote(s string) string {
+	if !strings.Contains(s, "`") {
+		return "`" + s + "`"
+	}
+	return strconv.Quote(s)
+}
+
+const header = `// DBTX is the subset of *sql.DB, *sql.Conn and *sql.Tx that the queries
+// use.
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// New returns the queries run against db.
+func New(db DBTX) *Queries {
+	return &Queries{db: db}
+}
+
+// Queries runs the generated queries.
+type Queries struct {
+	db DBTX
+}
+
+// WithTx returns the queries run in tx.
+func (q *Queries) WithTx(tx *sql.Tx) *Queries {
+	return &Queries{db: tx}
+}
+
+`
+
+const expandSlice = `// expandSlice replaces the /*SLICE:name*/@name marker in query with one
+// parameter per value, and appends the values to args. An empty slice
+// becomes NULL, which matches no rows in an IN list.
+func expandSlice[T any](query, name string, values []T, args []any) (string, []any) {
+	marker := "/*SLICE:" + name + "*/@" + name
+	if len(values) == 0 {
+		return strings.Replace(query, marker, "NULL", 1), args
+	}
+	params := make([]string, len(values))
+	for i, v := range values {
+		p := name + "_" + strconv.Itoa(i)
+		params[i] = "@" + p
+		args = append(args, sql.Named(p, v))
+	}
+	return strings.Replace(query, marker, strings.Join(params, ", "), 1), args
+}
+`
@@ -0,0 +1,140 @@
+package codegen
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sqlc-dev/teesql/ast"
+	"github.com/sqlc-dev/teesql/catalog"
+	"github.com/sqlc-dev/teesql/parser"
+)
+
+// Usage: go test ./codegen/... -update   # rewrite the golden files
+var update = flag.Bool("update", false, "rewrite the golden files")
+
+// TestGenerate generates code for each directory under testdata, from the
+// schema in its schema directory and the queries in query.sql, with the
+// options in metadata.json, and compares it with db.go.golden.
+func TestGenerate(t *testing.T) {
+	entries, err := os.ReadDir("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		t.Run(entry.Name(), func(t *testing.T) {
+			dir := filepath.Join("testdata", entry.Name())
+			ctx := context.Background()
+			var opts Options
+			if data, err := os.ReadFile(filepath.Join(dir, "metadata.json")); err == nil {
+				if err := json.Unmarshal(data, &opts); err != nil {
+					t.Fatal(err)
+				}
+			}
+			cat, err := catalog.LoadDir(ctx, filepath.Join(dir, "schema"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			sql, err := os.ReadFile(filepath.Join(dir, "query.sql"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			script, err := parser.Parse(ctx, bytes.NewReader(sql))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := Generate(cat, script, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			golden := filepath.Join(dir, "db.go.golden")
+			if *update {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("generated code differs from %s; run with -update to see the changes\n%s", golden, got)
+			}
+		})
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	cat, err := catalog.Build(parseSQL(t, "CREATE TABLE dbo.T (Id int NOT NULL);"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			name:  "unsupported command",
+			query: "-- name: Load :copyfrom\nINSERT INTO dbo.T (Id) VALUES (@id);",
+			want:  "1:1: query Load: command :copyfrom is not supported for SQL Server",
+		},
+		{
+			name:  "unknown columns",
+			query: "-- name: Get :one\nSELECT * FROM dbo.Missing;",
+			want:  "1:1: query Get: result columns could not be determined",
+		},
+		{
+			name:  "missing command",
+			query: "-- name: Get\nSELECT Id FROM dbo.T;",
+			want:  "1:1: query Get has no command",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Generate(cat, parseSQL(t, tt.query), Options{})
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("got error %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNames(t *testing.T) {
+	tests := []struct {
+		sql, exported, unexported string
+	}{
+		{"author_id", "AuthorID", "authorID"},
+		{"AuthorId", "AuthorId", "authorId"},
+		{"id", "ID", "id"},
+		{"Order Total", "OrderTotal", "orderTotal"},
+		{"type", "Type", "type_"},
+		{"rows", "Rows", "rows_"},
+		{"2nd", "X2nd", "x2nd"},
+	}
+	for _, tt := range tests {
+		if got := exported(tt.sql); got != tt.exported {
+			t.Errorf("exported(%q) = %q, want %q", tt.sql, got, tt.exported)
+		}
+		if got := unexported(tt.sql); got != tt.unexported {
+			t.Errorf("unexported(%q) = %q, want %q", tt.sql, got, tt.unexported)
+		}
+	}
+}
+
+func parseSQL(t *testing.T, sql string) *ast.Script {
+	t.Helper()
+	script, err := parser.Parse(context.Background(), bytes.NewReader([]byte(sql)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return script
+}
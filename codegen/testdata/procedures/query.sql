@@ -0,0 +1,2 @@
+-- name: GetOrder :one
+SELECT OrderId, CustomerId, Total, Note FROM dbo.Orders WHERE OrderId = @order_id;
@@ -0,0 +1,40 @@
+CREATE TABLE dbo.Orders (
+    OrderId int IDENTITY(1,1) NOT NULL PRIMARY KEY,
+    CustomerId int NOT NULL,
+    Total money NOT NULL,
+    Note nvarchar(200) NULL
+);
+GO
+CREATE TYPE dbo.IdList AS TABLE (Id int NOT NULL);
+GO
+CREATE SCHEMA sales;
+GO
+CREATE PROCEDURE dbo.GetOrders
+    @CustomerId int,
+    @MinTotal money = NULL
+AS
+BEGIN
+    SELECT OrderId, Total, Note FROM dbo.Orders
+    WHERE CustomerId = @CustomerId AND Total >= ISNULL(@MinTotal, 0);
+END
+GO
+CREATE PROCEDURE dbo.CountOrders
+    @CustomerId int,
+    @Count int OUTPUT
+AS
+    SELECT @Count = COUNT(*) FROM dbo.Orders WHERE CustomerId = @CustomerId;
+GO
+CREATE PROCEDURE sales.DeleteOrders
+    @Ids dbo.IdList READONLY
+AS
+    DELETE FROM dbo.Orders WHERE OrderId IN (SELECT Id FROM @Ids);
+GO
+CREATE PROCEDURE dbo.OrderReport
+    @Detailed bit
+AS
+BEGIN
+    IF @Detailed = 1
+        SELECT OrderId, CustomerId, Total FROM dbo.Orders;
+    ELSE
+        SELECT CustomerId, SUM(Total) AS Total FROM dbo.Orders GROUP BY CustomerId;
+END
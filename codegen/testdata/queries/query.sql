@@ -0,0 +1,25 @@
+-- name: GetAuthor :one
+SELECT AuthorId, Name, Bio FROM dbo.Authors WHERE AuthorId = @id;
+
+-- name: ListAuthors :many
+SELECT AuthorId, Name, ExternalId, CreatedAt FROM dbo.Authors ORDER BY Name;
+
+-- name: CountBooks :one
+SELECT COUNT(*) FROM dbo.Books WHERE AuthorId = @author_id;
+
+-- name: ListBooksByAuthors :many
+SELECT b.BookId, b.Title, a.Name AS AuthorName, b.Price
+FROM dbo.Books AS b
+JOIN dbo.Authors AS a ON a.AuthorId = b.AuthorId
+WHERE b.AuthorId IN (sqlc.slice(author_ids)) AND b.Title LIKE sqlc.arg(title) + '%';
+
+-- name: CreateAuthor :one
+INSERT INTO dbo.Authors (Name, Bio)
+OUTPUT inserted.AuthorId
+VALUES (@name, sqlc.narg(bio));
+
+-- name: UpdateBio :execrows
+UPDATE dbo.Authors SET Bio = @bio WHERE AuthorId = @id;
+
+-- name: DeleteBook :exec
+DELETE FROM dbo.Books WHERE BookId = @book_id;
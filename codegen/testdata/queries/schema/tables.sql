@@ -0,0 +1,14 @@
+CREATE TABLE dbo.Authors (
+    AuthorId int IDENTITY(1,1) NOT NULL PRIMARY KEY,
+    Name nvarchar(100) NOT NULL,
+    Bio nvarchar(max) NULL,
+    ExternalId uniqueidentifier NULL,
+    CreatedAt datetime2 NOT NULL DEFAULT SYSUTCDATETIME()
+);
+GO
+CREATE TABLE dbo.Books (
+    BookId int IDENTITY(1,1) NOT NULL PRIMARY KEY,
+    AuthorId int NOT NULL REFERENCES dbo.Authors (AuthorId),
+    Title nvarchar(200) NOT NULL,
+    Price decimal(10, 2) NULL
+);
@@ -0,0 +1,173 @@
+package codegen
+
+import (
+	"go/token"
+	"strings"
+	"unicode"
+
+	"github.com/sqlc-dev/teesql/catalog"
+	"github.com/sqlc-dev/teesql/typecheck"
+)
+
+const mssqlImport = "github.com/microsoft/go-mssqldb"
+
+// goType is the Go type of a value of a SQL Server type.
+type goType struct {
+	name string
+	// imp is the package the type needs, if any.
+	imp string
+}
+
+var (
+	anyType   = goType{name: "any"}
+	bytesType = goType{name: "[]byte"}
+)
+
+// baseTypes maps system types to the Go type used for non-null values.
+var baseTypes = map[string]goType{
+	"bit":              {name: "bool"},
+	"tinyint":          {name: "uint8"},
+	"smallint":         {name: "int16"},
+	"int":              {name: "int32"},
+	"bigint":           {name: "int64"},
+	"real":             {name: "float32"},
+	"float":            {name: "float64"},
+	"decimal":          {name: "string"},
+	"numeric":          {name: "string"},
+	"money":            {name: "string"},
+	"smallmoney":       {name: "string"},
+	"char":             {name: "string"},
+	"varchar":          {name: "string"},
+	"nchar":            {name: "string"},
+	"nvarchar":         {name: "string"},
+	"text":             {name: "string"},
+	"ntext":            {name: "string"},
+	"xml":              {name: "string"},
+	"json":             {name: "string"},
+	"binary":           bytesType,
+	"varbinary":        bytesType,
+	"image":            bytesType,
+	"rowversion":       bytesType,
+	"timestamp":        bytesType,
+	"date":             {name: "time.Time", imp: "time"},
+	"time":             {name: "time.Time", imp: "time"},
+	"smalldatetime":    {name: "time.Time", imp: "time"},
+	"datetime":         {name: "time.Time", imp: "time"},
+	"datetime2":        {name: "time.Time", imp: "time"},
+	"datetimeoffset":   {name: "time.Time", imp: "time"},
+	"uniqueidentifier": {name: "mssql.UniqueIdentifier", imp: mssqlImport},
+}
+
+// nullTypes maps system types to the Go type used for nullable values.
+// Types missing here hold null as nil.
+var nullTypes = map[string]goType{
+	"bit":              {name: "sql.NullBool", imp: "database/sql"},
+	"tinyint":          {name: "sql.NullByte", imp: "database/sql"},
+	"smallint":         {name: "sql.NullInt16", imp: "database/sql"},
+	"int":              {name: "sql.NullInt32", imp: "database/sql"},
+	"bigint":           {name: "sql.NullInt64", imp: "database/sql"},
+	"real":             {name: "sql.Null[float32]", imp: "database/sql"},
+	"float":            {name: "sql.NullFloat64", imp: "database/sql"},
+	"decimal":          {name: "sql.NullString", imp: "database/sql"},
+	"numeric":          {name: "sql.NullString", imp: "database/sql"},
+	"money":            {name: "sql.NullString", imp: "database/sql"},
+	"smallmoney":       {name: "sql.NullString", imp: "database/sql"},
+	"char":             {name: "sql.NullString", imp: "database/sql"},
+	"varchar":          {name: "sql.NullString", imp: "database/sql"},
+	"nchar":            {name: "sql.NullString", imp: "database/sql"},
+	"nvarchar":         {name: "sql.NullString", imp: "database/sql"},
+	"text":             {name: "sql.NullString", imp: "database/sql"},
+	"ntext":            {name: "sql.NullString", imp: "database/sql"},
+	"xml":              {name: "sql.NullString", imp: "database/sql"},
+	"json":             {name: "sql.NullString", imp: "database/sql"},
+	"date":             {name: "sql.NullTime", imp: "database/sql"},
+	"time":             {name: "sql.NullTime", imp: "database/sql"},
+	"smalldatetime":    {name: "sql.NullTime", imp: "database/sql"},
+	"datetime":         {name: "sql.NullTime", imp: "database/sql"},
+	"datetime2":        {name: "sql.NullTime", imp: "database/sql"},
+	"datetimeoffset":   {name: "sql.NullTime", imp: "database/sql"},
+	"uniqueidentifier": {name: "mssql.NullUniqueIdentifier", imp: mssqlImport},
+}
+
+// typeOf returns the Go type for values of t. Values of unknown type are
+// held as any.
+func typeOf(t catalog.Type, nullable bool) goType {
+	if nullable {
+		if g, ok := nullTypes[t.Name]; ok {
+			return g
+		}
+	}
+	if g, ok := baseTypes[t.Name]; ok {
+		return g
+	}
+	return anyType
+}
+
+func columnType(t typecheck.Type) goType {
+	if t.IsZero() {
+		return anyType
+	}
+	return typeOf(t.Type, t.Nullable)
+}
+
+// initialisms are name parts written in upper case, as Go style asks.
+var initialisms = map[string]string{
+	"id": "ID", "ids": "IDs", "url": "URL", "uri": "URI", "uuid": "UUID", "guid": "GUID",
+	"api": "API", "http": "HTTP", "json": "JSON", "xml": "XML", "sql": "SQL", "ip": "IP",
+}
+
+// nameParts splits a SQL name at underscores, spaces and other punctuation.
+func nameParts(name string) []string {
+	return strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// exported returns the Go name for a SQL name: its parts are capitalized
+// and joined.
+func exported(name string) string {
+	var b strings.Builder
+	for _, part := range nameParts(name) {
+		if s, ok := initialisms[strings.ToLower(part)]; ok {
+			b.WriteString(s)
+			continue
+		}
+		r := []rune(part)
+		b.WriteString(string(unicode.ToUpper(r[0])) + string(r[1:]))
+	}
+	s := b.String()
+	if s == "" || !unicode.IsLetter([]rune(s)[0]) {
+		s = "X" + s
+	}
+	return s
+}
+
+// unexported returns the Go name for a SQL name used as a function
+// parameter: like exported, but with the first part in lower case.
+func unexported(name string) string {
+	parts := nameParts(name)
+	if len(parts) == 0 || !unicode.IsLetter([]rune(parts[0])[0]) {
+		return "x" + strings.TrimPrefix(exported(name), "X")
+	}
+	first := parts[0]
+	if _, ok := initialisms[strings.ToLower(first)]; ok {
+		first = strings.ToLower(first)
+	} else {
+		r := []rune(first)
+		first = string(unicode.ToLower(r[0])) + string(r[1:])
+	}
+	s := first
+	if len(parts) > 1 {
+		s += exported(strings.Join(parts[1:], "_"))
+	}
+	if token.IsKeyword(s) || reserved[s] {
+		s += "_"
+	}
+	return s
+}
+
+// reserved are names the generated functions use for their own variables.
+var reserved = map[string]bool{
+	"ctx": true, "q": true, "arg": true, "row": true, "rows": true, "i": true,
+	"items": true, "err": true, "query": true, "args": true, "result": true,
+}
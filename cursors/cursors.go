@@ -0,0 +1,372 @@
+// Package cursors checks the lifecycle of the cursors that stored
+// procedures declare: DECLARE, OPEN, FETCH, CLOSE and DEALLOCATE.
+//
+// Each cursor is followed along the procedure's control flow as
+// unallocated, allocated or open. Check reports cursors that may still be
+// open when the procedure ends, cursors that are never deallocated, FETCH
+// statements whose INTO list does not match the columns of the cursor's
+// SELECT, and global cursors, which outlive the procedure and are shared by
+// all procedures run on the connection: those a procedure may leave
+// allocated, and those a procedure uses but another one declares.
+//
+// Cursors declared without LOCAL are global, as with the default
+// CURSOR_DEFAULT database option. Cursor variables are always local.
+package cursors
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sqlc-dev/teesql/ast"
+	"github.com/sqlc-dev/teesql/catalog"
+	"github.com/sqlc-dev/teesql/cfg"
+)
+
+// Finding kinds.
+const (
+	// NotClosed is a cursor that may be open when the procedure ends.
+	NotClosed = "not closed"
+	// NotDeallocated is a local cursor with no DEALLOCATE.
+	NotDeallocated = "not deallocated"
+	// FetchMismatch is a FETCH INTO with a different number of variables
+	// than the cursor has columns.
+	FetchMismatch = "fetch mismatch"
+	// Leak is a global cursor that may outlive the procedure, or that a
+	// procedure uses without declaring it.
+	Leak = "leak"
+)
+
+// Finding is a problem with a cursor.
+type Finding struct {
+	Procedure catalog.ObjectName `json:"Procedure"`
+	// Pos is the position of the cursor name in the declaration or the
+	// statement at fault.
+	Pos     ast.Position `json:"Pos"`
+	Kind    string       `json:"Kind"`
+	Cursor  string       `json:"Cursor"`
+	Message string       `json:"Message"`
+}
+
+// String describes the finding, e.g.
+// "4:10: dbo.Each: cursor c may still be open when the procedure ends".
+func (f *Finding) String() string {
+	return f.Pos.String() + ": " + f.Procedure.String() + ": " + f.Message
+}
+
+// Check returns the problems with cursors in the procedures that script
+// creates or alters, in source order.
+func Check(script *ast.Script) []*Finding {
+	var checkers []*checker
+	// globals holds the procedures declaring each global cursor.
+	globals := map[string][]catalog.ObjectName{}
+	for _, batch := range script.Batches {
+		for _, stmt := range batch.Statements {
+			p := catalog.NewProcedure(stmt)
+			if p == nil || p.Body == nil {
+				continue
+			}
+			c := &checker{proc: catalog.ObjectName{Schema: p.Schema, Name: p.Name}, cursors: map[string]*cursor{}}
+			c.check(p.Body.Statements)
+			for key, cur := range c.cursors {
+				if cur.global {
+					globals[key] = append(globals[key], c.proc)
+				}
+			}
+			checkers = append(checkers, c)
+		}
+	}
+	var findings []*Finding
+	for _, c := range checkers {
+		c.checkUses(globals)
+		findings = append(findings, c.findings...)
+	}
+	sort.SliceStable(findings, func(i, j int) bool { return findings[i].Pos.Offset < findings[j].Pos.Offset })
+	return findings
+}
+
+// Cursor states, combined in a mask for the states a cursor may be in.
+const (
+	unallocated = 1 << iota
+	allocated
+	open
+)
+
+type cursor struct {
+	name   string
+	pos    ast.Position
+	global bool
+	// columns is the number of columns the cursor selects, or -1 if it is
+	// not known.
+	columns     int
+	deallocated bool
+}
+
+// state holds the mask of states of each cursor, by key.
+type state map[string]int
+
+type checker struct {
+	proc     catalog.ObjectName
+	g        *cfg.Graph
+	cursors  map[string]*cursor
+	findings []*Finding
+	// uses holds the references to cursors that the procedure does not
+	// declare.
+	uses []*ast.CursorId
+}
+
+func (c *checker) report(pos ast.Position, kind, name, message string) {
+	c.findings = append(c.findings, &Finding{Procedure: c.proc, Pos: pos, Kind: kind, Cursor: name, Message: message})
+}
+
+// key returns the key of a cursor name: variables and cursors are in
+// separate namespaces, the first starting with @.
+func key(name string) string {
+	return strings.ToLower(name)
+}
+
+func cursorName(id *ast.CursorId) (string, ast.Position) {
+	if id == nil || id.Name == nil {
+		return "", ast.Position{}
+	}
+	switch {
+	case id.Name.Identifier != nil:
+		return id.Name.Identifier.Value, id.Name.Identifier.Pos
+	case id.Name.ValueExpression != nil:
+		if v, ok := id.Name.ValueExpression.(*ast.VariableReference); ok {
+			return v.Name, v.Pos
+		}
+	}
+	return id.Name.Value, ast.Position{}
+}
+
+func (c *checker) declare(name string, pos ast.Position, def *ast.CursorDefinition, global bool) {
+	if c.cursors[key(name)] != nil {
+		return
+	}
+	cur := &cursor{name: name, pos: pos, global: global, columns: -1}
+	if def != nil {
+		cur.columns = columns(def.Select)
+		for _, o := range def.Options {
+			switch o.OptionKind {
+			case "Local":
+				cur.global = false
+			case "Global":
+				cur.global = true
+			}
+		}
+	}
+	c.cursors[key(name)] = cur
+}
+
+func (c *checker) check(stmts []ast.Statement) {
+	for _, stmt := range stmts {
+		ast.Inspect(stmt, func(n ast.Node) bool {
+			switch s := n.(type) {
+			case *ast.DynamicSQL:
+				return false
+			case *ast.DeclareCursorStatement:
+				if s.Name != nil {
+					c.declare(s.Name.Value, s.Name.Pos, s.CursorDefinition, true)
+				}
+			case *ast.SetVariableStatement:
+				if s.CursorDefinition != nil && s.Variable != nil {
+					c.declare(s.Variable.Name, s.Variable.Pos, s.CursorDefinition, false)
+				}
+			case *ast.DeallocateCursorStatement:
+				name, _ := cursorName(s.Cursor)
+				if cur := c.cursors[key(name)]; cur != nil {
+					cur.deallocated = true
+				}
+			}
+			return true
+		})
+	}
+	c.g, _ = cfg.New(stmts)
+	in := c.flow()
+	for _, n := range c.g.Nodes {
+		if in[n] == nil {
+			continue
+		}
+		if s, ok := n.Stmt.(*ast.FetchCursorStatement); ok {
+			c.checkFetch(s)
+		}
+		for _, id := range cursorIDs(n.Stmt) {
+			name, _ := cursorName(id)
+			if cur := c.cursors[key(name)]; cur == nil || id.IsGlobal && !cur.global {
+				c.uses = append(c.uses, id)
+			}
+		}
+	}
+	exit := in[c.g.Exit]
+	for _, cur := range c.sorted() {
+		mask := exit[key(cur.name)]
+		if mask&open != 0 {
+			c.report(cur.pos, NotClosed, cur.name, "cursor "+cur.name+" may still be open when the procedure ends")
+		}
+		switch {
+		case cur.global && mask&(allocated|open) != 0 && !cur.deallocated:
+			c.report(cur.pos, Leak, cur.name, "global cursor "+cur.name+" is never deallocated and outlives the procedure")
+		case cur.global && mask&(allocated|open) != 0:
+			c.report(cur.pos, Leak, cur.name, "global cursor "+cur.name+" may remain allocated when the procedure ends")
+		case !cur.deallocated:
+			c.report(cur.pos, NotDeallocated, cur.name, "cursor "+cur.name+" is never deallocated")
+		}
+	}
+}
+
+// sorted returns the declared cursors in source order.
+func (c *checker) sorted() []*cursor {
+	var cursors []*cursor
+	for _, cur := range c.cursors {
+		cursors = append(cursors, cur)
+	}
+	sort.Slice(cursors, func(i, j int) bool { return cursors[i].pos.Offset < cursors[j].pos.Offset })
+	return cursors
+}
+
+// flow returns the states of the declared cursors on entry to each
+// reachable node.
+func (c *checker) flow() map[*cfg.Node]state {
+	entry := state{}
+	for k := range c.cursors {
+		entry[k] = unallocated
+	}
+	in := map[*cfg.Node]state{c.g.Entry: entry}
+	work := []*cfg.Node{c.g.Entry}
+	for len(work) > 0 {
+		n := work[0]
+		work = work[1:]
+		out := c.transfer(n, in[n])
+		for _, e := range n.Succs {
+			// A statement that fails has no effect on the cursors.
+			from := out
+			if e.Kind == cfg.Error {
+				from = in[n]
+			}
+			to := in[e.To]
+			changed := to == nil
+			if to == nil {
+				to = state{}
+				in[e.To] = to
+			}
+			for k, mask := range from {
+				if to[k]|mask != to[k] {
+					to[k] |= mask
+					changed = true
+				}
+			}
+			if changed {
+				work = append(work, e.To)
+			}
+		}
+	}
+	return in
+}
+
+// transfer returns the states after a node runs.
+func (c *checker) transfer(n *cfg.Node, in state) state {
+	out := state{}
+	for k, mask := range in {
+		out[k] = mask
+	}
+	set := func(id *ast.CursorId, mask int) {
+		name, _ := cursorName(id)
+		if _, ok := out[key(name)]; ok {
+			out[key(name)] = mask
+		}
+	}
+	switch s := n.Stmt.(type) {
+	case *ast.DeclareCursorStatement:
+		if s.Name != nil {
+			out[key(s.Name.Value)] = allocated
+		}
+	case *ast.SetVariableStatement:
+		if s.CursorDefinition != nil && s.Variable != nil {
+			out[key(s.Variable.Name)] = allocated
+		}
+	case *ast.OpenCursorStatement:
+		set(s.Cursor, open)
+	case *ast.CloseCursorStatement:
+		set(s.Cursor, allocated)
+	case *ast.DeallocateCursorStatement:
+		set(s.Cursor, unallocated)
+	}
+	return out
+}
+
+// cursorIDs returns the cursors that a statement refers to.
+func cursorIDs(stmt ast.Statement) []*ast.CursorId {
+	switch s := stmt.(type) {
+	case *ast.OpenCursorStatement:
+		return []*ast.CursorId{s.Cursor}
+	case *ast.FetchCursorStatement:
+		return []*ast.CursorId{s.Cursor}
+	case *ast.CloseCursorStatement:
+		return []*ast.CursorId{s.Cursor}
+	case *ast.DeallocateCursorStatement:
+		return []*ast.CursorId{s.Cursor}
+	}
+	return nil
+}
+
+func (c *checker) checkFetch(s *ast.FetchCursorStatement) {
+	name, pos := cursorName(s.Cursor)
+	cur := c.cursors[key(name)]
+	if cur == nil || cur.columns < 0 || len(s.IntoVariables) == 0 || len(s.IntoVariables) == cur.columns {
+		return
+	}
+	c.report(pos, FetchMismatch, cur.name, fmt.Sprintf("FETCH INTO lists %d variables but cursor %s selects %d columns",
+		len(s.IntoVariables), cur.name, cur.columns))
+}
+
+// checkUses reports references to cursors that the procedure does not
+// declare.
+func (c *checker) checkUses(globals map[string][]catalog.ObjectName) {
+	seen := map[string]bool{}
+	for _, id := range c.uses {
+		name, pos := cursorName(id)
+		if strings.HasPrefix(name, "@") || seen[key(name)] {
+			continue
+		}
+		seen[key(name)] = true
+		var others []string
+		for _, p := range globals[key(name)] {
+			if p != c.proc {
+				others = append(others, p.String())
+			}
+		}
+		if len(others) > 0 {
+			c.report(pos, Leak, name, "cursor "+name+" is the global cursor declared by "+strings.Join(others, ", "))
+		} else {
+			c.report(pos, Leak, name, "cursor "+name+" is not declared by the procedure and must be a global cursor left by another")
+		}
+	}
+}
+
+// columns returns the number of columns a query selects, or -1 if it
+// selects * or the query is not known.
+func columns(sel *ast.SelectStatement) int {
+	if sel == nil {
+		return -1
+	}
+	q := sel.QueryExpression
+	for {
+		switch e := q.(type) {
+		case *ast.BinaryQueryExpression:
+			q = e.FirstQueryExpression
+			continue
+		case *ast.QueryParenthesisExpression:
+			q = e.QueryExpression
+			continue
+		case *ast.QuerySpecification:
+			for _, el := range e.SelectElements {
+				if _, ok := el.(*ast.SelectStarExpression); ok {
+					return -1
+				}
+			}
+			return len(e.SelectElements)
+		}
+		return -1
+	}
+}
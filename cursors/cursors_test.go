@@ -0,0 +1,144 @@
+package cursors
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sqlc-dev/teesql/parser"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want []string
+	}{
+		{
+			name: "balanced",
+			sql: `CREATE PROCEDURE dbo.Each AS
+BEGIN
+	DECLARE @id int, @name nvarchar(50);
+	DECLARE c CURSOR LOCAL FAST_FORWARD FOR
+		SELECT Id, Name FROM dbo.T;
+	OPEN c;
+	FETCH NEXT FROM c INTO @id, @name;
+	WHILE @@FETCH_STATUS = 0
+	BEGIN
+		PRINT @name;
+		FETCH NEXT FROM c INTO @id, @name;
+	END
+	CLOSE c;
+	DEALLOCATE c;
+END`,
+		},
+		{
+			name: "early return",
+			sql: `CREATE PROCEDURE dbo.P @flag bit AS
+BEGIN
+	DECLARE c CURSOR LOCAL FOR SELECT Id FROM dbo.T;
+	OPEN c;
+	IF @flag = 1
+		RETURN;
+	CLOSE c;
+	DEALLOCATE c;
+END`,
+			want: []string{"3:10: dbo.P: cursor c may still be open when the procedure ends"},
+		},
+		{
+			name: "never deallocated",
+			sql: `CREATE PROCEDURE dbo.P AS
+BEGIN
+	DECLARE @id int;
+	DECLARE @c CURSOR;
+	SET @c = CURSOR FOR SELECT Id FROM dbo.T;
+	OPEN @c;
+	FETCH NEXT FROM @c INTO @id;
+	CLOSE @c;
+END`,
+			want: []string{"5:6: dbo.P: cursor @c is never deallocated"},
+		},
+		{
+			name: "fetch mismatch",
+			sql: `CREATE PROCEDURE dbo.P AS
+BEGIN
+	DECLARE @a int, @b int;
+	DECLARE c CURSOR LOCAL FOR
+		SELECT a, b, c FROM dbo.T
+		UNION ALL
+		SELECT a, b, c FROM dbo.U;
+	DECLARE s CURSOR LOCAL FOR SELECT * FROM dbo.T;
+	OPEN c;
+	OPEN s;
+	FETCH NEXT FROM c INTO @a, @b;
+	FETCH NEXT FROM s INTO @a;
+	CLOSE c;
+	CLOSE s;
+	DEALLOCATE c;
+	DEALLOCATE s;
+END`,
+			want: []string{"11:18: dbo.P: FETCH INTO lists 2 variables but cursor c selects 3 columns"},
+		},
+		{
+			name: "closed in catch",
+			sql: `CREATE PROCEDURE dbo.P AS
+BEGIN
+	DECLARE c CURSOR LOCAL FOR SELECT Id FROM dbo.T;
+	BEGIN TRY
+		OPEN c;
+		UPDATE dbo.T SET x = 1;
+		CLOSE c;
+	END TRY
+	BEGIN CATCH
+		IF CURSOR_STATUS('local', 'c') >= 0
+			CLOSE c;
+	END CATCH
+	DEALLOCATE c;
+END`,
+		},
+		{
+			name: "global cursors",
+			sql: `CREATE PROCEDURE dbo.Start AS
+BEGIN
+	DECLARE shared CURSOR GLOBAL FOR SELECT Id FROM dbo.T;
+	OPEN shared;
+END
+GO
+CREATE PROCEDURE dbo.Next AS
+BEGIN
+	DECLARE @id int;
+	FETCH NEXT FROM GLOBAL shared INTO @id;
+	FETCH NEXT FROM orphan INTO @id;
+END
+GO
+CREATE PROCEDURE dbo.Tidy AS
+BEGIN
+	DECLARE t CURSOR FOR SELECT Id FROM dbo.T;
+	OPEN t;
+	CLOSE t;
+	DEALLOCATE t;
+END`,
+			want: []string{
+				"3:10: dbo.Start: cursor shared may still be open when the procedure ends",
+				"3:10: dbo.Start: global cursor shared is never deallocated and outlives the procedure",
+				"10:25: dbo.Next: cursor shared is the global cursor declared by dbo.Start",
+				"11:18: dbo.Next: cursor orphan is not declared by the procedure and must be a global cursor left by another",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script, err := parser.Parse(context.Background(), strings.NewReader(tt.sql))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, f := range Check(script) {
+				got = append(got, f.String())
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
@@ -0,0 +1,516 @@
+// Package deps builds the dependency graph of the objects that a set of
+// DDL scripts defines: tables, views, procedures, functions, triggers,
+// synonyms, user-defined types and sequences.
+//
+// An object depends on the objects its definition names: the tables,
+// views, synonyms and functions a view or module reads or writes, the
+// procedures it executes, the tables a foreign key references, the table a
+// trigger is on, the target of a synonym, and the types and sequences it
+// uses. References to objects the scripts do not define, such as system
+// objects or objects in other databases, are left out. Unqualified names
+// are looked up in the default schema, then in the schema of the object
+// that uses them.
+package deps
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sqlc-dev/teesql/ast"
+	"github.com/sqlc-dev/teesql/catalog"
+)
+
+// Object kinds.
+const (
+	Table     = "table"
+	View      = "view"
+	Procedure = "procedure"
+	Function  = "function"
+	Trigger   = "trigger"
+	Synonym   = "synonym"
+	Type      = "type"
+	Sequence  = "sequence"
+)
+
+// Edge kinds.
+const (
+	// Reads is a query reading a table, view or synonym.
+	Reads = "reads"
+	// Writes is an INSERT, UPDATE, DELETE, MERGE or TRUNCATE TABLE.
+	Writes = "writes"
+	// Executes is an EXECUTE of a procedure.
+	Executes = "executes"
+	// Calls is a call of a scalar or table-valued function.
+	Calls = "calls"
+	// ForeignKey is a foreign key referencing a table.
+	ForeignKey = "foreign key"
+	// TriggerOn is a trigger depending on its table.
+	TriggerOn = "trigger on"
+	// SynonymFor is a synonym depending on its target.
+	SynonymFor = "synonym for"
+	// UsesType is a column, parameter or variable of a user-defined type.
+	UsesType = "uses type"
+	// UsesSequence is a NEXT VALUE FOR expression.
+	UsesSequence = "uses sequence"
+)
+
+// Graph is the dependency graph of a set of objects.
+type Graph struct {
+	// Nodes holds the objects in the order the scripts define them.
+	Nodes []*Node
+
+	nodes map[string]*Node
+}
+
+// Node is an object of the graph.
+type Node struct {
+	Kind string
+	Name catalog.ObjectName
+	// Deps are the edges to the objects this one depends on, and
+	// Dependents the edges from the objects that depend on it, in the
+	// order the references appear.
+	Deps       []*Edge
+	Dependents []*Edge
+	// Definition is the statement defining the object.
+	Definition ast.Statement
+
+	index int
+}
+
+func (n *Node) String() string {
+	return n.Name.String()
+}
+
+// Edge is a dependency of one object on another.
+type Edge struct {
+	From *Node
+	To   *Node
+	Kind string
+	// Dynamic is set if every reference is in dynamic SQL, which scripts
+	// parsed with parser.Options.DynamicSQL expose when its text is
+	// constant.
+	Dynamic bool
+}
+
+// Node returns the named object, or nil. An unqualified name is in the
+// default schema.
+func (g *Graph) Node(name catalog.ObjectName) *Node {
+	return g.nodes[key(name)]
+}
+
+func key(n catalog.ObjectName) string {
+	if n.Schema == "" {
+		n.Schema = catalog.DefaultSchema
+	}
+	return strings.ToLower(n.Schema + "." + n.Name)
+}
+
+// reference is a name that an object's definition uses.
+type reference struct {
+	from    *Node
+	name    *ast.SchemaObjectName
+	kind    string
+	dynamic bool
+}
+
+type builder struct {
+	g    *Graph
+	refs []reference
+	// dynamic holds the nodes of the definition being read that are in
+	// dynamic SQL.
+	dynamic map[ast.Node]bool
+}
+
+// Build returns the dependency graph of the objects that scripts define.
+// An object defined more than once, as by CREATE and then ALTER, is one
+// node with the dependencies of all its definitions.
+func Build(scripts ...*ast.Script) *Graph {
+	b := &builder{g: &Graph{nodes: map[string]*Node{}}}
+	for _, script := range scripts {
+		for _, batch := range script.Batches {
+			for _, stmt := range batch.Statements {
+				b.stmt(stmt)
+			}
+		}
+	}
+	seen := map[[3]any]*Edge{}
+	for _, r := range b.refs {
+		to := b.resolve(r.from, r.name)
+		if to == nil || to == r.from {
+			continue
+		}
+		if e := seen[[3]any{r.from, to, r.kind}]; e != nil {
+			e.Dynamic = e.Dynamic && r.dynamic
+			continue
+		}
+		e := &Edge{From: r.from, To: to, Kind: r.kind, Dynamic: r.dynamic}
+		seen[[3]any{r.from, to, r.kind}] = e
+		r.from.Deps = append(r.from.Deps, e)
+		to.Dependents = append(to.Dependents, e)
+	}
+	return b.g
+}
+
+// resolve returns the object a name used by from refers to, or nil.
+func (b *builder) resolve(from *Node, name *ast.SchemaObjectName) *Node {
+	if name.ServerIdentifier != nil || name.DatabaseIdentifier != nil {
+		return nil
+	}
+	n := catalog.NameOf(name)
+	if strings.HasPrefix(n.Name, "#") {
+		return nil
+	}
+	if node := b.g.Node(n); node != nil || n.Schema != "" {
+		return node
+	}
+	n.Schema = from.Name.Schema
+	return b.g.Node(n)
+}
+
+// node returns the node for an object, adding it if it is new.
+func (b *builder) node(kind string, name *ast.SchemaObjectName, def ast.Statement) *Node {
+	n := catalog.NameOf(name)
+	if n.Schema == "" {
+		n.Schema = catalog.DefaultSchema
+	}
+	if node := b.g.nodes[key(n)]; node != nil {
+		node.Definition = def
+		return node
+	}
+	node := &Node{Kind: kind, Name: n, Definition: def, index: len(b.g.Nodes)}
+	b.g.Nodes = append(b.g.Nodes, node)
+	b.g.nodes[key(n)] = node
+	return node
+}
+
+func (b *builder) ref(from *Node, name *ast.SchemaObjectName, kind string) {
+	if name != nil && name.BaseIdentifier != nil {
+		b.refs = append(b.refs, reference{from: from, name: name, kind: kind, dynamic: b.dynamic[name]})
+	}
+}
+
+func (b *builder) stmt(stmt ast.Statement) {
+	var node *Node
+	switch s := stmt.(type) {
+	case *ast.CreateTableStatement:
+		node = b.node(Table, s.SchemaObjectName, stmt)
+	case *ast.AlterTableAddTableElementStatement:
+		// Constraints added later are dependencies of the table.
+		if node = b.g.Node(catalog.NameOf(s.SchemaObjectName)); node == nil {
+			return
+		}
+	case *ast.CreateViewStatement:
+		node = b.node(View, s.SchemaObjectName, stmt)
+	case *ast.CreateOrAlterViewStatement:
+		node = b.node(View, s.SchemaObjectName, stmt)
+	case *ast.AlterViewStatement:
+		node = b.node(View, s.SchemaObjectName, stmt)
+	case *ast.CreateProcedureStatement, *ast.CreateOrAlterProcedureStatement, *ast.AlterProcedureStatement:
+		p := catalog.NewProcedure(stmt)
+		if p == nil {
+			return
+		}
+		node = b.node(Procedure, &ast.SchemaObjectName{
+			SchemaIdentifier: &ast.Identifier{Value: p.Schema},
+			BaseIdentifier:   &ast.Identifier{Value: p.Name},
+		}, stmt)
+	case *ast.CreateFunctionStatement:
+		node = b.node(Function, s.Name, stmt)
+	case *ast.CreateOrAlterFunctionStatement:
+		node = b.node(Function, s.Name, stmt)
+	case *ast.AlterFunctionStatement:
+		node = b.node(Function, s.Name, stmt)
+	case *ast.CreateTriggerStatement:
+		node = b.trigger(s.Name, s.TriggerObject, stmt)
+	case *ast.CreateOrAlterTriggerStatement:
+		node = b.trigger(s.Name, s.TriggerObject, stmt)
+	case *ast.AlterTriggerStatement:
+		node = b.trigger(s.Name, s.TriggerObject, stmt)
+	case *ast.CreateSynonymStatement:
+		node = b.node(Synonym, s.Name, stmt)
+		b.ref(node, s.ForName, SynonymFor)
+		return
+	case *ast.CreateTypeUddtStatement:
+		b.node(Type, s.Name, stmt)
+		return
+	case *ast.CreateTypeTableStatement:
+		node = b.node(Type, s.Name, stmt)
+	case *ast.CreateSequenceStatement:
+		b.node(Sequence, s.Name, stmt)
+		return
+	default:
+		return
+	}
+	b.references(node, stmt)
+}
+
+// trigger adds a trigger. A DML trigger is in the schema of its table.
+func (b *builder) trigger(name *ast.SchemaObjectName, on *ast.TriggerObject, stmt ast.Statement) *Node {
+	if on == nil || on.Name == nil {
+		return b.node(Trigger, name, stmt)
+	}
+	if name.SchemaIdentifier == nil && on.Name.SchemaIdentifier != nil {
+		name = &ast.SchemaObjectName{SchemaIdentifier: on.Name.SchemaIdentifier, BaseIdentifier: name.BaseIdentifier}
+	}
+	node := b.node(Trigger, name, stmt)
+	b.ref(node, on.Name, TriggerOn)
+	return node
+}
+
+// references records the names that a definition uses.
+func (b *builder) references(node *Node, stmt ast.Statement) {
+	// ctes holds the names of common table expressions, which are not
+	// objects.
+	ctes := map[string]bool{}
+	b.dynamic = map[ast.Node]bool{}
+	ast.Inspect(stmt, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.CommonTableExpression:
+			if n.ExpressionName != nil {
+				ctes[strings.ToLower(n.ExpressionName.Value)] = true
+			}
+		case *ast.DynamicSQL:
+			ast.Inspect(n, func(n ast.Node) bool {
+				if n != nil {
+					b.dynamic[n] = true
+				}
+				return true
+			})
+		}
+		return true
+	})
+	// targets holds the kind of reference of the table references that
+	// DML statements write, empty for a target naming an alias.
+	targets := map[*ast.NamedTableReference]string{}
+	ast.Inspect(stmt, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.NamedTableReference:
+			name := n.SchemaObject
+			if name == nil || name.BaseIdentifier == nil {
+				break
+			}
+			if name.SchemaIdentifier == nil && ctes[strings.ToLower(name.BaseIdentifier.Value)] {
+				break
+			}
+			kind, ok := targets[n]
+			if !ok {
+				kind = Reads
+			}
+			if kind != "" {
+				b.ref(node, name, kind)
+			}
+		case *ast.InsertSpecification:
+			target(n.Target, n, targets)
+		case *ast.UpdateSpecification:
+			target(n.Target, n, targets)
+		case *ast.DeleteSpecification:
+			target(n.Target, n, targets)
+		case *ast.MergeSpecification:
+			target(n.Target, n, targets)
+		case *ast.TruncateTableStatement:
+			b.ref(node, n.TableName, Writes)
+		case *ast.SchemaObjectFunctionTableReference:
+			b.ref(node, n.SchemaObject, Calls)
+		case *ast.FunctionCall:
+			if t, ok := n.CallTarget.(*ast.MultiPartIdentifierCallTarget); ok && t.MultiPartIdentifier != nil && n.FunctionName != nil {
+				ids := t.MultiPartIdentifier.Identifiers
+				if len(ids) == 1 {
+					name := &ast.SchemaObjectName{SchemaIdentifier: ids[0], BaseIdentifier: n.FunctionName}
+					b.dynamic[name] = b.dynamic[n]
+					b.ref(node, name, Calls)
+				}
+			}
+		case *ast.ExecutableProcedureReference:
+			if n.ProcedureReference != nil && n.ProcedureReference.ProcedureReference != nil {
+				b.ref(node, n.ProcedureReference.ProcedureReference.Name, Executes)
+			}
+		case *ast.ForeignKeyConstraintDefinition:
+			b.ref(node, n.ReferenceTableName, ForeignKey)
+		case *ast.UserDataTypeReference:
+			b.ref(node, n.Name, UsesType)
+		case *ast.SqlDataTypeReference:
+			if n.Name != nil && n.Name.SchemaIdentifier != nil {
+				b.ref(node, n.Name, UsesType)
+			}
+		case *ast.NextValueForExpression:
+			b.ref(node, n.SequenceName, UsesSequence)
+		}
+		return true
+	})
+}
+
+// target records the table reference that a DML statement writes. A
+// target naming an alias writes the table the alias stands for in the
+// statement.
+func target(t ast.TableReference, stmt ast.Node, targets map[*ast.NamedTableReference]string) {
+	ref, ok := t.(*ast.NamedTableReference)
+	if !ok || ref.SchemaObject == nil {
+		return
+	}
+	targets[ref] = Writes
+	if ref.SchemaObject.SchemaIdentifier != nil || ref.SchemaObject.BaseIdentifier == nil {
+		return
+	}
+	alias := ref.SchemaObject.BaseIdentifier.Value
+	ast.Inspect(stmt, func(n ast.Node) bool {
+		if t, ok := n.(*ast.NamedTableReference); ok && t != ref && t.Alias != nil && strings.EqualFold(t.Alias.Value, alias) {
+			targets[t] = Writes
+			targets[ref] = ""
+		}
+		return true
+	})
+}
+
+// Order returns the objects in an order they can be created in: each
+// after the objects it depends on. Objects are otherwise kept in the order
+// the scripts define them. Tables whose foreign keys reference each other
+// in a cycle are ordered without those keys, which are then added once the
+// tables exist, as DeferredForeignKeys lists. Procedures that execute each
+// other, or use tables that depend on them, are likewise ordered without
+// those references, which SQL Server resolves when the procedure runs. If
+// the graph has other cycles, the objects of each cycle are placed together, in definition
+// order, and the error is a *CycleError listing the cycles.
+func (g *Graph) Order() ([]*Node, error) {
+	order, cycles := g.order()
+	if len(cycles) > 0 {
+		return order, &CycleError{Cycles: cycles}
+	}
+	return order, nil
+}
+
+// DeferredForeignKeys returns the foreign keys that reference a table which
+// Order places after the referencing table, and which must be added once
+// both tables exist.
+func (g *Graph) DeferredForeignKeys() []*Edge {
+	order, _ := g.Order()
+	position := map[*Node]int{}
+	for i, n := range order {
+		position[n] = i
+	}
+	var deferred []*Edge
+	for _, n := range order {
+		for _, e := range n.Deps {
+			if e.Kind == ForeignKey && position[e.To] > position[n] {
+				deferred = append(deferred, e)
+			}
+		}
+	}
+	return deferred
+}
+
+// Cycles returns the sets of objects that depend on each other, directly
+// or through others, each in definition order. Foreign keys and the
+// references that SQL Server resolves when a procedure runs, which can be
+// deferred, do not make cycles.
+func (g *Graph) Cycles() [][]*Node {
+	_, cycles := g.order()
+	return cycles
+}
+
+// order returns the objects in creation order and the cycles that are not
+// broken by deferring edges. Deferrable edges order the objects unless they
+// make a cycle, whose objects are then ordered without them.
+func (g *Graph) order() (order []*Node, cycles [][]*Node) {
+	all := func(*Edge) bool { return true }
+	for _, c := range components(g.Nodes, all) {
+		if len(c) == 1 {
+			order = append(order, c...)
+			continue
+		}
+		in := map[*Node]bool{}
+		for _, n := range c {
+			in[n] = true
+		}
+		for _, sub := range components(c, func(e *Edge) bool { return !deferrable(e) && in[e.To] }) {
+			order = append(order, sub...)
+			if len(sub) > 1 {
+				cycles = append(cycles, sub)
+			}
+		}
+	}
+	return order, cycles
+}
+
+// deferrable reports whether e is needed to order the objects but not to
+// create them: a foreign key, which can be added once both tables exist, or
+// a procedure executed, or a table read or written by a procedure, which
+// SQL Server resolves when the procedure runs.
+func deferrable(e *Edge) bool {
+	switch {
+	case e.Kind == ForeignKey, e.Kind == Executes:
+		return true
+	case e.From.Kind == Procedure && e.To.Kind == Table:
+		return e.Kind == Reads || e.Kind == Writes
+	}
+	return false
+}
+
+// CycleError is returned by Order for a graph with cycles.
+type CycleError struct {
+	Cycles [][]*Node
+}
+
+func (e *CycleError) Error() string {
+	var cycles []string
+	for _, c := range e.Cycles {
+		names := make([]string, len(c))
+		for i, n := range c {
+			names[i] = n.String()
+		}
+		cycles = append(cycles, strings.Join(names, ", "))
+	}
+	return fmt.Sprintf("dependency cycles: %s", strings.Join(cycles, "; "))
+}
+
+// components returns the strongly connected components of the graph of
+// nodes and the edges that follow accepts, with every component after the
+// components it depends on, using Tarjan's algorithm.
+func components(nodes []*Node, follow func(*Edge) bool) [][]*Node {
+	var (
+		index    = map[*Node]int{}
+		low      = map[*Node]int{}
+		onStack  = map[*Node]bool{}
+		stack    []*Node
+		result   [][]*Node
+		strongly func(n *Node)
+	)
+	strongly = func(n *Node) {
+		index[n] = len(index)
+		low[n] = index[n]
+		stack = append(stack, n)
+		onStack[n] = true
+		for _, e := range n.Deps {
+			if !follow(e) {
+				continue
+			}
+			if _, ok := index[e.To]; !ok {
+				strongly(e.To)
+				low[n] = min(low[n], low[e.To])
+			} else if onStack[e.To] {
+				low[n] = min(low[n], index[e.To])
+			}
+		}
+		if low[n] != index[n] {
+			return
+		}
+		var c []*Node
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			c = append(c, top)
+			if top == n {
+				break
+			}
+		}
+		sort.Slice(c, func(i, j int) bool { return c[i].index < c[j].index })
+		result = append(result, c)
+	}
+	for _, n := range nodes {
+		if _, ok := index[n]; !ok {
+			strongly(n)
+		}
+	}
+	return result
+}
@@ -0,0 +1,211 @@
+package deps
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/sqlc-dev/teesql/ast"
+	"github.com/sqlc-dev/teesql/catalog"
+	"github.com/sqlc-dev/teesql/parser"
+)
+
+func parse(t *testing.T, sql string) *ast.Script {
+	t.Helper()
+	script, err := parser.Parse(context.Background(), strings.NewReader(sql))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return script
+}
+
+const schema = `CREATE TABLE dbo.Orders (
+	Id int NOT NULL PRIMARY KEY DEFAULT (NEXT VALUE FOR dbo.OrderIds),
+	CustomerId int NOT NULL REFERENCES dbo.Customers (Id),
+	Total dbo.Money NOT NULL
+);
+GO
+CREATE TABLE dbo.Customers (Id int NOT NULL PRIMARY KEY, Name nvarchar(50) NOT NULL);
+GO
+CREATE TYPE dbo.Money FROM decimal(19, 4) NOT NULL;
+GO
+CREATE SEQUENCE dbo.OrderIds START WITH 1;
+GO
+CREATE TABLE dbo.Audit (Id int NOT NULL, At datetime NOT NULL);
+GO
+CREATE VIEW dbo.CustomerTotals AS
+	SELECT c.Name, t.Total, dbo.Grade(t.Total) AS Grade
+	FROM (SELECT CustomerId, SUM(Total) AS Total FROM dbo.Orders GROUP BY CustomerId) t
+	JOIN Customers c ON t.CustomerId = c.Id;
+GO
+CREATE FUNCTION dbo.Grade (@total decimal(19, 4)) RETURNS char(1) AS
+BEGIN
+	RETURN CASE WHEN @total > 1000 THEN 'A' ELSE 'B' END;
+END
+GO
+CREATE SYNONYM dbo.Clients FOR dbo.Customers;
+GO
+CREATE PROCEDURE dbo.Rename @id int, @name nvarchar(50) AS
+BEGIN
+	UPDATE c SET Name = @name FROM dbo.Clients c WHERE c.Id = @id;
+	SELECT * INTO #t FROM other.dbo.Remote;
+	WITH recent AS (SELECT Id FROM dbo.Orders) SELECT Id FROM recent;
+	EXEC dbo.Log @id;
+END
+GO
+CREATE PROCEDURE dbo.Log @id int AS
+	INSERT INTO dbo.Audit (Id, At) VALUES (@id, getdate());
+GO
+CREATE TRIGGER trOrders ON dbo.Orders AFTER INSERT AS
+	EXEC dbo.Log 0;`
+
+func TestBuild(t *testing.T) {
+	g := Build(parse(t, schema))
+	var got []string
+	for _, n := range g.Nodes {
+		for _, e := range n.Deps {
+			got = append(got, n.Kind+" "+n.String()+" "+e.Kind+" "+e.To.Kind+" "+e.To.String())
+		}
+	}
+	want := []string{
+		"table dbo.Orders uses sequence sequence dbo.OrderIds",
+		"table dbo.Orders foreign key table dbo.Customers",
+		"table dbo.Orders uses type type dbo.Money",
+		"view dbo.CustomerTotals calls function dbo.Grade",
+		"view dbo.CustomerTotals reads table dbo.Orders",
+		"view dbo.CustomerTotals reads table dbo.Customers",
+		"synonym dbo.Clients synonym for table dbo.Customers",
+		"procedure dbo.Rename writes synonym dbo.Clients",
+		"procedure dbo.Rename reads table dbo.Orders",
+		"procedure dbo.Rename executes procedure dbo.Log",
+		"procedure dbo.Log writes table dbo.Audit",
+		"trigger dbo.trOrders trigger on table dbo.Orders",
+		"trigger dbo.trOrders executes procedure dbo.Log",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if n := g.Node(catalog.ObjectName{Name: "customers"}); n == nil || len(n.Dependents) != 3 {
+		t.Errorf("dbo.Customers dependents = %v", n)
+	}
+}
+
+func names(nodes []*Node) string {
+	var s []string
+	for _, n := range nodes {
+		s = append(s, n.String())
+	}
+	return strings.Join(s, " ")
+}
+
+func TestOrder(t *testing.T) {
+	order, err := Build(parse(t, schema)).Order()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "dbo.OrderIds dbo.Customers dbo.Money dbo.Orders dbo.Audit dbo.Grade dbo.CustomerTotals dbo.Clients dbo.Log dbo.Rename dbo.trOrders"
+	if got := names(order); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestCycles(t *testing.T) {
+	g := Build(parse(t, `CREATE TABLE dbo.A (Id int PRIMARY KEY, BId int REFERENCES dbo.B (Id));
+CREATE TABLE dbo.B (Id int PRIMARY KEY, AId int);
+CREATE TABLE dbo.C (Id int PRIMARY KEY REFERENCES dbo.C (Id));
+ALTER TABLE dbo.B ADD CONSTRAINT FK_B_A FOREIGN KEY (AId) REFERENCES dbo.A (Id);
+GO
+CREATE FUNCTION dbo.Ping (@n int) RETURNS int AS BEGIN RETURN dbo.Pong(@n - 1) END
+GO
+CREATE FUNCTION dbo.Pong (@n int) RETURNS int AS BEGIN RETURN dbo.Ping(@n - 1) END`))
+	order, err := g.Order()
+	var cycle *CycleError
+	if !errors.As(err, &cycle) {
+		t.Fatalf("err = %v, want a *CycleError", err)
+	}
+	if got, want := err.Error(), "dependency cycles: dbo.Ping, dbo.Pong"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := names(order), "dbo.A dbo.B dbo.C dbo.Ping dbo.Pong"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	if len(g.Cycles()) != 1 {
+		t.Errorf("got %d cycles, want 1", len(g.Cycles()))
+	}
+	var deferred []string
+	for _, e := range g.DeferredForeignKeys() {
+		deferred = append(deferred, e.From.String()+" -> "+e.To.String())
+	}
+	if got, want := strings.Join(deferred, ", "), "dbo.A -> dbo.B"; got != want {
+		t.Errorf("deferred foreign keys %s, want %s", got, want)
+	}
+}
+
+func TestForeignKeyCycle(t *testing.T) {
+	g := Build(parse(t, `CREATE TABLE dbo.A (Id int PRIMARY KEY, BId int REFERENCES dbo.B (Id));
+CREATE TABLE dbo.B (Id int PRIMARY KEY, AId int REFERENCES dbo.A (Id));`))
+	order, err := g.Order()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := names(order), "dbo.A dbo.B"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestRecursiveProcedures(t *testing.T) {
+	g := Build(parse(t, `CREATE PROCEDURE dbo.A @n int AS IF @n > 0 EXEC dbo.B @n;
+GO
+CREATE PROCEDURE dbo.B @n int AS EXEC dbo.A 0;
+GO
+CREATE TABLE dbo.T (Id int);
+GO
+CREATE PROCEDURE dbo.Log AS INSERT INTO dbo.T VALUES (1);
+GO
+CREATE TRIGGER dbo.trT ON dbo.T AFTER INSERT AS EXEC dbo.Log;`))
+	order, err := g.Order()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := names(order), "dbo.A dbo.B dbo.T dbo.Log dbo.trT"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	g := Build(parse(t, `CREATE TABLE dbo.T (Id int PRIMARY KEY);
+CREATE TABLE dbo.U (TId int REFERENCES dbo.T (Id));
+GO
+CREATE VIEW dbo.V AS SELECT Id FROM dbo.T;`))
+	var dot, mermaid bytes.Buffer
+	if err := g.WriteDOT(&dot); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.WriteMermaid(&mermaid); err != nil {
+		t.Fatal(err)
+	}
+	wantDOT := `digraph dependencies {
+	rankdir=LR;
+	n0 [label="dbo.T", shape=cylinder];
+	n1 [label="dbo.U", shape=cylinder];
+	n2 [label="dbo.V", shape=box];
+	n1 -> n0 [label="foreign key", style=dashed];
+	n2 -> n0 [label="reads"];
+}
+`
+	if dot.String() != wantDOT {
+		t.Errorf("got:\n%s\nwant:\n%s", dot.String(), wantDOT)
+	}
+	wantMermaid := `flowchart LR
+	n0[("dbo.T")]
+	n1[("dbo.U")]
+	n2["dbo.V"]
+	n1 -.->|foreign key| n0
+	n2 -->|reads| n0
+`
+	if mermaid.String() != wantMermaid {
+		t.Errorf("got:\n%s\nwant:\n%s", mermaid.String(), wantMermaid)
+	}
+}
@@ -0,0 +1,78 @@
+package deps
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// dotShapes are the Graphviz node shapes of the object kinds.
+var dotShapes = map[string]string{
+	Table:     "cylinder",
+	View:      "box",
+	Procedure: "component",
+	Function:  "hexagon",
+	Trigger:   "diamond",
+	Synonym:   "note",
+	Type:      "ellipse",
+	Sequence:  "ellipse",
+}
+
+// WriteDOT writes the graph in the Graphviz DOT language, with an edge
+// from each object to the objects it depends on.
+func (g *Graph) WriteDOT(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph dependencies {")
+	fmt.Fprintln(bw, "\trankdir=LR;")
+	for _, n := range g.Nodes {
+		fmt.Fprintf(bw, "\tn%d [label=%s, shape=%s];\n", n.index, strconv.Quote(n.String()), dotShapes[n.Kind])
+	}
+	for _, n := range g.Nodes {
+		for _, e := range n.Deps {
+			style := ""
+			if e.Kind == ForeignKey {
+				style = ", style=dashed"
+			}
+			fmt.Fprintf(bw, "\tn%d -> n%d [label=%s%s];\n", e.From.index, e.To.index, strconv.Quote(e.Kind), style)
+		}
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+// mermaidShapes are the opening and closing brackets of the Mermaid node
+// shapes of the object kinds.
+var mermaidShapes = map[string][2]string{
+	Table:     {"[(", ")]"},
+	View:      {"[", "]"},
+	Procedure: {"[[", "]]"},
+	Function:  {"{{", "}}"},
+	Trigger:   {"{", "}"},
+	Synonym:   {">", "]"},
+	Type:      {"(", ")"},
+	Sequence:  {"(", ")"},
+}
+
+// WriteMermaid writes the graph as a Mermaid flowchart, with an edge from
+// each object to the objects it depends on.
+func (g *Graph) WriteMermaid(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "flowchart LR")
+	for _, n := range g.Nodes {
+		shape := mermaidShapes[n.Kind]
+		label := strings.ReplaceAll(n.String(), `"`, "#quot;")
+		fmt.Fprintf(bw, "\tn%d%s\"%s\"%s\n", n.index, shape[0], label, shape[1])
+	}
+	for _, n := range g.Nodes {
+		for _, e := range n.Deps {
+			arrow := "-->"
+			if e.Kind == ForeignKey {
+				arrow = "-.->"
+			}
+			fmt.Fprintf(bw, "\tn%d %s|%s| n%d\n", e.From.index, arrow, e.Kind, e.To.index)
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,455 @@
+package format
+
+import (
+	"strings"
+
+	"github.com/sqlc-dev/teesql/ast"
+)
+
+var binaryOperators = map[string]string{
+	"Add":        "+",
+	"Subtract":   "-",
+	"Multiply":   "*",
+	"Divide":     "/",
+	"Modulo":     "%",
+	"BitwiseAnd": "&",
+	"BitwiseOr":  "|",
+	"BitwiseXor": "^",
+	"Concat":     "||",
+	"LeftShift":  "<<",
+	"RightShift": ">>",
+}
+
+var unaryOperators = map[string]string{
+	"Negative":   "-",
+	"Positive":   "+",
+	"BitwiseNot": "~",
+}
+
+var comparisonOperators = map[string]string{
+	"Equals":                "=",
+	"NotEqualToBrackets":    "<>",
+	"NotEqualToExclamation": "!=",
+	"LessThan":              "<",
+	"GreaterThan":           ">",
+	"LessThanOrEqualTo":     "<=",
+	"GreaterThanOrEqualTo":  ">=",
+	"NotLessThan":           "!<",
+	"NotGreaterThan":        "!>",
+	"LeftOuterJoin":         "*=",
+	"RightOuterJoin":        "=*",
+}
+
+var assignmentOperators = map[string]string{
+	"Equals":           "=",
+	"AddEquals":        "+=",
+	"SubtractEquals":   "-=",
+	"MultiplyEquals":   "*=",
+	"DivideEquals":     "/=",
+	"ModEquals":        "%=",
+	"BitwiseAndEquals": "&=",
+	"BitwiseOrEquals":  "|=",
+	"BitwiseXorEquals": "^=",
+	"ConcatEquals":     "||=",
+}
+
+// pseudoColumns are the names of the column references that are not
+// regular columns.
+var pseudoColumns = map[string]string{
+	"IdentityCol":             "IDENTITYCOL",
+	"RowGuidCol":              "ROWGUIDCOL",
+	"PseudoColumnAction":      "$ACTION",
+	"PseudoColumnIdentity":    "$IDENTITY",
+	"PseudoColumnRowGuid":     "$ROWGUID",
+	"PseudoColumnCuid":        "$CUID",
+	"PseudoColumnGraphNodeId": "$NODE_ID",
+	"PseudoColumnGraphEdgeId": "$EDGE_ID",
+	"PseudoColumnGraphFromId": "$FROM_ID",
+	"PseudoColumnGraphToId":   "$TO_ID",
+}
+
+var odbcLiterals = map[string]string{
+	"Date":      "d",
+	"Time":      "t",
+	"Timestamp": "ts",
+	"Guid":      "guid",
+}
+
+func (p *printer) literal(n ast.Node) (string, bool) {
+	switch n := n.(type) {
+	case *ast.IntegerLiteral:
+		return n.Value, true
+	case *ast.NumericLiteral:
+		return n.Value, true
+	case *ast.RealLiteral:
+		return n.Value, true
+	case *ast.MoneyLiteral:
+		return n.Value, true
+	case *ast.BinaryLiteral:
+		return n.Value, true
+	case *ast.StringLiteral:
+		return p.collate(QuoteString(n.Value, n.IsNational), n.Collation), true
+	case *ast.NullLiteral:
+		return "NULL", true
+	case *ast.DefaultLiteral:
+		return "DEFAULT", true
+	case *ast.MaxLiteral:
+		return "MAX", true
+	case *ast.IdentifierLiteral:
+		return p.identifier(&ast.Identifier{Value: n.Value, QuoteType: n.QuoteType}), true
+	case *ast.OdbcLiteral:
+		return "{" + odbcLiterals[n.OdbcLiteralType] + " " + QuoteString(n.Value, n.IsNational) + "}", true
+	}
+	return "", false
+}
+
+func (p *printer) scalar(n ast.Node) (string, bool) {
+	switch n := n.(type) {
+	case *ast.Identifier:
+		return p.identifier(n), true
+	case *ast.MultiPartIdentifier:
+		return p.multiPart(n), true
+	case *ast.SchemaObjectName:
+		return p.schemaObject(n), true
+	case *ast.VariableReference:
+		return n.Name, true
+	case *ast.GlobalVariableExpression:
+		return n.Name, true
+	case *ast.ColumnReferenceExpression:
+		return p.collate(p.column(n), n.Collation), true
+	case *ast.BinaryExpression:
+		return join(p.node(n.FirstExpression), binaryOperators[n.BinaryExpressionType], p.node(n.SecondExpression)), true
+	case *ast.UnaryExpression:
+		return unaryOperators[n.UnaryExpressionType] + p.node(n.Expression), true
+	case *ast.ParenthesisExpression:
+		return "(" + p.node(n.Expression) + ")", true
+	case *ast.FunctionCall:
+		return p.functionCall(n), true
+	case *ast.CastCall:
+		return p.collate("CAST("+join(p.node(n.Parameter), "AS", p.node(n.DataType))+")", n.Collation), true
+	case *ast.TryCastCall:
+		return p.collate("TRY_CAST("+join(p.node(n.Parameter), "AS", p.node(n.DataType))+")", n.Collation), true
+	case *ast.ConvertCall:
+		return p.collate("CONVERT("+p.list(n.DataType, n.Parameter, n.Style)+")", n.Collation), true
+	case *ast.TryConvertCall:
+		return p.collate("TRY_CONVERT("+p.list(n.DataType, n.Parameter, n.Style)+")", n.Collation), true
+	case *ast.ParseCall:
+		return "PARSE(" + p.parseArgs(n.StringValue, n.DataType, n.Culture) + ")", true
+	case *ast.TryParseCall:
+		return "TRY_PARSE(" + p.parseArgs(n.StringValue, n.DataType, n.Culture) + ")", true
+	case *ast.CoalesceExpression:
+		return "COALESCE(" + p.exprs(n.Expressions) + ")", true
+	case *ast.NullIfExpression:
+		return "NULLIF(" + p.list(n.FirstExpression, n.SecondExpression) + ")", true
+	case *ast.IIfCall:
+		return "IIF(" + p.list(n.Predicate, n.ThenExpression, n.ElseExpression) + ")", true
+	case *ast.LeftFunctionCall:
+		return "LEFT(" + p.exprs(n.Parameters) + ")", true
+	case *ast.RightFunctionCall:
+		return "RIGHT(" + p.exprs(n.Parameters) + ")", true
+	case *ast.SearchedCaseExpression:
+		parts := []string{"CASE"}
+		for _, w := range n.WhenClauses {
+			parts = append(parts, p.node(w))
+		}
+		if n.ElseExpression != nil {
+			parts = append(parts, "ELSE", p.node(n.ElseExpression))
+		}
+		return p.collate(join(append(parts, "END")...), n.Collation), true
+	case *ast.SimpleCaseExpression:
+		parts := []string{"CASE", p.node(n.InputExpression)}
+		for _, w := range n.WhenClauses {
+			parts = append(parts, p.node(w))
+		}
+		if n.ElseExpression != nil {
+			parts = append(parts, "ELSE", p.node(n.ElseExpression))
+		}
+		return p.collate(join(append(parts, "END")...), n.Collation), true
+	case *ast.SearchedWhenClause:
+		return join("WHEN", p.node(n.WhenExpression), "THEN", p.node(n.ThenExpression)), true
+	case *ast.SimpleWhenClause:
+		return join("WHEN", p.node(n.WhenExpression), "THEN", p.node(n.ThenExpression)), true
+	case *ast.ScalarSubquery:
+		return p.collate("("+p.node(n.QueryExpression)+")", n.Collation), true
+	case *ast.AtTimeZoneCall:
+		return join(p.node(n.DateValue), "AT TIME ZONE", p.node(n.TimeZone)), true
+	case *ast.NextValueForExpression:
+		return join("NEXT VALUE FOR", p.node(n.SequenceName), p.node(n.OverClause)), true
+	case *ast.ParameterlessCall:
+		return p.collate(strings.ReplaceAll(keyword(n.ParameterlessCallType), " ", "_"), n.Collation), true
+	case *ast.IdentityFunctionCall:
+		return "IDENTITY(" + p.list(n.DataType, n.Seed, n.Increment) + ")", true
+	case *ast.PartitionFunctionCall:
+		name := "$PARTITION." + p.identifier(n.FunctionName)
+		if n.SchemaName != nil {
+			name = p.identifier(n.SchemaName) + "." + name
+		}
+		if n.DatabaseName != nil {
+			name = p.identifier(n.DatabaseName) + "." + name
+		}
+		return name + "(" + p.exprs(n.Parameters) + ")", true
+	case *ast.UserDefinedTypePropertyAccess:
+		return p.collate(p.callTarget(n.CallTarget)+p.identifier(n.PropertyName), n.Collation), true
+	case *ast.OverClause:
+		return p.over(n), true
+	case *ast.SqlDataTypeReference:
+		return p.dataType(n.Name, n.SqlDataTypeOption, n.Parameters), true
+	case *ast.UserDataTypeReference:
+		return p.dataType(n.Name, "", n.Parameters), true
+	case *ast.XmlDataTypeReference:
+		s := p.dataType(n.Name, "Xml", nil)
+		if n.XmlSchemaCollection != nil {
+			option := ""
+			if n.XmlDataTypeOption != "" && n.XmlDataTypeOption != "None" {
+				option = strings.ToUpper(n.XmlDataTypeOption)
+			}
+			s += "(" + join(option, p.node(n.XmlSchemaCollection)) + ")"
+		}
+		return s, true
+	}
+	return "", false
+}
+
+func (p *printer) exprs(exprs []ast.ScalarExpression) string {
+	nodes := make([]ast.Node, len(exprs))
+	for i, e := range exprs {
+		nodes[i] = e
+	}
+	return p.list(nodes...)
+}
+
+func (p *printer) collate(s string, collation *ast.Identifier) string {
+	if collation == nil {
+		return s
+	}
+	return join(s, "COLLATE", p.identifier(collation))
+}
+
+func (p *printer) multiPart(m *ast.MultiPartIdentifier) string {
+	if m == nil {
+		return ""
+	}
+	parts := make([]string, len(m.Identifiers))
+	for i, id := range m.Identifiers {
+		parts[i] = p.node(id)
+	}
+	return strings.Join(parts, ".")
+}
+
+func (p *printer) schemaObject(n *ast.SchemaObjectName) string {
+	if len(n.Identifiers) > 0 {
+		parts := make([]string, len(n.Identifiers))
+		for i, id := range n.Identifiers {
+			parts[i] = p.node(id)
+		}
+		return strings.Join(parts, ".")
+	}
+	var parts []string
+	for _, id := range []*ast.Identifier{n.ServerIdentifier, n.DatabaseIdentifier, n.SchemaIdentifier, n.BaseIdentifier} {
+		if id != nil || len(parts) > 0 {
+			parts = append(parts, p.node(id))
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+func (p *printer) column(n *ast.ColumnReferenceExpression) string {
+	name := p.node(n.MultiPartIdentifier)
+	switch n.ColumnType {
+	case "", "Regular":
+		return name
+	case "Wildcard":
+		if name == "" {
+			return "*"
+		}
+		return name + ".*"
+	}
+	pseudo := pseudoColumns[n.ColumnType]
+	if name == "" {
+		return pseudo
+	}
+	return name + "." + pseudo
+}
+
+func (p *printer) callTarget(t ast.CallTarget) string {
+	switch t := t.(type) {
+	case *ast.MultiPartIdentifierCallTarget:
+		return p.node(t.MultiPartIdentifier) + "."
+	case *ast.ExpressionCallTarget:
+		return p.node(t.Expression) + "."
+	case *ast.UserDefinedTypeCallTarget:
+		return p.node(t.SchemaObjectName) + "::"
+	}
+	return ""
+}
+
+func (p *printer) functionCall(n *ast.FunctionCall) string {
+	var args string
+	switch {
+	case n.TrimOptions != nil && len(n.Parameters) == 2:
+		args = join(p.identifier(n.TrimOptions), p.node(n.Parameters[0]), "FROM", p.node(n.Parameters[1]))
+	case len(n.JsonParameters) > 0:
+		pairs := make([]string, len(n.JsonParameters))
+		for i, kv := range n.JsonParameters {
+			pairs[i] = p.node(kv.JsonKeyName) + ": " + p.node(kv.JsonValue)
+		}
+		args = strings.Join(pairs, ", ")
+	default:
+		args = p.exprs(n.Parameters)
+	}
+	if n.UniqueRowFilter == "Distinct" {
+		args = join("DISTINCT", args)
+	}
+	if len(n.AbsentOrNullOnNull) > 0 {
+		args = join(args, p.identifiers(n.AbsentOrNullOnNull, " "))
+	}
+	s := p.callTarget(n.CallTarget) + p.identifier(n.FunctionName) + "(" + args + ")"
+	if len(n.IgnoreRespectNulls) > 0 {
+		s = join(s, p.identifiers(n.IgnoreRespectNulls, " "))
+	}
+	if n.WithinGroupClause != nil {
+		s = join(s, "WITHIN GROUP ("+p.node(n.WithinGroupClause.OrderByClause)+")")
+	}
+	if n.OverClause != nil {
+		s = join(s, p.over(n.OverClause))
+	}
+	return p.collate(s, n.Collation)
+}
+
+func (p *printer) identifiers(ids []*ast.Identifier, sep string) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = p.node(id)
+	}
+	return strings.Join(parts, sep)
+}
+
+func (p *printer) over(n *ast.OverClause) string {
+	var parts []string
+	if n.WindowName != nil {
+		parts = append(parts, p.identifier(n.WindowName))
+	}
+	if len(n.Partitions) > 0 {
+		parts = append(parts, "PARTITION BY "+p.exprs(n.Partitions))
+	}
+	if n.OrderByClause != nil {
+		parts = append(parts, p.node(n.OrderByClause))
+	}
+	if f := n.WindowFrameClause; f != nil {
+		frame := strings.ToUpper(f.WindowFrameType)
+		if f.Bottom != nil {
+			frame = join(frame, "BETWEEN", p.windowDelimiter(f.Top), "AND", p.windowDelimiter(f.Bottom))
+		} else {
+			frame = join(frame, p.windowDelimiter(f.Top))
+		}
+		parts = append(parts, frame)
+	}
+	return "OVER (" + join(parts...) + ")"
+}
+
+func (p *printer) windowDelimiter(d *ast.WindowDelimiter) string {
+	if d == nil {
+		return ""
+	}
+	switch d.WindowDelimiterType {
+	case "ValuePreceding":
+		return join(p.node(d.OffsetValue), "PRECEDING")
+	case "ValueFollowing":
+		return join(p.node(d.OffsetValue), "FOLLOWING")
+	}
+	return keyword(d.WindowDelimiterType)
+}
+
+func (p *printer) parseArgs(value ast.ScalarExpression, t ast.DataTypeReference, culture ast.ScalarExpression) string {
+	s := join(p.node(value), "AS", p.node(t))
+	if culture != nil {
+		s = join(s, "USING", p.node(culture))
+	}
+	return s
+}
+
+func (p *printer) dataType(name *ast.SchemaObjectName, option string, params []ast.ScalarExpression) string {
+	s := p.node(name)
+	if s == "" {
+		s = strings.ToUpper(option)
+	}
+	if len(params) > 0 {
+		s += "(" + p.exprs(params) + ")"
+	}
+	return s
+}
+
+func (p *printer) boolean(n ast.Node) (string, bool) {
+	switch n := n.(type) {
+	case *ast.BooleanBinaryExpression:
+		return join(p.node(n.FirstExpression), strings.ToUpper(n.BinaryExpressionType), p.node(n.SecondExpression)), true
+	case *ast.BooleanComparisonExpression:
+		return join(p.node(n.FirstExpression), comparisonOperators[n.ComparisonType], p.node(n.SecondExpression)), true
+	case *ast.BooleanInExpression:
+		op := "IN"
+		if n.NotDefined {
+			op = "NOT IN"
+		}
+		values := p.exprs(n.Values)
+		if n.Subquery != nil {
+			values = p.node(n.Subquery)
+		}
+		return join(p.node(n.Expression), op, "("+values+")"), true
+	case *ast.BooleanIsNullExpression:
+		if n.IsNot {
+			return join(p.node(n.Expression), "IS NOT NULL"), true
+		}
+		return join(p.node(n.Expression), "IS NULL"), true
+	case *ast.BooleanLikeExpression:
+		op := "LIKE"
+		if n.NotDefined {
+			op = "NOT LIKE"
+		}
+		s := join(p.node(n.FirstExpression), op, p.node(n.SecondExpression))
+		if n.EscapeExpression != nil {
+			s = join(s, "ESCAPE", p.node(n.EscapeExpression))
+		}
+		return s, true
+	case *ast.BooleanNotExpression:
+		return join("NOT", p.node(n.Expression)), true
+	case *ast.BooleanParenthesisExpression:
+		return "(" + p.node(n.Expression) + ")", true
+	case *ast.BooleanTernaryExpression:
+		op := "BETWEEN"
+		if n.TernaryExpressionType == "NotBetween" {
+			op = "NOT BETWEEN"
+		}
+		return join(p.node(n.FirstExpression), op, p.node(n.SecondExpression), "AND", p.node(n.ThirdExpression)), true
+	case *ast.BooleanScalarPlaceholder:
+		return p.node(n.Scalar), true
+	case *ast.DistinctPredicate:
+		op := "IS DISTINCT FROM"
+		if n.IsNot {
+			op = "IS NOT DISTINCT FROM"
+		}
+		return join(p.node(n.FirstExpression), op, p.node(n.SecondExpression)), true
+	case *ast.ExistsPredicate:
+		return "EXISTS (" + p.node(n.Subquery) + ")", true
+	case *ast.SubqueryComparisonPredicate:
+		return join(p.node(n.Expression), comparisonOperators[n.ComparisonType], strings.ToUpper(n.SubqueryComparisonPredicateType), p.node(n.Subquery)), true
+	case *ast.FullTextPredicate:
+		cols := make([]string, len(n.Columns))
+		for i, c := range n.Columns {
+			cols[i] = p.node(c)
+		}
+		target := strings.Join(cols, ", ")
+		if len(cols) > 1 {
+			target = "(" + target + ")"
+		}
+		args := []string{target, p.node(n.Value)}
+		if n.LanguageTerm != nil {
+			args = append(args, join("LANGUAGE", p.node(n.LanguageTerm)))
+		}
+		return strings.ToUpper(n.FullTextFunctionType) + "(" + strings.Join(args, ", ") + ")", true
+	case *ast.TSEqualCall:
+		return "TSEQUAL(" + p.list(n.FirstExpression, n.SecondExpression) + ")", true
+	case *ast.UpdateCall:
+		return "UPDATE(" + p.identifier(n.Identifier) + ")", true
+	}
+	return "", false
+}
@@ -0,0 +1,95 @@
+package format
+
+import (
+	"hash/fnv"
+	"strings"
+
+	"github.com/sqlc-dev/teesql/ast"
+	"github.com/sqlc-dev/teesql/catalog"
+)
+
+// Fingerprint returns the normalized text of a statement and its 64-bit
+// FNV-1a hash, so that statements that differ only in their literals and
+// layout can be grouped.
+//
+// In the normalized text every string, binary, numeric, money, date and
+// other value literal is replaced by ?, including a negated number; an IN
+// list of literals is collapsed to IN (?); identifiers and variable names
+// are written in lower case and quoted only where they must be; the default
+// schema is dropped from two-part names; and comments and whitespace are
+// reduced to single spaces. The lengths in data types, as in varchar(10),
+// and the keywords NULL, DEFAULT and MAX are kept, as is the COLLATE
+// clause of a string literal.
+func Fingerprint(stmt ast.Statement) (string, uint64) {
+	text := normalized.Node(stmt)
+	h := fnv.New64a()
+	h.Write([]byte(text))
+	return text, h.Sum64()
+}
+
+var normalized *Config
+
+func init() {
+	normalized = &Config{Replace: normalize, Identifier: normalizeIdentifier}
+}
+
+func normalize(n, parent ast.Node) (string, bool) {
+	switch n := n.(type) {
+	case *ast.SqlDataTypeReference, *ast.UserDataTypeReference:
+		// Keep the literals of the type parameters.
+		c := &Config{Identifier: func(id *ast.Identifier) string { return strings.ToLower(id.Value) }}
+		return c.Node(n), true
+	case *ast.BooleanInExpression:
+		if len(n.Values) == 0 {
+			break
+		}
+		for _, v := range n.Values {
+			if !isValue(v) {
+				return "", false
+			}
+		}
+		op := "IN"
+		if n.NotDefined {
+			op = "NOT IN"
+		}
+		return join(normalized.Node(n.Expression), op, "(?)"), true
+	case *ast.VariableReference:
+		return strings.ToLower(n.Name), true
+	case *ast.SchemaObjectName:
+		if len(n.Identifiers) == 2 && strings.EqualFold(n.Identifiers[0].Value, catalog.DefaultSchema) {
+			return normalizeIdentifier(n.Identifiers[1]), true
+		}
+	}
+	if isValue(n) {
+		// The collation of a string literal is part of the statement.
+		if lit, ok := n.(*ast.StringLiteral); ok && lit.Collation != nil {
+			return join("?", "COLLATE", strings.ToLower(lit.Collation.Value)), true
+		}
+		return "?", true
+	}
+	return "", false
+}
+
+// isValue reports whether n is a literal value: a literal other than NULL,
+// DEFAULT and MAX, or a signed numeric literal.
+func isValue(n ast.Node) bool {
+	switch n := n.(type) {
+	case *ast.IntegerLiteral, *ast.NumericLiteral, *ast.RealLiteral, *ast.MoneyLiteral,
+		*ast.StringLiteral, *ast.BinaryLiteral, *ast.OdbcLiteral:
+		return true
+	case *ast.UnaryExpression:
+		switch n.Expression.(type) {
+		case *ast.IntegerLiteral, *ast.NumericLiteral, *ast.RealLiteral, *ast.MoneyLiteral:
+			return n.UnaryExpressionType != "BitwiseNot"
+		}
+	}
+	return false
+}
+
+func normalizeIdentifier(id *ast.Identifier) string {
+	name := strings.ToLower(id.Value)
+	if NeedsQuotes(name) {
+		return QuoteIdentifier(name)
+	}
+	return name
+}
@@ -0,0 +1,258 @@
+// Package format regenerates T-SQL source from syntax trees.
+//
+// The text is written on a single line with keywords in upper case and one
+// space between tokens; comments and the original layout are not kept. The
+// statements, expressions, queries and table references that queries use
+// are written as T-SQL. Other nodes, such as most DDL, are written in a
+// generic form that names the node type and lists its children, so that the
+// output is still deterministic and every literal it contains goes through
+// the Replace hook.
+package format
+
+import (
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/sqlc-dev/teesql/ast"
+	"github.com/sqlc-dev/teesql/parser"
+)
+
+// Config controls how nodes are written. The zero Config writes nodes as
+// they were parsed.
+type Config struct {
+	// Replace, if set, is called for each node before it is written, with
+	// the node that contains it, which is nil for the node being formatted.
+	// If it returns true, its text is written in place of the node's.
+	Replace func(n, parent ast.Node) (string, bool)
+	// Identifier, if set, returns the text written for an identifier in
+	// place of its source form.
+	Identifier func(id *ast.Identifier) string
+}
+
+// Node returns the T-SQL text of n.
+func Node(n ast.Node) string {
+	return (&Config{}).Node(n)
+}
+
+// Node returns the T-SQL text of n, written as c describes.
+func (c *Config) Node(n ast.Node) string {
+	p := &printer{cfg: c}
+	return p.node(n)
+}
+
+type printer struct {
+	cfg *Config
+	// stack holds the nodes being written, innermost last.
+	stack []ast.Node
+	// into is the INTO clause of the SELECT statement being written, and
+	// intoSpec the query specification it belongs in.
+	into     string
+	intoSpec *ast.QuerySpecification
+}
+
+func (p *printer) node(n ast.Node) string {
+	if isNil(n) {
+		return ""
+	}
+	if p.cfg.Replace != nil {
+		var parent ast.Node
+		if len(p.stack) > 0 {
+			parent = p.stack[len(p.stack)-1]
+		}
+		if s, ok := p.cfg.Replace(n, parent); ok {
+			return s
+		}
+	}
+	p.stack = append(p.stack, n)
+	defer func() { p.stack = p.stack[:len(p.stack)-1] }()
+	if s, ok := p.literal(n); ok {
+		return s
+	}
+	if s, ok := p.scalar(n); ok {
+		return s
+	}
+	if s, ok := p.boolean(n); ok {
+		return s
+	}
+	if s, ok := p.query(n); ok {
+		return s
+	}
+	if s, ok := p.table(n); ok {
+		return s
+	}
+	if s, ok := p.statement(n); ok {
+		return s
+	}
+	return p.generic(n)
+}
+
+// list writes nodes separated by commas.
+func (p *printer) list(nodes ...ast.Node) string {
+	parts := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		if s := p.node(n); s != "" {
+			parts = append(parts, s)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// join writes the non-empty parts separated by spaces.
+func join(parts ...string) string {
+	var b strings.Builder
+	for _, s := range parts {
+		if s == "" {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(s)
+	}
+	return b.String()
+}
+
+// identifier writes id as parsed: quoted as it was quoted in the source.
+func (p *printer) identifier(id *ast.Identifier) string {
+	if id == nil {
+		return ""
+	}
+	if p.cfg.Identifier != nil {
+		return p.cfg.Identifier(id)
+	}
+	return identifierText(id)
+}
+
+// identifierText returns id as it was written in the source.
+func identifierText(id *ast.Identifier) string {
+	switch id.QuoteType {
+	case "SquareBracket":
+		return QuoteIdentifier(id.Value)
+	case "DoubleQuote":
+		return `"` + strings.ReplaceAll(id.Value, `"`, `""`) + `"`
+	}
+	return id.Value
+}
+
+// QuoteIdentifier returns name in square brackets.
+func QuoteIdentifier(name string) string {
+	return "[" + strings.ReplaceAll(name, "]", "]]") + "]"
+}
+
+// NeedsQuotes reports whether name must be quoted to be read back as an
+// identifier: it is empty, is a keyword, or contains characters that end a
+// regular identifier.
+func NeedsQuotes(name string) bool {
+	if name == "" {
+		return true
+	}
+	tok := parser.NewLexer(name).NextToken()
+	return tok.Type != parser.TokenIdent || tok.Literal != name
+}
+
+// QuoteString returns s as a string literal.
+func QuoteString(s string, national bool) string {
+	q := "'" + strings.ReplaceAll(s, "'", "''") + "'"
+	if national {
+		return "N" + q
+	}
+	return q
+}
+
+// keyword returns the T-SQL keywords for a Pascal-case option name, such as
+// READ COMMITTED for ReadCommitted.
+func keyword(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if i > 0 && unicode.IsUpper(r) {
+			b.WriteByte(' ')
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
+
+// generic writes a node that has no T-SQL form here as its type name
+// followed by its children in parentheses.
+func (p *printer) generic(n ast.Node) string {
+	return p.other(n)
+}
+
+// other writes a value in the generic form. It is used for nodes and for
+// the ast types that are not nodes.
+func (p *printer) other(x any) string {
+	v := reflect.ValueOf(x)
+	name := reflect.Indirect(v).Type().Name()
+	parts := p.fields(reflect.Indirect(v))
+	return name + "(" + strings.Join(parts, " ") + ")"
+}
+
+var nodeType = reflect.TypeOf((*ast.Node)(nil)).Elem()
+
+func (p *printer) fields(v reflect.Value) []string {
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	var parts []string
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() || f.Type == reflect.TypeOf(ast.Position{}) || f.Name == "Count" {
+			continue
+		}
+		parts = append(parts, p.value(f.Name, v.Field(i))...)
+	}
+	return parts
+}
+
+func (p *printer) value(name string, v reflect.Value) []string {
+	switch v.Kind() {
+	case reflect.Interface, reflect.Pointer:
+		if v.IsNil() {
+			return nil
+		}
+		if v.Type().Implements(nodeType) {
+			if s := p.node(v.Interface().(ast.Node)); s != "" {
+				return []string{s}
+			}
+			return nil
+		}
+		if v.Kind() == reflect.Interface {
+			return p.value(name, v.Elem())
+		}
+		return p.fields(v.Elem())
+	case reflect.Slice:
+		var parts []string
+		for i := 0; i < v.Len(); i++ {
+			parts = append(parts, p.value(name, v.Index(i))...)
+		}
+		return parts
+	case reflect.String:
+		if s := v.String(); s != "" && s != "None" && s != "NotSpecified" {
+			return []string{name + "=" + s}
+		}
+	case reflect.Bool:
+		if v.Bool() {
+			return []string{name}
+		}
+	case reflect.Struct:
+		return p.fields(v)
+	}
+	return nil
+}
+
+// asNode returns v if it is a node. Some of the ast interfaces, such as
+// ast.TableHintType, do not embed ast.Node though all their types are nodes.
+func asNode(v any) ast.Node {
+	n, _ := v.(ast.Node)
+	return n
+}
+
+func isNil(n ast.Node) bool {
+	if n == nil {
+		return true
+	}
+	v := reflect.ValueOf(n)
+	return v.Kind() == reflect.Pointer && v.IsNil()
+}
@@ -0,0 +1,263 @@
+package format
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sqlc-dev/teesql/ast"
+	"github.com/sqlc-dev/teesql/parser"
+)
+
+func parseStatement(t *testing.T, sql string) ast.Statement {
+	t.Helper()
+	script, err := parser.Parse(context.Background(), strings.NewReader(sql))
+	if err != nil {
+		t.Fatalf("parse %q: %v", sql, err)
+	}
+	return script.Batches[0].Statements[0]
+}
+
+func TestNode(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{
+			sql:  "select top 10 a.Id, b.[Name] n, count(*) from dbo.A a with (nolock) left join B b on a.Id = b.AId where a.X in (1, 2) order by 1 desc",
+			want: "SELECT TOP (10) a.Id, b.[Name] AS n, count(*) FROM dbo.A AS a WITH (NOLOCK) LEFT OUTER JOIN B AS b ON a.Id = b.AId WHERE a.X IN (1, 2) ORDER BY 1 DESC",
+		},
+		{
+			sql:  "SELECT x INTO #t FROM y UNION ALL SELECT 1",
+			want: "SELECT x INTO #t FROM y UNION ALL SELECT 1",
+		},
+		{
+			sql:  "WITH c (a) AS (SELECT 1) UPDATE t SET a += 1 OUTPUT inserted.a FROM t JOIN c ON c.a = t.a WHERE t.d BETWEEN '2020-01-01' AND GETDATE()",
+			want: "WITH c (a) AS (SELECT 1) UPDATE t SET a += 1 OUTPUT inserted.a FROM t INNER JOIN c ON c.a = t.a WHERE t.d BETWEEN '2020-01-01' AND GETDATE()",
+		},
+		{
+			sql:  "MERGE t USING s AS src ON t.id = src.id WHEN MATCHED THEN UPDATE SET t.v = src.v WHEN NOT MATCHED THEN INSERT (id, v) VALUES (src.id, src.v);",
+			want: "MERGE INTO t USING s AS src ON t.id = src.id WHEN MATCHED THEN UPDATE SET t.v = src.v WHEN NOT MATCHED THEN INSERT (id, v) VALUES (src.id, src.v)",
+		},
+		{
+			sql:  "EXEC @r = dbo.p @a = 1, @b = @c OUTPUT",
+			want: "EXEC @r = dbo.p @a = 1, @b = @c OUTPUT",
+		},
+		{
+			sql:  "EXECUTE ('SELECT ' + @x)",
+			want: "EXEC ('SELECT ' + @x)",
+		},
+		{
+			sql:  "IF @a = 1\nBEGIN\n  DECLARE @b int = 5\n  SET @b = @b * 2\n  PRINT @b\nEND\nELSE RETURN 3",
+			want: "IF @a = 1 BEGIN DECLARE @b int = 5; SET @b = @b * 2; PRINT @b; END ELSE RETURN 3",
+		},
+		{
+			sql:  "SELECT CASE WHEN a IS NULL THEN N'it''s' ELSE CONVERT(varchar(10), a, 120) END, ROW_NUMBER() OVER (PARTITION BY b ORDER BY c) FROM t",
+			want: "SELECT CASE WHEN a IS NULL THEN N'it''s' ELSE CONVERT(varchar(10), a, 120) END, ROW_NUMBER() OVER (PARTITION BY b ORDER BY c) FROM t",
+		},
+		{
+			sql:  "INSERT dbo.T (a, b) VALUES (1, 'x'), (-2, 0x0F)",
+			want: "INSERT INTO dbo.T (a, b) VALUES (1, 'x'), (-2, 0x0F)",
+		},
+		{
+			sql:  "DELETE TOP (5) FROM t WHERE NOT EXISTS (SELECT 1 FROM u WHERE u.id = t.id)",
+			want: "DELETE TOP (5) FROM t WHERE NOT EXISTS (SELECT 1 FROM u WHERE u.id = t.id)",
+		},
+		{
+			sql:  "BEGIN TRY BEGIN TRAN; UPDATE t SET a = 1; COMMIT; END TRY BEGIN CATCH ROLLBACK; THROW; END CATCH",
+			want: "BEGIN TRY BEGIN TRANSACTION; UPDATE t SET a = 1; COMMIT TRANSACTION; END TRY BEGIN CATCH ROLLBACK TRANSACTION; THROW; END CATCH",
+		},
+		{
+			sql:  "CREATE TABLE t (id int NOT NULL DEFAULT 5)",
+			want: "CreateTableStatement(t TableDefinition(ColumnDefinition(id int DefaultConstraintDefinition(5) NullableConstraintDefinition())))",
+		},
+	}
+	for _, tt := range tests {
+		got := Node(parseStatement(t, tt.sql))
+		if got != tt.want {
+			t.Errorf("Node(%q)\n got %s\nwant %s", tt.sql, got, tt.want)
+			continue
+		}
+		if !strings.HasPrefix(got, "Create") {
+			// The text parses back to the same tree.
+			if again := Node(parseStatement(t, got)); again != got {
+				t.Errorf("Node(%q) does not round trip: %s", got, again)
+			}
+		}
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+		same bool
+	}{
+		{
+			name: "literals",
+			a:    "SELECT Name FROM Users WHERE Id = 42 AND Email = 'a@example.com'",
+			b:    "select name from users where id = -7 and email = N'b@example.com'",
+			want: "SELECT name FROM users WHERE id = ? AND email = ?",
+			same: true,
+		},
+		{
+			name: "in list",
+			a:    "SELECT * FROM t WHERE id IN (1, 2, 3)",
+			b:    "SELECT * FROM t WHERE id IN (4)",
+			want: "SELECT * FROM t WHERE id IN (?)",
+			same: true,
+		},
+		{
+			name: "quoting and default schema",
+			a:    "SELECT [Order Id], [Total] FROM [dbo].[Orders] WHERE [Status] = @Status",
+			b:    "SELECT [order id], total\nFROM   orders -- all of them\nWHERE  status = @status",
+			want: "SELECT [order id], total FROM orders WHERE status = @status",
+			same: true,
+		},
+		{
+			name: "types kept",
+			a:    "SELECT CAST(x AS varchar(10)), TOP_ = 1.5, m = $3 FROM t",
+			b:    "SELECT CAST(x AS varchar(20)), TOP_ = 2.5, m = $4 FROM t",
+			want: "SELECT CAST(x AS varchar(10)), ? AS top_, ? AS m FROM t",
+		},
+		{
+			name: "structure differs",
+			a:    "SELECT a FROM t WHERE b = 1",
+			b:    "SELECT a FROM t WHERE b > 1",
+			want: "SELECT a FROM t WHERE b = ?",
+		},
+		{
+			name: "in list with columns",
+			a:    "SELECT a FROM t WHERE b IN (c, 1)",
+			b:    "SELECT a FROM t WHERE b IN (c, 2)",
+			want: "SELECT a FROM t WHERE b IN (c, ?)",
+			same: true,
+		},
+		{
+			name: "collation kept",
+			a:    "SELECT N'x' COLLATE Latin1_General_CI_AS",
+			b:    "SELECT N'x'",
+			want: "SELECT ? COLLATE latin1_general_ci_as",
+		},
+		{
+			name: "other schema",
+			a:    "SELECT a FROM sales.t",
+			b:    "SELECT a FROM t",
+			want: "SELECT a FROM sales.t",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, hash := Fingerprint(parseStatement(t, tt.a))
+			if text != tt.want {
+				t.Errorf("text = %s, want %s", text, tt.want)
+			}
+			other, otherHash := Fingerprint(parseStatement(t, tt.b))
+			if (text == other) != tt.same || (hash == otherHash) != tt.same {
+				t.Errorf("fingerprints of %q and %q: %s (%x) and %s (%x), want same = %v", tt.a, tt.b, text, hash, other, otherHash, tt.same)
+			}
+		})
+	}
+}
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		opts *RedactOptions
+		want string
+	}{
+		{
+			name: "literals",
+			sql:  "SELECT TOP 5 Name FROM dbo.Users WHERE Email = N'a@example.com' AND Balance > 10.5 AND Born < {d '1990-01-01'} AND Token = 0xFF AND Fee <> $1 AND Note IS NULL",
+			want: "SELECT TOP ? Name FROM dbo.Users WHERE Email = ? AND Balance > ? AND Born < {d ?} AND Token = ? AND Fee <> ? AND Note IS NULL",
+		},
+		{
+			name: "keep top and styles",
+			sql:  "SELECT TOP (5) CONVERT(varchar(10), Born, 120), TRY_CONVERT(date, '2020-01-01', 23) FROM dbo.Users WHERE Id = -1",
+			opts: &RedactOptions{KeepTop: true, KeepStyles: true},
+			want: "SELECT TOP (5) CONVERT(varchar(10), Born, 120), TRY_CONVERT(date, ?, 23) FROM dbo.Users WHERE Id = -?",
+		},
+		{
+			name: "styles redacted by default",
+			sql:  "SELECT CONVERT(varchar(10), Born, 120) FROM t",
+			want: "SELECT CONVERT(varchar(10), Born, ?) FROM t",
+		},
+		{
+			name: "type lengths",
+			sql:  "DECLARE @c char(10) = CHAR(65), @d decimal(19, 4) = CAST(1 AS nchar(2))",
+			want: "DECLARE @c char(10) = CHAR(?), @d decimal(19, 4) = CAST(? AS nchar(2))",
+		},
+		{
+			name: "identifiers",
+			sql:  "SELECT SSN, [Name] FROM People WHERE [ssn] = '123-45-6789'",
+			opts: &RedactOptions{Identifier: func(id *ast.Identifier) bool { return strings.EqualFold(id.Value, "ssn") }},
+			want: "SELECT ?, [Name] FROM People WHERE ? = ?",
+		},
+		{
+			name: "comments",
+			sql:  "SELECT Name -- Email = 'a@example.com'\nFROM t /* Id = 7 */ WHERE Id = 7",
+			want: "SELECT Name \nFROM t   WHERE Id = ?",
+		},
+		{
+			name: "exec string",
+			sql:  "EXEC ('SELECT * FROM Users WHERE Email = ''a@example.com''')",
+			want: "EXEC ('SELECT * FROM Users WHERE Email = ?')",
+		},
+		{
+			name: "exec concatenation",
+			sql:  "EXEC ('SELECT * FROM Users WHERE Email = ''' + @email + '''')",
+			want: "EXEC (? + @email + ?)",
+		},
+		{
+			name: "exec constant concatenation",
+			sql:  "EXEC ('SELECT Name ' + 'FROM Users WHERE Id = 7')",
+			want: "EXEC ('SELECT Name FROM Users WHERE Id = ?')",
+		},
+		{
+			name: "sp_executesql",
+			sql:  "EXEC sp_executesql N'UPDATE Users SET Name = ''x'' WHERE Id = @id', N'@id int', @id = 42",
+			want: "EXEC sp_executesql N'UPDATE Users SET Name = ? WHERE Id = @id', ?, @id = ?",
+		},
+		{
+			name: "sp_executesql named",
+			sql:  "EXECUTE @rc = sys.sp_executesql @params = N'@id int', @stmt = N'SELECT 1 WHERE @id = 2', @id = 42",
+			want: "EXECUTE @rc = sys.sp_executesql @params = ?, @stmt = N'SELECT ? WHERE @id = ?', @id = ?",
+		},
+		{
+			name: "nested exec",
+			sql:  "EXEC ('EXEC (''SELECT 1'')')",
+			want: "EXEC ('EXEC (''SELECT ?'')')",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Redact(tt.sql, tt.opts); got != tt.want {
+				t.Errorf("Redact(%q)\n got %s\nwant %s", tt.sql, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRedactParses checks that statements the formatter does not cover stay
+// T-SQL when redacted: with each ? made a string again, the text parses.
+func TestRedactParses(t *testing.T) {
+	for _, sql := range []string{
+		"SET NOCOUNT ON;",
+		"CREATE LOGIN app WITH PASSWORD = 'secret';",
+		"WAITFOR DELAY '00:00:05';",
+		"SELECT Name FROM dbo.Users WHERE Id = 7 FOR XML PATH('user');",
+		"SELECT * FROM OPENQUERY(remote, 'SELECT Name FROM Users WHERE Id = 7');",
+		"SELECT * FROM OPENROWSET('SQLNCLI', 'Server=.;Trusted_Connection=yes;', 'SELECT 1') AS r;",
+		"BULK INSERT dbo.Users FROM 'C:\\users.csv' WITH (FIRSTROW = 2);",
+	} {
+		got := Redact(sql, nil)
+		if strings.ContainsAny(strings.ReplaceAll(got, "?", ""), "'0123456789") {
+			t.Errorf("Redact(%q) = %s, left a literal", sql, got)
+		}
+		if _, err := parser.Parse(context.Background(), strings.NewReader(strings.ReplaceAll(got, "?", "'x'"))); err != nil {
+			t.Errorf("Redact(%q) = %s, does not parse: %v", sql, got, err)
+		}
+	}
+}
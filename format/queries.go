@@ -0,0 +1,395 @@
+package format
+
+import (
+	"strings"
+
+	"github.com/sqlc-dev/teesql/ast"
+)
+
+// optimizerHints are the OPTION hints whose keywords are not the words of
+// their names.
+var optimizerHints = map[string]string{
+	"MaxDop":                             "MAXDOP",
+	"MaxRecursion":                       "MAXRECURSION",
+	"KeepFixedPlan":                      "KEEPFIXED PLAN",
+	"QueryTraceOn":                       "QUERYTRACEON",
+	"MaxGrantPercent":                    "MAX_GRANT_PERCENT",
+	"MinGrantPercent":                    "MIN_GRANT_PERCENT",
+	"NoPerformanceSpool":                 "NO_PERFORMANCE_SPOOL",
+	"IgnoreNonClusteredColumnStoreIndex": "IGNORE_NONCLUSTERED_COLUMNSTORE_INDEX",
+}
+
+var joinTypes = map[string]string{
+	"Inner":      "INNER JOIN",
+	"LeftOuter":  "LEFT OUTER JOIN",
+	"RightOuter": "RIGHT OUTER JOIN",
+	"FullOuter":  "FULL OUTER JOIN",
+	"CrossJoin":  "CROSS JOIN",
+	"CrossApply": "CROSS APPLY",
+	"OuterApply": "OUTER APPLY",
+}
+
+var mergeConditions = map[string]string{
+	"Matched":            "WHEN MATCHED",
+	"NotMatched":         "WHEN NOT MATCHED",
+	"NotMatchedByTarget": "WHEN NOT MATCHED BY TARGET",
+	"NotMatchedBySource": "WHEN NOT MATCHED BY SOURCE",
+}
+
+func (p *printer) query(n ast.Node) (string, bool) {
+	switch n := n.(type) {
+	case *ast.QuerySpecification:
+		return p.querySpecification(n), true
+	case *ast.BinaryQueryExpression:
+		op := strings.ToUpper(n.BinaryQueryExpressionType)
+		if n.All {
+			op += " ALL"
+		}
+		return join(p.node(n.FirstQueryExpression), op, p.node(n.SecondQueryExpression), p.node(n.OrderByClause)), true
+	case *ast.QueryParenthesisExpression:
+		return "(" + p.node(n.QueryExpression) + ")", true
+	case *ast.SelectScalarExpression:
+		s := p.node(n.Expression)
+		if c := n.ColumnName; c != nil {
+			switch {
+			case c.Identifier != nil:
+				s = join(s, "AS", p.node(c.Identifier))
+			case c.ValueExpression != nil:
+				s = join(s, "AS", p.node(c.ValueExpression))
+			case c.Value != "":
+				s = join(s, "AS", QuoteIdentifier(c.Value))
+			}
+		}
+		return s, true
+	case *ast.SelectStarExpression:
+		if n.Qualifier != nil {
+			return p.node(n.Qualifier) + ".*", true
+		}
+		return "*", true
+	case *ast.SelectSetVariable:
+		return join(p.node(n.Variable), assignmentOperators[n.AssignmentKind], p.node(n.Expression)), true
+	case *ast.TopRowFilter:
+		// TOP 10 and TOP (10) are the same; the parentheses are always
+		// written.
+		e := n.Expression
+		for {
+			paren, ok := e.(*ast.ParenthesisExpression)
+			if !ok {
+				break
+			}
+			e = paren.Expression
+		}
+		s := "TOP (" + p.node(e) + ")"
+		if n.Percent {
+			s += " PERCENT"
+		}
+		if n.WithTies {
+			s += " WITH TIES"
+		}
+		return s, true
+	case *ast.FromClause:
+		refs := make([]ast.Node, len(n.TableReferences))
+		for i, r := range n.TableReferences {
+			refs[i] = r
+		}
+		return "FROM " + p.list(refs...), true
+	case *ast.WhereClause:
+		if n.Cursor != nil {
+			return join("WHERE CURRENT OF", p.cursor(n.Cursor)), true
+		}
+		return join("WHERE", p.node(n.SearchCondition)), true
+	case *ast.IdentifierOrValueExpression:
+		if n.Identifier != nil {
+			return p.node(n.Identifier), true
+		}
+		if n.ValueExpression != nil {
+			return p.node(n.ValueExpression), true
+		}
+		return n.Value, true
+	case *ast.GroupByClause:
+		s := "GROUP BY"
+		if n.All {
+			s += " ALL"
+		}
+		s = join(s, p.groupings(n.GroupingSpecifications))
+		switch n.GroupByOption {
+		case "Rollup":
+			s += " WITH ROLLUP"
+		case "Cube":
+			s += " WITH CUBE"
+		}
+		return s, true
+	case *ast.ExpressionGroupingSpecification:
+		return p.node(n.Expression), true
+	case *ast.RollupGroupingSpecification:
+		return "ROLLUP (" + p.groupings(n.Arguments) + ")", true
+	case *ast.CubeGroupingSpecification:
+		return "CUBE (" + p.groupings(n.Arguments) + ")", true
+	case *ast.GroupingSetsGroupingSpecification:
+		return "GROUPING SETS (" + p.groupings(n.Arguments) + ")", true
+	case *ast.CompositeGroupingSpecification:
+		return "(" + p.groupings(n.Items) + ")", true
+	case *ast.GrandTotalGroupingSpecification:
+		return "()", true
+	case *ast.HavingClause:
+		return join("HAVING", p.node(n.SearchCondition)), true
+	case *ast.OrderByClause:
+		elems := make([]ast.Node, len(n.OrderByElements))
+		for i, e := range n.OrderByElements {
+			elems[i] = e
+		}
+		return "ORDER BY " + p.list(elems...), true
+	case *ast.ExpressionWithSortOrder:
+		switch n.SortOrder {
+		case "Ascending":
+			return join(p.node(n.Expression), "ASC"), true
+		case "Descending":
+			return join(p.node(n.Expression), "DESC"), true
+		}
+		return p.node(n.Expression), true
+	case *ast.OffsetClause:
+		s := join("OFFSET", p.node(n.OffsetExpression), "ROWS")
+		if n.FetchExpression != nil {
+			s = join(s, "FETCH NEXT", p.node(n.FetchExpression), "ROWS ONLY")
+		}
+		return s, true
+	case *ast.WithCtesAndXmlNamespaces:
+		var parts []string
+		if n.XmlNamespaces != nil {
+			parts = append(parts, p.node(n.XmlNamespaces))
+		}
+		for _, cte := range n.CommonTableExpressions {
+			parts = append(parts, p.node(cte))
+		}
+		return "WITH " + strings.Join(parts, ", "), true
+	case *ast.CommonTableExpression:
+		name := p.node(n.ExpressionName)
+		if len(n.Columns) > 0 {
+			name += " (" + p.identifiers(n.Columns, ", ") + ")"
+		}
+		return join(name, "AS", "("+p.node(n.QueryExpression)+")"), true
+	case *ast.OptimizerHint:
+		return p.hintKind(n.HintKind), true
+	case *ast.LiteralOptimizerHint:
+		return join(p.hintKind(n.HintKind), p.node(n.Value)), true
+	case *ast.OptimizeForOptimizerHint:
+		if n.IsForUnknown && len(n.Pairs) == 0 {
+			return "OPTIMIZE FOR UNKNOWN", true
+		}
+		pairs := make([]ast.Node, len(n.Pairs))
+		for i, v := range n.Pairs {
+			pairs[i] = v
+		}
+		return "OPTIMIZE FOR (" + p.list(pairs...) + ")", true
+	case *ast.VariableValuePair:
+		if n.IsForUnknown {
+			return join(p.node(n.Variable), "UNKNOWN"), true
+		}
+		return join(p.node(n.Variable), "=", p.node(n.Value)), true
+	case *ast.UseHintList:
+		return "USE HINT (" + p.exprs(n.Hints) + ")", true
+	case *ast.TableHintsOptimizerHint:
+		parts := []string{p.node(n.ObjectName)}
+		for _, h := range n.TableHints {
+			parts = append(parts, p.tableHint(h))
+		}
+		return "TABLE HINT (" + strings.Join(parts, ", ") + ")", true
+	case *ast.OutputClause:
+		return "OUTPUT " + p.selectElements(n.SelectColumns), true
+	case *ast.OutputIntoClause:
+		s := join("OUTPUT", p.selectElements(n.SelectColumns), "INTO", p.node(n.IntoTable))
+		if len(n.IntoTableColumns) > 0 {
+			s = join(s, "("+p.columns(n.IntoTableColumns)+")")
+		}
+		return s, true
+	}
+	return "", false
+}
+
+func (p *printer) querySpecification(n *ast.QuerySpecification) string {
+	parts := []string{"SELECT"}
+	if n.UniqueRowFilter == "Distinct" {
+		parts = append(parts, "DISTINCT")
+	}
+	parts = append(parts, p.node(n.TopRowFilter), p.selectElements(n.SelectElements))
+	if n == p.intoSpec {
+		parts = append(parts, p.into)
+	}
+	parts = append(parts,
+		p.node(n.FromClause),
+		p.node(n.WhereClause),
+		p.node(n.GroupByClause),
+		p.node(n.HavingClause),
+		p.node(n.WindowClause),
+		p.node(n.OrderByClause),
+		p.node(n.OffsetClause),
+		p.node(n.ForClause),
+	)
+	return join(parts...)
+}
+
+func (p *printer) selectElements(elems []ast.SelectElement) string {
+	nodes := make([]ast.Node, len(elems))
+	for i, e := range elems {
+		nodes[i] = e
+	}
+	return p.list(nodes...)
+}
+
+func (p *printer) columns(cols []*ast.ColumnReferenceExpression) string {
+	nodes := make([]ast.Node, len(cols))
+	for i, c := range cols {
+		nodes[i] = c
+	}
+	return p.list(nodes...)
+}
+
+func (p *printer) groupings(specs []ast.GroupingSpecification) string {
+	nodes := make([]ast.Node, len(specs))
+	for i, s := range specs {
+		nodes[i] = s
+	}
+	return p.list(nodes...)
+}
+
+func (p *printer) hintKind(kind string) string {
+	if s, ok := optimizerHints[kind]; ok {
+		return s
+	}
+	return keyword(kind)
+}
+
+// optimizerHintsClause writes the OPTION clause of a statement.
+func (p *printer) optimizerHintsClause(hints []ast.OptimizerHintBase) string {
+	if len(hints) == 0 {
+		return ""
+	}
+	nodes := make([]ast.Node, len(hints))
+	for i, h := range hints {
+		nodes[i] = h
+	}
+	return "OPTION (" + p.list(nodes...) + ")"
+}
+
+func (p *printer) table(n ast.Node) (string, bool) {
+	switch n := n.(type) {
+	case *ast.NamedTableReference:
+		s := join(p.node(n.SchemaObject), p.node(n.TemporalClause))
+		if n.Alias != nil {
+			s = join(s, "AS", p.node(n.Alias))
+		}
+		s = join(s, p.node(n.TableSampleClause), p.tableHints(n.TableHints))
+		return s, true
+	case *ast.TemporalClause:
+		switch n.TemporalClauseType {
+		case "AsOf":
+			return join("FOR SYSTEM_TIME AS OF", p.node(n.StartTime)), true
+		case "FromTo":
+			return join("FOR SYSTEM_TIME FROM", p.node(n.StartTime), "TO", p.node(n.EndTime)), true
+		case "Between":
+			return join("FOR SYSTEM_TIME BETWEEN", p.node(n.StartTime), "AND", p.node(n.EndTime)), true
+		case "ContainedIn":
+			return "FOR SYSTEM_TIME CONTAINED IN (" + p.list(n.StartTime, n.EndTime) + ")", true
+		case "TemporalAll":
+			return "FOR SYSTEM_TIME ALL", true
+		}
+	case *ast.TableSampleClause:
+		s := "TABLESAMPLE"
+		if n.System {
+			s += " SYSTEM"
+		}
+		unit := ""
+		switch n.TableSampleClauseOption {
+		case "Percent":
+			unit = "PERCENT"
+		case "Rows":
+			unit = "ROWS"
+		}
+		s = join(s, "("+join(p.node(n.SampleNumber), unit)+")")
+		if n.RepeatSeed != nil {
+			s = join(s, "REPEATABLE ("+p.node(n.RepeatSeed)+")")
+		}
+		return s, true
+	case *ast.QualifiedJoin:
+		op := joinTypes[n.QualifiedJoinType]
+		if n.JoinHint != "" && n.JoinHint != "None" {
+			op = strings.Replace(op, "JOIN", strings.ToUpper(n.JoinHint)+" JOIN", 1)
+		}
+		return join(p.node(n.FirstTableReference), op, p.node(n.SecondTableReference), "ON", p.node(n.SearchCondition)), true
+	case *ast.UnqualifiedJoin:
+		return join(p.node(n.FirstTableReference), joinTypes[n.UnqualifiedJoinType], p.node(n.SecondTableReference)), true
+	case *ast.JoinParenthesisTableReference:
+		return "(" + p.node(n.Join) + ")", true
+	case *ast.QueryDerivedTable:
+		return p.alias("("+p.node(n.QueryExpression)+")", n.Alias, n.Columns), true
+	case *ast.InlineDerivedTable:
+		return p.alias("("+p.values(n.RowValues)+")", n.Alias, n.Columns), true
+	case *ast.VariableTableReference:
+		return p.alias(p.node(n.Variable), n.Alias, nil), true
+	case *ast.SchemaObjectFunctionTableReference:
+		return p.alias(p.node(n.SchemaObject)+"("+p.exprs(n.Parameters)+")", n.Alias, n.Columns), true
+	case *ast.BuiltInFunctionTableReference:
+		return p.alias(p.node(n.Name)+"("+p.exprs(n.Parameters)+")", n.Alias, n.Columns), true
+	case *ast.GlobalFunctionTableReference:
+		return p.alias(p.node(n.Name)+"("+p.exprs(n.Parameters)+")", n.Alias, n.Columns), true
+	}
+	return "", false
+}
+
+func (p *printer) alias(s string, alias *ast.Identifier, columns []*ast.Identifier) string {
+	if alias != nil {
+		s = join(s, "AS", p.node(alias))
+	}
+	if len(columns) > 0 {
+		s = join(s, "("+p.identifiers(columns, ", ")+")")
+	}
+	return s
+}
+
+func (p *printer) tableHints(hints []ast.TableHintType) string {
+	if len(hints) == 0 {
+		return ""
+	}
+	parts := make([]string, len(hints))
+	for i, h := range hints {
+		parts[i] = p.tableHint(h)
+	}
+	return "WITH (" + strings.Join(parts, ", ") + ")"
+}
+
+func (p *printer) tableHint(h ast.TableHintType) string {
+	switch n := h.(type) {
+	case *ast.TableHint:
+		return strings.ToUpper(n.HintKind)
+	case *ast.IndexTableHint:
+		values := make([]ast.Node, len(n.IndexValues))
+		for i, v := range n.IndexValues {
+			values[i] = v
+		}
+		return "INDEX (" + p.list(values...) + ")"
+	case *ast.LiteralTableHint:
+		return join(strings.ToUpper(n.HintKind), "=", p.node(n.Value))
+	case *ast.ForceSeekTableHint:
+		if n.IndexValue == nil {
+			return "FORCESEEK"
+		}
+		return "FORCESEEK (" + p.node(n.IndexValue) + " (" + p.columns(n.ColumnValues) + "))"
+	}
+	return p.other(h)
+}
+
+func (p *printer) cursor(c *ast.CursorId) string {
+	if c.IsGlobal {
+		return join("GLOBAL", p.node(c.Name))
+	}
+	return p.node(c.Name)
+}
+
+// values writes a VALUES list.
+func (p *printer) values(rows []*ast.RowValue) string {
+	nodes := make([]ast.Node, len(rows))
+	for i, r := range rows {
+		nodes[i] = r
+	}
+	return "VALUES " + p.list(nodes...)
+}
@@ -0,0 +1,336 @@
+package format
+
+import (
+	"strings"
+
+	"github.com/sqlc-dev/teesql/ast"
+	"github.com/sqlc-dev/teesql/parser"
+)
+
+// RedactOptions configure Redact.
+type RedactOptions struct {
+	// KeepTop keeps the row counts of TOP.
+	KeepTop bool
+	// KeepStyles keeps the style codes of CONVERT and TRY_CONVERT.
+	KeepStyles bool
+	// Identifier, if set, reports whether an identifier is to be redacted
+	// too, for example a column whose name is itself sensitive.
+	Identifier func(id *ast.Identifier) bool
+}
+
+// Redact returns the T-SQL text sql with its string, binary, numeric, money
+// and date literals replaced by ?. The text is redacted token by token, so
+// the rest of it is kept as written, whatever the statements are; comments
+// are removed, as they may hold data too. The lengths in data types and the
+// keywords NULL, DEFAULT and MAX are kept.
+//
+// SQL passed as a constant string to EXEC (...) or to sp_executesql is
+// redacted in turn, and the pieces of a constant concatenation are joined
+// into one string. The pieces of a concatenation with variables are
+// replaced by ? like any other literal, so no literal in dynamic SQL is
+// left in the text.
+func Redact(sql string, opts *RedactOptions) string {
+	if opts == nil {
+		opts = &RedactOptions{}
+	}
+	r := &redactor{opts: opts, sql: sql, dynamic: map[int]int{}}
+	l := parser.NewLexer(sql)
+	for {
+		tok := l.NextToken()
+		r.tokens = append(r.tokens, tok)
+		if tok.Type == parser.TokenEOF {
+			break
+		}
+	}
+	return r.redact()
+}
+
+type redactor struct {
+	opts   *RedactOptions
+	sql    string
+	tokens []parser.Token
+	// dynamic maps the first string of dynamic SQL text to the last.
+	dynamic map[int]int
+	// frames are the open parentheses.
+	frames []frame
+}
+
+// frame is an open parenthesis, with the kind of list it opens and the
+// number of commas read in it so far.
+type frame struct {
+	kind   int
+	commas int
+}
+
+const (
+	otherList = iota
+	topList
+	typeList
+	convertList
+)
+
+func (r *redactor) redact() string {
+	var b strings.Builder
+	end := 0
+	for i := 0; i < len(r.tokens); i++ {
+		tok := r.tokens[i]
+		b.WriteString(stripComments(r.sql[end:tok.Pos]))
+		end = tok.Pos + len(tok.Literal)
+		if last, ok := r.dynamic[i]; ok {
+			b.WriteString(r.dynamicText(i, last))
+			end = r.tokens[last].Pos + len(r.tokens[last].Literal)
+			i = last
+			continue
+		}
+		switch tok.Type {
+		case parser.TokenExec, parser.TokenExecute:
+			r.execute(i)
+		case parser.TokenLParen:
+			r.frames = append(r.frames, frame{kind: r.list(i)})
+		case parser.TokenRParen:
+			if len(r.frames) > 0 {
+				r.frames = r.frames[:len(r.frames)-1]
+			}
+		case parser.TokenComma:
+			if len(r.frames) > 0 {
+				r.frames[len(r.frames)-1].commas++
+			}
+		}
+		b.WriteString(r.token(i))
+	}
+	return b.String()
+}
+
+// token returns the redacted text of token i.
+func (r *redactor) token(i int) string {
+	tok := r.tokens[i]
+	switch tok.Type {
+	case parser.TokenString, parser.TokenNationalString, parser.TokenBinary, parser.TokenMoney:
+		return "?"
+	case parser.TokenNumber:
+		if r.keep(i) {
+			return tok.Literal
+		}
+		return "?"
+	}
+	if r.opts.Identifier != nil {
+		if id := identifier(tok); id != nil && r.opts.Identifier(id) {
+			return "?"
+		}
+	}
+	return tok.Literal
+}
+
+// keep reports whether number i is kept: a length in a data type, or a TOP
+// count or CONVERT style that the options keep.
+func (r *redactor) keep(i int) bool {
+	if len(r.frames) == 0 {
+		return r.opts.KeepTop && r.is(i-1, "TOP")
+	}
+	f := r.frames[len(r.frames)-1]
+	alone := r.tokens[i+1].Type == parser.TokenRParen
+	switch f.kind {
+	case typeList:
+		return true
+	case topList:
+		return r.opts.KeepTop && alone && r.tokens[i-1].Type == parser.TokenLParen
+	case convertList:
+		return r.opts.KeepStyles && alone && f.commas == 2 && r.tokens[i-1].Type == parser.TokenComma
+	}
+	return r.opts.KeepTop && r.is(i-1, "TOP")
+}
+
+// list returns the kind of list that the parenthesis at i opens.
+func (r *redactor) list(i int) int {
+	switch {
+	case r.is(i-1, "TOP"):
+		return topList
+	case r.is(i-1, "CONVERT"), r.is(i-1, "TRY_CONVERT"):
+		return convertList
+	case i > 0 && r.dataType(i-1):
+		return typeList
+	}
+	return otherList
+}
+
+// lengthTypes are the data types that take a length, precision or scale.
+var lengthTypes = map[string]bool{
+	"binary":         true,
+	"char":           true,
+	"datetime2":      true,
+	"datetimeoffset": true,
+	"dec":            true,
+	"decimal":        true,
+	"float":          true,
+	"nchar":          true,
+	"numeric":        true,
+	"nvarchar":       true,
+	"time":           true,
+	"varbinary":      true,
+	"varchar":        true,
+}
+
+// dataType reports whether token i names a data type that takes a length.
+// CHAR and NCHAR are functions too; they are types where a type is
+// declared: after AS or RETURNS, a variable or parameter, or a column name
+// in a column definition.
+func (r *redactor) dataType(i int) bool {
+	id := identifier(r.tokens[i])
+	if id == nil || !lengthTypes[strings.ToLower(id.Value)] {
+		return false
+	}
+	name := strings.ToLower(id.Value)
+	if name != "char" && name != "nchar" {
+		return true
+	}
+	if i == 0 {
+		return false
+	}
+	prev := r.tokens[i-1]
+	switch {
+	case r.is(i-1, "AS"), r.is(i-1, "RETURNS"), strings.HasPrefix(prev.Literal, "@"):
+		return true
+	case identifier(prev) == nil || i < 2:
+		return false
+	}
+	before := r.tokens[i-2]
+	return before.Type == parser.TokenLParen || before.Type == parser.TokenComma || r.is(i-2, "ADD") || r.is(i-2, "COLUMN")
+}
+
+// is reports whether token i is the keyword or unquoted identifier word.
+func (r *redactor) is(i int, word string) bool {
+	return i >= 0 && i < len(r.tokens) && strings.EqualFold(r.tokens[i].Literal, word)
+}
+
+// execute records the dynamic SQL text of the EXEC or EXECUTE at i: a
+// constant string or concatenation of strings in parentheses, or the
+// statement argument of sp_executesql.
+func (r *redactor) execute(i int) {
+	j := i + 1
+	if r.tokens[j].Type == parser.TokenLParen {
+		first := j + 1
+		for j = first; ; j += 2 {
+			if t := r.tokens[j].Type; t != parser.TokenString && t != parser.TokenNationalString {
+				return
+			}
+			if r.tokens[j+1].Type != parser.TokenPlus {
+				break
+			}
+		}
+		if t := r.tokens[j+1].Type; t == parser.TokenRParen || t == parser.TokenComma {
+			r.dynamic[first] = j
+		}
+		return
+	}
+	// EXEC @status = procedure ...
+	if strings.HasPrefix(r.tokens[j].Literal, "@") && r.tokens[j+1].Type == parser.TokenEquals {
+		j += 2
+	}
+	var name *ast.Identifier
+	for {
+		if name = identifier(r.tokens[j]); name == nil {
+			return
+		}
+		j++
+		if r.tokens[j].Type != parser.TokenDot {
+			break
+		}
+		j++
+	}
+	if !strings.EqualFold(name.Value, "sp_executesql") {
+		return
+	}
+	// The statement is the first argument, or the one named @stmt.
+	for arg := 0; ; arg++ {
+		param := ""
+		if strings.HasPrefix(r.tokens[j].Literal, "@") && r.tokens[j+1].Type == parser.TokenEquals {
+			param = r.tokens[j].Literal
+			j += 2
+		}
+		t := r.tokens[j].Type
+		if (t == parser.TokenString || t == parser.TokenNationalString) && (strings.EqualFold(param, "@stmt") || (param == "" && arg == 0)) {
+			r.dynamic[j] = j
+		}
+		if t == parser.TokenEOF {
+			return
+		}
+		j++
+		if r.is(j, "OUTPUT") || r.is(j, "OUT") {
+			j++
+		}
+		if r.tokens[j].Type != parser.TokenComma {
+			return
+		}
+		j++
+	}
+}
+
+// dynamicText returns the string literal holding the redacted SQL text of
+// the strings first to last, which are concatenated.
+func (r *redactor) dynamicText(first, last int) string {
+	var sql strings.Builder
+	national := false
+	for i := first; i <= last; i += 2 {
+		lit := r.tokens[i].Literal
+		if r.tokens[i].Type == parser.TokenNationalString {
+			national = true
+			lit = lit[1:]
+		}
+		lit = strings.TrimPrefix(lit, "'")
+		lit = strings.TrimSuffix(lit, "'")
+		sql.WriteString(strings.ReplaceAll(lit, "''", "'"))
+	}
+	return QuoteString(Redact(sql.String(), r.opts), national)
+}
+
+// identifier returns the identifier that tok is, or nil if it is not a word
+// or a quoted identifier.
+func identifier(tok parser.Token) *ast.Identifier {
+	s := tok.Literal
+	switch {
+	case s == "" || tok.Type == parser.TokenNationalString || tok.Type == parser.TokenMoney:
+		return nil
+	case s[0] == '[':
+		s = strings.TrimSuffix(s[1:], "]")
+		return &ast.Identifier{Value: strings.ReplaceAll(s, "]]", "]"), QuoteType: "SquareBracket"}
+	case s[0] == '"':
+		s = strings.TrimSuffix(s[1:], `"`)
+		return &ast.Identifier{Value: strings.ReplaceAll(s, `""`, `"`), QuoteType: "DoubleQuote"}
+	case s[0] == '@' || !(isWordStart(s[0])):
+		return nil
+	}
+	return &ast.Identifier{Value: s}
+}
+
+func isWordStart(c byte) bool {
+	return c == '_' || c == '#' || c >= 0x80 || (c|0x20 >= 'a' && c|0x20 <= 'z')
+}
+
+// stripComments returns the text between two tokens with its comments
+// removed: a line comment is dropped and a block comment becomes a space.
+func stripComments(s string) string {
+	if !strings.Contains(s, "--") && !strings.Contains(s, "/*") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case strings.HasPrefix(s[i:], "--"):
+			n := strings.IndexByte(s[i:], '\n')
+			if n < 0 {
+				return b.String()
+			}
+			i += n - 1
+		case strings.HasPrefix(s[i:], "/*"):
+			n := strings.Index(s[i+2:], "*/")
+			b.WriteByte(' ')
+			if n < 0 {
+				return b.String()
+			}
+			i += n + 3
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
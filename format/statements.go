@@ -0,0 +1,278 @@
+package format
+
+import (
+	"strings"
+
+	"github.com/sqlc-dev/teesql/ast"
+)
+
+func (p *printer) statement(n ast.Node) (string, bool) {
+	switch n := n.(type) {
+	case *ast.Script:
+		var batches []string
+		for _, b := range n.Batches {
+			batches = append(batches, p.node(b))
+		}
+		return strings.Join(batches, " GO "), true
+	case *ast.Batch:
+		return p.statements(n.Statements), true
+	case *ast.StatementList:
+		return p.statements(n.Statements), true
+	case *ast.SelectStatement:
+		into := ""
+		if n.Into != nil {
+			into = join("INTO", p.node(n.Into))
+			if n.On != nil {
+				into = join(into, "ON", p.node(n.On))
+			}
+		}
+		saved, savedSpec := p.into, p.intoSpec
+		p.into, p.intoSpec = into, firstSpecification(n.QueryExpression)
+		defer func() { p.into, p.intoSpec = saved, savedSpec }()
+		return join(p.node(n.WithCtesAndXmlNamespaces), p.node(n.QueryExpression), p.optimizerHintsClause(n.OptimizerHints)), true
+	case *ast.InsertStatement:
+		return join(p.node(n.WithCtesAndXmlNamespaces), p.node(n.InsertSpecification), p.optimizerHintsClause(n.OptimizerHints)), true
+	case *ast.InsertSpecification:
+		op := "INTO"
+		if n.InsertOption == "Over" {
+			op = "OVER"
+		}
+		target := p.node(n.Target)
+		if len(n.Columns) > 0 {
+			target += " (" + p.columns(n.Columns) + ")"
+		}
+		return join("INSERT", p.node(n.TopRowFilter), op, target, p.node(n.OutputClause), p.node(n.OutputIntoClause), p.node(asNode(n.InsertSource))), true
+	case *ast.ValuesInsertSource:
+		if n.IsDefaultValues {
+			return "DEFAULT VALUES", true
+		}
+		return p.values(n.RowValues), true
+	case *ast.RowValue:
+		return "(" + p.exprs(n.ColumnValues) + ")", true
+	case *ast.SelectInsertSource:
+		return p.node(n.Select), true
+	case *ast.ExecuteInsertSource:
+		return p.node(n.Execute), true
+	case *ast.UpdateStatement:
+		return join(p.node(n.WithCtesAndXmlNamespaces), p.node(n.UpdateSpecification), p.optimizerHintsClause(n.OptimizerHints)), true
+	case *ast.UpdateSpecification:
+		return join("UPDATE", p.node(n.TopRowFilter), p.node(n.Target), "SET", p.setClauses(n.SetClauses),
+			p.node(n.OutputClause), p.node(n.OutputIntoClause), p.node(n.FromClause), p.node(n.WhereClause)), true
+	case *ast.AssignmentSetClause:
+		target := p.node(n.Column)
+		if n.Variable != nil {
+			target = p.node(n.Variable)
+			if n.Column != nil {
+				target = join(target, "=", p.node(n.Column))
+			}
+		}
+		return join(target, assignmentOperators[n.AssignmentKind], p.node(n.NewValue)), true
+	case *ast.FunctionCallSetClause:
+		return p.node(n.MutatorFunction), true
+	case *ast.DeleteStatement:
+		return join(p.node(n.WithCtesAndXmlNamespaces), p.node(n.DeleteSpecification), p.optimizerHintsClause(n.OptimizerHints)), true
+	case *ast.DeleteSpecification:
+		return join("DELETE", p.node(n.TopRowFilter), "FROM", p.node(n.Target),
+			p.node(n.OutputClause), p.node(n.OutputIntoClause), p.node(n.FromClause), p.node(n.WhereClause)), true
+	case *ast.MergeStatement:
+		return join(p.node(n.WithCtesAndXmlNamespaces), p.node(n.MergeSpecification), p.optimizerHintsClause(n.OptimizerHints)), true
+	case *ast.MergeSpecification:
+		source := p.node(n.TableReference)
+		if n.TableAlias != nil {
+			source = join(source, "AS", p.node(n.TableAlias))
+		}
+		parts := []string{"MERGE", p.node(n.TopRowFilter), "INTO", p.node(n.Target), "USING", source, "ON", p.node(n.SearchCondition)}
+		for _, a := range n.ActionClauses {
+			parts = append(parts, p.node(a))
+		}
+		return join(append(parts, p.node(n.OutputClause))...), true
+	case *ast.MergeActionClause:
+		s := mergeConditions[n.Condition]
+		if n.SearchCondition != nil {
+			s = join(s, "AND", p.node(n.SearchCondition))
+		}
+		return join(s, "THEN", p.node(n.Action)), true
+	case *ast.UpdateMergeAction:
+		return join("UPDATE SET", p.setClauses(n.SetClauses)), true
+	case *ast.DeleteMergeAction:
+		return "DELETE", true
+	case *ast.InsertMergeAction:
+		s := "INSERT"
+		if len(n.Columns) > 0 {
+			s += " (" + p.columns(n.Columns) + ")"
+		}
+		return join(s, p.node(asNode(n.Source))), true
+	case *ast.ExecuteStatement:
+		s := p.node(n.ExecuteSpecification)
+		var options []string
+		for _, o := range n.Options {
+			if o, ok := o.(*ast.ExecuteOption); ok {
+				options = append(options, keyword(o.OptionKind))
+				continue
+			}
+			options = append(options, p.other(o))
+		}
+		if len(options) > 0 {
+			s = join(s, "WITH", strings.Join(options, ", "))
+		}
+		return s, true
+	case *ast.ExecuteSpecification:
+		s := "EXEC"
+		if n.Variable != nil {
+			s = join(s, p.node(n.Variable), "=")
+		}
+		s = join(s, p.node(asNode(n.ExecutableEntity)))
+		if n.ExecuteContext != nil {
+			s = join(s, "AS", p.node(n.ExecuteContext))
+		}
+		if n.LinkedServer != nil {
+			s = join(s, "AT", p.node(n.LinkedServer))
+		}
+		return s, true
+	case *ast.ExecuteContext:
+		return join(strings.ToUpper(n.Kind), "=", p.node(n.Principal)), true
+	case *ast.ExecutableProcedureReference:
+		return join(p.node(n.ProcedureReference), p.executeParameters(n.Parameters)), true
+	case *ast.ProcedureReferenceName:
+		if n.ProcedureVariable != nil {
+			return p.node(n.ProcedureVariable), true
+		}
+		return p.node(n.ProcedureReference), true
+	case *ast.ProcedureReference:
+		if n.Number != nil {
+			return p.node(n.Name) + ";" + p.node(n.Number), true
+		}
+		return p.node(n.Name), true
+	case *ast.ExecutableStringList:
+		parts := make([]string, len(n.Strings))
+		for i, s := range n.Strings {
+			parts[i] = p.node(s)
+		}
+		s := "(" + strings.Join(parts, " + ")
+		if len(n.Parameters) > 0 {
+			s += ", " + p.executeParameters(n.Parameters)
+		}
+		return s + ")", true
+	case *ast.ExecuteParameter:
+		s := p.node(n.ParameterValue)
+		if n.Variable != nil {
+			s = join(p.node(n.Variable), "=", s)
+		}
+		if n.IsOutput {
+			s = join(s, "OUTPUT")
+		}
+		return s, true
+	case *ast.DeclareVariableStatement:
+		decls := make([]ast.Node, len(n.Declarations))
+		for i, d := range n.Declarations {
+			decls[i] = d
+		}
+		return "DECLARE " + p.list(decls...), true
+	case *ast.DeclareVariableElement:
+		s := join(p.node(n.VariableName), p.node(n.DataType))
+		if n.Value != nil {
+			s = join(s, "=", p.node(n.Value))
+		}
+		return s, true
+	case *ast.SetVariableStatement:
+		if n.CursorDefinition != nil || n.Variable == nil {
+			return "", false
+		}
+		target := p.node(n.Variable)
+		if n.Identifier != nil {
+			target += "." + p.node(n.Identifier)
+			if n.FunctionCallExists {
+				return "SET " + target + "(" + p.exprs(n.Parameters) + ")", true
+			}
+		}
+		return join("SET", target, assignmentOperators[n.AssignmentKind], p.node(n.Expression)), true
+	case *ast.IfStatement:
+		s := join("IF", p.node(n.Predicate), p.node(n.ThenStatement))
+		if n.ElseStatement != nil {
+			s = join(s, "ELSE", p.node(n.ElseStatement))
+		}
+		return s, true
+	case *ast.WhileStatement:
+		return join("WHILE", p.node(n.Predicate), p.node(n.Statement)), true
+	case *ast.BeginEndBlockStatement:
+		return join("BEGIN", p.node(n.StatementList), "END"), true
+	case *ast.TryCatchStatement:
+		return join("BEGIN TRY", p.node(n.TryStatements), "END TRY BEGIN CATCH", p.node(n.CatchStatements), "END CATCH"), true
+	case *ast.ReturnStatement:
+		return join("RETURN", p.node(n.Expression)), true
+	case *ast.BreakStatement:
+		return "BREAK", true
+	case *ast.ContinueStatement:
+		return "CONTINUE", true
+	case *ast.PrintStatement:
+		return join("PRINT", p.node(n.Expression)), true
+	case *ast.ThrowStatement:
+		return join("THROW", p.list(n.ErrorNumber, n.Message, n.State)), true
+	case *ast.RaiseErrorStatement:
+		args := append([]ast.ScalarExpression{n.FirstParameter, n.SecondParameter, n.ThirdParameter}, n.OptionalParameters...)
+		s := "RAISERROR(" + p.exprs(args) + ")"
+		if n.RaiseErrorOptions != "" && n.RaiseErrorOptions != "None" {
+			s = join(s, "WITH", strings.ToUpper(n.RaiseErrorOptions))
+		}
+		return s, true
+	case *ast.BeginTransactionStatement:
+		s := "BEGIN TRANSACTION"
+		if n.Distributed {
+			s = "BEGIN DISTRIBUTED TRANSACTION"
+		}
+		s = join(s, p.node(n.Name))
+		if n.MarkDefined {
+			s = join(s, "WITH MARK", p.node(n.MarkDescription))
+		}
+		return s, true
+	case *ast.CommitTransactionStatement:
+		return join("COMMIT TRANSACTION", p.node(n.Name)), true
+	case *ast.RollbackTransactionStatement:
+		return join("ROLLBACK TRANSACTION", p.node(n.Name)), true
+	case *ast.SaveTransactionStatement:
+		return join("SAVE TRANSACTION", p.node(n.Name)), true
+	}
+	return "", false
+}
+
+// statements writes a list of statements, each ended with a semicolon.
+func (p *printer) statements(stmts []ast.Statement) string {
+	parts := make([]string, len(stmts))
+	for i, s := range stmts {
+		parts[i] = p.node(s) + ";"
+	}
+	return join(parts...)
+}
+
+func (p *printer) setClauses(clauses []ast.SetClause) string {
+	nodes := make([]ast.Node, len(clauses))
+	for i, c := range clauses {
+		nodes[i] = asNode(c)
+	}
+	return p.list(nodes...)
+}
+
+func (p *printer) executeParameters(params []*ast.ExecuteParameter) string {
+	nodes := make([]ast.Node, len(params))
+	for i, e := range params {
+		nodes[i] = e
+	}
+	return p.list(nodes...)
+}
+
+// firstSpecification returns the query specification that the INTO clause
+// of a SELECT statement belongs to: the first of a set operation.
+func firstSpecification(q ast.QueryExpression) *ast.QuerySpecification {
+	for {
+		switch e := q.(type) {
+		case *ast.QuerySpecification:
+			return e
+		case *ast.BinaryQueryExpression:
+			q = e.FirstQueryExpression
+		case *ast.QueryParenthesisExpression:
+			q = e.QueryExpression
+		default:
+			return nil
+		}
+	}
+}
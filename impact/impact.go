@@ -0,0 +1,395 @@
+// Package impact finds the views, procedures, functions and triggers that a
+// change to an object or to one of its columns would affect: those that
+// read, write, execute or call it, directly or through other objects.
+//
+// Objects are related through the dependency graph of package deps. A
+// change to a column affects the modules whose column references bind to
+// it, the modules that select it with SELECT * or insert into its table
+// without a column list, and, through the view columns computed from it,
+// the modules that use those in turn. A module affected by a change is a
+// changed object to the modules that depend on it. References in dynamic
+// SQL are found when the scripts are parsed with parser.Options.DynamicSQL
+// and the text is constant.
+package impact
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sqlc-dev/teesql/ast"
+	"github.com/sqlc-dev/teesql/binder"
+	"github.com/sqlc-dev/teesql/catalog"
+	"github.com/sqlc-dev/teesql/deps"
+)
+
+// Impact is an object that a change to the target affects.
+type Impact struct {
+	// Kind is the object kind, one of the deps object kinds.
+	Kind string             `json:"Kind"`
+	Name catalog.ObjectName `json:"Name"`
+	// References are the ways the object uses Via, the deps edge kinds such
+	// as "reads" or "executes".
+	References []string `json:"References"`
+	// Via is the object or column the object uses: the target, or an
+	// object or view column the change affects.
+	Via string `json:"Via"`
+	// Depth is 1 for an object that uses the target itself, and one more
+	// than the depth of Via otherwise.
+	Depth int `json:"Depth"`
+	// Dynamic is set if the object uses Via in dynamic SQL alone.
+	Dynamic bool `json:"Dynamic,omitempty"`
+}
+
+// String describes the impact, e.g. "procedure dbo.P reads dbo.T.c".
+func (i *Impact) String() string {
+	s := i.Kind + " " + i.Name.String() + " " + strings.Join(i.References, " and ") + " " + i.Via
+	if i.Dynamic {
+		s += " in dynamic SQL"
+	}
+	return s
+}
+
+// modules are the object kinds that impacts are reported for. Synonyms are
+// reported too, as the objects reached through them are.
+var modules = map[string]bool{
+	deps.View:      true,
+	deps.Procedure: true,
+	deps.Function:  true,
+	deps.Trigger:   true,
+	deps.Synonym:   true,
+}
+
+// Analyze returns the objects that scripts define which a change to the
+// target would affect, nearest first. The target names an object, as
+// "schema.name" or "name" for the default schema, or a column of a table
+// or view, as "schema.table.column". Names may be quoted with brackets.
+func Analyze(target string, scripts ...*ast.Script) ([]*Impact, error) {
+	parts := splitName(target)
+	var name catalog.ObjectName
+	column := ""
+	switch len(parts) {
+	case 1:
+		name = catalog.ObjectName{Name: parts[0]}
+	case 2:
+		name = catalog.ObjectName{Schema: parts[0], Name: parts[1]}
+	case 3:
+		name = catalog.ObjectName{Schema: parts[0], Name: parts[1]}
+		column = parts[2]
+	default:
+		return nil, fmt.Errorf("invalid object name %q", target)
+	}
+	a := &analysis{
+		graph:   deps.Build(scripts...),
+		seen:    map[*deps.Node]bool{},
+		results: map[*deps.Node]*binder.Result{},
+	}
+	node := a.graph.Node(name)
+	if node == nil {
+		return nil, fmt.Errorf("object %s not found", name)
+	}
+	a.seen[node] = true
+	if column == "" {
+		a.objects([]*deps.Node{node}, []int{0})
+		return a.impacts, nil
+	}
+	// Objects that do not apply, such as a view of an undefined table,
+	// are left out of the catalog and bind no columns.
+	a.catalog, _ = catalog.Build(scripts...)
+	if err := a.columns(node, column); err != nil {
+		return nil, err
+	}
+	return a.impacts, nil
+}
+
+type analysis struct {
+	graph   *deps.Graph
+	catalog *catalog.Catalog
+	impacts []*Impact
+	// seen holds the target and the objects that have been reported.
+	seen map[*deps.Node]bool
+	// results holds the column bindings of the modules.
+	results map[*deps.Node]*binder.Result
+}
+
+func (a *analysis) add(i *Impact) {
+	a.impacts = append(a.impacts, i)
+}
+
+// objects reports the objects that depend on the changed objects start,
+// at the given depths, and on the objects that depend on those in turn.
+func (a *analysis) objects(start []*deps.Node, depths []int) {
+	type item struct {
+		node  *deps.Node
+		depth int
+	}
+	var queue []item
+	for i, n := range start {
+		queue = append(queue, item{n, depths[i]})
+	}
+	for len(queue) > 0 {
+		it := queue[0]
+		queue = queue[1:]
+		for _, e := range it.node.Dependents {
+			from := e.From
+			if a.seen[from] || !modules[from.Kind] {
+				continue
+			}
+			a.seen[from] = true
+			impact := &Impact{Kind: from.Kind, Name: from.Name, Via: it.node.String(), Depth: it.depth + 1, Dynamic: true}
+			for _, d := range from.Deps {
+				if d.To == it.node {
+					impact.References = append(impact.References, d.Kind)
+					impact.Dynamic = impact.Dynamic && d.Dynamic
+				}
+			}
+			a.add(impact)
+			queue = append(queue, item{from, it.depth + 1})
+		}
+	}
+}
+
+// columns reports the objects that a change to a column of the table or
+// view node would affect.
+func (a *analysis) columns(node *deps.Node, column string) error {
+	var cols []*catalog.Column
+	switch {
+	case a.catalog.Table(node.Name) != nil:
+		cols = a.catalog.Table(node.Name).Columns
+	case a.catalog.View(node.Name) != nil:
+		cols = a.catalog.View(node.Name).Columns
+	default:
+		return fmt.Errorf("%s %s has no columns", node.Kind, node.Name)
+	}
+	var col *catalog.Column
+	for _, c := range cols {
+		if strings.EqualFold(c.Name, column) {
+			col = c
+		}
+	}
+	if col == nil {
+		return fmt.Errorf("column %s.%s not found", node.Name, column)
+	}
+	// affected holds the columns the change affects, named after their
+	// table or view, and owner the table or view of each.
+	affected := map[*catalog.Column]string{col: node.Name.String() + "." + col.Name}
+	owner := map[*catalog.Column]*deps.Node{col: node}
+	frontier := map[*catalog.Column]bool{col: true}
+	var changed []*deps.Node
+	var depths []int
+	for depth := 1; len(frontier) > 0; depth++ {
+		next := map[*catalog.Column]bool{}
+		for _, m := range a.graph.Nodes {
+			if !modules[m.Kind] {
+				continue
+			}
+			r := a.bind(m)
+			use := a.uses(m, r, frontier, owner)
+			if use == nil {
+				continue
+			}
+			if !a.seen[m] {
+				a.seen[m] = true
+				a.add(&Impact{
+					Kind:       m.Kind,
+					Name:       m.Name,
+					References: use.refs,
+					Via:        affected[use.col],
+					Depth:      depth,
+					Dynamic:    use.dynamic,
+				})
+				if m.Kind != deps.View {
+					changed = append(changed, m)
+					depths = append(depths, depth)
+				}
+			}
+			if m.Kind != deps.View {
+				continue
+			}
+			for _, out := range a.viewColumns(m, r, affected) {
+				if _, ok := affected[out]; !ok {
+					affected[out] = m.Name.String() + "." + out.Name
+					owner[out] = m
+					next[out] = true
+				}
+			}
+		}
+		frontier = next
+	}
+	// Procedures, functions and triggers that change break the objects
+	// that execute or call them, whatever columns those use.
+	a.objects(changed, depths)
+	return nil
+}
+
+func (a *analysis) bind(m *deps.Node) *binder.Result {
+	r := a.results[m]
+	if r == nil {
+		r = binder.BindStatement(m.Definition, a.catalog)
+		a.results[m] = r
+	}
+	return r
+}
+
+// use is how a module uses the changed columns.
+type use struct {
+	// col is the first changed column used, and refs the ways it is used.
+	col     *catalog.Column
+	refs    []string
+	dynamic bool
+}
+
+// uses returns how module m, bound as r, uses the columns of frontier, or
+// nil if it does not.
+func (a *analysis) uses(m *deps.Node, r *binder.Result, frontier map[*catalog.Column]bool, owner map[*catalog.Column]*deps.Node) *use {
+	written := map[*ast.ColumnReferenceExpression]bool{}
+	dynamic := map[ast.Node]bool{}
+	var u *use
+	found := func(col *catalog.Column, ref string, dyn bool) {
+		if u == nil {
+			u = &use{col: col, dynamic: true}
+		}
+		if col != u.col {
+			return
+		}
+		u.dynamic = u.dynamic && dyn
+		for _, r := range u.refs {
+			if r == ref {
+				return
+			}
+		}
+		u.refs = append(u.refs, ref)
+	}
+	ast.Inspect(m.Definition, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.DynamicSQL:
+			ast.Inspect(n, func(n ast.Node) bool {
+				if n != nil {
+					dynamic[n] = true
+				}
+				return true
+			})
+		case *ast.InsertSpecification:
+			for _, c := range n.Columns {
+				written[c] = true
+			}
+			if len(n.Columns) == 0 {
+				// Inserting without a column list depends on every column.
+				for col := range frontier {
+					if a.target(n.Target) == owner[col] {
+						found(col, deps.Writes, dynamic[n])
+					}
+				}
+			}
+		case *ast.AssignmentSetClause:
+			written[n.Column] = true
+		case *ast.InsertMergeAction:
+			for _, c := range n.Columns {
+				written[c] = true
+			}
+		}
+		return true
+	})
+	for _, b := range r.Bindings {
+		if b.Column == nil || !frontier[b.Column] {
+			continue
+		}
+		if written[b.Ref] {
+			found(b.Column, deps.Writes, dynamic[b.Ref])
+		} else {
+			found(b.Column, deps.Reads, dynamic[b.Ref])
+		}
+	}
+	ast.Inspect(m.Definition, func(n ast.Node) bool {
+		if q, ok := n.(ast.QueryExpression); ok {
+			for _, col := range r.Columns(q) {
+				if src := r.Origin(col); src != nil && frontier[src.Column(col.Name)] {
+					// SELECT * returns the column.
+					found(src.Column(col.Name), deps.Reads, dynamic[q])
+				}
+			}
+		}
+		return true
+	})
+	return u
+}
+
+// target returns the table or view that the target of an INSERT names, or
+// nil.
+func (a *analysis) target(t ast.TableReference) *deps.Node {
+	ref, ok := t.(*ast.NamedTableReference)
+	if !ok || ref.SchemaObject == nil {
+		return nil
+	}
+	name := catalog.NameOf(ref.SchemaObject)
+	if syn := a.catalog.Synonym(name); syn != nil {
+		name = catalog.NameOf(syn.Target)
+	}
+	return a.graph.Node(name)
+}
+
+// viewColumns returns the columns of view m, bound as r, that are computed
+// from affected columns.
+func (a *analysis) viewColumns(m *deps.Node, r *binder.Result, affected map[*catalog.Column]string) []*catalog.Column {
+	v := a.catalog.View(m.Name)
+	if v == nil || v.Definition == nil {
+		return nil
+	}
+	var cols []*catalog.Column
+	for i, out := range r.Columns(v.Definition.QueryExpression) {
+		if i >= len(v.Columns) {
+			break
+		}
+		uses := false
+		if src := r.Origin(out); src != nil {
+			_, uses = affected[src.Column(out.Name)]
+		}
+		if e := r.Expression(out); e != nil {
+			ast.Inspect(e, func(n ast.Node) bool {
+				if ref, ok := n.(*ast.ColumnReferenceExpression); ok {
+					if b := r.Lookup(ref); b != nil && b.Column != nil {
+						if _, ok := affected[b.Column]; ok {
+							uses = true
+						}
+					}
+				}
+				return !uses
+			})
+		}
+		if uses {
+			cols = append(cols, v.Columns[i])
+		}
+	}
+	return cols
+}
+
+// splitName returns the parts of a multi-part name, with bracket and
+// double quote delimiters removed.
+func splitName(s string) []string {
+	var parts []string
+	var part strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '[', '"':
+			end := byte(']')
+			if c == '"' {
+				end = '"'
+			}
+			for i++; i < len(s); i++ {
+				if s[i] == end {
+					if i+1 < len(s) && s[i+1] == end {
+						i++
+					} else {
+						break
+					}
+				}
+				part.WriteByte(s[i])
+			}
+		case '.':
+			parts = append(parts, part.String())
+			part.Reset()
+		default:
+			part.WriteByte(c)
+		}
+	}
+	return append(parts, part.String())
+}
@@ -0,0 +1,113 @@
+package impact
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sqlc-dev/teesql/parser"
+)
+
+const project = `CREATE TABLE dbo.Orders (Id int NOT NULL PRIMARY KEY, CustomerId int NOT NULL, Total money NOT NULL, Note nvarchar(100) NULL);
+CREATE TABLE dbo.Audit (OrderId int NOT NULL, Total money NULL);
+GO
+CREATE SYNONYM dbo.Sales FOR dbo.Orders;
+GO
+CREATE VIEW dbo.OrderAmounts AS SELECT o.Id, o.Total * 1.2 AS Gross, o.Note FROM dbo.Orders o;
+GO
+CREATE VIEW dbo.AllOrders AS SELECT * FROM dbo.Orders;
+GO
+CREATE PROCEDURE dbo.Report AS SELECT Id, Gross FROM dbo.OrderAmounts;
+GO
+CREATE PROCEDURE dbo.Notes AS SELECT Id, Note FROM dbo.OrderAmounts;
+GO
+CREATE PROCEDURE dbo.Run AS EXEC dbo.Report;
+GO
+CREATE PROCEDURE dbo.Dyn AS EXEC ('SELECT Total FROM dbo.Orders');
+GO
+CREATE PROCEDURE dbo.Copy AS INSERT INTO dbo.Orders SELECT * FROM dbo.Orders;
+GO
+CREATE PROCEDURE dbo.Fix @id int AS UPDATE s SET Total = 0 FROM dbo.Sales s WHERE s.Id = @id;
+GO
+CREATE FUNCTION dbo.CustomerOf (@id int) RETURNS int AS
+BEGIN
+	RETURN (SELECT CustomerId FROM dbo.Orders WHERE Id = @id);
+END
+GO
+CREATE TRIGGER dbo.trOrders ON dbo.Orders AFTER UPDATE AS
+	INSERT INTO dbo.Audit (OrderId, Total) SELECT Id, Total FROM inserted;`
+
+func TestAnalyze(t *testing.T) {
+	script, err := parser.ParseWithOptions(context.Background(), strings.NewReader(project), parser.Options{DynamicSQL: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		target string
+		want   []string
+		err    string
+	}{
+		{
+			target: "dbo.Orders",
+			want: []string{
+				"synonym dbo.Sales synonym for dbo.Orders",
+				"view dbo.OrderAmounts reads dbo.Orders",
+				"view dbo.AllOrders reads dbo.Orders",
+				"procedure dbo.Dyn reads dbo.Orders in dynamic SQL",
+				"procedure dbo.Copy reads and writes dbo.Orders",
+				"function dbo.CustomerOf reads dbo.Orders",
+				"trigger dbo.trOrders trigger on dbo.Orders",
+				"procedure dbo.Fix writes dbo.Sales",
+				"procedure dbo.Report reads dbo.OrderAmounts",
+				"procedure dbo.Notes reads dbo.OrderAmounts",
+				"procedure dbo.Run executes dbo.Report",
+			},
+		},
+		{
+			target: "[dbo].[Orders].[Total]",
+			want: []string{
+				"view dbo.OrderAmounts reads dbo.Orders.Total",
+				"view dbo.AllOrders reads dbo.Orders.Total",
+				"procedure dbo.Dyn reads dbo.Orders.Total in dynamic SQL",
+				"procedure dbo.Copy writes and reads dbo.Orders.Total",
+				"procedure dbo.Fix writes dbo.Orders.Total",
+				"trigger dbo.trOrders reads dbo.Orders.Total",
+				"procedure dbo.Report reads dbo.OrderAmounts.Gross",
+				"procedure dbo.Run executes dbo.Report",
+			},
+		},
+		{
+			target: "Report",
+			want:   []string{"procedure dbo.Run executes dbo.Report"},
+		},
+		{
+			target: "dbo.Orders.Missing",
+			err:    "column dbo.Orders.Missing not found",
+		},
+		{
+			target: "dbo.Missing",
+			err:    "object dbo.Missing not found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			impacts, err := Analyze(tt.target, script)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("err = %v, want %s", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, i := range impacts {
+				got = append(got, i.String())
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
@@ -0,0 +1,468 @@
+// Package injection finds SQL injection risks in stored procedures: dynamic
+// SQL, run with EXEC (...) or sp_executesql, whose text is built from the
+// procedure's parameters or from table data without being sanitized with
+// QUOTENAME or REPLACE.
+//
+// Values are followed through DECLARE, SET, SELECT @var = ... and FETCH ...
+// INTO assignments along the procedure's control flow. Both branches of an
+// IF are taken, loop bodies are repeated until no more variables are
+// affected, and a CATCH block is entered with the variables as they may be
+// anywhere in its TRY block. Parameters passed to sp_executesql through its
+// @params declarations are not part of the text and are safe.
+package injection
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/sqlc-dev/teesql/ast"
+	"github.com/sqlc-dev/teesql/catalog"
+	"github.com/sqlc-dev/teesql/parser"
+)
+
+// Source kinds.
+const (
+	// SourceParameter is a parameter of the procedure.
+	SourceParameter = "parameter"
+	// SourceTableData is a value read from a table or view.
+	SourceTableData = "table data"
+)
+
+// Step is a value on the path from a source to the executed text.
+type Step struct {
+	// Name is a variable name including its @, or the column a value was
+	// read from.
+	Name string `json:"Name"`
+	// Pos is the position of the parameter declaration, the column
+	// reference or the variable assigned.
+	Pos ast.Position `json:"Pos"`
+}
+
+// Finding is a dynamic SQL call that runs text derived from a source.
+type Finding struct {
+	// Procedure is the name of the procedure, as written.
+	Procedure catalog.ObjectName `json:"Procedure"`
+	// Call is EXEC or sp_executesql.
+	Call string `json:"Call"`
+	// Pos is the position of the executed text.
+	Pos ast.Position `json:"Pos"`
+	// Source is the kind of the first step of Chain.
+	Source string `json:"Source"`
+	// Chain lists the values the text derives from, starting at the source
+	// and ending at the value that is executed.
+	Chain []Step `json:"Chain"`
+}
+
+// String describes the finding, e.g.
+// "9:14: dbo.Search: EXEC runs text built from parameter @name: @name (2:5) -> @sql (5:6)".
+func (f *Finding) String() string {
+	steps := make([]string, len(f.Chain))
+	for i, s := range f.Chain {
+		steps[i] = s.Name + " (" + s.Pos.String() + ")"
+	}
+	return f.Pos.String() + ": " + f.Procedure.String() + ": " + f.Call + " runs text built from " +
+		f.Source + " " + f.Chain[0].Name + ": " + strings.Join(steps, " -> ")
+}
+
+// Check returns the injection risks in the procedures that script creates
+// or alters, in source order.
+func Check(script *ast.Script) []*Finding {
+	var findings []*Finding
+	for _, batch := range script.Batches {
+		for _, stmt := range batch.Statements {
+			findings = append(findings, checkProcedure(stmt)...)
+		}
+	}
+	return findings
+}
+
+func checkProcedure(stmt ast.Statement) []*Finding {
+	var (
+		ref    *ast.ProcedureReference
+		params []*ast.ProcedureParameter
+		body   *ast.StatementList
+	)
+	switch s := stmt.(type) {
+	case *ast.CreateProcedureStatement:
+		ref, params, body = s.ProcedureReference, s.Parameters, s.StatementList
+	case *ast.CreateOrAlterProcedureStatement:
+		ref, params, body = s.ProcedureReference, s.Parameters, s.StatementList
+	case *ast.AlterProcedureStatement:
+		ref, params, body = s.ProcedureReference, s.Parameters, s.StatementList
+	default:
+		return nil
+	}
+	if ref == nil || body == nil {
+		return nil
+	}
+	c := &checker{
+		proc:  catalog.NameOf(ref.Name),
+		types: map[string]catalog.Type{},
+		seen:  map[string]bool{},
+	}
+	s := state{}
+	for _, p := range params {
+		if p.VariableName == nil {
+			continue
+		}
+		name := p.VariableName.Value
+		c.types[strings.ToLower(name)] = catalog.TypeOf(p.DataType)
+		if c.carries(name) {
+			step := Step{Name: name, Pos: p.VariableName.Pos}
+			s[strings.ToLower(name)] = taint{source(SourceParameter, step): {kind: SourceParameter, steps: []Step{step}}}
+		}
+	}
+	c.statements(s, body.Statements)
+	sort.SliceStable(c.findings, func(i, j int) bool { return c.findings[i].Pos.Offset < c.findings[j].Pos.Offset })
+	return c.findings
+}
+
+// chain is the path from a source to a value.
+type chain struct {
+	kind  string
+	steps []Step
+}
+
+// taint holds the chains of a value, by source.
+type taint map[string]chain
+
+// state holds the taint of each variable, by lower-case name.
+type state map[string]taint
+
+func source(kind string, s Step) string {
+	return kind + " " + strings.ToLower(s.Name) + " " + s.Pos.String()
+}
+
+func (t taint) add(u taint) {
+	for k, c := range u {
+		if old, ok := t[k]; !ok || len(c.steps) < len(old.steps) {
+			t[k] = c
+		}
+	}
+}
+
+func (s state) clone() state {
+	c := state{}
+	for name, t := range s {
+		c[name] = taint{}
+		c[name].add(t)
+	}
+	return c
+}
+
+// merge adds the taint of u to s and reports whether s changed.
+func (s state) merge(u state) bool {
+	changed := false
+	for name, t := range u {
+		if s[name] == nil {
+			s[name] = taint{}
+		}
+		for k := range t {
+			if _, ok := s[name][k]; !ok {
+				changed = true
+			}
+		}
+		s[name].add(t)
+	}
+	return changed
+}
+
+type checker struct {
+	proc catalog.ObjectName
+	// types holds the declared types of parameters and variables.
+	types    map[string]catalog.Type
+	findings []*Finding
+	// seen holds the findings reported, so that those in loops are
+	// reported once.
+	seen map[string]bool
+	// catches holds the states that the enclosing CATCH blocks start
+	// with, which gain every value assigned in their TRY blocks.
+	catches []state
+}
+
+// carries reports whether a variable can hold SQL text: it has a character
+// type, or a type that is not known.
+func (c *checker) carries(name string) bool {
+	return carries(c.types[strings.ToLower(name)])
+}
+
+func carries(t catalog.Type) bool {
+	switch t.Name {
+	case "", "char", "varchar", "nchar", "nvarchar", "text", "ntext", "sql_variant":
+		return true
+	}
+	return t.IsUserDefined()
+}
+
+// maxIterations bounds the repetitions of a loop body.
+const maxIterations = 10
+
+func (c *checker) statements(s state, stmts []ast.Statement) {
+	for _, stmt := range stmts {
+		c.statement(s, stmt)
+	}
+}
+
+func (c *checker) statement(s state, stmt ast.Statement) {
+	switch stmt := stmt.(type) {
+	case *ast.DeclareVariableStatement:
+		for _, d := range stmt.Declarations {
+			if d.VariableName == nil {
+				continue
+			}
+			name := d.VariableName.Value
+			if d.DataType != nil {
+				c.types[strings.ToLower(name)] = catalog.TypeOf(d.DataType)
+			}
+			delete(s, strings.ToLower(name))
+			if d.Value != nil {
+				c.assign(s, name, d.VariableName.Pos, c.expression(s, d.Value), false)
+			}
+		}
+	case *ast.SetVariableStatement:
+		if stmt.Variable != nil && stmt.Expression != nil {
+			c.assign(s, stmt.Variable.Name, stmt.Variable.Pos, c.expression(s, stmt.Expression), stmt.AssignmentKind != "Equals")
+		}
+	case *ast.SelectStatement:
+		// A SELECT that returns no rows leaves its variables unchanged.
+		for _, spec := range specifications(stmt.QueryExpression) {
+			for _, e := range spec.SelectElements {
+				if e, ok := e.(*ast.SelectSetVariable); ok && e.Variable != nil {
+					c.assign(s, e.Variable.Name, e.Variable.Pos, c.expression(s, e.Expression), true)
+				}
+			}
+		}
+	case *ast.FetchCursorStatement:
+		for _, e := range stmt.IntoVariables {
+			if v, ok := e.(*ast.VariableReference); ok {
+				step := Step{Name: v.Name, Pos: v.Pos}
+				c.assign(s, v.Name, v.Pos, taint{source(SourceTableData, step): {kind: SourceTableData, steps: []Step{step}}}, false)
+			}
+		}
+	case *ast.ExecuteStatement:
+		c.execute(s, stmt.ExecuteSpecification)
+	case *ast.InsertStatement:
+		if stmt.InsertSpecification != nil {
+			if src, ok := stmt.InsertSpecification.InsertSource.(*ast.ExecuteInsertSource); ok {
+				c.execute(s, src.Execute)
+			}
+		}
+	case *ast.BeginEndBlockStatement:
+		if stmt.StatementList != nil {
+			c.statements(s, stmt.StatementList.Statements)
+		}
+	case *ast.IfStatement:
+		then := s.clone()
+		c.statement(then, stmt.ThenStatement)
+		if stmt.ElseStatement != nil {
+			c.statement(s, stmt.ElseStatement)
+		}
+		s.merge(then)
+	case *ast.WhileStatement:
+		for i := 0; i < maxIterations; i++ {
+			body := s.clone()
+			c.statement(body, stmt.Statement)
+			if !s.merge(body) {
+				break
+			}
+		}
+	case *ast.TryCatchStatement:
+		// The CATCH block may be entered from any statement in the TRY
+		// block, so it starts with every value the TRY block may assign.
+		catch := s.clone()
+		c.catches = append(c.catches, catch)
+		if stmt.TryStatements != nil {
+			c.statements(s, stmt.TryStatements.Statements)
+		}
+		c.catches = c.catches[:len(c.catches)-1]
+		if stmt.CatchStatements != nil {
+			c.statements(catch, stmt.CatchStatements.Statements)
+			s.merge(catch)
+		}
+	}
+}
+
+// assign sets the taint of a variable. If keep is set, as for += or a
+// SELECT that may return no rows, the variable keeps its previous taint.
+func (c *checker) assign(s state, name string, pos ast.Position, t taint, keep bool) {
+	key := strings.ToLower(name)
+	if !c.carries(name) {
+		delete(s, key)
+		return
+	}
+	next := taint{}
+	if keep {
+		next.add(s[key])
+	}
+	for k, ch := range t {
+		if last := ch.steps[len(ch.steps)-1]; !strings.EqualFold(last.Name, name) {
+			ch.steps = append(append([]Step(nil), ch.steps...), Step{Name: name, Pos: pos})
+		}
+		next.add(taint{k: ch})
+	}
+	s[key] = next
+	for _, catch := range c.catches {
+		catch.merge(state{key: next})
+	}
+}
+
+// execute reports the sources of the text that spec runs.
+func (c *checker) execute(s state, spec *ast.ExecuteSpecification) {
+	if spec == nil {
+		return
+	}
+	var (
+		call string
+		text []ast.ScalarExpression
+	)
+	switch e := spec.ExecutableEntity.(type) {
+	case *ast.ExecutableStringList:
+		call, text = "EXEC", e.Strings
+	case *ast.ExecutableProcedureReference:
+		stmt, _, ok := parser.ExecuteSQLArguments(e)
+		if !ok || stmt == nil {
+			return
+		}
+		call, text = "sp_executesql", []ast.ScalarExpression{stmt}
+	default:
+		return
+	}
+	t := taint{}
+	for _, e := range text {
+		t.add(c.expression(s, e))
+	}
+	pos := position(text)
+	keys := make([]string, 0, len(t))
+	for k := range t {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := t[keys[i]].steps[0].Pos, t[keys[j]].steps[0].Pos
+		if a.Offset != b.Offset {
+			return a.Offset < b.Offset
+		}
+		return keys[i] < keys[j]
+	})
+	for _, k := range keys {
+		if c.seen[pos.String()+" "+k] {
+			continue
+		}
+		c.seen[pos.String()+" "+k] = true
+		ch := t[k]
+		c.findings = append(c.findings, &Finding{
+			Procedure: c.proc,
+			Call:      call,
+			Pos:       pos,
+			Source:    ch.kind,
+			Chain:     ch.steps,
+		})
+	}
+}
+
+// expression returns the taint of the value of e.
+func (c *checker) expression(s state, e ast.ScalarExpression) taint {
+	t := taint{}
+	if e == nil {
+		return t
+	}
+	ast.Inspect(e, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case ast.BooleanExpression:
+			// Conditions decide which value is used but are not part of it.
+			return false
+		case *ast.VariableReference:
+			t.add(s[strings.ToLower(n.Name)])
+			return false
+		case *ast.ColumnReferenceExpression:
+			if n.MultiPartIdentifier != nil && len(n.MultiPartIdentifier.Identifiers) > 0 {
+				ids := n.MultiPartIdentifier.Identifiers
+				names := make([]string, len(ids))
+				for i, id := range ids {
+					names[i] = id.Value
+				}
+				step := Step{Name: strings.Join(names, "."), Pos: ids[0].Pos}
+				t[source(SourceTableData, step)] = chain{kind: SourceTableData, steps: []Step{step}}
+			}
+			return false
+		case *ast.ScalarSubquery:
+			// Only the selected value is returned, not the values the
+			// subquery filters on.
+			for _, spec := range specifications(n.QueryExpression) {
+				for _, el := range spec.SelectElements {
+					if el, ok := el.(*ast.SelectScalarExpression); ok {
+						t.add(c.expression(s, el.Expression))
+					}
+				}
+			}
+			return false
+		case *ast.FunctionCall:
+			return !sanitizes(n)
+		case *ast.CastCall:
+			return carries(catalog.TypeOf(n.DataType))
+		case *ast.ConvertCall:
+			return carries(catalog.TypeOf(n.DataType))
+		case *ast.TryCastCall:
+			return carries(catalog.TypeOf(n.DataType))
+		case *ast.TryConvertCall:
+			return carries(catalog.TypeOf(n.DataType))
+		}
+		return true
+	})
+	return t
+}
+
+// sanitizes reports whether a function call makes its argument safe to
+// embed in SQL text: QUOTENAME, or a REPLACE that doubles quotes.
+func sanitizes(f *ast.FunctionCall) bool {
+	if f.FunctionName == nil || f.CallTarget != nil {
+		return false
+	}
+	switch strings.ToUpper(f.FunctionName.Value) {
+	case "QUOTENAME":
+		return true
+	case "REPLACE":
+		if len(f.Parameters) < 2 {
+			return false
+		}
+		lit, ok := f.Parameters[1].(*ast.StringLiteral)
+		return ok && strings.Contains(lit.Value, "'")
+	}
+	return false
+}
+
+// specifications returns the query specifications whose rows q returns.
+func specifications(q ast.QueryExpression) []*ast.QuerySpecification {
+	switch q := q.(type) {
+	case *ast.QuerySpecification:
+		return []*ast.QuerySpecification{q}
+	case *ast.QueryParenthesisExpression:
+		return specifications(q.QueryExpression)
+	case *ast.BinaryQueryExpression:
+		return append(specifications(q.FirstQueryExpression), specifications(q.SecondQueryExpression)...)
+	}
+	return nil
+}
+
+// position returns the position of the first variable or identifier in
+// exprs.
+func position(exprs []ast.ScalarExpression) ast.Position {
+	var pos ast.Position
+	for _, e := range exprs {
+		if e == nil {
+			continue
+		}
+		ast.Inspect(e, func(n ast.Node) bool {
+			if pos.IsValid() {
+				return false
+			}
+			switch n := n.(type) {
+			case *ast.VariableReference:
+				pos = n.Pos
+			case *ast.Identifier:
+				pos = n.Pos
+			}
+			return true
+		})
+	}
+	return pos
+}
@@ -0,0 +1,150 @@
+package injection
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sqlc-dev/teesql/parser"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want []string
+	}{
+		{
+			name: "concatenated parameter",
+			sql: `CREATE PROCEDURE dbo.Search @name nvarchar(50) AS
+BEGIN
+	DECLARE @sql nvarchar(max) = N'SELECT * FROM dbo.Users WHERE name = ''' + @name + N'''';
+	EXEC (@sql);
+END`,
+			want: []string{"4:8: dbo.Search: EXEC runs text built from parameter @name: @name (1:29) -> @sql (3:10)"},
+		},
+		{
+			name: "parameterized sp_executesql",
+			sql: `CREATE PROCEDURE dbo.Search @name nvarchar(50) AS
+EXEC sp_executesql N'SELECT * FROM dbo.Users WHERE name = @name', N'@name nvarchar(50)', @name = @name`,
+		},
+		{
+			name: "sanitized",
+			sql: `CREATE PROCEDURE dbo.Sorted @column sysname, @name nvarchar(50), @id int AS
+BEGIN
+	DECLARE @sql nvarchar(max) = N'SELECT * FROM dbo.Users ORDER BY ' + QUOTENAME(@column);
+	SET @sql += N' WHERE name = ''' + REPLACE(@name, '''', '''''') + N''' AND id = ' + CAST(@id AS nvarchar(10));
+	EXEC sp_executesql @sql;
+END`,
+		},
+		{
+			name: "chain of assignments",
+			sql: `CREATE PROCEDURE dbo.Report @table sysname AS
+BEGIN
+	DECLARE @from nvarchar(200), @sql nvarchar(max), @n int;
+	SET @from = N' FROM ' + @table;
+	SET @n = LEN(@from);
+	SET @sql = N'SELECT *' + @from;
+	EXEC sp_executesql @stmt = @sql;
+	EXEC (@n);
+END`,
+			want: []string{"7:29: dbo.Report: sp_executesql runs text built from parameter @table: @table (1:29) -> @from (4:6) -> @sql (6:6)"},
+		},
+		{
+			name: "reassigned",
+			sql: `CREATE PROCEDURE dbo.P @name nvarchar(50) AS
+BEGIN
+	DECLARE @sql nvarchar(max) = @name;
+	SET @sql = N'SELECT 1';
+	EXEC (@sql);
+END`,
+		},
+		{
+			name: "table data",
+			sql: `CREATE PROCEDURE dbo.Purge AS
+BEGIN
+	DECLARE @t sysname, @sql nvarchar(max);
+	SELECT @t = TableName FROM dbo.PurgeList WHERE Id = 1;
+	SET @sql = N'DELETE FROM ' + @t;
+	EXEC (@sql);
+	SET @sql = N'DELETE FROM ' + (SELECT TOP 1 TableName FROM dbo.PurgeList WHERE Id = 2);
+	EXEC (@sql);
+END`,
+			want: []string{
+				"6:8: dbo.Purge: EXEC runs text built from table data TableName: TableName (4:14) -> @t (4:9) -> @sql (5:6)",
+				"8:8: dbo.Purge: EXEC runs text built from table data TableName: TableName (7:45) -> @sql (7:6)",
+			},
+		},
+		{
+			name: "cursor",
+			sql: `CREATE PROCEDURE dbo.Each AS
+BEGIN
+	DECLARE @name sysname;
+	DECLARE c CURSOR FOR SELECT name FROM sys.tables;
+	OPEN c;
+	FETCH NEXT FROM c INTO @name;
+	WHILE @@FETCH_STATUS = 0
+	BEGIN
+		INSERT INTO #counts EXEC (N'SELECT COUNT(*) FROM ' + @name);
+		FETCH NEXT FROM c INTO @name;
+	END
+END`,
+			want: []string{
+				"9:56: dbo.Each: EXEC runs text built from table data @name: @name (6:25)",
+				"9:56: dbo.Each: EXEC runs text built from table data @name: @name (10:26)",
+			},
+		},
+		{
+			name: "branches and loops",
+			sql: `CREATE PROCEDURE dbo.Filter @a nvarchar(10), @b nvarchar(10) AS
+BEGIN
+	DECLARE @sql nvarchar(max) = N'SELECT 1', @where nvarchar(max) = N'', @i int = 0;
+	IF @a IS NOT NULL
+		SET @where = N' WHERE a = ' + @a;
+	ELSE
+		SET @where = N' WHERE a IS NULL';
+	WHILE @i < 2
+	BEGIN
+		SET @sql = @sql + @where;
+		SET @where = @b;
+		SET @i += 1;
+	END
+	EXEC (@sql);
+END`,
+			want: []string{
+				"14:8: dbo.Filter: EXEC runs text built from parameter @a: @a (1:29) -> @where (5:7) -> @sql (10:7)",
+				"14:8: dbo.Filter: EXEC runs text built from parameter @b: @b (1:46) -> @where (11:7) -> @sql (10:7)",
+			},
+		},
+		{
+			name: "catch block",
+			sql: `CREATE PROCEDURE dbo.Retry @name nvarchar(50) AS
+BEGIN
+	DECLARE @sql nvarchar(max) = N'';
+	BEGIN TRY
+		SET @sql = @name;
+		SET @sql = N'SELECT 1';
+	END TRY
+	BEGIN CATCH
+		EXEC (@sql);
+	END CATCH
+END`,
+			want: []string{"9:9: dbo.Retry: EXEC runs text built from parameter @name: @name (1:28) -> @sql (5:7)"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script, err := parser.Parse(context.Background(), strings.NewReader(tt.sql))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, f := range Check(script) {
+				got = append(got, f.String())
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
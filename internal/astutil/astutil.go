@@ -0,0 +1,43 @@
+// Package astutil holds helpers over the syntax tree that the binder,
+// typecheck and lineage packages share.
+package astutil
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/sqlc-dev/teesql/ast"
+)
+
+// datepartFunctions take a datepart keyword, such as day, as their first
+// argument.
+var datepartFunctions = map[string]bool{
+	"dateadd":      true,
+	"datediff":     true,
+	"datediff_big": true,
+	"datepart":     true,
+	"datename":     true,
+	"datetrunc":    true,
+	"date_bucket":  true,
+}
+
+// TakesDatepart reports whether the built-in function name takes a datepart
+// keyword as its first argument. The keyword parses as a column reference.
+func TakesDatepart(name string) bool {
+	return datepartFunctions[strings.ToLower(name)]
+}
+
+// Alias returns the Alias field shared by most table reference types, or
+// nil if ref has none.
+func Alias(ref ast.TableReference) *ast.Identifier {
+	rv := reflect.Indirect(reflect.ValueOf(ref))
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+	if f := rv.FieldByName("Alias"); f.IsValid() {
+		if id, ok := f.Interface().(*ast.Identifier); ok {
+			return id
+		}
+	}
+	return nil
+}
@@ -0,0 +1,298 @@
+// Package lineage computes column-level lineage for T-SQL scripts.
+//
+// Lineage is recorded for statements that persist data into a named object:
+// INSERT, UPDATE, MERGE, SELECT ... INTO, CREATE TABLE ... AS SELECT and view
+// definitions. Derived tables, CTEs and set operators are resolved through, so
+// every edge connects a column of a named object (a table, view, function or
+// table variable) to a column of the target object.
+package lineage
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/sqlc-dev/teesql/ast"
+)
+
+// Edge kinds.
+const (
+	// KindDirect means the target column is a copy of the source column.
+	KindDirect = "Direct"
+	// KindExpression means the source column is an input to an expression
+	// that computes the target column.
+	KindExpression = "Expression"
+)
+
+// Column identifies a column of a named object.
+type Column struct {
+	// Object is the multi-part object name as written in the script, e.g.
+	// "dbo.Orders". It is empty when the column could not be resolved to a
+	// FROM clause source.
+	Object string `json:"Object,omitempty"`
+	// Name is the column name. It is "*" for the unexpanded columns of a
+	// SELECT * over an object whose definition is not known.
+	Name string `json:"Name,omitempty"`
+	// Ordinal is the 1-based position of a target column whose name is not
+	// known, such as in an INSERT without a column list.
+	Ordinal int `json:"Ordinal,omitempty"`
+}
+
+// String returns the dotted form of the column.
+func (c Column) String() string {
+	name := c.Name
+	if name == "" {
+		name = "#" + strconv.Itoa(c.Ordinal)
+	}
+	if c.Object == "" {
+		return name
+	}
+	return c.Object + "." + name
+}
+
+func (c Column) key() string {
+	return strings.ToLower(c.String())
+}
+
+// Edge records that Target derives from Source.
+type Edge struct {
+	Source Column `json:"Source"`
+	Target Column `json:"Target"`
+	Kind   string `json:"Kind"`
+}
+
+// Graph is a column lineage graph. It marshals to JSON with encoding/json.
+type Graph struct {
+	Nodes []Column `json:"Nodes,omitempty"`
+	Edges []*Edge  `json:"Edges,omitempty"`
+
+	seen  map[string]bool
+	edges map[string]*Edge
+}
+
+// Analyze computes the column lineage of every statement in the script,
+// including statements nested in procedure, function and trigger bodies.
+func Analyze(script *ast.Script) *Graph {
+	g := &Graph{}
+	if script == nil {
+		return g
+	}
+	ast.Inspect(script, func(n ast.Node) bool {
+		stmt, ok := n.(ast.Statement)
+		if !ok {
+			return true
+		}
+		return !g.statement(stmt)
+	})
+	return g
+}
+
+// AnalyzeStatement computes the column lineage of a single statement.
+func AnalyzeStatement(stmt ast.Statement) *Graph {
+	g := &Graph{}
+	if stmt != nil {
+		g.statement(stmt)
+	}
+	return g
+}
+
+// Sources returns the edges whose target is the given column.
+func (g *Graph) Sources(target Column) []*Edge {
+	var out []*Edge
+	for _, e := range g.Edges {
+		if e.Target.key() == target.key() {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+func (g *Graph) addNode(c Column) {
+	if g.seen == nil {
+		g.seen = map[string]bool{}
+	}
+	if !g.seen[c.key()] {
+		g.seen[c.key()] = true
+		g.Nodes = append(g.Nodes, c)
+	}
+}
+
+func (g *Graph) addEdge(src source, target Column) {
+	kind := KindExpression
+	if src.direct {
+		kind = KindDirect
+	}
+	key := src.col.key() + "\x00" + target.key()
+	if g.edges == nil {
+		g.edges = map[string]*Edge{}
+	}
+	if e, ok := g.edges[key]; ok {
+		// A column copied on one path and computed on another is an
+		// expression dependency.
+		if kind == KindExpression {
+			e.Kind = KindExpression
+		}
+		return
+	}
+	g.addNode(src.col)
+	g.addNode(target)
+	e := &Edge{Source: src.col, Target: target, Kind: kind}
+	g.edges[key] = e
+	g.Edges = append(g.Edges, e)
+}
+
+// link adds edges from each output column to the matching target column.
+// Target names are taken from names when present, otherwise from the output
+// column names, falling back to ordinals.
+func (g *Graph) link(object string, names []string, cols []outColumn) {
+	for i, col := range cols {
+		target := Column{Object: object}
+		switch {
+		case i < len(names) && names[i] != "":
+			target.Name = names[i]
+		case len(names) == 0 && col.name != "":
+			target.Name = col.name
+		default:
+			target.Ordinal = i + 1
+		}
+		for _, src := range col.sources {
+			g.addEdge(src, target)
+		}
+	}
+}
+
+// statement records the lineage of stmt and reports whether it was handled.
+func (g *Graph) statement(stmt ast.Statement) bool {
+	switch s := stmt.(type) {
+	case *ast.InsertStatement:
+		g.insert(s)
+	case *ast.UpdateStatement:
+		g.update(s)
+	case *ast.MergeStatement:
+		g.merge(s)
+	case *ast.SelectStatement:
+		if s.Into == nil {
+			return false
+		}
+		sc := withCtes(nil, s.WithCtesAndXmlNamespaces)
+		g.link(objectName(s.Into), nil, queryOutputs(s.QueryExpression, sc))
+	case *ast.CreateTableStatement:
+		if s.SelectStatement == nil {
+			return false
+		}
+		g.view(s.SchemaObjectName, s.CtasColumns, s.SelectStatement)
+	case *ast.CreateViewStatement:
+		g.view(s.SchemaObjectName, s.Columns, s.SelectStatement)
+	case *ast.CreateOrAlterViewStatement:
+		g.view(s.SchemaObjectName, s.Columns, s.SelectStatement)
+	case *ast.AlterViewStatement:
+		g.view(s.SchemaObjectName, s.Columns, s.SelectStatement)
+	default:
+		return false
+	}
+	return true
+}
+
+func (g *Graph) view(name *ast.SchemaObjectName, columns []*ast.Identifier, sel *ast.SelectStatement) {
+	if sel == nil {
+		return
+	}
+	sc := withCtes(nil, sel.WithCtesAndXmlNamespaces)
+	g.link(objectName(name), identifierValues(columns), queryOutputs(sel.QueryExpression, sc))
+}
+
+func (g *Graph) insert(s *ast.InsertStatement) {
+	spec := s.InsertSpecification
+	if spec == nil {
+		return
+	}
+	target := targetName(spec.Target)
+	names := columnNames(spec.Columns)
+	sc := withCtes(nil, s.WithCtesAndXmlNamespaces)
+	switch src := spec.InsertSource.(type) {
+	case *ast.SelectInsertSource:
+		cols := queryOutputs(src.Select, sc)
+		g.link(target, positional(names, len(cols)), cols)
+	case *ast.ValuesInsertSource:
+		for _, row := range src.RowValues {
+			g.link(target, positional(names, len(row.ColumnValues)), valueOutputs(row, sc))
+		}
+	}
+}
+
+// positional returns the INSERT column list, or n unnamed columns when the
+// statement has none so that targets are matched by ordinal.
+func positional(names []string, n int) []string {
+	if len(names) > 0 {
+		return names
+	}
+	return make([]string, n)
+}
+
+func (g *Graph) update(s *ast.UpdateStatement) {
+	spec := s.UpdateSpecification
+	if spec == nil {
+		return
+	}
+	sc := withCtes(nil, s.WithCtesAndXmlNamespaces)
+	if spec.FromClause != nil {
+		sc.addFrom(spec.FromClause.TableReferences)
+	} else {
+		sc.addTableReference(spec.Target)
+	}
+	target := sc.targetObject(spec.Target)
+	g.setClauses(target, spec.SetClauses, sc)
+}
+
+func (g *Graph) merge(s *ast.MergeStatement) {
+	spec := s.MergeSpecification
+	if spec == nil {
+		return
+	}
+	sc := withCtes(nil, s.WithCtesAndXmlNamespaces)
+	sc.addTableReference(spec.Target)
+	if spec.TableAlias != nil && len(sc.rels) > 0 {
+		sc.rels[len(sc.rels)-1].alias = spec.TableAlias.Value
+	}
+	target := targetName(spec.Target)
+	sc.addTableReference(spec.TableReference)
+	for _, clause := range spec.ActionClauses {
+		switch action := clause.Action.(type) {
+		case *ast.UpdateMergeAction:
+			g.setClauses(target, action.SetClauses, sc)
+		case *ast.InsertMergeAction:
+			names := columnNames(action.Columns)
+			if values, ok := action.Source.(*ast.ValuesInsertSource); ok {
+				for _, row := range values.RowValues {
+					g.link(target, positional(names, len(row.ColumnValues)), valueOutputs(row, sc))
+				}
+			}
+		}
+	}
+}
+
+func (g *Graph) setClauses(target string, clauses []ast.SetClause, sc *scope) {
+	for _, clause := range clauses {
+		assign, ok := clause.(*ast.AssignmentSetClause)
+		if !ok || assign.Column == nil {
+			continue
+		}
+		name := columnName(assign.Column)
+		if name == "" {
+			continue
+		}
+		sources := expressionSources(assign.NewValue, sc)
+		if assign.AssignmentKind != "" && assign.AssignmentKind != "Equals" {
+			// Compound assignments (+=, -=, ...) read the target column.
+			sources = append(indirect(sources), source{col: Column{Object: target, Name: name}})
+		}
+		g.link(target, []string{name}, []outColumn{{name: name, sources: sources}})
+	}
+}
+
+func valueOutputs(row *ast.RowValue, sc *scope) []outColumn {
+	cols := make([]outColumn, len(row.ColumnValues))
+	for i, v := range row.ColumnValues {
+		cols[i] = outColumn{sources: expressionSources(v, sc)}
+	}
+	return cols
+}
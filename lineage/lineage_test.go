@@ -0,0 +1,111 @@
+package lineage
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sqlc-dev/teesql/parser"
+)
+
+func TestAnalyze(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want []string
+	}{
+		{
+			name: "insert select",
+			sql:  "INSERT INTO dbo.Target (a, b) SELECT s.x, s.y + 1 FROM dbo.Source s",
+			want: []string{
+				"dbo.Source.x -> dbo.Target.a Direct",
+				"dbo.Source.y -> dbo.Target.b Expression",
+			},
+		},
+		{
+			name: "insert without column list",
+			sql:  "INSERT INTO t SELECT x, y FROM s",
+			want: []string{
+				"s.x -> t.#1 Direct",
+				"s.y -> t.#2 Direct",
+			},
+		},
+		{
+			name: "derived table and union",
+			sql: `INSERT INTO t (a) SELECT d.v FROM (SELECT x AS v FROM s1) d
+UNION ALL SELECT y FROM s2`,
+			want: []string{
+				"s1.x -> t.a Direct",
+				"s2.y -> t.a Direct",
+			},
+		},
+		{
+			name: "cte",
+			sql: `WITH totals AS (SELECT o.CustomerId, SUM(o.Amount) AS Total FROM dbo.Orders o GROUP BY o.CustomerId)
+INSERT INTO dbo.Summary (CustomerId, Total) SELECT CustomerId, Total FROM totals`,
+			want: []string{
+				"dbo.Orders.CustomerId -> dbo.Summary.CustomerId Direct",
+				"dbo.Orders.Amount -> dbo.Summary.Total Expression",
+			},
+		},
+		{
+			name: "view",
+			sql:  "CREATE VIEW dbo.v (id, label) AS SELECT p.id, UPPER(p.name) FROM dbo.people p",
+			want: []string{
+				"dbo.people.id -> dbo.v.id Direct",
+				"dbo.people.name -> dbo.v.label Expression",
+			},
+		},
+		{
+			name: "merge",
+			sql: `MERGE dbo.T AS t USING dbo.S AS s ON t.id = s.id
+WHEN MATCHED THEN UPDATE SET t.v = s.v
+WHEN NOT MATCHED THEN INSERT (id, v) VALUES (s.id, s.v);`,
+			want: []string{
+				"dbo.S.v -> dbo.T.v Direct",
+				"dbo.S.id -> dbo.T.id Direct",
+			},
+		},
+		{
+			name: "update from alias",
+			sql:  "UPDATE a SET total = b.amount FROM dbo.A a JOIN dbo.B b ON a.id = b.id",
+			want: []string{
+				"dbo.B.amount -> dbo.A.total Direct",
+			},
+		},
+		{
+			name: "select into with scalar subquery",
+			sql:  "SELECT r.a, (SELECT MAX(q.z) FROM q) AS m INTO #tmp FROM dbo.R r",
+			want: []string{
+				"dbo.R.a -> #tmp.a Direct",
+				"q.z -> #tmp.m Expression",
+			},
+		},
+		{
+			name: "procedure body",
+			sql: `CREATE PROCEDURE p AS
+BEGIN
+	IF 1 = 1
+		INSERT INTO t (a) SELECT b FROM s
+END`,
+			want: []string{
+				"s.b -> t.a Direct",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script, err := parser.Parse(context.Background(), strings.NewReader(tt.sql))
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			var got []string
+			for _, e := range Analyze(script).Edges {
+				got = append(got, e.Source.String()+" -> "+e.Target.String()+" "+e.Kind)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("edges mismatch:\ngot:\n%s\n\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
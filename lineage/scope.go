@@ -0,0 +1,420 @@
+package lineage
+
+import (
+	"strings"
+
+	"github.com/sqlc-dev/teesql/ast"
+	"github.com/sqlc-dev/teesql/internal/astutil"
+)
+
+// source is a named-object column that feeds an output column.
+type source struct {
+	col    Column
+	direct bool
+}
+
+// outColumn is a column produced by a query expression.
+type outColumn struct {
+	name    string
+	sources []source
+}
+
+// relation is a table source in a FROM clause.
+type relation struct {
+	alias  string
+	object string
+	// cols holds the columns of derived tables and CTEs. It is nil for named
+	// objects, whose columns are not known and resolve by name.
+	cols []outColumn
+	// derived is set for derived tables and CTEs.
+	derived bool
+}
+
+func (r *relation) column(name string) ([]source, bool) {
+	if !r.derived {
+		return []source{{col: Column{Object: r.object, Name: name}, direct: true}}, true
+	}
+	for _, c := range r.cols {
+		if strings.EqualFold(c.name, name) {
+			return append([]source(nil), c.sources...), true
+		}
+	}
+	return nil, false
+}
+
+func (r *relation) matches(qualifier []string) bool {
+	if len(qualifier) == 0 {
+		return false
+	}
+	if r.alias != "" {
+		return len(qualifier) == 1 && strings.EqualFold(r.alias, qualifier[0])
+	}
+	parts := strings.Split(r.object, ".")
+	if len(qualifier) > len(parts) {
+		return false
+	}
+	parts = parts[len(parts)-len(qualifier):]
+	for i := range qualifier {
+		if !strings.EqualFold(parts[i], qualifier[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+type cte struct {
+	name string
+	cols []outColumn
+}
+
+// scope is the set of table sources visible to an expression.
+type scope struct {
+	parent *scope
+	rels   []*relation
+	ctes   []*cte
+}
+
+func newScope(parent *scope) *scope {
+	return &scope{parent: parent}
+}
+
+// withCtes returns a new scope with the CTEs of w defined.
+func withCtes(parent *scope, w *ast.WithCtesAndXmlNamespaces) *scope {
+	sc := newScope(parent)
+	if w == nil {
+		return sc
+	}
+	for _, def := range w.CommonTableExpressions {
+		if def.ExpressionName == nil {
+			continue
+		}
+		// Register the CTE before computing its columns so a recursive
+		// reference resolves to it rather than to a base table.
+		c := &cte{name: def.ExpressionName.Value}
+		sc.ctes = append(sc.ctes, c)
+		cols := queryOutputs(def.QueryExpression, newScope(sc))
+		c.cols = rename(cols, identifierValues(def.Columns))
+	}
+	return sc
+}
+
+func (sc *scope) lookupCte(name string) *cte {
+	for s := sc; s != nil; s = s.parent {
+		for _, c := range s.ctes {
+			if strings.EqualFold(c.name, name) {
+				return c
+			}
+		}
+	}
+	return nil
+}
+
+func (sc *scope) addFrom(refs []ast.TableReference) {
+	for _, ref := range refs {
+		sc.addTableReference(ref)
+	}
+}
+
+func (sc *scope) addTableReference(ref ast.TableReference) {
+	switch r := ref.(type) {
+	case *ast.NamedTableReference:
+		rel := &relation{alias: identifierValue(r.Alias), object: objectName(r.SchemaObject)}
+		if r.SchemaObject != nil && r.SchemaObject.Count <= 1 && r.SchemaObject.BaseIdentifier != nil {
+			if c := sc.lookupCte(r.SchemaObject.BaseIdentifier.Value); c != nil {
+				rel.derived = true
+				rel.cols = c.cols
+				if rel.alias == "" {
+					rel.alias = c.name
+				}
+			}
+		}
+		sc.rels = append(sc.rels, rel)
+	case *ast.QueryDerivedTable:
+		// Derived tables see the enclosing scope, which also covers the
+		// right-hand side of APPLY.
+		cols := queryOutputs(r.QueryExpression, newScope(sc))
+		sc.rels = append(sc.rels, &relation{
+			alias:   identifierValue(r.Alias),
+			cols:    rename(cols, identifierValues(r.Columns)),
+			derived: true,
+		})
+	case *ast.InlineDerivedTable:
+		names := identifierValues(r.Columns)
+		cols := make([]outColumn, len(names))
+		for i, name := range names {
+			cols[i].name = name
+		}
+		for _, row := range r.RowValues {
+			for i, v := range row.ColumnValues {
+				if i < len(cols) {
+					cols[i].sources = append(cols[i].sources, expressionSources(v, sc)...)
+				}
+			}
+		}
+		sc.rels = append(sc.rels, &relation{alias: identifierValue(r.Alias), cols: cols, derived: true})
+	case *ast.SchemaObjectFunctionTableReference:
+		sc.rels = append(sc.rels, &relation{alias: identifierValue(r.Alias), object: objectName(r.SchemaObject)})
+	case *ast.VariableTableReference:
+		rel := &relation{alias: identifierValue(r.Alias)}
+		if r.Variable != nil {
+			rel.object = r.Variable.Name
+		}
+		sc.rels = append(sc.rels, rel)
+	case *ast.QualifiedJoin:
+		sc.addTableReference(r.FirstTableReference)
+		sc.addTableReference(r.SecondTableReference)
+	case *ast.UnqualifiedJoin:
+		sc.addTableReference(r.FirstTableReference)
+		sc.addTableReference(r.SecondTableReference)
+	case *ast.JoinParenthesisTableReference:
+		sc.addTableReference(r.Join)
+	case nil:
+	default:
+		// Rowset functions and other sources whose columns cannot be
+		// traced are still visible by alias so references to them do
+		// not resolve to an unrelated table.
+		sc.rels = append(sc.rels, &relation{alias: identifierValue(astutil.Alias(ref)), derived: true})
+	}
+}
+
+// targetObject returns the object name of a DML target, resolving an alias
+// declared in the statement's FROM clause.
+func (sc *scope) targetObject(ref ast.TableReference) string {
+	named, ok := ref.(*ast.NamedTableReference)
+	if !ok || named.SchemaObject == nil || named.SchemaObject.Count > 1 {
+		return targetName(ref)
+	}
+	name := objectName(named.SchemaObject)
+	for _, rel := range sc.rels {
+		if !rel.derived && strings.EqualFold(rel.alias, name) {
+			return rel.object
+		}
+	}
+	return name
+}
+
+// resolve returns the sources of a column reference.
+func (sc *scope) resolve(ref *ast.ColumnReferenceExpression) []source {
+	if ref.ColumnType != "" && ref.ColumnType != "Regular" {
+		return nil
+	}
+	parts := multiPartValues(ref.MultiPartIdentifier)
+	if len(parts) == 0 {
+		return nil
+	}
+	name, qualifier := parts[len(parts)-1], parts[:len(parts)-1]
+	for s := sc; s != nil; s = s.parent {
+		if len(qualifier) > 0 {
+			for _, rel := range s.rels {
+				if rel.matches(qualifier) {
+					srcs, _ := rel.column(name)
+					return srcs
+				}
+			}
+			continue
+		}
+		var found []source
+		var named []*relation
+		matched := 0
+		for _, rel := range s.rels {
+			if !rel.derived {
+				named = append(named, rel)
+				continue
+			}
+			if srcs, ok := rel.column(name); ok {
+				found = srcs
+				matched++
+			}
+		}
+		switch {
+		case matched == 1:
+			return found
+		case matched == 0 && len(named) == 1:
+			srcs, _ := named[0].column(name)
+			return srcs
+		case matched > 1 || len(named) > 1:
+			// Ambiguous without the table definitions.
+			return []source{{col: Column{Name: name}, direct: true}}
+		}
+	}
+	return []source{{col: Column{Name: name}, direct: true}}
+}
+
+// queryOutputs returns the columns produced by a query expression.
+func queryOutputs(qe ast.QueryExpression, parent *scope) []outColumn {
+	switch q := qe.(type) {
+	case *ast.QuerySpecification:
+		sc := newScope(parent)
+		if q.FromClause != nil {
+			sc.addFrom(q.FromClause.TableReferences)
+		}
+		var cols []outColumn
+		for _, elem := range q.SelectElements {
+			cols = append(cols, sc.selectElement(elem)...)
+		}
+		return cols
+	case *ast.QueryParenthesisExpression:
+		return queryOutputs(q.QueryExpression, parent)
+	case *ast.BinaryQueryExpression:
+		cols := queryOutputs(q.FirstQueryExpression, parent)
+		for i, c := range queryOutputs(q.SecondQueryExpression, parent) {
+			if i < len(cols) {
+				cols[i].sources = append(append([]source(nil), cols[i].sources...), c.sources...)
+			}
+		}
+		return cols
+	}
+	return nil
+}
+
+func (sc *scope) selectElement(elem ast.SelectElement) []outColumn {
+	switch e := elem.(type) {
+	case *ast.SelectScalarExpression:
+		col := outColumn{sources: expressionSources(e.Expression, sc)}
+		if e.ColumnName != nil {
+			col.name = e.ColumnName.Value
+			if e.ColumnName.Identifier != nil {
+				col.name = e.ColumnName.Identifier.Value
+			}
+		} else if ref, ok := e.Expression.(*ast.ColumnReferenceExpression); ok {
+			col.name = columnName(ref)
+		}
+		return []outColumn{col}
+	case *ast.SelectStarExpression:
+		qualifier := multiPartValues(e.Qualifier)
+		var cols []outColumn
+		for _, rel := range sc.rels {
+			if len(qualifier) > 0 && !rel.matches(qualifier) {
+				continue
+			}
+			if rel.derived {
+				cols = append(cols, rel.cols...)
+				continue
+			}
+			cols = append(cols, outColumn{
+				name:    "*",
+				sources: []source{{col: Column{Object: rel.object, Name: "*"}, direct: true}},
+			})
+		}
+		return cols
+	}
+	return nil
+}
+
+// expressionSources returns the sources of every column read by expr. Only
+// a bare column reference is a direct copy.
+func expressionSources(expr ast.ScalarExpression, sc *scope) []source {
+	for {
+		paren, ok := expr.(*ast.ParenthesisExpression)
+		if !ok {
+			break
+		}
+		expr = paren.Expression
+	}
+	if expr == nil {
+		return nil
+	}
+	if ref, ok := expr.(*ast.ColumnReferenceExpression); ok {
+		return sc.resolve(ref)
+	}
+	var srcs []source
+	ast.Inspect(expr, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.ColumnReferenceExpression:
+			srcs = append(srcs, sc.resolve(n)...)
+		case *ast.ScalarSubquery:
+			for _, c := range queryOutputs(n.QueryExpression, sc) {
+				srcs = append(srcs, c.sources...)
+			}
+			return false
+		case ast.QueryExpression:
+			// Subqueries in predicates filter rows but do not supply values.
+			return false
+		}
+		return true
+	})
+	return indirect(srcs)
+}
+
+func indirect(srcs []source) []source {
+	out := make([]source, len(srcs))
+	for i, s := range srcs {
+		out[i] = source{col: s.col}
+	}
+	return out
+}
+
+func rename(cols []outColumn, names []string) []outColumn {
+	for i := range cols {
+		if i < len(names) {
+			cols[i].name = names[i]
+		}
+	}
+	return cols
+}
+
+func objectName(name *ast.SchemaObjectName) string {
+	if name == nil {
+		return ""
+	}
+	parts := make([]string, len(name.Identifiers))
+	for i, id := range name.Identifiers {
+		parts[i] = identifierValue(id)
+	}
+	if len(parts) == 0 {
+		return identifierValue(name.BaseIdentifier)
+	}
+	return strings.Join(parts, ".")
+}
+
+func targetName(ref ast.TableReference) string {
+	switch r := ref.(type) {
+	case *ast.NamedTableReference:
+		return objectName(r.SchemaObject)
+	case *ast.VariableTableReference:
+		if r.Variable != nil {
+			return r.Variable.Name
+		}
+	case *ast.SchemaObjectFunctionTableReference:
+		return objectName(r.SchemaObject)
+	}
+	return ""
+}
+
+func identifierValue(id *ast.Identifier) string {
+	if id == nil {
+		return ""
+	}
+	return id.Value
+}
+
+func identifierValues(ids []*ast.Identifier) []string {
+	out := make([]string, len(ids))
+	for i, id := range ids {
+		out[i] = identifierValue(id)
+	}
+	return out
+}
+
+func multiPartValues(mpi *ast.MultiPartIdentifier) []string {
+	if mpi == nil {
+		return nil
+	}
+	return identifierValues(mpi.Identifiers)
+}
+
+func columnName(ref *ast.ColumnReferenceExpression) string {
+	parts := multiPartValues(ref.MultiPartIdentifier)
+	if len(parts) == 0 {
+		return ""
+	}
+	return parts[len(parts)-1]
+}
+
+func columnNames(refs []*ast.ColumnReferenceExpression) []string {
+	out := make([]string, len(refs))
+	for i, ref := range refs {
+		out[i] = columnName(ref)
+	}
+	return out
+}
@@ -0,0 +1,745 @@
+// Package migration classifies the statements of a migration script by the
+// risk they carry when deployed:
+//
+//   - data loss: DROP statements, ALTER TABLE ... DROP, TRUNCATE TABLE and
+//     ALTER COLUMN changes to a type that cannot hold every value of the old
+//     one;
+//   - blocking: index builds without ONLINE = ON, and NOT NULL columns added
+//     without a default, on tables that exist before the migration;
+//   - non-idempotent: CREATE statements that fail when run a second time
+//     because they are neither guarded by IF nor preceded by a DROP ... IF
+//     EXISTS of the same object. Views, procedures, functions and triggers,
+//     which must be first in their batch, are advised CREATE OR ALTER.
+//
+// Statements nested in IF, BEGIN...END and TRY...CATCH blocks are checked;
+// the bodies of procedures, functions and triggers, which are not run by
+// the migration, are not.
+package migration
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/sqlc-dev/teesql/ast"
+	"github.com/sqlc-dev/teesql/catalog"
+)
+
+// Risk categories.
+const (
+	DataLoss      = "data loss"
+	Blocking      = "blocking"
+	NonIdempotent = "non-idempotent"
+)
+
+// Options configures Check.
+type Options struct {
+	// Schema holds the scripts defining the schema before the migration.
+	// Without it, column narrowing is not detected.
+	Schema []*ast.Script
+	// Large reports whether a table is large enough for blocking
+	// statements to matter. If nil, every table is.
+	Large func(table catalog.ObjectName) bool
+}
+
+// Report is the classification of a migration script.
+type Report struct {
+	// Statements lists every top-level statement of the script and every
+	// statement nested in IF, BEGIN...END and TRY...CATCH blocks, in source
+	// order.
+	Statements []*Statement `json:"Statements"`
+}
+
+// Statement is a statement of the script with its risks.
+type Statement struct {
+	// Batch and Index are the 1-based positions of the batch in the script
+	// and of the top-level statement in the batch that holds the statement.
+	Batch int `json:"Batch"`
+	Index int `json:"Index"`
+	// Pos is the position of the first name in the statement.
+	Pos ast.Position `json:"Pos"`
+	// Type is the syntax tree type of the statement, e.g.
+	// "DropTableStatement".
+	Type  string  `json:"Type"`
+	Risks []*Risk `json:"Risks,omitempty"`
+}
+
+// Risk is a reason a statement is dangerous.
+type Risk struct {
+	Category string `json:"Category"`
+	// Object is the table, column or other object at risk, e.g. "dbo.T.c".
+	Object  string `json:"Object"`
+	Message string `json:"Message"`
+}
+
+// String describes the risk, e.g. "data loss: drops table dbo.T and all
+// of its rows".
+func (r *Risk) String() string {
+	return r.Category + ": " + r.Message
+}
+
+// Risky returns the statements with at least one risk.
+func (r *Report) Risky() []*Statement {
+	var stmts []*Statement
+	for _, s := range r.Statements {
+		if len(s.Risks) > 0 {
+			stmts = append(stmts, s)
+		}
+	}
+	return stmts
+}
+
+// Count returns the number of risks in a category.
+func (r *Report) Count(category string) int {
+	n := 0
+	for _, s := range r.Statements {
+		for _, risk := range s.Risks {
+			if risk.Category == category {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// Check classifies the statements of a migration script.
+func Check(script *ast.Script, opts Options) *Report {
+	// Errors building the schema are ignored: the report covers what is
+	// known.
+	cat, _ := catalog.Build(opts.Schema...)
+	c := &checker{
+		opts:    opts,
+		cat:     cat,
+		created: map[string]bool{},
+		dropped: map[string]bool{},
+		report:  &Report{Statements: []*Statement{}},
+	}
+	for i, batch := range script.Batches {
+		for j, stmt := range batch.Statements {
+			c.batch, c.index = i+1, j+1
+			c.stmt(stmt, false)
+			// Errors are ignored: a migration may drop objects the schema
+			// scripts did not create.
+			c.cat.Apply(stmt)
+		}
+	}
+	return c.report
+}
+
+type checker struct {
+	opts         Options
+	cat          *catalog.Catalog
+	batch, index int
+	// created holds the keys of the tables the migration creates, which
+	// are empty and cannot block.
+	created map[string]bool
+	// dropped holds the keys of the objects dropped with IF EXISTS, or
+	// dropped under an IF, which can then be created again.
+	dropped map[string]bool
+	report  *Report
+}
+
+// key returns the key of a schema-qualified name.
+func key(n catalog.ObjectName) string {
+	if n.Schema == "" {
+		n.Schema = catalog.DefaultSchema
+	}
+	return strings.ToLower(n.String())
+}
+
+func (c *checker) stmt(stmt ast.Statement, guarded bool) {
+	switch s := stmt.(type) {
+	case *ast.IfStatement:
+		c.add(stmt)
+		c.stmt(s.ThenStatement, true)
+		c.stmt(s.ElseStatement, true)
+		return
+	case *ast.BeginEndBlockStatement:
+		c.add(stmt)
+		for _, inner := range statements(s.StatementList) {
+			c.stmt(inner, guarded)
+		}
+		return
+	case *ast.TryCatchStatement:
+		c.add(stmt)
+		for _, inner := range statements(s.TryStatements) {
+			c.stmt(inner, guarded)
+		}
+		for _, inner := range statements(s.CatchStatements) {
+			c.stmt(inner, guarded)
+		}
+		return
+	case nil:
+		return
+	}
+	st := c.add(stmt)
+	switch s := stmt.(type) {
+	case *ast.TruncateTableStatement:
+		name := catalog.NameOf(s.TableName)
+		if !temporary(name) {
+			st.risk(DataLoss, name.String(), "deletes every row of "+name.String())
+		}
+	case *ast.AlterTableDropTableElementStatement:
+		c.alterTableDrop(st, s)
+	case *ast.AlterTableAlterColumnStatement:
+		c.alterColumn(st, s)
+	case *ast.AlterTableAddTableElementStatement:
+		c.alterTableAdd(st, s)
+	case *ast.CreateIndexStatement:
+		c.indexBuild(st, catalog.NameOf(s.OnName), identifierValue(s.Name), s.IndexOptions)
+	case *ast.CreateColumnStoreIndexStatement:
+		c.indexBuild(st, catalog.NameOf(s.OnName), identifierValue(s.Name), s.IndexOptions)
+	case *ast.AlterIndexStatement:
+		if s.AlterIndexType == "Rebuild" {
+			name := identifierValue(s.Name)
+			if s.All {
+				name = "ALL"
+			}
+			c.indexBuild(st, catalog.NameOf(s.OnName), name, s.IndexOptions)
+		}
+	case *ast.DropIndexStatement:
+		for _, cl := range s.DropIndexClauses {
+			table, index := catalog.NameOf(cl.Object), identifierValue(cl.Index)
+			if cl.LegacyIndex != nil {
+				// DROP INDEX table.index names the table as the schema.
+				legacy := catalog.NameOf(cl.LegacyIndex)
+				table, index = catalog.ObjectName{Name: legacy.Schema}, legacy.Name
+			}
+			if s.IsIfExists || guarded {
+				c.dropped[key(table)+"."+strings.ToLower(index)] = true
+			}
+			st.risk(DataLoss, table.String()+"."+index, "drops index "+index+" on "+table.String())
+		}
+	case *ast.CreateTableStatement:
+		c.created[key(catalog.NameOf(s.SchemaObjectName))] = true
+	}
+	what, drop := created(stmt), false
+	if what == "" {
+		what, drop = dropped(stmt), true
+	}
+	if what == "" {
+		return
+	}
+	for _, name := range objectNames(stmt) {
+		if temporary(name) {
+			continue
+		}
+		k, object, display := key(name), name.String(), name.String()
+		if idx, ok := stmt.(*ast.CreateIndexStatement); ok {
+			table := catalog.NameOf(idx.OnName)
+			k = key(table) + "." + strings.ToLower(name.Name)
+			object, display = table.String()+"."+name.Name, name.Name+" on "+table.String()
+		}
+		if drop {
+			if guarded || ifExists(stmt) {
+				c.dropped[k] = true
+			}
+			if what == "table" {
+				st.risk(DataLoss, object, "drops table "+display+" and all of its rows")
+			} else {
+				st.risk(DataLoss, object, "drops "+what+" "+display)
+			}
+			continue
+		}
+		if guarded || c.dropped[k] {
+			continue
+		}
+		switch what {
+		case "view", "procedure", "function", "trigger":
+			// These must be first in their batch, so IF cannot guard them.
+			st.risk(NonIdempotent, object, "creates "+what+" "+display+
+				" without a preceding DROP ... IF EXISTS; use CREATE OR ALTER")
+		default:
+			st.risk(NonIdempotent, object, "creates "+what+" "+display+
+				" without an IF NOT EXISTS guard or a preceding DROP ... IF EXISTS")
+		}
+	}
+}
+
+// add adds a statement to the report.
+func (c *checker) add(stmt ast.Statement) *Statement {
+	st := &Statement{Batch: c.batch, Index: c.index, Pos: position(stmt), Type: typeName(stmt)}
+	c.report.Statements = append(c.report.Statements, st)
+	return st
+}
+
+func (s *Statement) risk(category, object, message string) {
+	s.Risks = append(s.Risks, &Risk{Category: category, Object: object, Message: message})
+}
+
+func (c *checker) alterTableDrop(st *Statement, s *ast.AlterTableDropTableElementStatement) {
+	table := catalog.NameOf(s.SchemaObjectName)
+	for _, el := range s.AlterTableDropTableElements {
+		name := identifierValue(el.Name)
+		switch el.TableElementType {
+		case "Column":
+			st.risk(DataLoss, table.String()+"."+name, "drops column "+name+" of "+table.String()+" and its data")
+		case "Index":
+			st.risk(DataLoss, table.String()+"."+name, "drops index "+name+" on "+table.String())
+		default:
+			st.risk(DataLoss, table.String()+"."+name, "drops constraint "+name+" on "+table.String())
+		}
+	}
+}
+
+func (c *checker) alterColumn(st *Statement, s *ast.AlterTableAlterColumnStatement) {
+	if s.DataType == nil {
+		return
+	}
+	table := catalog.NameOf(s.SchemaObjectName)
+	t := c.cat.Table(table)
+	if t == nil {
+		return
+	}
+	col := t.Column(identifierValue(s.ColumnIdentifier))
+	if col == nil {
+		return
+	}
+	from, to := c.cat.ResolveType(col.Type), c.cat.ResolveType(catalog.TypeOf(s.DataType))
+	if from.IsZero() || to.IsZero() || from.IsUserDefined() || to.IsUserDefined() || widens(from, to) {
+		return
+	}
+	st.risk(DataLoss, table.String()+"."+col.Name, fmt.Sprintf("changes column %s of %s from %s to %s, which cannot hold every existing value",
+		col.Name, table, from, to))
+}
+
+func (c *checker) alterTableAdd(st *Statement, s *ast.AlterTableAddTableElementStatement) {
+	if s.Definition == nil {
+		return
+	}
+	table := catalog.NameOf(s.SchemaObjectName)
+	if !c.existing(table) {
+		return
+	}
+	added := catalog.NewTable(table, s.Definition)
+	for _, col := range added.Columns {
+		if !col.Nullable && col.Default == nil && col.Identity == nil && col.Computed == nil && col.Type.Name != "rowversion" && col.Type.Name != "timestamp" {
+			st.risk(Blocking, table.String()+"."+col.Name, "adds NOT NULL column "+col.Name+" to "+table.String()+
+				" without a default, which fails if the table has rows")
+		}
+	}
+	for _, tc := range s.Definition.TableConstraints {
+		if u, ok := tc.(*ast.UniqueConstraintDefinition); ok {
+			c.indexBuild(st, table, identifierValue(u.ConstraintIdentifier), u.IndexOptions)
+		}
+	}
+	for _, ix := range s.Definition.Indexes {
+		c.indexBuild(st, table, identifierValue(ix.Name), ix.IndexOptions)
+	}
+}
+
+// existing reports whether blocking statements on a table matter: the table
+// was not created by the migration and is large.
+func (c *checker) existing(table catalog.ObjectName) bool {
+	if c.created[key(table)] {
+		return false
+	}
+	if c.opts.Large != nil && !c.opts.Large(table) {
+		return false
+	}
+	return true
+}
+
+// indexBuild reports an index build on table that is not run online.
+func (c *checker) indexBuild(st *Statement, table catalog.ObjectName, index string, opts []ast.IndexOption) {
+	if !c.existing(table) || online(opts) {
+		return
+	}
+	what := "index " + index
+	if index == "" {
+		what = "an index"
+	}
+	st.risk(Blocking, table.String()+"."+index, "builds "+what+" on "+table.String()+
+		" without ONLINE = ON, locking the table until it completes")
+}
+
+// online reports whether index options include ONLINE = ON.
+func online(opts []ast.IndexOption) bool {
+	for _, o := range opts {
+		switch o := o.(type) {
+		case *ast.OnlineIndexOption:
+			if o.OptionState == "On" {
+				return true
+			}
+		case *ast.IndexStateOption:
+			if o.OptionKind == "Online" && o.OptionState == "On" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// temporary reports whether a name is that of a temporary table.
+func temporary(n catalog.ObjectName) bool {
+	return strings.HasPrefix(n.Name, "#")
+}
+
+// typeName returns the name of the syntax tree type of stmt.
+func typeName(stmt ast.Statement) string {
+	t := reflect.TypeOf(stmt)
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Name()
+}
+
+// created returns the kind of object a CREATE statement is for, e.g.
+// "security policy" for CreateSecurityPolicyStatement, or "" if stmt is not
+// one. CREATE OR ALTER statements, which can be run again, are not.
+func created(stmt ast.Statement) string {
+	switch stmt.(type) {
+	case *ast.CreateAggregateStatement:
+		return "aggregate"
+	case *ast.CreateApplicationRoleStatement:
+		return "application role"
+	case *ast.CreateAssemblyStatement:
+		return "assembly"
+	case *ast.CreateAsymmetricKeyStatement:
+		return "asymmetric key"
+	case *ast.CreateAvailabilityGroupStatement:
+		return "availability group"
+	case *ast.CreateBrokerPriorityStatement:
+		return "broker priority"
+	case *ast.CreateCertificateStatement:
+		return "certificate"
+	case *ast.CreateColumnEncryptionKeyStatement:
+		return "column encryption key"
+	case *ast.CreateColumnMasterKeyStatement:
+		return "column master key"
+	case *ast.CreateColumnStoreIndexStatement:
+		return "columnstore index"
+	case *ast.CreateContractStatement:
+		return "contract"
+	case *ast.CreateCredentialStatement:
+		return "credential"
+	case *ast.CreateCryptographicProviderStatement:
+		return "cryptographic provider"
+	case *ast.CreateDatabaseStatement:
+		return "database"
+	case *ast.CreateDatabaseAuditSpecificationStatement:
+		return "database audit specification"
+	case *ast.CreateDatabaseEncryptionKeyStatement:
+		return "database encryption key"
+	case *ast.CreateDefaultStatement:
+		return "default"
+	case *ast.CreateEndpointStatement:
+		return "endpoint"
+	case *ast.CreateEventNotificationStatement:
+		return "event notification"
+	case *ast.CreateEventSessionStatement:
+		return "event session"
+	case *ast.CreateExternalDataSourceStatement:
+		return "external data source"
+	case *ast.CreateExternalFileFormatStatement:
+		return "external file format"
+	case *ast.CreateExternalLanguageStatement:
+		return "external language"
+	case *ast.CreateExternalLibraryStatement:
+		return "external library"
+	case *ast.CreateExternalResourcePoolStatement:
+		return "external resource pool"
+	case *ast.CreateExternalTableStatement:
+		return "external table"
+	case *ast.CreateFederationStatement:
+		return "federation"
+	case *ast.CreateFullTextCatalogStatement, *ast.CreateFulltextCatalogStatement:
+		return "full-text catalog"
+	case *ast.CreateFulltextIndexStatement:
+		return "full-text index"
+	case *ast.CreateFullTextStopListStatement:
+		return "full-text stoplist"
+	case *ast.CreateFunctionStatement:
+		return "function"
+	case *ast.CreateIndexStatement:
+		return "index"
+	case *ast.CreateLoginStatement:
+		return "login"
+	case *ast.CreateMasterKeyStatement:
+		return "master key"
+	case *ast.CreateMessageTypeStatement:
+		return "message type"
+	case *ast.CreatePartitionFunctionStatement:
+		return "partition function"
+	case *ast.CreatePartitionSchemeStatement:
+		return "partition scheme"
+	case *ast.CreateProcedureStatement:
+		return "procedure"
+	case *ast.CreateQueueStatement:
+		return "queue"
+	case *ast.CreateRemoteServiceBindingStatement:
+		return "remote service binding"
+	case *ast.CreateResourcePoolStatement:
+		return "resource pool"
+	case *ast.CreateRoleStatement:
+		return "role"
+	case *ast.CreateRouteStatement:
+		return "route"
+	case *ast.CreateRuleStatement:
+		return "rule"
+	case *ast.CreateSchemaStatement:
+		return "schema"
+	case *ast.CreateSearchPropertyListStatement:
+		return "search property list"
+	case *ast.CreateSecurityPolicyStatement:
+		return "security policy"
+	case *ast.CreateSelectiveXmlIndexStatement:
+		return "selective xml index"
+	case *ast.CreateSequenceStatement:
+		return "sequence"
+	case *ast.CreateServerAuditStatement:
+		return "server audit"
+	case *ast.CreateServerAuditSpecificationStatement:
+		return "server audit specification"
+	case *ast.CreateServerRoleStatement:
+		return "server role"
+	case *ast.CreateServiceStatement:
+		return "service"
+	case *ast.CreateSpatialIndexStatement:
+		return "spatial index"
+	case *ast.CreateStatisticsStatement:
+		return "statistics"
+	case *ast.CreateSymmetricKeyStatement:
+		return "symmetric key"
+	case *ast.CreateSynonymStatement:
+		return "synonym"
+	case *ast.CreateTableStatement:
+		return "table"
+	case *ast.CreateTriggerStatement:
+		return "trigger"
+	case *ast.CreateTypeStatement, *ast.CreateTypeTableStatement, *ast.CreateTypeUddtStatement, *ast.CreateTypeUdtStatement:
+		return "type"
+	case *ast.CreateUserStatement:
+		return "user"
+	case *ast.CreateViewStatement:
+		return "view"
+	case *ast.CreateWorkloadClassifierStatement:
+		return "workload classifier"
+	case *ast.CreateWorkloadGroupStatement:
+		return "workload group"
+	case *ast.CreateXmlIndexStatement:
+		return "xml index"
+	case *ast.CreateXmlSchemaCollectionStatement:
+		return "xml schema collection"
+	}
+	return ""
+}
+
+// dropped returns the kind of object a DROP statement is for, or "" if stmt
+// is not one. DROP INDEX, which names tables too, is checked on its own.
+func dropped(stmt ast.Statement) string {
+	switch stmt.(type) {
+	case *ast.DropAggregateStatement:
+		return "aggregate"
+	case *ast.DropApplicationRoleStatement:
+		return "application role"
+	case *ast.DropAssemblyStatement:
+		return "assembly"
+	case *ast.DropAsymmetricKeyStatement:
+		return "asymmetric key"
+	case *ast.DropAvailabilityGroupStatement:
+		return "availability group"
+	case *ast.DropBrokerPriorityStatement:
+		return "broker priority"
+	case *ast.DropCertificateStatement:
+		return "certificate"
+	case *ast.DropColumnEncryptionKeyStatement:
+		return "column encryption key"
+	case *ast.DropColumnMasterKeyStatement:
+		return "column master key"
+	case *ast.DropContractStatement:
+		return "contract"
+	case *ast.DropCredentialStatement:
+		return "credential"
+	case *ast.DropCryptographicProviderStatement:
+		return "cryptographic provider"
+	case *ast.DropDatabaseStatement:
+		return "database"
+	case *ast.DropDatabaseAuditSpecificationStatement:
+		return "database audit specification"
+	case *ast.DropDatabaseEncryptionKeyStatement:
+		return "database encryption key"
+	case *ast.DropDefaultStatement:
+		return "default"
+	case *ast.DropEndpointStatement:
+		return "endpoint"
+	case *ast.DropEventNotificationStatement:
+		return "event notification"
+	case *ast.DropEventSessionStatement:
+		return "event session"
+	case *ast.DropExternalDataSourceStatement:
+		return "external data source"
+	case *ast.DropExternalFileFormatStatement:
+		return "external file format"
+	case *ast.DropExternalLanguageStatement:
+		return "external language"
+	case *ast.DropExternalLibraryStatement:
+		return "external library"
+	case *ast.DropExternalModelStatement:
+		return "external model"
+	case *ast.DropExternalResourcePoolStatement:
+		return "external resource pool"
+	case *ast.DropExternalTableStatement:
+		return "external table"
+	case *ast.DropFederationStatement:
+		return "federation"
+	case *ast.DropFullTextCatalogStatement:
+		return "full-text catalog"
+	case *ast.DropFulltextIndexStatement:
+		return "full-text index"
+	case *ast.DropFullTextStopListStatement:
+		return "full-text stoplist"
+	case *ast.DropFunctionStatement:
+		return "function"
+	case *ast.DropLoginStatement:
+		return "login"
+	case *ast.DropMasterKeyStatement:
+		return "master key"
+	case *ast.DropMessageTypeStatement:
+		return "message type"
+	case *ast.DropPartitionFunctionStatement:
+		return "partition function"
+	case *ast.DropPartitionSchemeStatement:
+		return "partition scheme"
+	case *ast.DropProcedureStatement:
+		return "procedure"
+	case *ast.DropQueueStatement:
+		return "queue"
+	case *ast.DropRemoteServiceBindingStatement:
+		return "remote service binding"
+	case *ast.DropResourcePoolStatement:
+		return "resource pool"
+	case *ast.DropRoleStatement:
+		return "role"
+	case *ast.DropRouteStatement:
+		return "route"
+	case *ast.DropRuleStatement:
+		return "rule"
+	case *ast.DropSchemaStatement:
+		return "schema"
+	case *ast.DropSearchPropertyListStatement:
+		return "search property list"
+	case *ast.DropSecurityPolicyStatement:
+		return "security policy"
+	case *ast.DropSensitivityClassificationStatement:
+		return "sensitivity classification"
+	case *ast.DropSequenceStatement:
+		return "sequence"
+	case *ast.DropServerAuditStatement:
+		return "server audit"
+	case *ast.DropServerAuditSpecificationStatement:
+		return "server audit specification"
+	case *ast.DropServerRoleStatement:
+		return "server role"
+	case *ast.DropServiceStatement:
+		return "service"
+	case *ast.DropSignatureStatement:
+		return "signature"
+	case *ast.DropStatisticsStatement:
+		return "statistics"
+	case *ast.DropSymmetricKeyStatement:
+		return "symmetric key"
+	case *ast.DropSynonymStatement:
+		return "synonym"
+	case *ast.DropTableStatement:
+		return "table"
+	case *ast.DropTriggerStatement:
+		return "trigger"
+	case *ast.DropTypeStatement:
+		return "type"
+	case *ast.DropUserStatement:
+		return "user"
+	case *ast.DropViewStatement:
+		return "view"
+	case *ast.DropWorkloadClassifierStatement:
+		return "workload classifier"
+	case *ast.DropWorkloadGroupStatement:
+		return "workload group"
+	case *ast.DropXmlSchemaCollectionStatement:
+		return "xml schema collection"
+	}
+	return ""
+}
+
+// objectNames returns the names of the objects a CREATE or DROP statement
+// is for, found in its Objects, Databases, Name, SchemaObjectName, Schema
+// or ProcedureReference field.
+func objectNames(stmt ast.Statement) []catalog.ObjectName {
+	v := reflect.ValueOf(stmt)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return nil
+	}
+	v = v.Elem()
+	for _, field := range []string{"Objects", "Databases", "Name", "SchemaObjectName", "Schema", "ProcedureReference"} {
+		f := v.FieldByName(field)
+		if !f.IsValid() {
+			continue
+		}
+		var names []catalog.ObjectName
+		switch x := f.Interface().(type) {
+		case []*ast.SchemaObjectName:
+			for _, n := range x {
+				names = append(names, catalog.NameOf(n))
+			}
+		case []*ast.Identifier:
+			for _, id := range x {
+				names = append(names, catalog.ObjectName{Name: id.Value})
+			}
+		case *ast.SchemaObjectName:
+			if x != nil {
+				names = append(names, catalog.NameOf(x))
+			}
+		case *ast.Identifier:
+			if x != nil {
+				names = append(names, catalog.ObjectName{Name: x.Value})
+			}
+		case *ast.ProcedureReference:
+			if x != nil && x.Name != nil {
+				names = append(names, catalog.NameOf(x.Name))
+			}
+		}
+		if len(names) > 0 {
+			return names
+		}
+	}
+	return nil
+}
+
+// ifExists reports whether a DROP statement has IF EXISTS.
+func ifExists(stmt ast.Statement) bool {
+	v := reflect.ValueOf(stmt)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return false
+	}
+	f := v.Elem().FieldByName("IsIfExists")
+	return f.IsValid() && f.Kind() == reflect.Bool && f.Bool()
+}
+
+// position returns the position of the first name in stmt.
+func position(stmt ast.Statement) ast.Position {
+	var pos ast.Position
+	ast.Inspect(stmt, func(n ast.Node) bool {
+		if pos.Line > 0 {
+			return false
+		}
+		if id, ok := n.(*ast.Identifier); ok && id.Pos.Line > 0 {
+			pos = id.Pos
+		}
+		return true
+	})
+	return pos
+}
+
+func statements(list *ast.StatementList) []ast.Statement {
+	if list == nil {
+		return nil
+	}
+	return list.Statements
+}
+
+func identifierValue(id *ast.Identifier) string {
+	if id == nil {
+		return ""
+	}
+	return id.Value
+}
@@ -0,0 +1,167 @@
+package migration
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/sqlc-dev/teesql/ast"
+	"github.com/sqlc-dev/teesql/catalog"
+	"github.com/sqlc-dev/teesql/parser"
+)
+
+const schema = `CREATE TABLE dbo.Customers (
+	Id int NOT NULL PRIMARY KEY,
+	Name nvarchar(100) NOT NULL,
+	Code varchar(10) NULL,
+	Balance decimal(10, 2) NOT NULL,
+	Visits smallint NOT NULL,
+	CreatedAt datetime2(3) NOT NULL
+);
+CREATE TABLE dbo.Lookup (Id int NOT NULL);`
+
+func parse(t *testing.T, sql string) *ast.Script {
+	t.Helper()
+	script, err := parser.Parse(context.Background(), strings.NewReader(sql))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return script
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want []string
+	}{
+		{
+			name: "data loss",
+			sql: `DROP TABLE dbo.Lookup;
+DROP VIEW IF EXISTS dbo.V, dbo.W;
+DROP TABLE #scratch;
+TRUNCATE TABLE dbo.Customers;
+ALTER TABLE dbo.Customers DROP COLUMN Code, CONSTRAINT CK_Balance;
+DROP INDEX IX_Name ON dbo.Customers;`,
+			want: []string{
+				"1:12: data loss: drops table dbo.Lookup and all of its rows",
+				"2:21: data loss: drops view dbo.V",
+				"2:21: data loss: drops view dbo.W",
+				"4:16: data loss: deletes every row of dbo.Customers",
+				"5:13: data loss: drops column Code of dbo.Customers and its data",
+				"5:13: data loss: drops constraint CK_Balance on dbo.Customers",
+				"6:12: data loss: drops index IX_Name on dbo.Customers",
+			},
+		},
+		{
+			name: "column narrowing",
+			sql: `ALTER TABLE dbo.Customers ALTER COLUMN Name nvarchar(50) NOT NULL;
+ALTER TABLE dbo.Customers ALTER COLUMN Name nvarchar(max) NOT NULL;
+ALTER TABLE dbo.Customers ALTER COLUMN Code nvarchar(10) NULL;
+ALTER TABLE dbo.Customers ALTER COLUMN Code varchar(5) NULL;
+ALTER TABLE dbo.Customers ALTER COLUMN Balance decimal(12, 2) NOT NULL;
+ALTER TABLE dbo.Customers ALTER COLUMN Balance decimal(12, 1) NOT NULL;
+ALTER TABLE dbo.Customers ALTER COLUMN Visits int NOT NULL;
+ALTER TABLE dbo.Customers ALTER COLUMN Visits tinyint NOT NULL;
+ALTER TABLE dbo.Customers ALTER COLUMN CreatedAt date NOT NULL;`,
+			want: []string{
+				"1:13: data loss: changes column Name of dbo.Customers from nvarchar(100) to nvarchar(50), which cannot hold every existing value",
+				"4:13: data loss: changes column Code of dbo.Customers from nvarchar(10) to varchar(5), which cannot hold every existing value",
+				"6:13: data loss: changes column Balance of dbo.Customers from decimal(12,2) to decimal(12,1), which cannot hold every existing value",
+				"8:13: data loss: changes column Visits of dbo.Customers from int to tinyint, which cannot hold every existing value",
+				"9:13: data loss: changes column CreatedAt of dbo.Customers from datetime2(3) to date, which cannot hold every existing value",
+			},
+		},
+		{
+			name: "blocking",
+			sql: `CREATE INDEX IX_Name ON dbo.Customers (Name);
+CREATE INDEX IX_Code ON dbo.Customers (Code) WITH (ONLINE = ON);
+ALTER INDEX ALL ON dbo.Customers REBUILD;
+ALTER TABLE dbo.Customers ADD
+	Email nvarchar(200) NOT NULL,
+	Phone varchar(20) NULL,
+	Active bit NOT NULL CONSTRAINT DF_Active DEFAULT 1,
+	CONSTRAINT UQ_Email UNIQUE (Email) WITH (ONLINE = ON);
+ALTER TABLE dbo.Lookup ADD CONSTRAINT PK_Lookup PRIMARY KEY (Id);`,
+			want: []string{
+				"1:14: blocking: builds index IX_Name on dbo.Customers without ONLINE = ON, locking the table until it completes",
+				"1:14: non-idempotent: creates index IX_Name on dbo.Customers without an IF NOT EXISTS guard or a preceding DROP ... IF EXISTS",
+				"2:14: non-idempotent: creates index IX_Code on dbo.Customers without an IF NOT EXISTS guard or a preceding DROP ... IF EXISTS",
+				"3:20: blocking: builds index ALL on dbo.Customers without ONLINE = ON, locking the table until it completes",
+				"4:13: blocking: adds NOT NULL column Email to dbo.Customers without a default, which fails if the table has rows",
+				"9:13: blocking: builds index PK_Lookup on dbo.Lookup without ONLINE = ON, locking the table until it completes",
+			},
+		},
+		{
+			name: "new tables",
+			sql: `IF OBJECT_ID('dbo.Orders') IS NULL
+	CREATE TABLE dbo.Orders (Id int NOT NULL);
+ALTER TABLE dbo.Orders ADD CustomerId int NOT NULL;
+DROP INDEX IF EXISTS IX_Customer ON dbo.Orders;
+CREATE INDEX IX_Customer ON dbo.Orders (CustomerId);
+CREATE TABLE dbo.Audit (Id int NOT NULL);
+GO
+CREATE OR ALTER VIEW dbo.OrderCount AS SELECT COUNT(*) AS n FROM dbo.Orders;
+GO
+DROP PROCEDURE IF EXISTS dbo.GetOrders;
+GO
+CREATE PROCEDURE dbo.GetOrders AS SELECT Id FROM dbo.Orders;`,
+			want: []string{
+				"4:22: data loss: drops index IX_Customer on dbo.Orders",
+				"6:14: non-idempotent: creates table dbo.Audit without an IF NOT EXISTS guard or a preceding DROP ... IF EXISTS",
+				"10:26: data loss: drops procedure dbo.GetOrders",
+			},
+		},
+		{
+			name: "batch-first objects",
+			sql: `CREATE VIEW dbo.Names AS SELECT Name FROM dbo.Customers;
+GO
+CREATE TRIGGER dbo.trCustomers ON dbo.Customers AFTER INSERT AS SELECT 1;
+GO
+CREATE OR ALTER FUNCTION dbo.One() RETURNS int AS BEGIN RETURN 1 END;`,
+			want: []string{
+				"1:13: non-idempotent: creates view dbo.Names without a preceding DROP ... IF EXISTS; use CREATE OR ALTER",
+				"3:16: non-idempotent: creates trigger dbo.trCustomers without a preceding DROP ... IF EXISTS; use CREATE OR ALTER",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := Check(parse(t, tt.sql), Options{Schema: []*ast.Script{parse(t, schema)}})
+			var got []string
+			for _, s := range report.Risky() {
+				for _, r := range s.Risks {
+					got = append(got, s.Pos.String()+": "+r.String())
+				}
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestReport(t *testing.T) {
+	sql := `CREATE INDEX IX_Name ON dbo.Customers (Name);
+IF NOT EXISTS (SELECT * FROM sys.indexes WHERE name = 'IX_Code')
+	CREATE INDEX IX_Code ON dbo.Lookup (Id);`
+	small := func(table catalog.ObjectName) bool { return table.Name != "Lookup" }
+	report := Check(parse(t, sql), Options{Large: small})
+	got, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"Statements":[` +
+		`{"Batch":1,"Index":1,"Pos":{"Offset":13,"Line":1,"Column":14},"Type":"CreateIndexStatement","Risks":[` +
+		`{"Category":"blocking","Object":"dbo.Customers.IX_Name","Message":"builds index IX_Name on dbo.Customers without ONLINE = ON, locking the table until it completes"},` +
+		`{"Category":"non-idempotent","Object":"dbo.Customers.IX_Name","Message":"creates index IX_Name on dbo.Customers without an IF NOT EXISTS guard or a preceding DROP ... IF EXISTS"}]},` +
+		`{"Batch":1,"Index":2,"Pos":{"Offset":75,"Line":2,"Column":30},"Type":"IfStatement"},` +
+		`{"Batch":1,"Index":2,"Pos":{"Offset":125,"Line":3,"Column":15},"Type":"CreateIndexStatement"}]}`
+	if string(got) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if n := report.Count(Blocking); n != 1 {
+		t.Errorf("Count(Blocking) = %d, want 1", n)
+	}
+}
@@ -0,0 +1,80 @@
+package migration
+
+import "github.com/sqlc-dev/teesql/catalog"
+
+// integerDigits holds the rank of each integer type and the number of
+// decimal digits its values need.
+var integerDigits = map[string]struct{ rank, digits int }{
+	"bit":      {0, 1},
+	"tinyint":  {1, 3},
+	"smallint": {2, 5},
+	"int":      {3, 10},
+	"bigint":   {4, 19},
+}
+
+// widenings lists the changes between different types that keep every
+// value, other than those between sizes of the same family.
+var widenings = map[[2]string]bool{
+	{"real", "float"}:              true,
+	{"smallmoney", "money"}:        true,
+	{"smalldatetime", "datetime"}:  true,
+	{"text", "varchar"}:            true,
+	{"ntext", "nvarchar"}:          true,
+	{"image", "varbinary"}:         true,
+	{"date", "datetime2"}:          true,
+	{"smalldatetime", "datetime2"}: true,
+	{"datetime", "datetime2"}:      true,
+}
+
+// widens reports whether every value of type from can be stored in type to.
+func widens(from, to catalog.Type) bool {
+	if from == to {
+		return true
+	}
+	if f, ok := integerDigits[from.Name]; ok {
+		if t, ok := integerDigits[to.Name]; ok {
+			return t.rank >= f.rank
+		}
+		if to.Name == "decimal" || to.Name == "numeric" {
+			return to.Precision-to.Scale >= f.digits
+		}
+		return false
+	}
+	switch from.Name {
+	case "char", "varchar", "nchar", "nvarchar":
+		switch to.Name {
+		case "nchar", "nvarchar":
+		case "char", "varchar":
+			// Unicode characters do not all have a code page equivalent.
+			if from.Name == "nchar" || from.Name == "nvarchar" {
+				return false
+			}
+		default:
+			return false
+		}
+		return longer(from.Length, to.Length)
+	case "binary", "varbinary":
+		return (to.Name == "binary" || to.Name == "varbinary") && longer(from.Length, to.Length)
+	case "decimal", "numeric":
+		return (to.Name == "decimal" || to.Name == "numeric") &&
+			to.Scale >= from.Scale && to.Precision-to.Scale >= from.Precision-from.Scale
+	case "float", "real":
+		return to.Name == "float" && to.Precision >= from.Precision
+	case "time", "datetime2", "datetimeoffset":
+		return to.Name == from.Name && to.Scale >= from.Scale
+	case "text", "ntext", "image":
+		return widenings[[2]string{from.Name, to.Name}] && to.Length == catalog.MaxLength
+	case "datetime":
+		// datetime has a precision of 1/300 second.
+		return to.Name == "datetime2" && to.Scale >= 3
+	}
+	return widenings[[2]string{from.Name, to.Name}]
+}
+
+// longer reports whether a length to is at least the length from.
+func longer(from, to int) bool {
+	if to == catalog.MaxLength {
+		return true
+	}
+	return from != catalog.MaxLength && to >= from
+}
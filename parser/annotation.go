@@ -0,0 +1,54 @@
+package parser
+
+import (
+	"strings"
+
+	"github.com/sqlc-dev/teesql/ast"
+)
+
+// annotation returns the query annotation among the comments before the
+// statement that starts at offset start and has just been parsed, such as
+// "-- name: GetUser :one". If there are several, the last one applies.
+func (p *Parser) annotation(comments []Comment, start int) *ast.QueryAnnotation {
+	for i := len(comments) - 1; i >= 0; i-- {
+		if a := parseAnnotation(comments[i].Text); a != nil {
+			a.Pos = p.lexer.Position(comments[i].Pos)
+			a.TextPos = p.lexer.Position(start)
+			a.Text = p.sourceSince(start)
+			return a
+		}
+	}
+	return nil
+}
+
+// sourceSince returns the source text from offset start to the end of the
+// last token read, excluding comments before the current token.
+func (p *Parser) sourceSince(start int) string {
+	end := p.curTok.Pos
+	if len(p.curTok.Comments) > 0 {
+		end = p.curTok.Comments[0].Pos
+	}
+	input := p.lexer.input
+	end = min(end, len(input))
+	if start >= end {
+		return ""
+	}
+	return strings.TrimSpace(input[start:end])
+}
+
+func parseAnnotation(text string) *ast.QueryAnnotation {
+	text = strings.TrimSpace(strings.TrimPrefix(text, "--"))
+	rest, ok := strings.CutPrefix(text, "name:")
+	if !ok {
+		return nil
+	}
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return nil
+	}
+	a := &ast.QueryAnnotation{Name: fields[0]}
+	if len(fields) > 1 && strings.HasPrefix(fields[1], ":") {
+		a.Command = fields[1][1:]
+	}
+	return a
+}
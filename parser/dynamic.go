@@ -0,0 +1,120 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sqlc-dev/teesql/ast"
+)
+
+// dynamicSQL returns the SQL text that entity runs, parsed with the options
+// of p, or nil if entity does not run SQL text.
+func (p *Parser) dynamicSQL(entity ast.ExecutableEntity) *ast.DynamicSQL {
+	switch e := entity.(type) {
+	case *ast.ExecutableStringList:
+		return p.parseDynamic(e.Strings...)
+	case *ast.ExecutableProcedureReference:
+		stmt, params, ok := ExecuteSQLArguments(e)
+		if !ok || stmt == nil {
+			return nil
+		}
+		d := p.parseDynamic(stmt)
+		if text, ok := constantString(params); ok {
+			var err error
+			d.Parameters, err = p.parseParameterDeclarations(text)
+			if d.Err == nil {
+				d.Err = err
+			}
+		}
+		return d
+	}
+	return nil
+}
+
+// parseDynamic parses the concatenation of exprs if it is constant.
+func (p *Parser) parseDynamic(exprs ...ast.ScalarExpression) *ast.DynamicSQL {
+	d := &ast.DynamicSQL{Constant: true}
+	var text strings.Builder
+	for _, e := range exprs {
+		s, ok := constantString(e)
+		if !ok {
+			return &ast.DynamicSQL{}
+		}
+		text.WriteString(s)
+	}
+	d.Text = text.String()
+	if strings.TrimSpace(d.Text) == "" {
+		d.Script = &ast.Script{}
+		return d
+	}
+	nested := newParser(d.Text)
+	nested.opts = p.opts
+	d.Script, d.Err = nested.parseScript()
+	return d
+}
+
+// parseParameterDeclarations parses the @params argument of sp_executesql,
+// such as "@id int, @name nvarchar(50) OUTPUT".
+func (p *Parser) parseParameterDeclarations(text string) ([]*ast.ProcedureParameter, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, nil
+	}
+	nested := newParser(text)
+	nested.opts = p.opts
+	params, err := nested.parseProcedureParameters()
+	if err != nil {
+		return nil, err
+	}
+	if nested.curTok.Type != TokenEOF {
+		return nil, fmt.Errorf("unexpected %q in parameter declarations", nested.curTok.Literal)
+	}
+	return params, nil
+}
+
+// constantString returns the value of e if it is a string literal or a
+// concatenation of string literals.
+func constantString(e ast.ScalarExpression) (string, bool) {
+	switch e := e.(type) {
+	case *ast.StringLiteral:
+		return e.Value, true
+	case *ast.ParenthesisExpression:
+		return constantString(e.Expression)
+	case *ast.BinaryExpression:
+		if e.BinaryExpressionType != "Add" {
+			return "", false
+		}
+		first, ok := constantString(e.FirstExpression)
+		if !ok {
+			return "", false
+		}
+		second, ok := constantString(e.SecondExpression)
+		if !ok {
+			return "", false
+		}
+		return first + second, true
+	}
+	return "", false
+}
+
+// ExecuteSQLArguments reports whether ref calls sp_executesql, and returns
+// the arguments that hold its statement and its parameter declarations: the
+// first two, or the ones named @stmt and @params. Either is nil if it is not
+// given.
+func ExecuteSQLArguments(ref *ast.ExecutableProcedureReference) (stmt, params ast.ScalarExpression, ok bool) {
+	if ref.ProcedureReference == nil || ref.ProcedureReference.ProcedureReference == nil {
+		return nil, nil, false
+	}
+	name := ref.ProcedureReference.ProcedureReference.Name
+	if name == nil || name.BaseIdentifier == nil || !strings.EqualFold(name.BaseIdentifier.Value, "sp_executesql") {
+		return nil, nil, false
+	}
+	for i, arg := range ref.Parameters {
+		switch {
+		case arg.Variable == nil && i == 0, arg.Variable != nil && strings.EqualFold(arg.Variable.Name, "@stmt"):
+			stmt = arg.ParameterValue
+		case arg.Variable == nil && i == 1, arg.Variable != nil && strings.EqualFold(arg.Variable.Name, "@params"):
+			params = arg.ParameterValue
+		}
+	}
+	return stmt, params, true
+}
@@ -2,9 +2,12 @@ package parser
 
 import (
 	"encoding/binary"
+	"sort"
 	"strings"
 	"unicode"
 	"unicode/utf16"
+
+	"github.com/sqlc-dev/teesql/ast"
 )
 
 // TokenType represents the type of a token.
@@ -234,6 +237,15 @@ type Token struct {
 	Type    TokenType
 	Literal string
 	Pos     int
+	// Comments are the line comments between the previous token and this
+	// one.
+	Comments []Comment
+}
+
+// Comment is a line comment. Text includes the leading "--".
+type Comment struct {
+	Text string
+	Pos  int
 }
 
 // Lexer tokenizes T-SQL input.
@@ -242,6 +254,9 @@ type Lexer struct {
 	pos     int
 	readPos int
 	ch      byte
+
+	lines    []int // offsets of line starts, computed on first use
+	comments []Comment
 }
 
 // NewLexer creates a new Lexer for the given input.
@@ -274,6 +289,20 @@ func utf16LEToUTF8(data string) string {
 	return string(runes)
 }
 
+// Position returns the line and column of a byte offset in the input.
+func (l *Lexer) Position(offset int) ast.Position {
+	if l.lines == nil {
+		l.lines = []int{0}
+		for i := 0; i < len(l.input); i++ {
+			if l.input[i] == '\n' {
+				l.lines = append(l.lines, i+1)
+			}
+		}
+	}
+	line := sort.Search(len(l.lines), func(i int) bool { return l.lines[i] > offset })
+	return ast.Position{Offset: offset, Line: line, Column: offset - l.lines[line-1] + 1}
+}
+
 func (l *Lexer) readChar() {
 	if l.readPos >= len(l.input) {
 		l.ch = 0
@@ -293,6 +322,13 @@ func (l *Lexer) peekChar() byte {
 
 // NextToken returns the next token from the input.
 func (l *Lexer) NextToken() Token {
+	tok := l.nextToken()
+	tok.Comments = l.comments
+	l.comments = nil
+	return tok
+}
+
+func (l *Lexer) nextToken() Token {
 	l.skipWhitespaceAndComments()
 
 	tok := Token{Pos: l.pos}
@@ -604,9 +640,11 @@ func (l *Lexer) skipWhitespaceAndComments() {
 
 		// Skip line comments (-- ...)
 		if l.ch == '-' && l.peekChar() == '-' {
+			start := l.pos
 			for l.ch != 0 && l.ch != '\n' {
 				l.readChar()
 			}
+			l.comments = append(l.comments, Comment{Text: strings.TrimRight(l.input[start:l.pos], "\r"), Pos: start})
 			continue
 		}
 
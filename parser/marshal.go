@@ -17230,6 +17230,9 @@ func stringLiteralToJSON(s *ast.StringLiteral) jsonNode {
 	node["IsLargeObject"] = s.IsLargeObject
 	// Always include Value for StringLiteral, even if empty
 	node["Value"] = s.Value
+	if s.Collation != nil {
+		node["Collation"] = identifierToJSON(s.Collation)
+	}
 	return node
 }
 
@@ -316,10 +316,11 @@ func (p *Parser) parseInsertSpecification() (*ast.InsertSpecification, error) {
 func (p *Parser) parseDMLTarget() (ast.TableReference, error) {
 	// Check for variable
 	if p.curTok.Type == TokenIdent && strings.HasPrefix(p.curTok.Literal, "@") {
+		namePos := p.pos()
 		name := p.curTok.Literal
 		p.nextToken()
 		return &ast.VariableTableReference{
-			Variable: &ast.VariableReference{Name: name},
+			Variable: &ast.VariableReference{Name: name, Pos: namePos},
 			ForPath:  false,
 		}, nil
 	}
@@ -373,10 +374,11 @@ func (p *Parser) parseDMLTarget() (ast.TableReference, error) {
 func (p *Parser) parseInsertTarget() (ast.TableReference, error) {
 	// Check for variable
 	if p.curTok.Type == TokenIdent && strings.HasPrefix(p.curTok.Literal, "@") {
+		namePos := p.pos()
 		name := p.curTok.Literal
 		p.nextToken()
 		return &ast.VariableTableReference{
-			Variable: &ast.VariableReference{Name: name},
+			Variable: &ast.VariableReference{Name: name, Pos: namePos},
 			ForPath:  false,
 		}, nil
 	}
@@ -496,7 +498,7 @@ func (p *Parser) parseOpenRowset() (ast.TableReference, error) {
 	if p.curTok.Type != TokenIdent {
 		return nil, fmt.Errorf("expected identifier in OPENROWSET, got %s", p.curTok.Literal)
 	}
-	id := &ast.Identifier{Value: p.curTok.Literal, QuoteType: "NotQuoted"}
+	id := &ast.Identifier{Value: p.curTok.Literal, QuoteType: "NotQuoted", Pos: p.pos()}
 	p.nextToken()
 
 	var varArgs []ast.ScalarExpression
@@ -1330,16 +1332,17 @@ func (p *Parser) parseExecuteSpecification() (*ast.ExecuteSpecification, error)
 
 	// Check for return variable assignment @var =
 	if p.curTok.Type == TokenIdent && strings.HasPrefix(p.curTok.Literal, "@") {
+		varNamePos := p.pos()
 		varName := p.curTok.Literal
 		p.nextToken()
 		if p.curTok.Type == TokenEquals {
-			spec.Variable = &ast.VariableReference{Name: varName}
+			spec.Variable = &ast.VariableReference{Name: varName, Pos: varNamePos}
 			p.nextToken()
 		} else {
 			// It's actually the procedure variable
 			spec.ExecutableEntity = &ast.ExecutableProcedureReference{
 				ProcedureReference: &ast.ProcedureReferenceName{
-					ProcedureVariable: &ast.VariableReference{Name: varName},
+					ProcedureVariable: &ast.VariableReference{Name: varName, Pos: varNamePos},
 				},
 			}
 			return spec, nil
@@ -1395,7 +1398,7 @@ func (p *Parser) parseExecuteSpecification() (*ast.ExecuteSpecification, error)
 	if p.curTok.Type == TokenIdent && strings.HasPrefix(p.curTok.Literal, "@") {
 		// Procedure variable
 		procRef.ProcedureReference = &ast.ProcedureReferenceName{
-			ProcedureVariable: &ast.VariableReference{Name: p.curTok.Literal},
+			ProcedureVariable: &ast.VariableReference{Name: p.curTok.Literal, Pos: p.pos()},
 		}
 		p.nextToken()
 	} else if p.curTok.Type != TokenEOF && p.curTok.Type != TokenSemicolon {
@@ -1565,13 +1568,14 @@ func (p *Parser) parseExecuteParameter() (*ast.ExecuteParameter, error) {
 
 	// Check for named parameter: @name = value
 	if p.curTok.Type == TokenIdent && strings.HasPrefix(p.curTok.Literal, "@") {
+		varNamePos := p.pos()
 		varName := p.curTok.Literal
 		p.nextToken()
 
 		if p.curTok.Type == TokenEquals {
 			// Named parameter
 			p.nextToken() // consume =
-			param.Variable = &ast.VariableReference{Name: varName}
+			param.Variable = &ast.VariableReference{Name: varName, Pos: varNamePos}
 
 			// Check for DEFAULT keyword as value
 			if strings.ToUpper(p.curTok.Literal) == "DEFAULT" {
@@ -1587,7 +1591,7 @@ func (p *Parser) parseExecuteParameter() (*ast.ExecuteParameter, error) {
 			}
 		} else {
 			// Just a variable as value (not a named parameter)
-			param.ParameterValue = &ast.VariableReference{Name: varName}
+			param.ParameterValue = &ast.VariableReference{Name: varName, Pos: varNamePos}
 		}
 	} else {
 		// Check for bare identifier as IdentifierLiteral (e.g., EXEC sp_addtype birthday, datetime)
@@ -1968,11 +1972,12 @@ func (p *Parser) parseAssignmentSetClause() (*ast.AssignmentSetClause, error) {
 
 	// Could be @var = col = value, @var = value, @var ||= value, or col = value, col ||= value
 	if p.curTok.Type == TokenIdent && strings.HasPrefix(p.curTok.Literal, "@") {
+		varNamePos := p.pos()
 		varName := p.curTok.Literal
 		p.nextToken()
 		if p.isCompoundAssignment() {
 			clause.AssignmentKind = p.getAssignmentKind()
-			clause.Variable = &ast.VariableReference{Name: varName}
+			clause.Variable = &ast.VariableReference{Name: varName, Pos: varNamePos}
 			p.nextToken()
 
 			// Check if next is column = value or column ||= value (SET @a = col = value)
@@ -2895,10 +2900,11 @@ func (p *Parser) parseOutputClause() (*ast.OutputClause, *ast.OutputIntoClause,
 		// Parse target table (variable or table name)
 		var intoTable ast.TableReference
 		if p.curTok.Type == TokenIdent && strings.HasPrefix(p.curTok.Literal, "@") {
+			namePos := p.pos()
 			name := p.curTok.Literal
 			p.nextToken()
 			intoTable = &ast.VariableTableReference{
-				Variable: &ast.VariableReference{Name: name},
+				Variable: &ast.VariableReference{Name: name, Pos: namePos},
 				ForPath:  false,
 			}
 		} else {
@@ -493,13 +493,14 @@ func (p *Parser) parseSelectElement() (ast.SelectElement, error) {
 
 	// Check for variable assignment: @var = expr or @var ||= expr
 	if p.curTok.Type == TokenIdent && strings.HasPrefix(p.curTok.Literal, "@") {
+		varNamePos := p.pos()
 		varName := p.curTok.Literal
 		p.nextToken() // consume variable
 
 		// Check if this is an assignment
 		if p.isCompoundAssignment() {
 			ssv := &ast.SelectSetVariable{
-				Variable:       &ast.VariableReference{Name: varName},
+				Variable:       &ast.VariableReference{Name: varName, Pos: varNamePos},
 				AssignmentKind: p.getAssignmentKind(),
 			}
 			p.nextToken() // consume assignment operator
@@ -517,7 +518,7 @@ func (p *Parser) parseSelectElement() (ast.SelectElement, error) {
 		if strings.HasPrefix(varName, "@@") {
 			varExpr = &ast.GlobalVariableExpression{Name: varName}
 		} else {
-			varExpr = &ast.VariableReference{Name: varName}
+			varExpr = &ast.VariableReference{Name: varName, Pos: varNamePos}
 		}
 
 		// Handle postfix operations (method calls, property access)
@@ -759,6 +760,7 @@ func (p *Parser) parseIdentifier() *ast.Identifier {
 	id := &ast.Identifier{
 		Value:     literal,
 		QuoteType: quoteType,
+		Pos:       p.pos(),
 	}
 	p.nextToken()
 	return id
@@ -1212,9 +1214,10 @@ func (p *Parser) parsePrimaryExpression() (ast.ScalarExpression, error) {
 		}
 		// Check if it's a variable reference (starts with @)
 		if strings.HasPrefix(p.curTok.Literal, "@") {
+			namePos := p.pos()
 			name := p.curTok.Literal
 			p.nextToken()
-			return &ast.VariableReference{Name: name}, nil
+			return &ast.VariableReference{Name: name, Pos: namePos}, nil
 		}
 		// Check for N-prefixed national string (N'...')
 		if strings.ToUpper(p.curTok.Literal) == "N" && p.peekTok.Type == TokenString {
@@ -1328,10 +1331,23 @@ func (p *Parser) parsePrimaryExpression() (ast.ScalarExpression, error) {
 		val := p.curTok.Literal
 		p.nextToken()
 		return &ast.BinaryLiteral{LiteralType: "Binary", Value: val, IsLargeObject: false}, nil
-	case TokenString:
-		return p.parseStringLiteral()
-	case TokenNationalString:
-		return p.parseNationalStringFromToken()
+	case TokenString, TokenNationalString:
+		var lit *ast.StringLiteral
+		var err error
+		if p.curTok.Type == TokenString {
+			lit, err = p.parseStringLiteral()
+		} else {
+			lit, err = p.parseNationalStringFromToken()
+		}
+		if err != nil {
+			return nil, err
+		}
+		// Check for optional COLLATE clause
+		if strings.ToUpper(p.curTok.Literal) == "COLLATE" {
+			p.nextToken() // consume COLLATE
+			lit.Collation = p.parseIdentifier()
+		}
+		return lit, nil
 	case TokenLBrace:
 		return p.parseOdbcLiteral()
 	case TokenLParen:
@@ -1904,6 +1920,7 @@ func (p *Parser) parseColumnReferenceOrFunctionCall() (ast.ScalarExpression, err
 		id := &ast.Identifier{
 			Value:     literal,
 			QuoteType: quoteType,
+			Pos:       p.pos(),
 		}
 		identifiers = append(identifiers, id)
 		p.nextToken()
@@ -2143,6 +2160,7 @@ func (p *Parser) parseColumnReferenceWithLeadingDots() (ast.ScalarExpression, er
 		id := &ast.Identifier{
 			Value:     literal,
 			QuoteType: quoteType,
+			Pos:       p.pos(),
 		}
 		identifiers = append(identifiers, id)
 		p.nextToken()
@@ -2363,7 +2381,7 @@ func (p *Parser) parsePostExpressionAccess(expr ast.ScalarExpression) (ast.Scala
 			if p.curTok.Type != TokenIdent {
 				return nil, fmt.Errorf("expected property name after ., got %s", p.curTok.Literal)
 			}
-			propName := &ast.Identifier{Value: p.curTok.Literal, QuoteType: "NotQuoted"}
+			propName := &ast.Identifier{Value: p.curTok.Literal, QuoteType: "NotQuoted", Pos: p.pos()}
 			p.nextToken()
 
 			// Check if it's a method call: .method()
@@ -2855,6 +2873,7 @@ func (p *Parser) parseSingleTableReference() (ast.TableReference, error) {
 
 	// Check for variable table reference or variable method call
 	if p.curTok.Type == TokenIdent && strings.HasPrefix(p.curTok.Literal, "@") {
+		namePos := p.pos()
 		name := p.curTok.Literal
 		p.nextToken()
 
@@ -2903,7 +2922,7 @@ func (p *Parser) parseSingleTableReference() (ast.TableReference, error) {
 			}
 
 			return &ast.VariableMethodCallTableReference{
-				Variable:   &ast.VariableReference{Name: name},
+				Variable:   &ast.VariableReference{Name: name, Pos: namePos},
 				MethodName: methodName,
 				Parameters: params,
 				Alias:      alias,
@@ -2914,7 +2933,7 @@ func (p *Parser) parseSingleTableReference() (ast.TableReference, error) {
 
 		// Parse optional alias for variable table reference
 		varRef := &ast.VariableTableReference{
-			Variable: &ast.VariableReference{Name: name},
+			Variable: &ast.VariableReference{Name: name, Pos: namePos},
 			ForPath:  false,
 		}
 		if p.curTok.Type == TokenAs {
@@ -4241,7 +4260,7 @@ func (p *Parser) parseTemporalTimeValue() (ast.ScalarExpression, error) {
 		return lit, nil
 	}
 	if p.curTok.Type == TokenIdent && strings.HasPrefix(p.curTok.Literal, "@") {
-		varRef := &ast.VariableReference{Name: p.curTok.Literal}
+		varRef := &ast.VariableReference{Name: p.curTok.Literal, Pos: p.pos()}
 		p.nextToken()
 		return varRef, nil
 	}
@@ -7118,7 +7137,7 @@ func (p *Parser) parsePredictTableReference() (*ast.PredictTableReference, error
 				ref.ModelVariable = &ast.ScalarSubquery{QueryExpression: qe}
 			} else if p.curTok.Type == TokenIdent && strings.HasPrefix(p.curTok.Literal, "@") {
 				// Variable
-				ref.ModelVariable = &ast.VariableReference{Name: p.curTok.Literal}
+				ref.ModelVariable = &ast.VariableReference{Name: p.curTok.Literal, Pos: p.pos()}
 				p.nextToken()
 			}
 		case "DATA":
@@ -35,7 +35,7 @@ func (p *Parser) parseDeclareVariableStatement() (ast.Statement, error) {
 	if p.curTok.Type != TokenIdent || !strings.HasPrefix(p.curTok.Literal, "@") {
 		return nil, fmt.Errorf("expected variable name, got %s", p.curTok.Literal)
 	}
-	varName := &ast.Identifier{Value: p.curTok.Literal, QuoteType: "NotQuoted"}
+	varName := &ast.Identifier{Value: p.curTok.Literal, QuoteType: "NotQuoted", Pos: p.pos()}
 	p.nextToken()
 
 	// Skip optional AS
@@ -841,7 +841,7 @@ func (p *Parser) parseDeclareVariableElement() (*ast.DeclareVariableElement, err
 	if p.curTok.Type != TokenIdent || !strings.HasPrefix(p.curTok.Literal, "@") {
 		return nil, fmt.Errorf("expected variable name, got %s", p.curTok.Literal)
 	}
-	elem.VariableName = &ast.Identifier{Value: p.curTok.Literal, QuoteType: "NotQuoted"}
+	elem.VariableName = &ast.Identifier{Value: p.curTok.Literal, QuoteType: "NotQuoted", Pos: p.pos()}
 	p.nextToken()
 
 	// Skip optional AS
@@ -1292,7 +1292,7 @@ func (p *Parser) parseSetVariableStatement() (ast.Statement, error) {
 			p.nextToken() // consume ROWCOUNT
 			var numRows ast.ScalarExpression
 			if strings.HasPrefix(p.curTok.Literal, "@") {
-				numRows = &ast.VariableReference{Name: p.curTok.Literal}
+				numRows = &ast.VariableReference{Name: p.curTok.Literal, Pos: p.pos()}
 				p.nextToken()
 			} else {
 				numRows = &ast.IntegerLiteral{LiteralType: "Integer", Value: p.curTok.Literal}
@@ -1355,7 +1355,7 @@ func (p *Parser) parseSetVariableStatement() (ast.Statement, error) {
 	if p.curTok.Type != TokenIdent || !strings.HasPrefix(p.curTok.Literal, "@") {
 		return nil, fmt.Errorf("expected variable name, got %s", p.curTok.Literal)
 	}
-	stmt.Variable = &ast.VariableReference{Name: p.curTok.Literal}
+	stmt.Variable = &ast.VariableReference{Name: p.curTok.Literal, Pos: p.pos()}
 	p.nextToken()
 
 	// Check for dot or double-colon separator (SET @a.b = ... or SET @a::b ...)
@@ -1363,14 +1363,14 @@ func (p *Parser) parseSetVariableStatement() (ast.Statement, error) {
 		stmt.SeparatorType = "Dot"
 		p.nextToken()
 		if p.curTok.Type == TokenIdent {
-			stmt.Identifier = &ast.Identifier{Value: p.curTok.Literal, QuoteType: "NotQuoted"}
+			stmt.Identifier = &ast.Identifier{Value: p.curTok.Literal, QuoteType: "NotQuoted", Pos: p.pos()}
 			p.nextToken()
 		}
 	} else if p.curTok.Type == TokenColonColon {
 		stmt.SeparatorType = "DoubleColon"
 		p.nextToken() // consume ::
 		if p.curTok.Type == TokenIdent {
-			stmt.Identifier = &ast.Identifier{Value: p.curTok.Literal, QuoteType: "NotQuoted"}
+			stmt.Identifier = &ast.Identifier{Value: p.curTok.Literal, QuoteType: "NotQuoted", Pos: p.pos()}
 			p.nextToken()
 		}
 	}
@@ -1864,7 +1864,7 @@ func (p *Parser) parseSetCommand(optName string) (ast.SetCommand, error) {
 func (p *Parser) parseSetCommandParameter() (ast.ScalarExpression, error) {
 	if strings.HasPrefix(p.curTok.Literal, "@") {
 		// Variable reference
-		v := &ast.VariableReference{Name: p.curTok.Literal}
+		v := &ast.VariableReference{Name: p.curTok.Literal, Pos: p.pos()}
 		p.nextToken()
 		return v, nil
 	} else if p.curTok.Type == TokenString {
@@ -2049,13 +2049,14 @@ func (p *Parser) parseWhileStatement() (*ast.WhileStatement, error) {
 
 func (p *Parser) parseBeginStatement() (ast.Statement, error) {
 	// Peek at what follows BEGIN
+	pos := p.pos()
 	p.nextToken() // consume BEGIN
 
 	switch p.curTok.Type {
 	case TokenTransaction, TokenTran:
-		return p.parseBeginTransactionStatementContinued(false)
+		return p.parseBeginTransactionStatementContinued(pos, false)
 	case TokenTry:
-		return p.parseTryCatchStatement()
+		return p.parseTryCatchStatement(pos)
 	case TokenDialog:
 		return p.parseBeginDialogStatement()
 	case TokenConversation:
@@ -2065,7 +2066,7 @@ func (p *Parser) parseBeginStatement() (ast.Statement, error) {
 		if strings.ToUpper(p.curTok.Literal) == "DISTRIBUTED" {
 			p.nextToken() // consume DISTRIBUTED
 			if p.curTok.Type == TokenTransaction || p.curTok.Type == TokenTran {
-				return p.parseBeginTransactionStatementContinued(true)
+				return p.parseBeginTransactionStatementContinued(pos, true)
 			}
 			return nil, fmt.Errorf("expected TRANSACTION after DISTRIBUTED, got %s", p.curTok.Literal)
 		}
@@ -2248,12 +2249,13 @@ func (p *Parser) parseBeginAtomicBlockStatement() (*ast.BeginEndAtomicBlockState
 	return stmt, nil
 }
 
-func (p *Parser) parseBeginTransactionStatementContinued(distributed bool) (*ast.BeginTransactionStatement, error) {
+func (p *Parser) parseBeginTransactionStatementContinued(pos ast.Position, distributed bool) (*ast.BeginTransactionStatement, error) {
 	// TRANSACTION or TRAN already consumed by caller
 	p.nextToken()
 
 	stmt := &ast.BeginTransactionStatement{
 		Distributed: distributed,
+		Pos:         pos,
 	}
 
 	// Optional transaction name or variable - check for variable first
@@ -2301,12 +2303,13 @@ func (p *Parser) parseBeginTransactionStatementContinued(distributed bool) (*ast
 	return stmt, nil
 }
 
-func (p *Parser) parseTryCatchStatement() (*ast.TryCatchStatement, error) {
+func (p *Parser) parseTryCatchStatement(pos ast.Position) (*ast.TryCatchStatement, error) {
 	// TRY already seen, consume it
 	p.nextToken()
 
 	stmt := &ast.TryCatchStatement{
 		TryStatements: &ast.StatementList{},
+		Pos:           pos,
 	}
 
 	// Parse statements until END TRY
@@ -2351,6 +2354,7 @@ func (p *Parser) parseTryCatchStatement() (*ast.TryCatchStatement, error) {
 
 	// Expect BEGIN CATCH
 	if p.curTok.Type == TokenBegin {
+		stmt.CatchPos = p.pos()
 		p.nextToken() // consume BEGIN
 		if p.curTok.Type == TokenCatch {
 			p.nextToken() // consume CATCH
@@ -2465,7 +2469,7 @@ func (p *Parser) parseBeginDialogStatement() (*ast.BeginDialogStatement, error)
 
 	// Parse dialog handle (variable reference)
 	if p.curTok.Type == TokenIdent && len(p.curTok.Literal) > 0 && p.curTok.Literal[0] == '@' {
-		stmt.Handle = &ast.VariableReference{Name: p.curTok.Literal}
+		stmt.Handle = &ast.VariableReference{Name: p.curTok.Literal, Pos: p.pos()}
 		p.nextToken()
 	} else {
 		return nil, fmt.Errorf("expected variable for dialog handle")
@@ -2508,7 +2512,7 @@ func (p *Parser) parseBeginDialogStatement() (*ast.BeginDialogStatement, error)
 		}
 		stmt.TargetServiceName = strLit
 	} else if p.curTok.Type == TokenIdent && len(p.curTok.Literal) > 0 && p.curTok.Literal[0] == '@' {
-		stmt.TargetServiceName = &ast.VariableReference{Name: p.curTok.Literal}
+		stmt.TargetServiceName = &ast.VariableReference{Name: p.curTok.Literal, Pos: p.pos()}
 		p.nextToken()
 	} else {
 		return nil, fmt.Errorf("expected string literal or variable for target service name")
@@ -2524,7 +2528,7 @@ func (p *Parser) parseBeginDialogStatement() (*ast.BeginDialogStatement, error)
 			}
 			stmt.InstanceSpec = strLit
 		} else if p.curTok.Type == TokenIdent && len(p.curTok.Literal) > 0 && p.curTok.Literal[0] == '@' {
-			stmt.InstanceSpec = &ast.VariableReference{Name: p.curTok.Literal}
+			stmt.InstanceSpec = &ast.VariableReference{Name: p.curTok.Literal, Pos: p.pos()}
 			p.nextToken()
 		}
 	}
@@ -2555,7 +2559,7 @@ func (p *Parser) parseBeginDialogStatement() (*ast.BeginDialogStatement, error)
 			case "RELATED_CONVERSATION":
 				if p.curTok.Type == TokenIdent && len(p.curTok.Literal) > 0 && p.curTok.Literal[0] == '@' {
 					stmt.Options = append(stmt.Options, &ast.ScalarExpressionDialogOption{
-						Value:      &ast.VariableReference{Name: p.curTok.Literal},
+						Value:      &ast.VariableReference{Name: p.curTok.Literal, Pos: p.pos()},
 						OptionKind: "RelatedConversation",
 					})
 					p.nextToken()
@@ -2563,7 +2567,7 @@ func (p *Parser) parseBeginDialogStatement() (*ast.BeginDialogStatement, error)
 			case "RELATED_CONVERSATION_GROUP":
 				if p.curTok.Type == TokenIdent && len(p.curTok.Literal) > 0 && p.curTok.Literal[0] == '@' {
 					stmt.Options = append(stmt.Options, &ast.ScalarExpressionDialogOption{
-						Value:      &ast.VariableReference{Name: p.curTok.Literal},
+						Value:      &ast.VariableReference{Name: p.curTok.Literal, Pos: p.pos()},
 						OptionKind: "RelatedConversationGroup",
 					})
 					p.nextToken()
@@ -2628,7 +2632,7 @@ func (p *Parser) parseBeginConversationTimerStatement() (*ast.BeginConversationT
 	p.nextToken() // consume (
 
 	if p.curTok.Type == TokenIdent && len(p.curTok.Literal) > 0 && p.curTok.Literal[0] == '@' {
-		stmt.Handle = &ast.VariableReference{Name: p.curTok.Literal}
+		stmt.Handle = &ast.VariableReference{Name: p.curTok.Literal, Pos: p.pos()}
 		p.nextToken()
 	} else {
 		return nil, fmt.Errorf("expected variable for conversation handle")
@@ -4924,7 +4928,7 @@ func (p *Parser) parseCreateViewStatement() (*ast.CreateViewStatement, error) {
 		p.nextToken()
 		for p.curTok.Type != TokenRParen && p.curTok.Type != TokenEOF {
 			if p.curTok.Type == TokenIdent {
-				stmt.Columns = append(stmt.Columns, &ast.Identifier{Value: p.curTok.Literal, QuoteType: "NotQuoted"})
+				stmt.Columns = append(stmt.Columns, &ast.Identifier{Value: p.curTok.Literal, QuoteType: "NotQuoted", Pos: p.pos()})
 				p.nextToken()
 			}
 			if p.curTok.Type == TokenComma {
@@ -5439,6 +5443,9 @@ func (p *Parser) parseExecuteStatement() (ast.Statement, error) {
 	}
 
 	stmt := &ast.ExecuteStatement{ExecuteSpecification: execSpec}
+	if p.opts.DynamicSQL {
+		stmt.Dynamic = p.dynamicSQL(execSpec.ExecutableEntity)
+	}
 
 	// Parse WITH options (RESULT SETS, RECOMPILE)
 	for p.curTok.Type == TokenWith {
@@ -5724,10 +5731,12 @@ func (p *Parser) parseContinueStatement() (*ast.ContinueStatement, error) {
 
 func (p *Parser) parseCommitTransactionStatement() (*ast.CommitTransactionStatement, error) {
 	// Consume COMMIT
+	pos := p.pos()
 	p.nextToken()
 
 	stmt := &ast.CommitTransactionStatement{
 		DelayedDurabilityOption: "NotSet",
+		Pos:                     pos,
 	}
 
 	// Skip optional WORK, TRAN, or TRANSACTION
@@ -5794,9 +5803,10 @@ func (p *Parser) parseCommitTransactionStatement() (*ast.CommitTransactionStatem
 
 func (p *Parser) parseRollbackTransactionStatement() (*ast.RollbackTransactionStatement, error) {
 	// Consume ROLLBACK
+	pos := p.pos()
 	p.nextToken()
 
-	stmt := &ast.RollbackTransactionStatement{}
+	stmt := &ast.RollbackTransactionStatement{Pos: pos}
 
 	// Skip optional WORK, TRAN, or TRANSACTION
 	if p.curTok.Type == TokenWork || p.curTok.Type == TokenTran || p.curTok.Type == TokenTransaction {
@@ -5833,9 +5843,10 @@ func (p *Parser) parseRollbackTransactionStatement() (*ast.RollbackTransactionSt
 
 func (p *Parser) parseSaveTransactionStatement() (*ast.SaveTransactionStatement, error) {
 	// Consume SAVE
+	pos := p.pos()
 	p.nextToken()
 
-	stmt := &ast.SaveTransactionStatement{}
+	stmt := &ast.SaveTransactionStatement{Pos: pos}
 
 	// Skip optional TRAN or TRANSACTION
 	if p.curTok.Type == TokenTran || p.curTok.Type == TokenTransaction {
@@ -6721,7 +6732,7 @@ func (p *Parser) parseReadTextStatement() (*ast.ReadTextStatement, error) {
 		}
 		p.nextToken()
 	} else if p.curTok.Type == TokenIdent && strings.HasPrefix(p.curTok.Literal, "@") {
-		stmt.TextPointer = &ast.VariableReference{Name: p.curTok.Literal}
+		stmt.TextPointer = &ast.VariableReference{Name: p.curTok.Literal, Pos: p.pos()}
 		p.nextToken()
 	} else {
 		return nil, fmt.Errorf("expected text pointer, got %s", p.curTok.Literal)
@@ -6794,7 +6805,7 @@ func (p *Parser) parseWriteTextStatement() (*ast.WriteTextStatement, error) {
 		}
 		p.nextToken()
 	} else if p.curTok.Type == TokenIdent && strings.HasPrefix(p.curTok.Literal, "@") {
-		stmt.TextId = &ast.VariableReference{Name: p.curTok.Literal}
+		stmt.TextId = &ast.VariableReference{Name: p.curTok.Literal, Pos: p.pos()}
 		p.nextToken()
 	} else if p.curTok.Type == TokenNumber {
 		stmt.TextId = &ast.IntegerLiteral{
@@ -6869,7 +6880,7 @@ func (p *Parser) parseUpdateTextStatement() (*ast.UpdateTextStatement, error) {
 		}
 		p.nextToken()
 	} else if p.curTok.Type == TokenIdent && strings.HasPrefix(p.curTok.Literal, "@") {
-		stmt.TextId = &ast.VariableReference{Name: p.curTok.Literal}
+		stmt.TextId = &ast.VariableReference{Name: p.curTok.Literal, Pos: p.pos()}
 		p.nextToken()
 	} else if p.curTok.Type == TokenNumber {
 		stmt.TextId = &ast.IntegerLiteral{
@@ -6954,7 +6965,7 @@ func (p *Parser) parseUpdateTextStatement() (*ast.UpdateTextStatement, error) {
 					ColumnType:          "Regular",
 					MultiPartIdentifier: srcMultiPart,
 				}
-				stmt.SourceParameter = &ast.VariableReference{Name: p.curTok.Literal}
+				stmt.SourceParameter = &ast.VariableReference{Name: p.curTok.Literal, Pos: p.pos()}
 				p.nextToken()
 			} else if p.curTok.Type == TokenBinary {
 				// sourceColumn followed by binary sourceParam
@@ -7286,7 +7297,7 @@ func (p *Parser) parseReceiveStatement() (*ast.ReceiveStatement, error) {
 			return nil, fmt.Errorf("expected @variable after INTO, got %s", p.curTok.Literal)
 		}
 		stmt.Into = &ast.VariableTableReference{
-			Variable: &ast.VariableReference{Name: p.curTok.Literal},
+			Variable: &ast.VariableReference{Name: p.curTok.Literal, Pos: p.pos()},
 		}
 		p.nextToken()
 	}
@@ -14866,7 +14877,7 @@ func (p *Parser) parseCursorId() *ast.CursorId {
 
 	// Check if it's a variable
 	if p.curTok.Type == TokenIdent && strings.HasPrefix(p.curTok.Literal, "@") {
-		cursorId.Name.ValueExpression = &ast.VariableReference{Name: p.curTok.Literal}
+		cursorId.Name.ValueExpression = &ast.VariableReference{Name: p.curTok.Literal, Pos: p.pos()}
 	} else {
 		// Create identifier inline (same logic as parseIdentifier but without advancing)
 		literal := p.curTok.Literal
@@ -14878,6 +14889,7 @@ func (p *Parser) parseCursorId() *ast.CursorId {
 		cursorId.Name.Identifier = &ast.Identifier{
 			Value:     literal,
 			QuoteType: quoteType,
+			Pos:       p.pos(),
 		}
 	}
 	p.nextToken()
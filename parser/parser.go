@@ -12,6 +12,22 @@ import (
 
 // Parse parses T-SQL from the given reader and returns an AST Script.
 func Parse(ctx context.Context, r io.Reader) (*ast.Script, error) {
+	return ParseWithOptions(ctx, r, Options{})
+}
+
+// Options controls optional parsing behavior.
+type Options struct {
+	// DynamicSQL parses the SQL text that EXECUTE statements run, given as
+	// string literals, into the Dynamic field of each ast.ExecuteStatement.
+	DynamicSQL bool
+	// Sources records the source text of each statement, as written, in
+	// the Sources field of its ast.Batch.
+	Sources bool
+}
+
+// ParseWithOptions parses T-SQL from the given reader as Parse does, with
+// the behavior opts selects.
+func ParseWithOptions(ctx context.Context, r io.Reader, opts Options) (*ast.Script, error) {
 	data, err := io.ReadAll(r)
 	if err != nil {
 		return nil, fmt.Errorf("reading input: %w", err)
@@ -23,6 +39,7 @@ func Parse(ctx context.Context, r io.Reader) (*ast.Script, error) {
 	}
 
 	p := newParser(string(data))
+	p.opts = opts
 	return p.parseScript()
 }
 
@@ -31,6 +48,7 @@ type Parser struct {
 	lexer   *Lexer
 	curTok  Token
 	peekTok Token
+	opts    Options
 }
 
 func newParser(input string) *Parser {
@@ -46,6 +64,11 @@ func (p *Parser) nextToken() {
 	p.peekTok = p.lexer.NextToken()
 }
 
+// pos returns the source position of the current token.
+func (p *Parser) pos() ast.Position {
+	return p.lexer.Position(p.curTok.Pos)
+}
+
 func (p *Parser) parseScript() (*ast.Script, error) {
 	script := &ast.Script{}
 
@@ -73,12 +96,25 @@ func (p *Parser) parseBatch() (*ast.Batch, error) {
 			break
 		}
 
+		comments, start := p.curTok.Comments, p.curTok.Pos
 		stmt, err := p.parseStatement()
 		if err != nil {
 			return nil, err
 		}
 		if stmt != nil {
 			batch.Statements = append(batch.Statements, stmt)
+			if p.opts.Sources {
+				if batch.Sources == nil {
+					batch.Sources = map[ast.Statement]string{}
+				}
+				batch.Sources[stmt] = p.sourceSince(start)
+			}
+			if a := p.annotation(comments, start); a != nil {
+				if batch.Annotations == nil {
+					batch.Annotations = map[ast.Statement]*ast.QueryAnnotation{}
+				}
+				batch.Annotations[stmt] = a
+			}
 		}
 	}
 
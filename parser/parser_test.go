@@ -7,7 +7,10 @@ import (
 	"flag"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/sqlc-dev/teesql/ast"
 )
 
 type testMetadata struct {
@@ -123,3 +126,125 @@ func TestParse(t *testing.T) {
 		})
 	}
 }
+
+func TestParseDynamicSQL(t *testing.T) {
+	tests := []struct {
+		sql        string
+		constant   bool
+		text       string
+		statements int
+		params     []string
+	}{
+		{sql: "EXEC ('SELECT * FROM t1')", constant: true, text: "SELECT * FROM t1", statements: 1},
+		{sql: "EXEC (N'SELECT 1; ' + 'SELECT ''a''')", constant: true, text: "SELECT 1; SELECT 'a'", statements: 2},
+		{sql: "EXEC ('SELECT * FROM ' + @table)"},
+		{sql: "EXEC sp_executesql @sql"},
+		{
+			sql:        "EXEC sp_executesql N'SELECT * FROM t WHERE id = @id', N'@id int, @name nvarchar(10) OUTPUT', @id = 1, @name = @n OUTPUT",
+			constant:   true,
+			text:       "SELECT * FROM t WHERE id = @id",
+			statements: 1,
+			params:     []string{"@id None", "@name Output"},
+		},
+		{
+			sql:        "EXEC sys.sp_executesql @params = N'@id int', @stmt = N'DELETE FROM t WHERE id = @id', @id = 1",
+			constant:   true,
+			text:       "DELETE FROM t WHERE id = @id",
+			statements: 1,
+			params:     []string{"@id None"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			script, err := ParseWithOptions(context.Background(), strings.NewReader(tt.sql), Options{DynamicSQL: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			d := script.Batches[0].Statements[0].(*ast.ExecuteStatement).Dynamic
+			if d == nil {
+				t.Fatal("Dynamic is nil")
+			}
+			if d.Err != nil {
+				t.Fatal(d.Err)
+			}
+			if d.Constant != tt.constant || d.Text != tt.text {
+				t.Errorf("got constant %v, text %q; want %v, %q", d.Constant, d.Text, tt.constant, tt.text)
+			}
+			statements := 0
+			if d.Script != nil {
+				for _, b := range d.Script.Batches {
+					statements += len(b.Statements)
+				}
+			}
+			if statements != tt.statements {
+				t.Errorf("got %d statements, want %d", statements, tt.statements)
+			}
+			var params []string
+			for _, p := range d.Parameters {
+				params = append(params, p.VariableName.Value+" "+p.Modifier)
+			}
+			if strings.Join(params, ", ") != strings.Join(tt.params, ", ") {
+				t.Errorf("got parameters %v, want %v", params, tt.params)
+			}
+		})
+	}
+
+	// Without the option, or for procedures other than sp_executesql, the
+	// statement has no dynamic SQL.
+	for _, tt := range []struct {
+		sql  string
+		opts Options
+	}{
+		{"EXEC ('SELECT 1')", Options{}},
+		{"EXEC dbo.p 'SELECT 1'", Options{DynamicSQL: true}},
+	} {
+		script, err := ParseWithOptions(context.Background(), strings.NewReader(tt.sql), tt.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d := script.Batches[0].Statements[0].(*ast.ExecuteStatement).Dynamic; d != nil {
+			t.Errorf("%s: got dynamic SQL %q", tt.sql, d.Text)
+		}
+	}
+
+	// Nested scripts are walked, so that their references are found.
+	script, err := ParseWithOptions(context.Background(), strings.NewReader("EXEC ('EXEC (''SELECT * FROM inner_table'')')"), Options{DynamicSQL: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found bool
+	ast.Inspect(script, func(n ast.Node) bool {
+		if n, ok := n.(*ast.NamedTableReference); ok && n.SchemaObject.BaseIdentifier.Value == "inner_table" {
+			found = true
+		}
+		return true
+	})
+	if !found {
+		t.Error("table in nested dynamic SQL not walked")
+	}
+}
+
+func TestParseSources(t *testing.T) {
+	const sql = "SELECT 1;\nCREATE VIEW v AS SELECT 2 AS x -- two\n"
+	for _, sources := range []bool{false, true} {
+		script, err := ParseWithOptions(context.Background(), strings.NewReader(sql), Options{Sources: sources})
+		if err != nil {
+			t.Fatal(err)
+		}
+		batch := script.Batches[0]
+		if !sources {
+			if batch.Sources != nil {
+				t.Errorf("got sources %q without the option", batch.Sources)
+			}
+			continue
+		}
+		var got []string
+		for _, stmt := range batch.Statements {
+			got = append(got, batch.Source(stmt))
+		}
+		want := []string{"SELECT 1;", "CREATE VIEW v AS SELECT 2 AS x"}
+		if strings.Join(got, "|") != strings.Join(want, "|") {
+			t.Errorf("got sources %q, want %q", got, want)
+		}
+	}
+}
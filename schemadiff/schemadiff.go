@@ -0,0 +1,380 @@
+// Package schemadiff compares two schema catalogs, such as the current and
+// the desired schema of a database, and writes the T-SQL migration script
+// that turns one into the other.
+//
+// Schemas, tables, columns, constraints, indexes, views and procedures are
+// compared. Constraints and indexes are matched by name, and unnamed
+// constraints by their definition; a changed constraint or index is
+// dropped and created again. Views and procedures are compared by their
+// source text, token by token, so layout, comments and keyword case do not
+// count as changes, and the script creates them from the same text.
+package schemadiff
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/sqlc-dev/teesql/ast"
+	"github.com/sqlc-dev/teesql/catalog"
+	"github.com/sqlc-dev/teesql/format"
+	"github.com/sqlc-dev/teesql/parser"
+)
+
+// Change kinds.
+const (
+	Added   = "added"
+	Dropped = "dropped"
+	Altered = "altered"
+)
+
+// Object kinds.
+const (
+	Schema     = "schema"
+	Table      = "table"
+	Column     = "column"
+	Constraint = "constraint"
+	Index      = "index"
+	View       = "view"
+	Procedure  = "procedure"
+)
+
+// Change is a difference between the two catalogs.
+type Change struct {
+	Kind   string `json:"Kind"`
+	Object string `json:"Object"`
+	// Name is the schema-qualified name of the object. Columns,
+	// constraints and indexes are named after their table, as in
+	// "dbo.Orders.CustomerId"; unnamed constraints by their definition.
+	Name string `json:"Name"`
+	// Details lists what changed in an altered column, e.g.
+	// "type int -> bigint".
+	Details []string `json:"Details,omitempty"`
+
+	// table is the table of a column, constraint or index.
+	table catalog.ObjectName
+	// from and to are the object in each catalog, nil where it does not
+	// exist.
+	from, to any
+}
+
+// String describes the change, e.g.
+// "altered column dbo.T.c: type int -> bigint".
+func (c *Change) String() string {
+	s := c.Kind + " " + c.Object + " " + c.Name
+	if len(c.Details) > 0 {
+		s += ": " + strings.Join(c.Details, ", ")
+	}
+	return s
+}
+
+// Diff is the set of changes that turn one catalog into another.
+type Diff struct {
+	// Changes lists the changes by schema, then table or object, in the
+	// order of the target catalog, with dropped objects last.
+	Changes []*Change `json:"Changes"`
+
+	from, to *catalog.Catalog
+}
+
+// Compare returns the changes that turn catalog from into catalog to.
+func Compare(from, to *catalog.Catalog) *Diff {
+	d := &Diff{Changes: []*Change{}, from: from, to: to}
+	d.schemas()
+	d.tables()
+	d.views()
+	d.procedures()
+	return d
+}
+
+func (d *Diff) add(c *Change) {
+	d.Changes = append(d.Changes, c)
+}
+
+// builtinSchemas are the schemas every database has.
+var builtinSchemas = map[string]bool{"dbo": true, "guest": true, "sys": true, "information_schema": true}
+
+func (d *Diff) schemas() {
+	for _, s := range d.to.Schemas {
+		if !builtinSchemas[strings.ToLower(s.Name)] && d.from.Schema(s.Name) == nil {
+			d.add(&Change{Kind: Added, Object: Schema, Name: s.Name, to: s})
+		}
+	}
+	for _, s := range d.from.Schemas {
+		if !builtinSchemas[strings.ToLower(s.Name)] && d.to.Schema(s.Name) == nil {
+			d.add(&Change{Kind: Dropped, Object: Schema, Name: s.Name, from: s})
+		}
+	}
+}
+
+func tableName(t *catalog.Table) catalog.ObjectName {
+	return catalog.ObjectName{Schema: t.Schema, Name: t.Name}
+}
+
+func (d *Diff) tables() {
+	for _, s := range d.to.Schemas {
+		for _, t := range s.Tables {
+			name := tableName(t)
+			old := d.from.Table(name)
+			if old == nil {
+				d.add(&Change{Kind: Added, Object: Table, Name: name.String(), table: name, to: t})
+				continue
+			}
+			d.columns(name, old, t)
+			d.constraints(name, old, t)
+			d.indexes(name, old, t)
+		}
+	}
+	for _, s := range d.from.Schemas {
+		for _, t := range s.Tables {
+			if d.to.Table(tableName(t)) == nil {
+				d.add(&Change{Kind: Dropped, Object: Table, Name: tableName(t).String(), table: tableName(t), from: t})
+			}
+		}
+	}
+}
+
+func (d *Diff) columns(table catalog.ObjectName, from, to *catalog.Table) {
+	for _, col := range to.Columns {
+		old := from.Column(col.Name)
+		if old == nil {
+			d.add(&Change{Kind: Added, Object: Column, Name: table.String() + "." + col.Name, table: table, to: col})
+			continue
+		}
+		if details := columnChanges(old, col); len(details) > 0 {
+			d.add(&Change{Kind: Altered, Object: Column, Name: table.String() + "." + col.Name, Details: details, table: table, from: old, to: col})
+		}
+	}
+	for _, col := range from.Columns {
+		if to.Column(col.Name) == nil {
+			d.add(&Change{Kind: Dropped, Object: Column, Name: table.String() + "." + col.Name, table: table, from: col})
+		}
+	}
+}
+
+// columnChanges describes the differences between two definitions of a
+// column.
+func columnChanges(from, to *catalog.Column) []string {
+	var details []string
+	change := func(what, a, b string) {
+		if a != b {
+			details = append(details, what+" "+a+" -> "+b)
+		}
+	}
+	if computed(from) || computed(to) {
+		change("definition", columnDefinition(from), columnDefinition(to))
+		return details
+	}
+	change("type", from.Type.String(), to.Type.String())
+	change("nullability", nullability(from), nullability(to))
+	change("collation", orNone(from.Collation), orNone(to.Collation))
+	change("identity", identity(from), identity(to))
+	change("default", defaultDefinition(from.Default), defaultDefinition(to.Default))
+	return details
+}
+
+func computed(col *catalog.Column) bool {
+	return col.Computed != nil
+}
+
+func nullability(col *catalog.Column) string {
+	if col.Nullable {
+		return "NULL"
+	}
+	return "NOT NULL"
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
+
+func identity(col *catalog.Column) string {
+	if col.Identity == nil {
+		return "none"
+	}
+	return identitySpec(col.Identity)
+}
+
+// constraintKey returns the key matching a constraint across catalogs: its
+// name, or its definition if it has none.
+func constraintKey(c *catalog.Constraint) string {
+	if c.Name != "" {
+		return strings.ToLower(c.Name)
+	}
+	return "\x00" + constraintDefinition(c)
+}
+
+func constraintName(table catalog.ObjectName, c *catalog.Constraint) string {
+	if c.Name != "" {
+		return table.String() + "." + c.Name
+	}
+	return table.String() + " " + constraintDefinition(c)
+}
+
+func (d *Diff) constraints(table catalog.ObjectName, from, to *catalog.Table) {
+	old := map[string]*catalog.Constraint{}
+	for _, c := range from.Constraints {
+		old[constraintKey(c)] = c
+	}
+	seen := map[string]bool{}
+	for _, c := range to.Constraints {
+		k := constraintKey(c)
+		seen[k] = true
+		switch o := old[k]; {
+		case o == nil:
+			d.add(&Change{Kind: Added, Object: Constraint, Name: constraintName(table, c), table: table, to: c})
+		case constraintDefinition(o) != constraintDefinition(c):
+			d.add(&Change{Kind: Altered, Object: Constraint, Name: constraintName(table, c), table: table, from: o, to: c})
+		}
+	}
+	for _, c := range from.Constraints {
+		if !seen[constraintKey(c)] {
+			d.add(&Change{Kind: Dropped, Object: Constraint, Name: constraintName(table, c), table: table, from: c})
+		}
+	}
+}
+
+func (d *Diff) indexes(table catalog.ObjectName, from, to *catalog.Table) {
+	for _, ix := range to.Indexes {
+		switch o := from.Index(ix.Name); {
+		case o == nil:
+			d.add(&Change{Kind: Added, Object: Index, Name: table.String() + "." + ix.Name, table: table, to: ix})
+		case indexDefinition(table, o) != indexDefinition(table, ix):
+			d.add(&Change{Kind: Altered, Object: Index, Name: table.String() + "." + ix.Name, table: table, from: o, to: ix})
+		}
+	}
+	for _, ix := range from.Indexes {
+		if to.Index(ix.Name) == nil {
+			d.add(&Change{Kind: Dropped, Object: Index, Name: table.String() + "." + ix.Name, table: table, from: ix})
+		}
+	}
+}
+
+func (d *Diff) views() {
+	for _, s := range d.to.Schemas {
+		for _, v := range s.Views {
+			name := catalog.ObjectName{Schema: v.Schema, Name: v.Name}
+			switch old := d.from.View(name); {
+			case old == nil:
+				d.add(&Change{Kind: Added, Object: View, Name: name.String(), to: v})
+			case normalize(viewDefinition(old)) != normalize(viewDefinition(v)):
+				d.add(&Change{Kind: Altered, Object: View, Name: name.String(), from: old, to: v})
+			}
+		}
+	}
+	for _, s := range d.from.Schemas {
+		for _, v := range s.Views {
+			name := catalog.ObjectName{Schema: v.Schema, Name: v.Name}
+			if d.to.View(name) == nil {
+				d.add(&Change{Kind: Dropped, Object: View, Name: name.String(), from: v})
+			}
+		}
+	}
+}
+
+func (d *Diff) procedures() {
+	for _, s := range d.to.Schemas {
+		for _, p := range s.Procedures {
+			name := catalog.ObjectName{Schema: p.Schema, Name: p.Name}
+			switch old := d.from.Procedure(name); {
+			case old == nil:
+				d.add(&Change{Kind: Added, Object: Procedure, Name: name.String(), to: p})
+			case normalize(procedureDefinition(old)) != normalize(procedureDefinition(p)):
+				d.add(&Change{Kind: Altered, Object: Procedure, Name: name.String(), from: old, to: p})
+			}
+		}
+	}
+	for _, s := range d.from.Schemas {
+		for _, p := range s.Procedures {
+			name := catalog.ObjectName{Schema: p.Schema, Name: p.Name}
+			if d.to.Procedure(name) == nil {
+				d.add(&Change{Kind: Dropped, Object: Procedure, Name: name.String(), from: p})
+			}
+		}
+	}
+}
+
+// viewDependencies returns the views of c that view v selects from.
+func viewDependencies(c *catalog.Catalog, v *catalog.View) []*catalog.View {
+	var deps []*catalog.View
+	seen := map[*catalog.View]bool{}
+	ast.Inspect(v.Definition, func(n ast.Node) bool {
+		ref, ok := n.(*ast.NamedTableReference)
+		if !ok || ref.SchemaObject == nil {
+			return true
+		}
+		name := catalog.NameOf(ref.SchemaObject)
+		if name.Schema == "" {
+			name.Schema = c.DefaultSchema
+		}
+		if dep := c.View(name); dep != nil && dep != v && !seen[dep] {
+			seen[dep] = true
+			deps = append(deps, dep)
+		}
+		return true
+	})
+	return deps
+}
+
+// orderViews returns views ordered so that each comes after the views of c
+// it selects from.
+func orderViews(c *catalog.Catalog, views []*catalog.View) []*catalog.View {
+	want := map[*catalog.View]bool{}
+	for _, v := range views {
+		want[v] = true
+	}
+	sort.SliceStable(views, func(i, j int) bool {
+		return strings.ToLower(views[i].Schema+"."+views[i].Name) < strings.ToLower(views[j].Schema+"."+views[j].Name)
+	})
+	var ordered []*catalog.View
+	done := map[*catalog.View]bool{}
+	var visit func(v *catalog.View)
+	visit = func(v *catalog.View) {
+		if done[v] {
+			return
+		}
+		// Marking before visiting the dependencies stops at cycles, which
+		// SQL Server does not allow between views.
+		done[v] = true
+		for _, dep := range viewDependencies(c, v) {
+			visit(dep)
+		}
+		if want[v] {
+			ordered = append(ordered, v)
+		}
+	}
+	for _, v := range views {
+		visit(v)
+	}
+	return ordered
+}
+
+// normalize returns the tokens of a view or procedure definition separated
+// by single spaces, with comments and trailing semicolons removed and all
+// but string literals in lower case.
+func normalize(def string) string {
+	var tokens []string
+	l := parser.NewLexer(def)
+	for tok := l.NextToken(); tok.Type != parser.TokenEOF; tok = l.NextToken() {
+		switch tok.Type {
+		case parser.TokenString, parser.TokenNationalString:
+			tokens = append(tokens, tok.Literal)
+		default:
+			tokens = append(tokens, strings.ToLower(tok.Literal))
+		}
+	}
+	for len(tokens) > 0 && tokens[len(tokens)-1] == ";" {
+		tokens = tokens[:len(tokens)-1]
+	}
+	return strings.Join(tokens, " ")
+}
+
+// text returns the T-SQL text of a node, or empty for nil.
+func text(n ast.Node) string {
+	if n == nil {
+		return ""
+	}
+	return format.Node(n)
+}
@@ -0,0 +1,247 @@
+package schemadiff
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sqlc-dev/teesql/catalog"
+	"github.com/sqlc-dev/teesql/parser"
+)
+
+func build(t *testing.T, sql string) *catalog.Catalog {
+	t.Helper()
+	script, err := parser.ParseWithOptions(context.Background(), strings.NewReader(sql), parser.Options{Sources: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := catalog.Build(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+const current = `CREATE TABLE dbo.Customers (
+	Id int NOT NULL CONSTRAINT PK_Customers PRIMARY KEY,
+	Name nvarchar(50) NOT NULL,
+	Fax varchar(20) NULL DEFAULT '',
+	Created datetime NOT NULL CONSTRAINT DF_Created DEFAULT (getdate())
+);
+CREATE TABLE dbo.Orders (
+	Id int NOT NULL PRIMARY KEY,
+	CustomerId int NOT NULL CONSTRAINT FK_Orders_Customers REFERENCES dbo.Customers (Id),
+	Total money NOT NULL
+);
+CREATE INDEX IX_Orders_Customer ON dbo.Orders (CustomerId);
+CREATE TABLE dbo.Legacy (Id int NOT NULL PRIMARY KEY);
+CREATE TABLE dbo.LegacyItems (
+	LegacyId int NOT NULL CONSTRAINT FK_LegacyItems_Legacy FOREIGN KEY REFERENCES dbo.Legacy (Id)
+);
+GO
+CREATE VIEW dbo.BigOrders AS SELECT Id, Total FROM dbo.Orders WHERE Total > 100;
+GO
+CREATE VIEW dbo.BigOrderCount AS SELECT COUNT(*) AS n FROM dbo.BigOrders;
+GO
+CREATE PROCEDURE dbo.GetCustomer @id int AS
+	SELECT Id, Name FROM dbo.Customers WHERE Id = @id;`
+
+const desired = `CREATE SCHEMA sales;
+GO
+CREATE TABLE dbo.Customers (
+	Id bigint NOT NULL CONSTRAINT PK_Customers PRIMARY KEY,
+	Name nvarchar(100) NOT NULL,
+	Created datetime NOT NULL CONSTRAINT DF_Created DEFAULT (sysutcdatetime()),
+	Email nvarchar(200) NULL
+);
+CREATE TABLE dbo.Orders (
+	Id int NOT NULL PRIMARY KEY,
+	CustomerId bigint NOT NULL CONSTRAINT FK_Orders_Customers REFERENCES dbo.Customers (Id) ON DELETE CASCADE,
+	Total money NOT NULL CONSTRAINT CK_Total CHECK (Total >= 0)
+);
+CREATE INDEX IX_Orders_Customer ON dbo.Orders (CustomerId) INCLUDE (Total);
+CREATE TABLE sales.Regions (
+	Id int NOT NULL CONSTRAINT PK_Regions PRIMARY KEY,
+	ParentId int NULL CONSTRAINT FK_Regions_Parent REFERENCES sales.Regions (Id)
+);
+GO
+CREATE VIEW dbo.BigOrders AS
+	select Id, Total
+	from dbo.Orders
+	where Total > 100
+GO
+CREATE VIEW dbo.BigOrderCount AS SELECT COUNT(*) AS n FROM dbo.BigOrders WHERE Total > 1000;
+GO
+CREATE VIEW dbo.RegionTree AS SELECT r.Id, p.Id AS ParentId FROM sales.Regions r LEFT JOIN sales.Regions p ON p.Id = r.ParentId;
+GO
+CREATE PROCEDURE dbo.GetCustomer @id bigint AS
+	SELECT Id, Name, Email FROM dbo.Customers WHERE Id = @id;`
+
+func TestCompare(t *testing.T) {
+	d := Compare(build(t, current), build(t, desired))
+	var got []string
+	for _, c := range d.Changes {
+		got = append(got, c.String())
+	}
+	want := []string{
+		"added schema sales",
+		"altered column dbo.Customers.Id: type int -> bigint",
+		"altered column dbo.Customers.Name: type nvarchar(50) -> nvarchar(100)",
+		"altered column dbo.Customers.Created: default CONSTRAINT [DF_Created] DEFAULT (getdate()) -> CONSTRAINT [DF_Created] DEFAULT (sysutcdatetime())",
+		"added column dbo.Customers.Email",
+		"dropped column dbo.Customers.Fax",
+		"altered column dbo.Orders.CustomerId: type int -> bigint",
+		"altered constraint dbo.Orders.FK_Orders_Customers",
+		"added constraint dbo.Orders.CK_Total",
+		"altered index dbo.Orders.IX_Orders_Customer",
+		"added table sales.Regions",
+		"dropped table dbo.Legacy",
+		"dropped table dbo.LegacyItems",
+		"altered view dbo.BigOrderCount",
+		"added view dbo.RegionTree",
+		"altered procedure dbo.GetCustomer",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestScript(t *testing.T) {
+	got := Compare(build(t, current), build(t, desired)).Script()
+	want := `ALTER TABLE [dbo].[Orders] DROP CONSTRAINT [FK_Orders_Customers];
+GO
+ALTER TABLE [dbo].[LegacyItems] DROP CONSTRAINT [FK_LegacyItems_Legacy];
+GO
+DROP INDEX [IX_Orders_Customer] ON [dbo].[Orders];
+GO
+ALTER TABLE [dbo].[Customers] DROP CONSTRAINT [PK_Customers];
+GO
+ALTER TABLE [dbo].[Customers] DROP CONSTRAINT [DF_Created];
+GO
+DECLARE @sql nvarchar(max) = N'ALTER TABLE [dbo].[Customers] DROP CONSTRAINT ' + QUOTENAME((SELECT name FROM sys.default_constraints WHERE parent_object_id = OBJECT_ID(N'[dbo].[Customers]') AND parent_column_id = COLUMNPROPERTY(OBJECT_ID(N'[dbo].[Customers]'), N'Fax', 'ColumnId')));
+EXEC (@sql);
+GO
+ALTER TABLE [dbo].[Customers] DROP COLUMN [Fax];
+GO
+DROP TABLE [dbo].[Legacy];
+GO
+DROP TABLE [dbo].[LegacyItems];
+GO
+CREATE SCHEMA [sales];
+GO
+CREATE TABLE [sales].[Regions] (
+	[Id] int NOT NULL,
+	[ParentId] int NULL,
+	CONSTRAINT [PK_Regions] PRIMARY KEY CLUSTERED ([Id])
+);
+GO
+ALTER TABLE [dbo].[Customers] ALTER COLUMN [Id] bigint NOT NULL;
+GO
+ALTER TABLE [dbo].[Customers] ALTER COLUMN [Name] nvarchar(100) NOT NULL;
+GO
+ALTER TABLE [dbo].[Customers] ADD CONSTRAINT [DF_Created] DEFAULT (sysutcdatetime()) FOR [Created];
+GO
+ALTER TABLE [dbo].[Customers] ADD [Email] nvarchar(200) NULL;
+GO
+ALTER TABLE [dbo].[Orders] ALTER COLUMN [CustomerId] bigint NOT NULL;
+GO
+ALTER TABLE [dbo].[Orders] ADD CONSTRAINT [CK_Total] CHECK (Total >= 0);
+GO
+CREATE NONCLUSTERED INDEX [IX_Orders_Customer] ON [dbo].[Orders] ([CustomerId]) INCLUDE ([Total]);
+GO
+ALTER TABLE [dbo].[Customers] ADD CONSTRAINT [PK_Customers] PRIMARY KEY CLUSTERED ([Id]);
+GO
+ALTER TABLE [sales].[Regions] ADD CONSTRAINT [FK_Regions_Parent] FOREIGN KEY ([ParentId]) REFERENCES [sales].[Regions] ([Id]);
+GO
+ALTER TABLE [dbo].[Orders] ADD CONSTRAINT [FK_Orders_Customers] FOREIGN KEY ([CustomerId]) REFERENCES [dbo].[Customers] ([Id]) ON DELETE CASCADE;
+GO
+CREATE OR ALTER VIEW [dbo].[BigOrderCount] AS SELECT COUNT(*) AS n FROM dbo.BigOrders WHERE Total > 1000;
+GO
+CREATE VIEW [dbo].[RegionTree] AS SELECT r.Id, p.Id AS ParentId FROM sales.Regions r LEFT JOIN sales.Regions p ON p.Id = r.ParentId;
+GO
+CREATE OR ALTER PROCEDURE [dbo].[GetCustomer] @id bigint AS
+	SELECT Id, Name, Email FROM dbo.Customers WHERE Id = @id;
+GO
+`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestSourceText(t *testing.T) {
+	from := build(t, `CREATE TABLE dbo.T (Id int NOT NULL, Name nvarchar(50) NULL);
+GO
+CREATE VIEW dbo.Names (Id, Name) AS SELECT Id, Name FROM dbo.T;
+GO
+CREATE PROCEDURE dbo.Load AS
+SET NOCOUNT ON;
+CREATE TABLE #t (Id int);
+INSERT INTO #t SELECT Id FROM dbo.T;
+WAITFOR DELAY '00:00:01';
+DROP TABLE #t;`)
+	to := build(t, `CREATE TABLE dbo.T (Id int NOT NULL, Name nvarchar(50) NULL);
+GO
+-- The view is checked now.
+create view Names (Id, FullName) as
+	select Id, Name from dbo.T
+	with check option
+GO
+CREATE PROCEDURE dbo.Load AS
+SET NOCOUNT ON;
+CREATE TABLE #t (Id int, Name nvarchar(50));
+INSERT INTO #t SELECT Id, Name FROM dbo.T;
+WAITFOR DELAY '00:00:01';
+DROP TABLE #t;
+-- Loaded.
+GO`)
+	d := Compare(from, to)
+	var got []string
+	for _, c := range d.Changes {
+		got = append(got, c.String())
+	}
+	if want := "altered view dbo.Names\naltered procedure dbo.Load"; strings.Join(got, "\n") != want {
+		t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), want)
+	}
+	want := `CREATE OR ALTER VIEW [dbo].[Names] (Id, FullName) as
+	select Id, Name from dbo.T
+	with check option
+GO
+CREATE OR ALTER PROCEDURE [dbo].[Load] AS
+SET NOCOUNT ON;
+CREATE TABLE #t (Id int, Name nvarchar(50));
+INSERT INTO #t SELECT Id, Name FROM dbo.T;
+WAITFOR DELAY '00:00:01';
+DROP TABLE #t;
+GO
+`
+	if got := d.Script(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	unchecked := build(t, `CREATE TABLE dbo.T (Id int NOT NULL, Name nvarchar(50) NULL);
+GO
+CREATE VIEW dbo.Names (Id, FullName) AS SELECT Id, Name FROM dbo.T;`)
+	if d := Compare(to, unchecked); len(d.Changes) == 0 || d.Changes[0].String() != "altered view dbo.Names" {
+		t.Errorf("dropping WITH CHECK OPTION: got %v, want the view altered", d.Changes)
+	}
+}
+
+func TestDropUnnamedConstraints(t *testing.T) {
+	from := build(t, `CREATE TABLE dbo.P (id int NOT NULL PRIMARY KEY);
+CREATE TABLE dbo.C (pid int REFERENCES dbo.P (id), code int UNIQUE, n int CHECK (n > 0));`)
+	to := build(t, `CREATE TABLE dbo.C (pid int, code int, n int);`)
+	want := `DECLARE @sql nvarchar(max) = N'ALTER TABLE [dbo].[C] DROP CONSTRAINT ' + QUOTENAME((SELECT name FROM sys.foreign_keys AS f WHERE parent_object_id = OBJECT_ID(N'[dbo].[C]') AND referenced_object_id = OBJECT_ID(N'[dbo].[P]') AND (SELECT COUNT(*) FROM sys.foreign_key_columns WHERE constraint_object_id = f.object_id) = 1 AND EXISTS (SELECT * FROM sys.foreign_key_columns WHERE constraint_object_id = f.object_id AND constraint_column_id = 1 AND parent_column_id = COLUMNPROPERTY(OBJECT_ID(N'[dbo].[C]'), N'pid', 'ColumnId') AND referenced_column_id = COLUMNPROPERTY(OBJECT_ID(N'[dbo].[P]'), N'id', 'ColumnId'))));
+EXEC (@sql);
+GO
+DECLARE @sql nvarchar(max) = N'ALTER TABLE [dbo].[C] DROP CONSTRAINT ' + QUOTENAME((SELECT name FROM sys.key_constraints AS k WHERE type = 'UQ' AND parent_object_id = OBJECT_ID(N'[dbo].[C]') AND (SELECT COUNT(*) FROM sys.index_columns WHERE object_id = k.parent_object_id AND index_id = k.unique_index_id AND key_ordinal > 0) = 1 AND EXISTS (SELECT * FROM sys.index_columns WHERE object_id = k.parent_object_id AND index_id = k.unique_index_id AND key_ordinal = 1 AND column_id = COLUMNPROPERTY(OBJECT_ID(N'[dbo].[C]'), N'code', 'ColumnId'))));
+EXEC (@sql);
+GO
+DECLARE @sql nvarchar(max) = N'ALTER TABLE [dbo].[C] DROP CONSTRAINT ' + QUOTENAME((SELECT name FROM sys.check_constraints WHERE parent_object_id = OBJECT_ID(N'[dbo].[C]') AND parent_column_id = COLUMNPROPERTY(OBJECT_ID(N'[dbo].[C]'), N'n', 'ColumnId')));
+EXEC (@sql);
+GO
+DROP TABLE [dbo].[P];
+GO
+`
+	if got := Compare(from, to).Script(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,733 @@
+package schemadiff
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/sqlc-dev/teesql/ast"
+	"github.com/sqlc-dev/teesql/catalog"
+	"github.com/sqlc-dev/teesql/parser"
+)
+
+// Script returns the T-SQL script that applies the changes, one statement
+// per batch. Statements run in an order that SQL Server accepts:
+//
+//   - procedures and views are dropped first, views after the views that
+//     select from them;
+//   - foreign keys are dropped before the keys, columns and tables they
+//     reference, and constraints, indexes and defaults before the columns
+//     they are on;
+//   - schemas are created before their tables, tables before the
+//     constraints, indexes and foreign keys on them, and views after the
+//     views they select from.
+//
+// Constraints and indexes on a column whose type changes, and foreign keys
+// referencing a key that is dropped or changed, are dropped and created
+// again around the change. Unnamed constraints are dropped by looking up
+// their system name where the catalog views allow it; otherwise the script
+// has a comment in their place.
+func (d *Diff) Script() string {
+	w := &writer{}
+	rebuilt := d.rebuilt()
+
+	for _, c := range d.changes(Dropped, Procedure) {
+		w.stmt("DROP PROCEDURE " + qualified(name(c.from)) + ";")
+	}
+	var views []*catalog.View
+	for _, c := range d.changes(Dropped, View) {
+		views = append(views, c.from.(*catalog.View))
+	}
+	views = orderViews(d.from, views)
+	for i := len(views) - 1; i >= 0; i-- {
+		w.stmt("DROP VIEW " + qualified(name(views[i])) + ";")
+	}
+
+	// Foreign keys, then other constraints and indexes.
+	for _, fk := range []bool{true, false} {
+		for _, c := range d.Changes {
+			switch {
+			case c.Object == Table && c.Kind == Dropped && fk:
+				for _, cons := range c.from.(*catalog.Table).Constraints {
+					if cons.Kind == catalog.ForeignKey {
+						w.dropConstraint(c.table, cons)
+					}
+				}
+			case c.Object == Constraint && c.Kind != Added:
+				if cons := c.from.(*catalog.Constraint); (cons.Kind == catalog.ForeignKey) == fk {
+					w.dropConstraint(c.table, cons)
+				}
+			case c.Object == Index && c.Kind != Added && !fk:
+				w.stmt("DROP INDEX " + quote(c.from.(*catalog.Index).Name) + " ON " + qualified(c.table) + ";")
+			}
+		}
+		for _, r := range rebuilt {
+			switch {
+			case r.cons != nil && (r.cons.Kind == catalog.ForeignKey) == fk:
+				w.dropConstraint(r.table, r.cons)
+			case r.index != nil && !fk:
+				w.stmt("DROP INDEX " + quote(r.index.Name) + " ON " + qualified(r.table) + ";")
+			}
+		}
+	}
+
+	// Defaults on columns that are dropped or altered, and the columns.
+	for _, c := range d.changes(Altered, Column) {
+		from, to := c.from.(*catalog.Column), c.to.(*catalog.Column)
+		if computed(from) || computed(to) {
+			w.dropDefault(c.table, from)
+			w.stmt("ALTER TABLE " + qualified(c.table) + " DROP COLUMN " + quote(from.Name) + ";")
+		} else if from.Default != nil && (altersType(from, to) || defaultDefinition(from.Default) != defaultDefinition(to.Default)) {
+			w.dropDefault(c.table, from)
+		}
+	}
+	for _, c := range d.changes(Dropped, Column) {
+		col := c.from.(*catalog.Column)
+		w.dropDefault(c.table, col)
+		w.stmt("ALTER TABLE " + qualified(c.table) + " DROP COLUMN " + quote(col.Name) + ";")
+	}
+	for _, c := range d.changes(Dropped, Table) {
+		w.stmt("DROP TABLE " + qualified(c.table) + ";")
+	}
+
+	for _, c := range d.changes(Added, Schema) {
+		w.stmt("CREATE SCHEMA " + quote(c.Name) + ";")
+	}
+	for _, c := range d.changes(Added, Table) {
+		t := c.to.(*catalog.Table)
+		var defs []string
+		for _, col := range t.Columns {
+			defs = append(defs, columnDefinition(col))
+		}
+		for _, cons := range t.Constraints {
+			if cons.Kind != catalog.ForeignKey {
+				defs = append(defs, constraintDefinition(cons))
+			}
+		}
+		w.stmt("CREATE TABLE " + qualified(c.table) + " (\n\t" + strings.Join(defs, ",\n\t") + "\n);")
+		for _, ix := range t.Indexes {
+			w.stmt(indexDefinition(c.table, ix) + ";")
+		}
+	}
+	for _, c := range d.Changes {
+		if c.Object != Column || c.Kind == Dropped {
+			continue
+		}
+		to := c.to.(*catalog.Column)
+		if c.Kind == Added {
+			w.stmt("ALTER TABLE " + qualified(c.table) + " ADD " + columnDefinition(to) + ";")
+			continue
+		}
+		from := c.from.(*catalog.Column)
+		if computed(from) || computed(to) {
+			w.stmt("ALTER TABLE " + qualified(c.table) + " ADD " + columnDefinition(to) + ";")
+			continue
+		}
+		if identity(from) != identity(to) {
+			w.comment("ALTER COLUMN cannot change the IDENTITY property of " + c.Name + "; the table must be rebuilt")
+		}
+		if altersType(from, to) {
+			def := quote(to.Name) + " " + to.Type.String()
+			if to.Collation != "" {
+				def += " COLLATE " + to.Collation
+			}
+			w.stmt("ALTER TABLE " + qualified(c.table) + " ALTER COLUMN " + def + " " + nullability(to) + ";")
+		}
+		if to.Default != nil && (altersType(from, to) || defaultDefinition(from.Default) != defaultDefinition(to.Default)) {
+			w.stmt("ALTER TABLE " + qualified(c.table) + " ADD " + defaultDefinition(to.Default) + " FOR " + quote(to.Name) + ";")
+		}
+	}
+
+	// Constraints and indexes, then foreign keys.
+	for _, fk := range []bool{false, true} {
+		if fk {
+			for _, c := range d.changes(Added, Table) {
+				for _, cons := range c.to.(*catalog.Table).Constraints {
+					if cons.Kind == catalog.ForeignKey {
+						w.stmt("ALTER TABLE " + qualified(c.table) + " ADD " + constraintDefinition(cons) + ";")
+					}
+				}
+			}
+		}
+		for _, c := range d.Changes {
+			switch {
+			case c.Object == Constraint && c.Kind != Dropped:
+				if cons := c.to.(*catalog.Constraint); (cons.Kind == catalog.ForeignKey) == fk {
+					w.stmt("ALTER TABLE " + qualified(c.table) + " ADD " + constraintDefinition(cons) + ";")
+				}
+			case c.Object == Index && c.Kind != Dropped && !fk:
+				w.stmt(indexDefinition(c.table, c.to.(*catalog.Index)) + ";")
+			}
+		}
+		for _, r := range rebuilt {
+			t := d.to.Table(r.table)
+			switch {
+			case t == nil:
+			case r.cons != nil && (r.cons.Kind == catalog.ForeignKey) == fk:
+				for _, cons := range t.Constraints {
+					if constraintKey(cons) == constraintKey(r.cons) {
+						w.stmt("ALTER TABLE " + qualified(r.table) + " ADD " + constraintDefinition(cons) + ";")
+					}
+				}
+			case r.index != nil && !fk:
+				if ix := t.Index(r.index.Name); ix != nil {
+					w.stmt(indexDefinition(r.table, ix) + ";")
+				}
+			}
+		}
+	}
+
+	views = nil
+	for _, c := range d.Changes {
+		if c.Object == View && c.Kind != Dropped {
+			views = append(views, c.to.(*catalog.View))
+		}
+	}
+	for _, v := range orderViews(d.to, views) {
+		verb := "CREATE VIEW "
+		if d.from.View(name(v)) != nil {
+			verb = "CREATE OR ALTER VIEW "
+		}
+		w.stmt(verb + viewDefinition(v))
+	}
+	for _, c := range d.Changes {
+		if c.Object == Procedure && c.Kind != Dropped {
+			verb := "CREATE PROCEDURE "
+			if c.Kind == Altered {
+				verb = "CREATE OR ALTER PROCEDURE "
+			}
+			w.stmt(verb + procedureDefinition(c.to.(*catalog.Procedure)))
+		}
+	}
+	for _, c := range d.changes(Dropped, Schema) {
+		w.stmt("DROP SCHEMA " + quote(c.Name) + ";")
+	}
+	return w.String()
+}
+
+func (d *Diff) changes(kind, object string) []*Change {
+	var changes []*Change
+	for _, c := range d.Changes {
+		if c.Kind == kind && c.Object == object {
+			changes = append(changes, c)
+		}
+	}
+	return changes
+}
+
+// rebuild is an unchanged constraint or index that must be dropped and
+// created again.
+type rebuild struct {
+	table catalog.ObjectName
+	cons  *catalog.Constraint
+	index *catalog.Index
+}
+
+// rebuilt returns the unchanged constraints and indexes that block the
+// changes: those on a column whose type changes, and foreign keys
+// referencing such a column or a key that is dropped or changed.
+func (d *Diff) rebuilt() []rebuild {
+	changed := map[any]bool{}
+	for _, c := range d.Changes {
+		if c.from != nil {
+			changed[c.from] = true
+		}
+		// The foreign keys of dropped tables are dropped with them.
+		if t, ok := c.from.(*catalog.Table); ok {
+			for _, cons := range t.Constraints {
+				changed[cons] = true
+			}
+		}
+	}
+	var rebuilt []rebuild
+	add := func(r rebuild) {
+		if r.cons != nil && !changed[r.cons] || r.index != nil && !changed[r.index] {
+			rebuilt = append(rebuilt, r)
+			if r.cons != nil {
+				changed[r.cons] = true
+			} else {
+				changed[r.index] = true
+			}
+		}
+	}
+	for _, c := range d.changes(Altered, Column) {
+		from, to := c.from.(*catalog.Column), c.to.(*catalog.Column)
+		if computed(from) || computed(to) || !altersType(from, to) {
+			continue
+		}
+		t := d.from.Table(c.table)
+		for _, cons := range t.Constraints {
+			if involves(cons, from.Name) {
+				add(rebuild{table: c.table, cons: cons})
+			}
+		}
+		for _, ix := range t.Indexes {
+			if indexInvolves(ix, from.Name) {
+				add(rebuild{table: c.table, index: ix})
+			}
+		}
+		d.eachForeignKey(func(table catalog.ObjectName, fk *catalog.Constraint) {
+			if d.sameTable(fk.References, c.table) && contains(fk.ReferencedColumns, from.Name) {
+				add(rebuild{table: table, cons: fk})
+			}
+		})
+	}
+	// Keys that are dropped, changed or rebuilt.
+	var keys []rebuild
+	for _, c := range d.Changes {
+		if c.Object == Constraint && c.Kind != Added {
+			keys = append(keys, rebuild{table: c.table, cons: c.from.(*catalog.Constraint)})
+		}
+	}
+	keys = append(keys, rebuilt...)
+	for _, key := range keys {
+		if key.cons == nil || key.cons.Kind != catalog.PrimaryKey && key.cons.Kind != catalog.Unique {
+			continue
+		}
+		d.eachForeignKey(func(table catalog.ObjectName, fk *catalog.Constraint) {
+			if d.sameTable(fk.References, key.table) && sameColumns(fk.ReferencedColumns, key.cons.Columns) {
+				add(rebuild{table: table, cons: fk})
+			}
+		})
+	}
+	return rebuilt
+}
+
+// eachForeignKey calls f for each foreign key in the source catalog.
+func (d *Diff) eachForeignKey(f func(table catalog.ObjectName, fk *catalog.Constraint)) {
+	for _, s := range d.from.Schemas {
+		for _, t := range s.Tables {
+			for _, cons := range t.Constraints {
+				if cons.Kind == catalog.ForeignKey {
+					f(tableName(t), cons)
+				}
+			}
+		}
+	}
+}
+
+func (d *Diff) sameTable(ref, table catalog.ObjectName) bool {
+	if ref.Schema == "" {
+		ref.Schema = d.from.DefaultSchema
+	}
+	return strings.EqualFold(ref.Schema, table.Schema) && strings.EqualFold(ref.Name, table.Name)
+}
+
+// altersType reports whether a column needs ALTER COLUMN.
+func altersType(from, to *catalog.Column) bool {
+	return from.Type != to.Type || from.Nullable != to.Nullable || !strings.EqualFold(from.Collation, to.Collation)
+}
+
+// involves reports whether a constraint is on a column or, for a CHECK
+// constraint, refers to it.
+func involves(cons *catalog.Constraint, column string) bool {
+	if contains(cons.Columns, column) {
+		return true
+	}
+	found := false
+	ast.Inspect(cons.Check, func(n ast.Node) bool {
+		if ref, ok := n.(*ast.ColumnReferenceExpression); ok && ref.MultiPartIdentifier != nil {
+			ids := ref.MultiPartIdentifier.Identifiers
+			if len(ids) > 0 && strings.EqualFold(ids[len(ids)-1].Value, column) {
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+func indexInvolves(ix *catalog.Index, column string) bool {
+	for _, c := range ix.Columns {
+		if strings.EqualFold(c.Name, column) {
+			return true
+		}
+	}
+	return contains(ix.Include, column)
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func sameColumns(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, n := range a {
+		if !contains(b, n) {
+			return false
+		}
+	}
+	return true
+}
+
+type writer struct {
+	b strings.Builder
+}
+
+func (w *writer) String() string {
+	return w.b.String()
+}
+
+// stmt writes a statement in a batch of its own.
+func (w *writer) stmt(s string) {
+	w.b.WriteString(s)
+	w.b.WriteString("\nGO\n")
+}
+
+func (w *writer) comment(s string) {
+	w.b.WriteString("-- " + s + "\n")
+}
+
+// dropConstraint writes the statement dropping a constraint. Unnamed
+// constraints are looked up by their table and columns: a foreign key by
+// its columns and referenced table and columns, in order, a UNIQUE
+// constraint by its key columns, and a CHECK constraint by the column it
+// is declared on, or as a table constraint. If several constraints match,
+// the lookup fails rather than dropping one of them.
+func (w *writer) dropConstraint(table catalog.ObjectName, cons *catalog.Constraint) {
+	id := "OBJECT_ID(" + literal(qualified(table)) + ")"
+	switch {
+	case cons.Name != "":
+		w.stmt("ALTER TABLE " + qualified(table) + " DROP CONSTRAINT " + quote(cons.Name) + ";")
+	case cons.Kind == catalog.PrimaryKey:
+		w.stmt(dropByLookup(table, "SELECT name FROM sys.key_constraints WHERE type = 'PK' AND parent_object_id = "+id))
+	case cons.Kind == catalog.Unique:
+		query := "SELECT name FROM sys.key_constraints AS k WHERE type = 'UQ' AND parent_object_id = " + id +
+			" AND (SELECT COUNT(*) FROM sys.index_columns WHERE object_id = k.parent_object_id AND index_id = k.unique_index_id AND key_ordinal > 0) = " + strconv.Itoa(len(cons.Columns))
+		for i, col := range cons.Columns {
+			query += " AND EXISTS (SELECT * FROM sys.index_columns WHERE object_id = k.parent_object_id AND index_id = k.unique_index_id" +
+				" AND key_ordinal = " + strconv.Itoa(i+1) + " AND column_id = " + columnID(id, col) + ")"
+		}
+		w.stmt(dropByLookup(table, query))
+	case cons.Kind == catalog.ForeignKey:
+		ref := cons.References
+		if ref.Schema == "" {
+			ref.Schema = table.Schema
+		}
+		refID := "OBJECT_ID(" + literal(qualified(ref)) + ")"
+		query := "SELECT name FROM sys.foreign_keys AS f WHERE parent_object_id = " + id + " AND referenced_object_id = " + refID +
+			" AND (SELECT COUNT(*) FROM sys.foreign_key_columns WHERE constraint_object_id = f.object_id) = " + strconv.Itoa(len(cons.Columns))
+		for i, col := range cons.Columns {
+			query += " AND EXISTS (SELECT * FROM sys.foreign_key_columns WHERE constraint_object_id = f.object_id" +
+				" AND constraint_column_id = " + strconv.Itoa(i+1) + " AND parent_column_id = " + columnID(id, col)
+			if len(cons.ReferencedColumns) == len(cons.Columns) {
+				query += " AND referenced_column_id = " + columnID(refID, cons.ReferencedColumns[i])
+			}
+			query += ")"
+		}
+		w.stmt(dropByLookup(table, query))
+	case cons.Kind == catalog.Check:
+		column := "0"
+		if len(cons.Columns) == 1 {
+			column = columnID(id, cons.Columns[0])
+		}
+		w.stmt(dropByLookup(table, "SELECT name FROM sys.check_constraints WHERE parent_object_id = "+id+" AND parent_column_id = "+column))
+	default:
+		w.comment("drop the unnamed constraint " + constraintName(table, cons) + " by its system name")
+	}
+}
+
+// columnID returns the expression for the id of a column of the table
+// whose id is table.
+func columnID(table, column string) string {
+	return "COLUMNPROPERTY(" + table + ", " + literal(column) + ", 'ColumnId')"
+}
+
+func (w *writer) dropDefault(table catalog.ObjectName, col *catalog.Column) {
+	switch {
+	case col.Default == nil:
+	case col.Default.Name != "":
+		w.stmt("ALTER TABLE " + qualified(table) + " DROP CONSTRAINT " + quote(col.Default.Name) + ";")
+	default:
+		id := "OBJECT_ID(" + literal(qualified(table)) + ")"
+		w.stmt(dropByLookup(table, "SELECT name FROM sys.default_constraints WHERE parent_object_id = "+id+" AND parent_column_id = "+columnID(id, col.Name)))
+	}
+}
+
+// dropByLookup returns a statement dropping the constraint whose name
+// query returns.
+func dropByLookup(table catalog.ObjectName, query string) string {
+	return "DECLARE @sql nvarchar(max) = N'ALTER TABLE " + strings.ReplaceAll(qualified(table), "'", "''") +
+		" DROP CONSTRAINT ' + QUOTENAME((" + query + "));\nEXEC (@sql);"
+}
+
+// quote returns a name as a delimited identifier.
+func quote(name string) string {
+	return "[" + strings.ReplaceAll(name, "]", "]]") + "]"
+}
+
+func qualified(n catalog.ObjectName) string {
+	if n.Schema == "" {
+		return quote(n.Name)
+	}
+	return quote(n.Schema) + "." + quote(n.Name)
+}
+
+// literal returns s as a Unicode string literal.
+func literal(s string) string {
+	return "N'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+func name(obj any) catalog.ObjectName {
+	switch o := obj.(type) {
+	case *catalog.View:
+		return catalog.ObjectName{Schema: o.Schema, Name: o.Name}
+	case *catalog.Procedure:
+		return catalog.ObjectName{Schema: o.Schema, Name: o.Name}
+	}
+	return catalog.ObjectName{}
+}
+
+func identitySpec(id *catalog.Identity) string {
+	return "IDENTITY(" + strconv.FormatInt(id.Seed, 10) + ", " + strconv.FormatInt(id.Increment, 10) + ")"
+}
+
+// unparen removes the parentheses around an expression.
+func unparen(e ast.ScalarExpression) ast.ScalarExpression {
+	for {
+		p, ok := e.(*ast.ParenthesisExpression)
+		if !ok {
+			return e
+		}
+		e = p.Expression
+	}
+}
+
+func unparenBoolean(e ast.BooleanExpression) ast.BooleanExpression {
+	for {
+		p, ok := e.(*ast.BooleanParenthesisExpression)
+		if !ok {
+			return e
+		}
+		e = p.Expression
+	}
+}
+
+func columnDefinition(col *catalog.Column) string {
+	def := quote(col.Name)
+	if computed(col) {
+		def += " AS (" + text(unparen(col.Computed)) + ")"
+		if col.Persisted {
+			def += " PERSISTED"
+		}
+		return def
+	}
+	def += " " + col.Type.String()
+	if col.Collation != "" {
+		def += " COLLATE " + col.Collation
+	}
+	if col.Identity != nil {
+		def += " " + identitySpec(col.Identity)
+	}
+	def += " " + nullability(col)
+	if col.Default != nil {
+		def += " " + defaultDefinition(col.Default)
+	}
+	return def
+}
+
+func defaultDefinition(def *catalog.Default) string {
+	if def == nil {
+		return "none"
+	}
+	s := "DEFAULT (" + text(unparen(def.Expression)) + ")"
+	if def.Name != "" {
+		s = "CONSTRAINT " + quote(def.Name) + " " + s
+	}
+	return s
+}
+
+func columnList(names []string) string {
+	quoted := make([]string, len(names))
+	for i, n := range names {
+		quoted[i] = quote(n)
+	}
+	return "(" + strings.Join(quoted, ", ") + ")"
+}
+
+func constraintDefinition(c *catalog.Constraint) string {
+	var def string
+	switch c.Kind {
+	case catalog.PrimaryKey, catalog.Unique:
+		def = "UNIQUE"
+		if c.Kind == catalog.PrimaryKey {
+			def = "PRIMARY KEY"
+		}
+		if c.Clustered {
+			def += " CLUSTERED "
+		} else {
+			def += " NONCLUSTERED "
+		}
+		def += columnList(c.Columns)
+	case catalog.ForeignKey:
+		ref := c.References
+		if ref.Schema == "" {
+			ref.Schema = catalog.DefaultSchema
+		}
+		def = "FOREIGN KEY " + columnList(c.Columns) + " REFERENCES " + qualified(ref)
+		if len(c.ReferencedColumns) > 0 {
+			def += " " + columnList(c.ReferencedColumns)
+		}
+		if c.OnDelete != "" {
+			def += " ON DELETE " + keywords(c.OnDelete)
+		}
+		if c.OnUpdate != "" {
+			def += " ON UPDATE " + keywords(c.OnUpdate)
+		}
+	case catalog.Check:
+		def = "CHECK (" + text(unparenBoolean(c.Check)) + ")"
+	}
+	if c.Name != "" {
+		def = "CONSTRAINT " + quote(c.Name) + " " + def
+	}
+	return def
+}
+
+// keywords returns an action such as NoAction as the keywords NO ACTION.
+func keywords(action string) string {
+	var b strings.Builder
+	for i, r := range action {
+		if i > 0 && unicode.IsUpper(r) {
+			b.WriteByte(' ')
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
+
+func indexDefinition(table catalog.ObjectName, ix *catalog.Index) string {
+	def := "CREATE "
+	if ix.Unique {
+		def += "UNIQUE "
+	}
+	if ix.Clustered {
+		def += "CLUSTERED "
+	} else {
+		def += "NONCLUSTERED "
+	}
+	if ix.Columnstore {
+		def += "COLUMNSTORE "
+	}
+	def += "INDEX " + quote(ix.Name) + " ON " + qualified(table)
+	if len(ix.Columns) > 0 {
+		cols := make([]string, len(ix.Columns))
+		for i, c := range ix.Columns {
+			cols[i] = quote(c.Name)
+			if c.Descending {
+				cols[i] += " DESC"
+			}
+		}
+		def += " (" + strings.Join(cols, ", ") + ")"
+	}
+	if len(ix.Include) > 0 {
+		def += " INCLUDE " + columnList(ix.Include)
+	}
+	if ix.Filter != nil {
+		def += " WHERE " + text(ix.Filter)
+	}
+	return def
+}
+
+// viewDefinition returns the text of a CREATE VIEW statement after CREATE
+// VIEW: the source text of the view after its name, or the formatted
+// definition if the view has no source text.
+func viewDefinition(v *catalog.View) string {
+	def := qualified(name(v))
+	if rest, ok := afterName(v.Source); ok {
+		return def + rest
+	}
+	if v.SchemaBinding {
+		def += " WITH SCHEMABINDING"
+	}
+	return def + "\nAS\n" + text(v.Definition)
+}
+
+// procedureDefinition returns the text of a CREATE PROCEDURE statement
+// after CREATE PROCEDURE: the source text of the procedure after its name,
+// or the formatted definition if the procedure has no source text.
+func procedureDefinition(p *catalog.Procedure) string {
+	def := qualified(name(p))
+	if rest, ok := afterName(p.Source); ok {
+		return def + rest
+	}
+	var params []string
+	for _, prm := range p.Parameters {
+		s := prm.Name + " " + prm.Type.String()
+		if prm.Varying {
+			s += " VARYING"
+		}
+		if prm.NotNull {
+			s += " NOT NULL"
+		}
+		if prm.Default != nil {
+			s += " = " + text(prm.Default)
+		}
+		if prm.Output {
+			s += " OUTPUT"
+		}
+		if prm.ReadOnly {
+			s += " READONLY"
+		}
+		params = append(params, s)
+	}
+	if len(params) > 0 {
+		def += "\n\t" + strings.Join(params, ",\n\t")
+	}
+	var opts []string
+	if p.NativeCompilation {
+		opts = append(opts, "NATIVE_COMPILATION")
+	}
+	if p.SchemaBinding {
+		opts = append(opts, "SCHEMABINDING")
+	}
+	if p.Encryption {
+		opts = append(opts, "ENCRYPTION")
+	}
+	if p.Recompile {
+		opts = append(opts, "RECOMPILE")
+	}
+	switch strings.ToUpper(p.ExecuteAs) {
+	case "":
+	case "CALLER", "SELF", "OWNER":
+		opts = append(opts, "EXECUTE AS "+strings.ToUpper(p.ExecuteAs))
+	default:
+		opts = append(opts, "EXECUTE AS '"+strings.ReplaceAll(p.ExecuteAs, "'", "''")+"'")
+	}
+	if len(opts) > 0 {
+		def += "\nWITH " + strings.Join(opts, ", ")
+	}
+	return def + "\nAS\n" + text(p.Body)
+}
+
+// afterName returns the text of a CREATE, CREATE OR ALTER or ALTER VIEW or
+// PROCEDURE statement after the object name, which the script writes
+// qualified in its place.
+func afterName(source string) (string, bool) {
+	l := parser.NewLexer(source)
+	for {
+		tok := l.NextToken()
+		switch {
+		case tok.Type == parser.TokenEOF:
+			return "", false
+		case tok.Type != parser.TokenView && !strings.EqualFold(tok.Literal, "PROCEDURE") && !strings.EqualFold(tok.Literal, "PROC"):
+			continue
+		}
+		for {
+			tok = l.NextToken()
+			if tok.Type == parser.TokenEOF {
+				return "", false
+			}
+			next := l.NextToken()
+			if next.Type != parser.TokenDot {
+				return source[tok.Pos+len(tok.Literal):], true
+			}
+		}
+	}
+}
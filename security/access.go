@@ -0,0 +1,140 @@
+package security
+
+import (
+	"sort"
+	"strings"
+)
+
+// fixedRoles holds the fixed database roles with the permissions their
+// members have on the database without a GRANT or DENY.
+var fixedRoles = map[string][]Entry{
+	"db_owner":          {{Permission: "CONTROL", State: Grant}},
+	"db_datareader":     {{Permission: "SELECT", State: Grant}},
+	"db_datawriter":     {{Permission: "INSERT", State: Grant}, {Permission: "UPDATE", State: Grant}, {Permission: "DELETE", State: Grant}},
+	"db_denydatareader": {{Permission: "SELECT", State: Deny}},
+	"db_denydatawriter": {{Permission: "INSERT", State: Deny}, {Permission: "UPDATE", State: Deny}, {Permission: "DELETE", State: Deny}},
+	"db_accessadmin":    nil,
+	"db_backupoperator": nil,
+	"db_ddladmin":       nil,
+	"db_securityadmin":  nil,
+}
+
+// Access is the effective state of a permission for a principal.
+type Access struct {
+	Permission string `json:"Permission"`
+	Allowed    bool   `json:"Allowed"`
+	// GrantOption reports whether the principal may grant the permission
+	// to others.
+	GrantOption bool `json:"GrantOption,omitempty"`
+	// Because lists the entries that decide: the denials if the permission
+	// is denied, and otherwise the grants that allow it. The permissions of
+	// fixed database roles are listed as entries of the role.
+	Because []*Entry `json:"Because,omitempty"`
+}
+
+// Roles returns the roles that principal belongs to, directly or through
+// other roles, in the order they are reached. Every user belongs to
+// public, which is listed last.
+func (m *Model) Roles(principal string) []string {
+	var roles []string
+	seen := map[string]bool{strings.ToLower(principal): true}
+	queue := []string{principal}
+	for len(queue) > 0 {
+		p := m.Principal(queue[0])
+		queue = queue[1:]
+		if p == nil {
+			continue
+		}
+		for _, r := range p.MemberOf {
+			if !seen[strings.ToLower(r)] {
+				seen[strings.ToLower(r)] = true
+				roles = append(roles, r)
+				queue = append(queue, r)
+			}
+		}
+	}
+	if !seen[Public] {
+		roles = append(roles, Public)
+	}
+	return roles
+}
+
+// Check returns whether principal may use permission on a securable. The
+// permission is granted or denied by an entry for the principal or any of
+// its roles, on the securable or on the object, schema or database that
+// contains it, for the permission itself or for CONTROL. Object names
+// without a schema are in the model's default schema.
+func (m *Model) Check(principal, permission string, on Securable) *Access {
+	permission = strings.ToUpper(permission)
+	a := &Access{Permission: permission}
+	var grants []*Entry
+	for _, e := range m.applicable(principal, on) {
+		if e.Permission != permission && e.Permission != "CONTROL" {
+			continue
+		}
+		if e.State == Deny {
+			a.Because = append(a.Because, e)
+			continue
+		}
+		grants = append(grants, e)
+	}
+	if len(a.Because) > 0 {
+		return a
+	}
+	a.Allowed = len(grants) > 0
+	a.Because = grants
+	for _, e := range grants {
+		a.GrantOption = a.GrantOption || e.State == GrantWithGrantOption
+	}
+	return a
+}
+
+// Permissions returns the effective state of each permission granted or
+// denied to principal on a securable, as Check describes, ordered by
+// permission name.
+func (m *Model) Permissions(principal string, on Securable) []*Access {
+	names := map[string]bool{}
+	for _, e := range m.applicable(principal, on) {
+		names[e.Permission] = true
+	}
+	var access []*Access
+	for name := range names {
+		access = append(access, m.Check(principal, name, on))
+	}
+	sort.Slice(access, func(i, j int) bool { return access[i].Permission < access[j].Permission })
+	return access
+}
+
+// applicable returns the entries for principal or its roles on the
+// securable or those that contain it, including those of fixed roles.
+func (m *Model) applicable(principal string, on Securable) []*Entry {
+	if on.Class == ClassObject && on.Name != "" && !strings.Contains(on.Name, ".") {
+		on.Name = m.DefaultSchema + "." + on.Name
+	}
+	who := map[string]bool{strings.ToLower(principal): true}
+	for _, r := range m.Roles(principal) {
+		who[strings.ToLower(r)] = true
+	}
+	within := map[string]bool{}
+	for s, ok := on, true; ok; s, ok = s.parent() {
+		within[s.key()] = true
+	}
+	var entries []*Entry
+	for _, e := range m.Entries {
+		if who[strings.ToLower(e.Principal)] && within[e.Securable.key()] {
+			entries = append(entries, e)
+		}
+	}
+	var roles []string
+	for r := range who {
+		roles = append(roles, r)
+	}
+	sort.Strings(roles)
+	for _, r := range roles {
+		for _, e := range fixedRoles[r] {
+			e.Principal, e.Securable = r, Database
+			entries = append(entries, &e)
+		}
+	}
+	return entries
+}
@@ -0,0 +1,86 @@
+package security
+
+import (
+	"github.com/sqlc-dev/teesql/catalog"
+)
+
+// Protection describes how the rows and columns of a table are protected
+// from the users who can query it.
+type Protection struct {
+	Table catalog.ObjectName `json:"Table"`
+	// Predicates are the row-level security predicates on the table.
+	Predicates []*PolicyPredicate `json:"Predicates,omitempty"`
+	// Masked are the columns with a dynamic data masking function.
+	Masked []*MaskedColumn `json:"Masked,omitempty"`
+	// Encrypted are the columns encrypted with Always Encrypted.
+	Encrypted []*EncryptedColumn `json:"Encrypted,omitempty"`
+	// NoPolicy is set if no enabled security policy filters or blocks rows
+	// of the table.
+	NoPolicy bool `json:"NoPolicy,omitempty"`
+}
+
+// PolicyPredicate is a security predicate and the policy it belongs to.
+type PolicyPredicate struct {
+	Policy  catalog.ObjectName `json:"Policy"`
+	Enabled bool               `json:"Enabled"`
+	// Kind is catalog.FilterPredicate or catalog.BlockPredicate.
+	Kind      string             `json:"Kind"`
+	Function  catalog.ObjectName `json:"Function"`
+	Operation string             `json:"Operation"`
+}
+
+// MaskedColumn is a column and its masking function.
+type MaskedColumn struct {
+	Column   string `json:"Column"`
+	Function string `json:"Function"`
+}
+
+// EncryptedColumn is a column and the key that encrypts it.
+type EncryptedColumn struct {
+	Column string `json:"Column"`
+	catalog.Encryption
+}
+
+// Protections returns the protection of every table in the catalog, in
+// catalog order. Predicates of policies on tables missing from the catalog
+// are ignored.
+func Protections(c *catalog.Catalog) []*Protection {
+	var protections []*Protection
+	byTable := map[*catalog.Table]*Protection{}
+	for _, s := range c.Schemas {
+		for _, t := range s.Tables {
+			p := &Protection{Table: catalog.ObjectName{Schema: t.Schema, Name: t.Name}, NoPolicy: true}
+			for _, col := range t.Columns {
+				if col.Mask != "" {
+					p.Masked = append(p.Masked, &MaskedColumn{Column: col.Name, Function: col.Mask})
+				}
+				if col.Encryption != nil {
+					p.Encrypted = append(p.Encrypted, &EncryptedColumn{Column: col.Name, Encryption: *col.Encryption})
+				}
+			}
+			byTable[t] = p
+			protections = append(protections, p)
+		}
+	}
+	for _, s := range c.Schemas {
+		for _, policy := range s.SecurityPolicies {
+			for _, pred := range policy.Predicates {
+				p := byTable[c.Table(pred.Table)]
+				if p == nil {
+					continue
+				}
+				p.Predicates = append(p.Predicates, &PolicyPredicate{
+					Policy:    catalog.ObjectName{Schema: policy.Schema, Name: policy.Name},
+					Enabled:   policy.Enabled,
+					Kind:      pred.Kind,
+					Function:  pred.Function,
+					Operation: pred.Operation,
+				})
+				if policy.Enabled {
+					p.NoPolicy = false
+				}
+			}
+		}
+	}
+	return protections
+}
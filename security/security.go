@@ -0,0 +1,630 @@
+// Package security models the principals and permissions that security
+// scripts set up.
+//
+// A Model is built by replaying CREATE LOGIN, CREATE USER, CREATE ROLE and
+// ALTER ROLE statements (and the legacy sp_addrolemember procedures) into
+// principals and role memberships, and GRANT, DENY and REVOKE statements
+// into permission entries, much as SQL Server records them in
+// sys.database_principals, sys.database_role_members and
+// sys.database_permissions. The model then answers what a principal may do
+// on a securable, taking role membership, the schema and database that
+// contain an object, CONTROL, and the fixed database roles into account.
+// A DENY anywhere among these overrides any GRANT.
+//
+// Protections reports, from a catalog, which tables are guarded by
+// row-level security policies, dynamic data masking or Always Encrypted.
+package security
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/sqlc-dev/teesql/ast"
+	"github.com/sqlc-dev/teesql/catalog"
+)
+
+// Principal kinds.
+const (
+	KindLogin           = "Login"
+	KindUser            = "User"
+	KindRole            = "Role"
+	KindApplicationRole = "ApplicationRole"
+	KindServerRole      = "ServerRole"
+)
+
+// Public is the role every user belongs to.
+const Public = "public"
+
+// Principal is a login, user or role.
+type Principal struct {
+	Name string `json:"Name"`
+	Kind string `json:"Kind"`
+	// Login is the login a user is mapped to. It is empty for users
+	// created WITHOUT LOGIN.
+	Login string `json:"Login,omitempty"`
+	// Owner is the owner named by AUTHORIZATION in CREATE ROLE.
+	Owner string `json:"Owner,omitempty"`
+	// MemberOf lists the roles the principal is a direct member of, in the
+	// order it was added to them.
+	MemberOf []string `json:"MemberOf,omitempty"`
+}
+
+// Permission states, as in the state_desc column of
+// sys.database_permissions.
+const (
+	Grant                = "GRANT"
+	GrantWithGrantOption = "GRANT_WITH_GRANT_OPTION"
+	Deny                 = "DENY"
+)
+
+// Securable classes. Object is used for ON clauses without a class, such as
+// GRANT SELECT ON dbo.Orders, and Database for statements without an ON
+// clause. The other classes are those of the ON class::name form, such as
+// Type or XmlSchemaCollection.
+const (
+	ClassDatabase = "Database"
+	ClassSchema   = "Schema"
+	ClassObject   = "Object"
+)
+
+// Securable is what a permission applies to.
+type Securable struct {
+	Class string `json:"Class"`
+	// Name is the name of the securable: schema-qualified for objects, and
+	// empty for the database.
+	Name string `json:"Name,omitempty"`
+	// Column is set for column permissions.
+	Column string `json:"Column,omitempty"`
+}
+
+// Database is the current database.
+var Database = Securable{Class: ClassDatabase}
+
+// Object returns the securable for a table, view, procedure or function
+// named "schema.name".
+func Object(name string) Securable {
+	return Securable{Class: ClassObject, Name: name}
+}
+
+// String returns the securable as written in an ON clause, e.g.
+// "OBJECT::dbo.Orders(Price)".
+func (s Securable) String() string {
+	if s.Class == ClassDatabase && s.Name == "" {
+		return "DATABASE"
+	}
+	str := strings.ToUpper(s.Class) + "::" + s.Name
+	if s.Column != "" {
+		str += "(" + s.Column + ")"
+	}
+	return str
+}
+
+func (s Securable) key() string {
+	return strings.ToLower(s.Class + "::" + s.Name + "(" + s.Column + ")")
+}
+
+// parent returns the securable that contains s: the object of a column,
+// the schema of an object, and the database of a schema.
+func (s Securable) parent() (Securable, bool) {
+	switch {
+	case s.Column != "":
+		return Securable{Class: s.Class, Name: s.Name}, true
+	case s.Class == ClassDatabase:
+		return Securable{}, false
+	case s.Class == ClassSchema:
+		return Database, true
+	}
+	if schema, _, ok := strings.Cut(s.Name, "."); ok {
+		return Securable{Class: ClassSchema, Name: schema}, true
+	}
+	return Database, true
+}
+
+// Entry is a permission granted or denied to a principal.
+type Entry struct {
+	Principal  string    `json:"Principal"`
+	Permission string    `json:"Permission"`
+	Securable  Securable `json:"Securable"`
+	State      string    `json:"State"`
+	// Grantor is the principal named by the AS clause, or empty.
+	Grantor string `json:"Grantor,omitempty"`
+}
+
+// String returns the entry as the statement that creates it, e.g.
+// "GRANT SELECT ON OBJECT::dbo.Orders TO Sales WITH GRANT OPTION".
+func (e *Entry) String() string {
+	verb := "GRANT"
+	if e.State == Deny {
+		verb = "DENY"
+	}
+	s := verb + " " + e.Permission
+	if e.Securable.Class != ClassDatabase {
+		s += " ON " + e.Securable.String()
+	}
+	s += " TO " + e.Principal
+	if e.State == GrantWithGrantOption {
+		s += " WITH GRANT OPTION"
+	}
+	return s
+}
+
+// ErrNotFound is wrapped by errors returned when a statement refers to a
+// principal that does not exist.
+var ErrNotFound = errors.New("does not exist")
+
+// ErrExists is wrapped by errors returned when a statement creates a
+// principal that already exists.
+var ErrExists = errors.New("already exists")
+
+// Model holds principals and permissions.
+type Model struct {
+	// DefaultSchema is the schema assumed for object names without one.
+	DefaultSchema string `json:"DefaultSchema"`
+	// Principals are the principals created, in order of creation.
+	Principals []*Principal `json:"Principals,omitempty"`
+	// Entries are the permissions granted or denied, in the order they
+	// were first set.
+	Entries []*Entry `json:"Entries,omitempty"`
+}
+
+// New returns an empty model with the public role.
+func New() *Model {
+	return &Model{
+		DefaultSchema: catalog.DefaultSchema,
+		Principals:    []*Principal{{Name: Public, Kind: KindRole}},
+	}
+}
+
+// Build returns a model populated from the given scripts, applied in order.
+func Build(scripts ...*ast.Script) (*Model, error) {
+	m := New()
+	var errs []error
+	for _, script := range scripts {
+		if err := m.Update(script); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return m, errors.Join(errs...)
+}
+
+// Principal returns the named database principal, a user or role, or nil.
+// Fixed database roles such as db_datareader are principals without being
+// created. Logins and server roles are named apart from database
+// principals; ServerPrincipal returns them.
+func (m *Model) Principal(name string) *Principal {
+	if p := m.lookup(name, false); p != nil {
+		return p
+	}
+	if _, ok := fixedRoles[strings.ToLower(name)]; ok {
+		return &Principal{Name: strings.ToLower(name), Kind: KindRole}
+	}
+	return nil
+}
+
+// ServerPrincipal returns the named login or server role, or nil.
+func (m *Model) ServerPrincipal(name string) *Principal {
+	return m.lookup(name, true)
+}
+
+// lookup returns the named server or database principal, or nil.
+func (m *Model) lookup(name string, server bool) *Principal {
+	for _, p := range m.Principals {
+		if isServer(p.Kind) == server && strings.EqualFold(p.Name, name) {
+			return p
+		}
+	}
+	return nil
+}
+
+// isServer reports whether principals of the kind are server principals.
+func isServer(kind string) bool {
+	return kind == KindLogin || kind == KindServerRole
+}
+
+// Update applies the statements of script to the model. Statements that
+// do not concern security are ignored. Errors for individual statements
+// are joined; the others are still applied.
+func (m *Model) Update(script *ast.Script) error {
+	if script == nil {
+		return nil
+	}
+	var errs []error
+	for _, batch := range script.Batches {
+		for _, stmt := range batch.Statements {
+			if err := m.Apply(stmt); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Apply applies a single statement to the model.
+func (m *Model) Apply(stmt ast.Statement) error {
+	switch s := stmt.(type) {
+	case *ast.BeginEndBlockStatement:
+		if s.StatementList == nil {
+			return nil
+		}
+		var errs []error
+		for _, inner := range s.StatementList.Statements {
+			if err := m.Apply(inner); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	case *ast.CreateLoginStatement:
+		return m.create(&Principal{Name: name(s.Name), Kind: KindLogin})
+	case *ast.CreateUserStatement:
+		p := &Principal{Name: name(s.Name), Kind: KindUser, Login: name(s.Name)}
+		if o := s.UserLoginOption; o != nil {
+			p.Login = name(o.Identifier)
+		}
+		return m.create(p)
+	case *ast.CreateRoleStatement:
+		return m.create(&Principal{Name: name(s.Name), Kind: KindRole, Owner: name(s.Owner)})
+	case *ast.CreateApplicationRoleStatement:
+		return m.create(&Principal{Name: name(s.Name), Kind: KindApplicationRole})
+	case *ast.CreateServerRoleStatement:
+		return m.create(&Principal{Name: name(s.Name), Kind: KindServerRole, Owner: name(s.Owner)})
+	case *ast.AlterRoleStatement:
+		return m.alterRole(name(s.Name), s.Action, false)
+	case *ast.AlterServerRoleStatement:
+		return m.alterRole(name(s.Name), s.Action, true)
+	case *ast.DropUserStatement:
+		return m.drop(name(s.Name), s.IsIfExists, false)
+	case *ast.DropRoleStatement:
+		return m.drop(name(s.Name), s.IsIfExists, false)
+	case *ast.DropLoginStatement:
+		return m.drop(name(s.Name), s.IsIfExists, true)
+	case *ast.ExecuteStatement:
+		return m.execute(s)
+	case *ast.GrantStatement:
+		state := Grant
+		if s.WithGrantOption {
+			state = GrantWithGrantOption
+		}
+		for _, e := range m.entries(s.Permissions, s.Principals, s.SecurityTargetObject, s.AsClause) {
+			m.grant(e, state)
+		}
+	case *ast.DenyStatement:
+		var errs []error
+		for _, e := range m.entries(s.Permissions, s.Principals, s.SecurityTargetObject, s.AsClause) {
+			if err := m.deny(e, s.CascadeOption); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	case *ast.RevokeStatement:
+		var errs []error
+		for _, e := range m.entries(s.Permissions, s.Principals, s.SecurityTargetObject, s.AsClause) {
+			if err := m.revoke(e, s.GrantOptionFor, s.CascadeOption); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	}
+	return nil
+}
+
+func (m *Model) create(p *Principal) error {
+	if old := m.get(p.Name, isServer(p.Kind)); old != nil {
+		return fmt.Errorf("%s %s %w", strings.ToLower(p.Kind), p.Name, ErrExists)
+	}
+	m.Principals = append(m.Principals, p)
+	return nil
+}
+
+// get returns the named server or database principal, or nil.
+func (m *Model) get(name string, server bool) *Principal {
+	if server {
+		return m.ServerPrincipal(name)
+	}
+	return m.Principal(name)
+}
+
+// drop removes a server or database principal with its memberships and,
+// for a database principal, its permissions.
+func (m *Model) drop(name string, ifExists, server bool) error {
+	p := m.get(name, server)
+	if p == nil {
+		if ifExists {
+			return nil
+		}
+		return fmt.Errorf("principal %s %w", name, ErrNotFound)
+	}
+	m.Principals = remove(m.Principals, func(x *Principal) bool { return x == p })
+	for _, other := range m.Principals {
+		if isServer(other.Kind) == server {
+			other.MemberOf = remove(other.MemberOf, func(r string) bool { return strings.EqualFold(r, p.Name) })
+		}
+	}
+	if !server {
+		m.Entries = remove(m.Entries, func(e *Entry) bool { return strings.EqualFold(e.Principal, p.Name) })
+	}
+	return nil
+}
+
+// alterRole applies ALTER ROLE, or ALTER SERVER ROLE if server is set, whose
+// members are logins and server roles.
+func (m *Model) alterRole(role string, action ast.AlterRoleAction, server bool) error {
+	r := m.get(role, server)
+	if r == nil {
+		return fmt.Errorf("role %s %w", role, ErrNotFound)
+	}
+	switch a := action.(type) {
+	case *ast.AddMemberAlterRoleAction:
+		return m.addMember(r, name(a.Member), server)
+	case *ast.DropMemberAlterRoleAction:
+		return m.dropMember(r, name(a.Member), server)
+	case *ast.RenameAlterRoleAction:
+		newName := name(a.NewName)
+		for _, p := range m.Principals {
+			if isServer(p.Kind) != server {
+				continue
+			}
+			for i, x := range p.MemberOf {
+				if strings.EqualFold(x, r.Name) {
+					p.MemberOf[i] = newName
+				}
+			}
+		}
+		if !server {
+			for _, e := range m.Entries {
+				if strings.EqualFold(e.Principal, r.Name) {
+					e.Principal = newName
+				}
+				if strings.EqualFold(e.Grantor, r.Name) {
+					e.Grantor = newName
+				}
+			}
+		}
+		r.Name = newName
+	}
+	return nil
+}
+
+func (m *Model) addMember(role *Principal, member string, server bool) error {
+	p := m.get(member, server)
+	if p == nil {
+		return fmt.Errorf("principal %s %w", member, ErrNotFound)
+	}
+	for _, x := range p.MemberOf {
+		if strings.EqualFold(x, role.Name) {
+			return nil
+		}
+	}
+	p.MemberOf = append(p.MemberOf, role.Name)
+	return nil
+}
+
+func (m *Model) dropMember(role *Principal, member string, server bool) error {
+	p := m.get(member, server)
+	if p == nil {
+		return fmt.Errorf("principal %s %w", member, ErrNotFound)
+	}
+	p.MemberOf = remove(p.MemberOf, func(x string) bool { return strings.EqualFold(x, role.Name) })
+	return nil
+}
+
+// execute applies the legacy sp_addrolemember and sp_droprolemember
+// procedures, whose arguments are the role and member names.
+func (m *Model) execute(s *ast.ExecuteStatement) error {
+	if s.ExecuteSpecification == nil {
+		return nil
+	}
+	ref, ok := s.ExecuteSpecification.ExecutableEntity.(*ast.ExecutableProcedureReference)
+	if !ok || ref.ProcedureReference == nil || ref.ProcedureReference.ProcedureReference == nil {
+		return nil
+	}
+	proc := strings.ToLower(catalog.NameOf(ref.ProcedureReference.ProcedureReference.Name).Name)
+	if proc != "sp_addrolemember" && proc != "sp_droprolemember" {
+		return nil
+	}
+	var role, member string
+	for i, p := range ref.Parameters {
+		lit, ok := p.ParameterValue.(*ast.StringLiteral)
+		if !ok {
+			return nil
+		}
+		switch {
+		case p.Variable == nil && i == 0, p.Variable != nil && strings.EqualFold(p.Variable.Name, "@rolename"):
+			role = lit.Value
+		case p.Variable == nil && i == 1, p.Variable != nil && strings.EqualFold(p.Variable.Name, "@membername"):
+			member = lit.Value
+		}
+	}
+	r := m.Principal(role)
+	if r == nil {
+		return fmt.Errorf("role %s %w", role, ErrNotFound)
+	}
+	if proc == "sp_addrolemember" {
+		return m.addMember(r, member, false)
+	}
+	return m.dropMember(r, member, false)
+}
+
+// entries returns an entry, without a state, for each combination of
+// permission, column and principal of a GRANT, DENY or REVOKE statement.
+func (m *Model) entries(perms []*ast.Permission, principals []*ast.SecurityPrincipal, target *ast.SecurityTargetObject, as *ast.Identifier) []*Entry {
+	on := Database
+	var columns []*ast.Identifier
+	if target != nil && target.ObjectName != nil {
+		on = m.securable(target)
+		columns = target.Columns
+	}
+	var entries []*Entry
+	for _, perm := range perms {
+		var words []string
+		for _, id := range perm.Identifiers {
+			words = append(words, strings.ToUpper(id.Value))
+		}
+		names := []string{strings.Join(words, " ")}
+		if names[0] == "ALL" || names[0] == "ALL PRIVILEGES" {
+			names = allPermissions
+		}
+		cols := append(append([]*ast.Identifier(nil), columns...), perm.Columns...)
+		for _, principal := range principals {
+			who := Public
+			if principal.PrincipalType == ast.PrincipalTypeIdentifier {
+				who = name(principal.Identifier)
+			}
+			for _, perm := range names {
+				if len(cols) == 0 {
+					entries = append(entries, &Entry{Principal: who, Permission: perm, Securable: on, Grantor: name(as)})
+				}
+				for _, col := range cols {
+					s := on
+					s.Column = col.Value
+					entries = append(entries, &Entry{Principal: who, Permission: perm, Securable: s, Grantor: name(as)})
+				}
+			}
+		}
+	}
+	return entries
+}
+
+// allPermissions are the permissions that the deprecated ALL grants on
+// tables, views and procedures.
+var allPermissions = []string{"DELETE", "EXECUTE", "INSERT", "REFERENCES", "SELECT", "UPDATE"}
+
+func (m *Model) securable(target *ast.SecurityTargetObject) Securable {
+	var parts []string
+	if target.ObjectName.MultiPartIdentifier != nil {
+		for _, id := range target.ObjectName.MultiPartIdentifier.Identifiers {
+			parts = append(parts, id.Value)
+		}
+	}
+	class := target.ObjectKind
+	if class == "" || class == "NotSpecified" {
+		class = ClassObject
+	}
+	switch class {
+	case ClassObject, "Type", "XmlSchemaCollection":
+		// Objects, types and XML schema collections belong to a schema.
+		if len(parts) == 1 {
+			parts = []string{m.DefaultSchema, parts[0]}
+		}
+		if len(parts) > 2 {
+			parts = parts[len(parts)-2:]
+		}
+	}
+	return Securable{Class: class, Name: strings.Join(parts, ".")}
+}
+
+// find returns the entry for the principal, permission and securable of e,
+// or nil.
+func (m *Model) find(e *Entry) *Entry {
+	for _, x := range m.Entries {
+		if same(x, e) {
+			return x
+		}
+	}
+	return nil
+}
+
+func same(a, b *Entry) bool {
+	return strings.EqualFold(a.Principal, b.Principal) && a.Permission == b.Permission && a.Securable.key() == b.Securable.key()
+}
+
+// grant records e. A GRANT replaces a DENY of the same permission, and
+// does not take away a grant option given before.
+func (m *Model) grant(e *Entry, state string) {
+	e.State = state
+	old := m.find(e)
+	if old == nil {
+		m.Entries = append(m.Entries, e)
+		return
+	}
+	if old.State == GrantWithGrantOption && state == Grant {
+		return
+	}
+	*old = *e
+}
+
+// deny records e as denied. With CASCADE, the permission is also denied to
+// the principals that e's principal granted it to.
+func (m *Model) deny(e *Entry, cascade bool) error {
+	old := m.find(e)
+	dependents := m.dependents(e)
+	if err := requireCascade(old, dependents, cascade); err != nil {
+		return err
+	}
+	e.State = Deny
+	if old != nil {
+		*old = *e
+	} else {
+		m.Entries = append(m.Entries, e)
+	}
+	if cascade {
+		for _, d := range dependents {
+			m.deny(d, true)
+		}
+	}
+	return nil
+}
+
+// revoke removes the grant or denial e, or only its grant option. With
+// CASCADE, the grants that e's principal made of the permission are
+// revoked too.
+func (m *Model) revoke(e *Entry, grantOption, cascade bool) error {
+	old := m.find(e)
+	if old == nil {
+		return nil
+	}
+	dependents := m.dependents(e)
+	if err := requireCascade(old, dependents, cascade); err != nil {
+		return err
+	}
+	if !grantOption {
+		m.Entries = remove(m.Entries, func(x *Entry) bool { return x == old })
+	} else if old.State == GrantWithGrantOption {
+		old.State = Grant
+	}
+	if cascade {
+		for _, d := range dependents {
+			m.revoke(d, false, true)
+		}
+	}
+	return nil
+}
+
+// dependents returns the grants that e's principal made of e's permission,
+// without their state.
+func (m *Model) dependents(e *Entry) []*Entry {
+	var deps []*Entry
+	for _, x := range m.Entries {
+		if strings.EqualFold(x.Grantor, e.Principal) && x.Permission == e.Permission && x.Securable.key() == e.Securable.key() && x.State != Deny {
+			deps = append(deps, &Entry{Principal: x.Principal, Permission: x.Permission, Securable: x.Securable})
+		}
+	}
+	return deps
+}
+
+// requireCascade returns an error if a permission held WITH GRANT OPTION
+// and passed on to others is revoked or denied without CASCADE, which SQL
+// Server rejects.
+func requireCascade(old *Entry, dependents []*Entry, cascade bool) error {
+	if cascade || len(dependents) == 0 || old == nil || old.State != GrantWithGrantOption {
+		return nil
+	}
+	return fmt.Errorf("%s on %s was granted by %s to other principals; CASCADE is required", old.Permission, old.Securable, old.Principal)
+}
+
+func name(id *ast.Identifier) string {
+	if id == nil {
+		return ""
+	}
+	return id.Value
+}
+
+func remove[T any](s []T, drop func(T) bool) []T {
+	var kept []T
+	for _, x := range s {
+		if !drop(x) {
+			kept = append(kept, x)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,280 @@
+package security
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/sqlc-dev/teesql/ast"
+	"github.com/sqlc-dev/teesql/catalog"
+	"github.com/sqlc-dev/teesql/parser"
+)
+
+func parse(t *testing.T, sql string) *ast.Script {
+	t.Helper()
+	script, err := parser.Parse(context.Background(), strings.NewReader(sql))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return script
+}
+
+const setup = `
+CREATE LOGIN AliceLogin WITH PASSWORD = 'x';
+CREATE USER Alice FOR LOGIN AliceLogin;
+CREATE USER Bob WITHOUT LOGIN;
+CREATE USER Carol;
+CREATE USER Dave;
+CREATE ROLE Sales;
+CREATE ROLE Managers AUTHORIZATION dbo;
+ALTER ROLE Sales ADD MEMBER Alice;
+ALTER ROLE Managers ADD MEMBER Sales;
+ALTER ROLE db_datareader ADD MEMBER Carol;
+EXEC sp_addrolemember 'db_denydatawriter', 'Carol';
+
+GRANT SELECT, INSERT ON dbo.Orders TO Sales;
+GRANT UPDATE ON Orders TO Managers WITH GRANT OPTION;
+DENY INSERT ON OBJECT::dbo.Orders TO Alice;
+GRANT EXECUTE ON SCHEMA::Reports TO public;
+GRANT SELECT ON dbo.Products (Name, Price) TO Bob;
+DENY SELECT ON dbo.Products (Cost) TO Sales;
+GRANT CONTROL ON SCHEMA::Reports TO Dave;
+DENY DELETE ON Reports.Totals TO Dave;
+`
+
+func TestCheck(t *testing.T) {
+	m, err := Build(parse(t, setup))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		principal  string
+		permission string
+		on         Securable
+		allowed    bool
+		because    []string
+	}{
+		{"Alice", "SELECT", Object("dbo.Orders"), true, []string{"GRANT SELECT ON OBJECT::dbo.Orders TO Sales"}},
+		{"Alice", "INSERT", Object("Orders"), false, []string{"DENY INSERT ON OBJECT::dbo.Orders TO Alice"}},
+		{"Alice", "UPDATE", Object("dbo.Orders"), true, []string{"GRANT UPDATE ON OBJECT::dbo.Orders TO Managers WITH GRANT OPTION"}},
+		{"Alice", "DELETE", Object("dbo.Orders"), false, nil},
+		{"Bob", "SELECT", Object("dbo.Orders"), false, nil},
+		{"Bob", "SELECT", Securable{Class: ClassObject, Name: "dbo.Products", Column: "Price"}, true, []string{"GRANT SELECT ON OBJECT::dbo.Products(Price) TO Bob"}},
+		{"Bob", "SELECT", Object("dbo.Products"), false, nil},
+		{"Alice", "SELECT", Securable{Class: ClassObject, Name: "dbo.Products", Column: "Cost"}, false, []string{"DENY SELECT ON OBJECT::dbo.Products(Cost) TO Sales"}},
+		{"Bob", "EXECUTE", Object("Reports.MonthlySales"), true, []string{"GRANT EXECUTE ON SCHEMA::Reports TO public"}},
+		{"Carol", "SELECT", Object("dbo.Orders"), true, []string{"GRANT SELECT TO db_datareader"}},
+		{"Carol", "INSERT", Object("dbo.Orders"), false, []string{"DENY INSERT TO db_denydatawriter"}},
+		{"Dave", "ALTER", Object("Reports.Totals"), true, []string{"GRANT CONTROL ON SCHEMA::Reports TO Dave"}},
+		{"Dave", "DELETE", Object("Reports.Totals"), false, []string{"DENY DELETE ON OBJECT::Reports.Totals TO Dave"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.principal+" "+tt.permission+" "+tt.on.String(), func(t *testing.T) {
+			a := m.Check(tt.principal, tt.permission, tt.on)
+			var because []string
+			for _, e := range a.Because {
+				because = append(because, e.String())
+			}
+			if a.Allowed != tt.allowed || strings.Join(because, "; ") != strings.Join(tt.because, "; ") {
+				t.Errorf("got %v because %q, want %v because %q", a.Allowed, because, tt.allowed, tt.because)
+			}
+		})
+	}
+
+	var got []string
+	for _, a := range m.Permissions("Alice", Object("dbo.Orders")) {
+		s := a.Permission
+		if !a.Allowed {
+			s = "!" + s
+		} else if a.GrantOption {
+			s += "+"
+		}
+		got = append(got, s)
+	}
+	if want := "!INSERT SELECT UPDATE+"; strings.Join(got, " ") != want {
+		t.Errorf("Permissions(Alice, dbo.Orders) = %q, want %q", strings.Join(got, " "), want)
+	}
+	if got, want := strings.Join(m.Roles("Alice"), " "), "Sales Managers public"; got != want {
+		t.Errorf("Roles(Alice) = %q, want %q", got, want)
+	}
+	if p := m.Principal("Alice"); p == nil || p.Login != "AliceLogin" {
+		t.Errorf("Principal(Alice) = %+v", p)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	tests := []struct {
+		name    string
+		sql     string
+		entries []string
+		err     string
+	}{
+		{
+			name: "grant replaces deny",
+			sql: `DENY SELECT ON dbo.T TO Sales;
+GRANT SELECT ON dbo.T TO Sales;`,
+			entries: []string{"GRANT SELECT ON OBJECT::dbo.T TO Sales"},
+		},
+		{
+			name: "revoke",
+			sql: `GRANT SELECT, UPDATE ON dbo.T TO Sales;
+DENY DELETE ON dbo.T TO Sales;
+REVOKE SELECT, DELETE ON dbo.T FROM Sales;`,
+			entries: []string{"GRANT UPDATE ON OBJECT::dbo.T TO Sales"},
+		},
+		{
+			name: "deny cascade",
+			sql: `GRANT SELECT ON dbo.T TO Sales WITH GRANT OPTION;
+GRANT SELECT ON dbo.T TO Bob AS Sales;
+GRANT SELECT ON dbo.T TO Carol AS Bob;
+DENY SELECT ON dbo.T TO Sales CASCADE;`,
+			entries: []string{
+				"DENY SELECT ON OBJECT::dbo.T TO Sales",
+				"DENY SELECT ON OBJECT::dbo.T TO Bob",
+				"DENY SELECT ON OBJECT::dbo.T TO Carol",
+			},
+		},
+		{
+			name: "revoke grant option cascade",
+			sql: `GRANT SELECT ON dbo.T TO Sales WITH GRANT OPTION;
+GRANT SELECT ON dbo.T TO Bob AS Sales;
+REVOKE GRANT OPTION FOR SELECT ON dbo.T FROM Sales CASCADE;`,
+			entries: []string{"GRANT SELECT ON OBJECT::dbo.T TO Sales"},
+		},
+		{
+			name: "revoke without cascade",
+			sql: `GRANT SELECT ON dbo.T TO Sales WITH GRANT OPTION;
+GRANT SELECT ON dbo.T TO Bob AS Sales;
+REVOKE SELECT ON dbo.T FROM Sales;`,
+			entries: []string{
+				"GRANT SELECT ON OBJECT::dbo.T TO Sales WITH GRANT OPTION",
+				"GRANT SELECT ON OBJECT::dbo.T TO Bob",
+			},
+			err: "SELECT on OBJECT::dbo.T was granted by Sales to other principals; CASCADE is required",
+		},
+		{
+			name: "drop role",
+			sql: `GRANT SELECT ON dbo.T TO Sales;
+GRANT SELECT ON dbo.T TO Bob;
+DROP ROLE Sales;`,
+			entries: []string{"GRANT SELECT ON OBJECT::dbo.T TO Bob"},
+		},
+		{
+			name: "rename role",
+			sql: `GRANT SELECT ON dbo.T TO Sales;
+ALTER ROLE Sales WITH NAME = Sellers;`,
+			entries: []string{"GRANT SELECT ON OBJECT::dbo.T TO Sellers"},
+		},
+		{
+			name:    "all",
+			sql:     `GRANT ALL ON dbo.T TO Bob;`,
+			entries: []string{"GRANT DELETE ON OBJECT::dbo.T TO Bob", "GRANT EXECUTE ON OBJECT::dbo.T TO Bob", "GRANT INSERT ON OBJECT::dbo.T TO Bob", "GRANT REFERENCES ON OBJECT::dbo.T TO Bob", "GRANT SELECT ON OBJECT::dbo.T TO Bob", "GRANT UPDATE ON OBJECT::dbo.T TO Bob"},
+		},
+		{
+			name: "missing role",
+			sql:  `ALTER ROLE Nobody ADD MEMBER Bob;`,
+			err:  "role Nobody does not exist",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New()
+			if err := m.Update(parse(t, "CREATE ROLE Sales; CREATE USER Bob; CREATE USER Carol;")); err != nil {
+				t.Fatal(err)
+			}
+			err := m.Update(parse(t, tt.sql))
+			if tt.err == "" && err != nil {
+				t.Fatal(err)
+			}
+			if tt.err != "" && (err == nil || err.Error() != tt.err) {
+				t.Fatalf("got error %v, want %q", err, tt.err)
+			}
+			var entries []string
+			for _, e := range m.Entries {
+				entries = append(entries, e.String())
+			}
+			if strings.Join(entries, "\n") != strings.Join(tt.entries, "\n") {
+				t.Errorf("got entries:\n%s\nwant:\n%s", strings.Join(entries, "\n"), strings.Join(tt.entries, "\n"))
+			}
+		})
+	}
+
+	m := New()
+	err := m.Update(parse(t, "CREATE USER Bob; CREATE ROLE Bob;"))
+	if !errors.Is(err, ErrExists) {
+		t.Errorf("got %v, want ErrExists", err)
+	}
+}
+
+func TestLoginAndUserOfSameName(t *testing.T) {
+	m, err := Build(parse(t, `CREATE LOGIN alice WITH PASSWORD = 'x';
+CREATE USER alice FOR LOGIN alice;
+CREATE ROLE sales;
+ALTER ROLE sales ADD MEMBER alice;
+CREATE SERVER ROLE auditors;
+ALTER SERVER ROLE auditors ADD MEMBER alice;
+GRANT SELECT ON dbo.T TO sales;`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := m.Principal("alice"); p == nil || p.Kind != KindUser || strings.Join(p.MemberOf, " ") != "sales" {
+		t.Errorf("Principal(alice) = %+v", p)
+	}
+	if p := m.ServerPrincipal("alice"); p == nil || p.Kind != KindLogin || strings.Join(p.MemberOf, " ") != "auditors" {
+		t.Errorf("ServerPrincipal(alice) = %+v", p)
+	}
+	if got, want := strings.Join(m.Roles("alice"), " "), "sales public"; got != want {
+		t.Errorf("Roles(alice) = %q, want %q", got, want)
+	}
+	if !m.Check("alice", "SELECT", Object("dbo.T")).Allowed {
+		t.Error("alice may not SELECT from dbo.T through sales")
+	}
+	if err := m.Update(parse(t, "DROP LOGIN alice;")); err != nil {
+		t.Fatal(err)
+	}
+	if m.ServerPrincipal("alice") != nil || m.Principal("alice") == nil {
+		t.Error("DROP LOGIN did not drop the login alone")
+	}
+}
+
+func TestProtections(t *testing.T) {
+	c, err := catalog.Build(parse(t, `
+CREATE TABLE dbo.Orders (Id int, TenantId int, Card char(16) ENCRYPTED WITH (COLUMN_ENCRYPTION_KEY = CEK1, ENCRYPTION_TYPE = Randomized, ALGORITHM = 'AEAD_AES_256_CBC_HMAC_SHA_256'));
+CREATE TABLE dbo.Customers (Id int, Email varchar(100) MASKED WITH (FUNCTION = 'email()'));
+CREATE TABLE dbo.Audit (Id int);
+CREATE SECURITY POLICY Security.Tenant
+	ADD FILTER PREDICATE Security.fn_tenant(TenantId) ON dbo.Orders,
+	ADD BLOCK PREDICATE Security.fn_tenant(TenantId) ON dbo.Orders AFTER INSERT;
+CREATE SECURITY POLICY Security.Off ADD FILTER PREDICATE Security.fn_all(Id) ON dbo.Audit WITH (STATE = OFF);
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, p := range Protections(c) {
+		s := p.Table.String()
+		for _, pred := range p.Predicates {
+			s += fmt.Sprintf(" %s:%s:%s(%s,%v)", pred.Policy, pred.Kind, pred.Function, pred.Operation, pred.Enabled)
+		}
+		for _, m := range p.Masked {
+			s += " masked:" + m.Column + "=" + m.Function
+		}
+		for _, e := range p.Encrypted {
+			s += " encrypted:" + e.Column + "=" + e.Key + "/" + e.Type
+		}
+		if p.NoPolicy {
+			s += " no policy"
+		}
+		got = append(got, s)
+	}
+	want := []string{
+		"dbo.Orders Security.Tenant:Filter:Security.fn_tenant(All,true) Security.Tenant:Block:Security.fn_tenant(AfterInsert,true) encrypted:Card=CEK1/Randomized",
+		"dbo.Customers masked:Email=email() no policy",
+		"dbo.Audit Security.Off:Filter:Security.fn_all(All,false) no policy",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
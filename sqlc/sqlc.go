@@ -0,0 +1,233 @@
+// Package sqlc reads sqlc-style annotated query files.
+//
+// Each query is a statement preceded by a "-- name: GetUser :one" comment
+// giving the query's name and command kind. Within a query, the pseudo
+// functions sqlc.arg(name), sqlc.narg(name) and sqlc.slice(name) name its
+// parameters; the parser reads them as function calls whose call target is
+// sqlc.
+package sqlc
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/sqlc-dev/teesql/ast"
+)
+
+// Command kinds.
+const (
+	CommandOne        = "one"
+	CommandMany       = "many"
+	CommandExec       = "exec"
+	CommandExecRows   = "execrows"
+	CommandExecResult = "execresult"
+	CommandExecLastID = "execlastid"
+	CommandCopyFrom   = "copyfrom"
+	CommandBatchExec  = "batchexec"
+	CommandBatchMany  = "batchmany"
+	CommandBatchOne   = "batchone"
+)
+
+var commands = map[string]bool{
+	CommandOne:        true,
+	CommandMany:       true,
+	CommandExec:       true,
+	CommandExecRows:   true,
+	CommandExecResult: true,
+	CommandExecLastID: true,
+	CommandCopyFrom:   true,
+	CommandBatchExec:  true,
+	CommandBatchMany:  true,
+	CommandBatchOne:   true,
+}
+
+// Macro kinds.
+const (
+	// MacroArg names a parameter: sqlc.arg(name).
+	MacroArg = "arg"
+	// MacroNarg names a nullable parameter: sqlc.narg(name).
+	MacroNarg = "narg"
+	// MacroSlice names a parameter that expands to a list of values, as in
+	// IN (sqlc.slice(ids)).
+	MacroSlice = "slice"
+)
+
+// Query is an annotated statement.
+type Query struct {
+	Name string
+	// Command is the command kind, e.g. CommandOne.
+	Command   string
+	Statement ast.Statement
+	// Macros are the sqlc macros used by the statement, in source order.
+	Macros []*Macro
+	// Pos is the position of the annotation.
+	Pos ast.Position
+	// Text is the source text of the statement, and TextPos the position at
+	// which it starts.
+	Text    string
+	TextPos ast.Position
+}
+
+// SQL returns the statement's text with each macro replaced by its
+// parameter: @name for sqlc.arg and sqlc.narg, and /*SLICE:name*/@name for
+// sqlc.slice, which callers expand to one parameter per value.
+func (q *Query) SQL() string {
+	text := q.Text
+	// Replace from the end so that earlier offsets stay valid.
+	macros := slices.Clone(q.Macros)
+	sort.Slice(macros, func(i, j int) bool { return macros[i].start() > macros[j].start() })
+	for _, m := range macros {
+		start := m.start() - q.TextPos.Offset
+		end := closeParen(text, m.Call.FunctionName.Pos.Offset-q.TextPos.Offset)
+		if start < 0 || end < 0 {
+			continue
+		}
+		param := "@" + m.Name
+		if m.Kind == MacroSlice {
+			param = "/*SLICE:" + m.Name + "*/" + param
+		}
+		text = text[:start] + param + text[end:]
+	}
+	return text
+}
+
+// Macro is a use of sqlc.arg, sqlc.narg or sqlc.slice.
+type Macro struct {
+	Kind string
+	// Name is the parameter name given as the macro's argument.
+	Name string
+	Call *ast.FunctionCall
+}
+
+// start returns the offset of the macro's call target.
+func (m *Macro) start() int {
+	t := m.Call.CallTarget.(*ast.MultiPartIdentifierCallTarget)
+	return t.MultiPartIdentifier.Identifiers[0].Pos.Offset
+}
+
+// closeParen returns the offset just past the parenthesis that closes the
+// argument list following offset i, or -1.
+func closeParen(text string, i int) int {
+	depth := 0
+	for ; i >= 0 && i < len(text); i++ {
+		switch c := text[i]; c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		case '\'', '"', '[':
+			closing := c
+			if c == '[' {
+				closing = ']'
+			}
+			for i++; i < len(text) && text[i] != closing; i++ {
+			}
+		}
+	}
+	return -1
+}
+
+// Error is a problem with an annotation or macro.
+type Error struct {
+	Pos     ast.Position
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Pos.String() + ": " + e.Message
+}
+
+// File holds the queries of an annotated script.
+type File struct {
+	Queries []*Query
+	Errors  []*Error
+}
+
+// Read returns the annotated queries of a script. Statements without an
+// annotation are not queries.
+func Read(script *ast.Script) *File {
+	f := &File{}
+	names := map[string]bool{}
+	for _, batch := range script.Batches {
+		for _, stmt := range batch.Statements {
+			a := batch.Annotation(stmt)
+			if a == nil {
+				continue
+			}
+			q := &Query{Name: a.Name, Command: a.Command, Statement: stmt, Pos: a.Pos, Text: a.Text, TextPos: a.TextPos}
+			switch {
+			case a.Command == "":
+				f.errorf(a.Pos, "query %s has no command", a.Name)
+			case !commands[a.Command]:
+				f.errorf(a.Pos, "query %s has unknown command :%s", a.Name, a.Command)
+			}
+			if names[strings.ToLower(a.Name)] {
+				f.errorf(a.Pos, "duplicate query name %s", a.Name)
+			}
+			names[strings.ToLower(a.Name)] = true
+			f.macros(q)
+			f.Queries = append(f.Queries, q)
+		}
+	}
+	return f
+}
+
+func (f *File) macros(q *Query) {
+	ast.Inspect(q.Statement, func(n ast.Node) bool {
+		call, ok := n.(*ast.FunctionCall)
+		if !ok || !IsMacro(call) {
+			return true
+		}
+		kind := strings.ToLower(call.FunctionName.Value)
+		switch kind {
+		case MacroArg, MacroNarg, MacroSlice:
+		default:
+			f.errorf(call.FunctionName.Pos, "unknown macro sqlc.%s", call.FunctionName.Value)
+			return true
+		}
+		if len(call.Parameters) != 1 {
+			f.errorf(call.FunctionName.Pos, "sqlc.%s takes one argument", kind)
+			return true
+		}
+		name := macroName(call.Parameters[0])
+		if name == "" {
+			f.errorf(call.FunctionName.Pos, "sqlc.%s argument must be a name or string", kind)
+			return true
+		}
+		q.Macros = append(q.Macros, &Macro{Kind: kind, Name: name, Call: call})
+		return true
+	})
+}
+
+func (f *File) errorf(pos ast.Position, format string, args ...any) {
+	f.Errors = append(f.Errors, &Error{Pos: pos, Message: fmt.Sprintf(format, args...)})
+}
+
+// IsMacro reports whether a function call has the call target sqlc.
+func IsMacro(call *ast.FunctionCall) bool {
+	t, ok := call.CallTarget.(*ast.MultiPartIdentifierCallTarget)
+	if !ok || t.MultiPartIdentifier == nil || call.FunctionName == nil {
+		return false
+	}
+	ids := t.MultiPartIdentifier.Identifiers
+	return len(ids) == 1 && strings.EqualFold(ids[0].Value, "sqlc")
+}
+
+// macroName returns the parameter name a macro argument gives: a bare name
+// or a string.
+func macroName(e ast.ScalarExpression) string {
+	switch e := e.(type) {
+	case *ast.ColumnReferenceExpression:
+		if e.MultiPartIdentifier != nil && len(e.MultiPartIdentifier.Identifiers) == 1 {
+			return e.MultiPartIdentifier.Identifiers[0].Value
+		}
+	case *ast.StringLiteral:
+		return e.Value
+	}
+	return ""
+}
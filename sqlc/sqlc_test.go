@@ -0,0 +1,100 @@
+package sqlc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/sqlc-dev/teesql/parser"
+)
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want string
+	}{
+		{
+			name: "annotated",
+			sql: `-- name: GetUser :one
+SELECT * FROM dbo.Users WHERE Id = sqlc.arg(id);
+
+-- a plain comment
+-- name: ListUsers :many
+-- Lists users by name.
+SELECT * FROM dbo.Users
+WHERE Name LIKE sqlc.narg('pattern') AND Id IN (sqlc.slice(ids));
+
+SELECT 1;
+GO
+-- name: DeleteUser :exec
+DELETE FROM dbo.Users WHERE Id = @id`,
+			want: `GetUser :one SelectStatement 1:1 [arg id]
+ListUsers :many SelectStatement 5:1 [narg pattern, slice ids]
+DeleteUser :exec DeleteStatement 12:1 []`,
+		},
+		{
+			name: "errors",
+			sql: `-- name: A
+SELECT 1;
+-- name: B :first
+SELECT sqlc.arg(a.b), sqlc.embed(x), sqlc.arg();
+-- name: a :exec
+DELETE FROM t`,
+			want: `A : SelectStatement 1:1 []
+B :first SelectStatement 3:1 []
+a :exec DeleteStatement 5:1 []
+1:1: query A has no command
+3:1: query B has unknown command :first
+4:13: sqlc.arg argument must be a name or string
+4:28: unknown macro sqlc.embed
+4:43: sqlc.arg takes one argument
+5:1: duplicate query name a`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script, err := parser.Parse(context.Background(), strings.NewReader(tt.sql))
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			f := Read(script)
+			var lines []string
+			for _, q := range f.Queries {
+				var macros []string
+				for _, m := range q.Macros {
+					macros = append(macros, m.Kind+" "+m.Name)
+				}
+				lines = append(lines, fmt.Sprintf("%s :%s %T %s [%s]", q.Name, q.Command, q.Statement, q.Pos, strings.Join(macros, ", ")))
+			}
+			for _, e := range f.Errors {
+				lines = append(lines, e.Error())
+			}
+			got := strings.ReplaceAll(strings.Join(lines, "\n"), "*ast.", "")
+			if got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuerySQL(t *testing.T) {
+	script, err := parser.Parse(context.Background(), strings.NewReader(`-- name: Find :many
+SELECT * FROM dbo.Users
+WHERE Name = sqlc.arg(name) AND Email = SQLC.NARG('email') AND Id IN (sqlc.slice(ids))
+	AND Note = 'sqlc.arg(x)';`))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	f := Read(script)
+	if len(f.Errors) > 0 || len(f.Queries) != 1 {
+		t.Fatalf("Read = %+v", f)
+	}
+	want := `SELECT * FROM dbo.Users
+WHERE Name = @name AND Email = @email AND Id IN (/*SLICE:ids*/@ids)
+	AND Note = 'sqlc.arg(x)';`
+	if got := f.Queries[0].SQL(); got != want {
+		t.Errorf("SQL() =\n%s\nwant:\n%s", got, want)
+	}
+}
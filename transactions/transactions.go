@@ -0,0 +1,472 @@
+// Package transactions checks that stored procedures end the transactions
+// they begin, on every path through their control flow.
+//
+// Each path is followed with the stack of transactions it has begun and not
+// yet ended: BEGIN TRANSACTION pushes, COMMIT pops and ROLLBACK, unless it
+// rolls back to a savepoint, empties the stack. The procedure is assumed to
+// be called outside of a transaction, so that tests of @@TRANCOUNT and
+// XACT_STATE() in IF and WHILE predicates tell which paths have one open.
+//
+// Check reports transactions that may still be open when the procedure
+// ends, COMMIT, ROLLBACK and SAVE TRANSACTION statements that may run with
+// no transaction open, CATCH blocks that may be entered with a transaction
+// open and neither roll it back nor re-throw the error, and savepoint
+// names that SAVE TRANSACTION and ROLLBACK TRANSACTION do not both use.
+package transactions
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sqlc-dev/teesql/ast"
+	"github.com/sqlc-dev/teesql/catalog"
+	"github.com/sqlc-dev/teesql/cfg"
+)
+
+// Finding kinds.
+const (
+	// Open is a transaction that may be open when the procedure ends.
+	Open = "open"
+	// NoTransaction is a statement that may run with no transaction open.
+	NoTransaction = "no transaction"
+	// Catch is a CATCH block that neither rolls back nor re-throws.
+	Catch = "catch"
+	// Savepoint is a savepoint name used by SAVE TRANSACTION or ROLLBACK
+	// TRANSACTION but not both.
+	Savepoint = "savepoint"
+)
+
+// Finding is a problem with the transactions of a procedure.
+type Finding struct {
+	Procedure catalog.ObjectName `json:"Procedure"`
+	// Pos is the position of the statement at fault.
+	Pos     ast.Position `json:"Pos"`
+	Kind    string       `json:"Kind"`
+	Message string       `json:"Message"`
+}
+
+// String describes the finding, e.g.
+// "3:2: dbo.Transfer: transaction may still be open when the procedure ends".
+func (f *Finding) String() string {
+	return f.Pos.String() + ": " + f.Procedure.String() + ": " + f.Message
+}
+
+// Check returns the problems in the procedures that script creates or
+// alters, in source order.
+func Check(script *ast.Script) []*Finding {
+	var findings []*Finding
+	for _, batch := range script.Batches {
+		for _, stmt := range batch.Statements {
+			p := catalog.NewProcedure(stmt)
+			if p == nil || p.Body == nil {
+				continue
+			}
+			c := &checker{proc: catalog.ObjectName{Schema: p.Schema, Name: p.Name}}
+			c.check(p.Body.Statements)
+			findings = append(findings, c.findings...)
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool { return findings[i].Pos.Offset < findings[j].Pos.Offset })
+	return findings
+}
+
+// maxDepth bounds the transaction stacks; transactions begun beyond it are
+// not counted.
+const maxDepth = 4
+
+// stack lists the IDs of the nodes that began the open transactions,
+// outermost first, separated by commas.
+type stack string
+
+func (s stack) push(id int) stack {
+	switch {
+	case s == "":
+		return stack(strconv.Itoa(id))
+	case strings.Count(string(s), ",") >= maxDepth-1:
+		return s
+	}
+	return s + "," + stack(strconv.Itoa(id))
+}
+
+func (s stack) pop() stack {
+	i := strings.LastIndex(string(s), ",")
+	if i < 0 {
+		return ""
+	}
+	return s[:i]
+}
+
+func (s stack) depth() int {
+	if s == "" {
+		return 0
+	}
+	return strings.Count(string(s), ",") + 1
+}
+
+// outermost returns the ID of the node that began the outermost open
+// transaction.
+func (s stack) outermost() int {
+	first, _, _ := strings.Cut(string(s), ",")
+	id, _ := strconv.Atoi(first)
+	return id
+}
+
+// state is the set of stacks that a point may be reached with.
+type state map[stack]bool
+
+type checker struct {
+	proc     catalog.ObjectName
+	g        *cfg.Graph
+	findings []*Finding
+	// saves and begins hold the names given to SAVE TRANSACTION and BEGIN
+	// TRANSACTION, in lower case.
+	saves  map[string]bool
+	begins map[string]bool
+	// in holds the state on entry to each reachable node.
+	in map[*cfg.Node]state
+}
+
+func (c *checker) report(pos ast.Position, kind, message string) {
+	c.findings = append(c.findings, &Finding{Procedure: c.proc, Pos: pos, Kind: kind, Message: message})
+}
+
+func (c *checker) check(stmts []ast.Statement) {
+	c.g, _ = cfg.New(stmts)
+	c.saves, c.begins = map[string]bool{}, map[string]bool{}
+	for _, n := range c.g.Nodes {
+		switch s := n.Stmt.(type) {
+		case *ast.SaveTransactionStatement:
+			if name := transactionName(s.Name); name != "" {
+				c.saves[name] = true
+			}
+		case *ast.BeginTransactionStatement:
+			if name := transactionName(s.Name); name != "" {
+				c.begins[name] = true
+			}
+		}
+	}
+	c.flow()
+	c.checkStatements()
+	c.checkExit()
+	for _, stmt := range stmts {
+		ast.Inspect(stmt, func(n ast.Node) bool {
+			if tc, ok := n.(*ast.TryCatchStatement); ok {
+				c.checkCatch(tc)
+			}
+			return true
+		})
+	}
+}
+
+// flow computes the state on entry to each node.
+func (c *checker) flow() {
+	c.in = map[*cfg.Node]state{c.g.Entry: {"": true}}
+	work := []*cfg.Node{c.g.Entry}
+	for len(work) > 0 {
+		n := work[0]
+		work = work[1:]
+		for _, e := range n.Succs {
+			to := c.in[e.To]
+			if to == nil {
+				to = state{}
+				c.in[e.To] = to
+			}
+			changed := false
+			for s := range c.out(e) {
+				if !to[s] {
+					to[s] = true
+					changed = true
+				}
+			}
+			if changed {
+				work = append(work, e.To)
+			}
+		}
+	}
+}
+
+// out returns the state that leaves a node along an edge.
+func (c *checker) out(e *cfg.Edge) state {
+	out := state{}
+	for s := range c.in[e.From] {
+		if e.Kind == cfg.Error {
+			// A statement that fails has no effect on the transactions.
+			out[s] = true
+			continue
+		}
+		switch stmt := e.From.Stmt.(type) {
+		case *ast.BeginTransactionStatement:
+			s = s.push(e.From.ID)
+		case *ast.CommitTransactionStatement:
+			s = s.pop()
+		case *ast.RollbackTransactionStatement:
+			if c.rollsBack(stmt) {
+				s = ""
+			}
+		case *ast.IfStatement:
+			if !follows(e, stmt.Predicate, s.depth()) {
+				continue
+			}
+		case *ast.WhileStatement:
+			if !follows(e, stmt.Predicate, s.depth()) {
+				continue
+			}
+		}
+		out[s] = true
+	}
+	return out
+}
+
+// rollsBack reports whether a ROLLBACK rolls back the transaction, rather
+// than to a savepoint or, failing, to a name that was never used.
+func (c *checker) rollsBack(s *ast.RollbackTransactionStatement) bool {
+	name := transactionName(s.Name)
+	return name == "" || c.begins[name] && !c.saves[name]
+}
+
+// follows reports whether an edge out of a condition node may be taken
+// with depth transactions open.
+func follows(e *cfg.Edge, pred ast.BooleanExpression, depth int) bool {
+	canTrue, canFalse := eval(pred, depth)
+	switch e.Kind {
+	case cfg.True:
+		return canTrue
+	case cfg.False:
+		return canFalse
+	}
+	return true
+}
+
+// eval reports whether pred may be true and whether it may be false with
+// depth transactions open.
+func eval(pred ast.BooleanExpression, depth int) (canTrue, canFalse bool) {
+	switch p := pred.(type) {
+	case *ast.BooleanParenthesisExpression:
+		return eval(p.Expression, depth)
+	case *ast.BooleanNotExpression:
+		t, f := eval(p.Expression, depth)
+		return f, t
+	case *ast.BooleanBinaryExpression:
+		t1, f1 := eval(p.FirstExpression, depth)
+		t2, f2 := eval(p.SecondExpression, depth)
+		if p.BinaryExpressionType == "And" {
+			return t1 && t2, f1 || f2
+		}
+		return t1 || t2, f1 && f2
+	case *ast.BooleanComparisonExpression:
+		op := p.ComparisonType
+		values, ok := transactionValues(p.FirstExpression, depth)
+		k, isConst := constant(p.SecondExpression)
+		if !ok || !isConst {
+			values, ok = transactionValues(p.SecondExpression, depth)
+			k, isConst = constant(p.FirstExpression)
+			op = reverse(op)
+		}
+		if !ok || !isConst {
+			break
+		}
+		for _, v := range values {
+			if compare(v, op, k) {
+				canTrue = true
+			} else {
+				canFalse = true
+			}
+		}
+		return canTrue, canFalse
+	}
+	return true, true
+}
+
+// transactionValues returns the values that @@TRANCOUNT or XACT_STATE()
+// may have with depth transactions open.
+func transactionValues(e ast.ScalarExpression, depth int) ([]int, bool) {
+	switch e := e.(type) {
+	case *ast.ParenthesisExpression:
+		return transactionValues(e.Expression, depth)
+	case *ast.GlobalVariableExpression:
+		if strings.EqualFold(e.Name, "@@TRANCOUNT") {
+			return []int{depth}, true
+		}
+	case *ast.FunctionCall:
+		if e.CallTarget == nil && e.FunctionName != nil && strings.EqualFold(e.FunctionName.Value, "XACT_STATE") {
+			if depth == 0 {
+				return []int{0}, true
+			}
+			// The transaction may be committable or not.
+			return []int{1, -1}, true
+		}
+	}
+	return nil, false
+}
+
+func constant(e ast.ScalarExpression) (int, bool) {
+	switch e := e.(type) {
+	case *ast.IntegerLiteral:
+		n, err := strconv.Atoi(e.Value)
+		return n, err == nil
+	case *ast.UnaryExpression:
+		n, ok := constant(e.Expression)
+		if e.UnaryExpressionType == "Negative" {
+			n = -n
+		}
+		return n, ok
+	case *ast.ParenthesisExpression:
+		return constant(e.Expression)
+	}
+	return 0, false
+}
+
+// reverse returns the comparison with its operands swapped.
+func reverse(op string) string {
+	switch op {
+	case "GreaterThan":
+		return "LessThan"
+	case "LessThan":
+		return "GreaterThan"
+	case "GreaterThanOrEqualTo":
+		return "LessThanOrEqualTo"
+	case "LessThanOrEqualTo":
+		return "GreaterThanOrEqualTo"
+	}
+	return op
+}
+
+func compare(a int, op string, b int) bool {
+	switch op {
+	case "Equals":
+		return a == b
+	case "NotEqualToBrackets", "NotEqualToExclamation":
+		return a != b
+	case "GreaterThan":
+		return a > b
+	case "LessThan":
+		return a < b
+	case "GreaterThanOrEqualTo":
+		return a >= b
+	case "LessThanOrEqualTo":
+		return a <= b
+	}
+	return true
+}
+
+func transactionName(name *ast.IdentifierOrValueExpression) string {
+	if name == nil || name.ValueExpression != nil || strings.HasPrefix(name.Value, "@") {
+		return ""
+	}
+	return strings.ToLower(name.Value)
+}
+
+// checkStatements reports statements that need an open transaction and may
+// run without one, and mismatched savepoints.
+func (c *checker) checkStatements() {
+	rolledBack := map[string]bool{}
+	for _, n := range c.g.Nodes {
+		if s, ok := n.Stmt.(*ast.RollbackTransactionStatement); ok {
+			rolledBack[transactionName(s.Name)] = true
+		}
+	}
+	for _, n := range c.g.Nodes {
+		in := c.in[n]
+		var (
+			what string
+			pos  ast.Position
+		)
+		switch s := n.Stmt.(type) {
+		case *ast.CommitTransactionStatement:
+			what, pos = "COMMIT", s.Pos
+		case *ast.RollbackTransactionStatement:
+			what, pos = "ROLLBACK", s.Pos
+			name := transactionName(s.Name)
+			if name != "" && !c.saves[name] && !c.begins[name] {
+				c.report(pos, Savepoint, "ROLLBACK TRANSACTION "+name+" names no savepoint or transaction")
+			}
+		case *ast.SaveTransactionStatement:
+			what, pos = "SAVE TRANSACTION", s.Pos
+			if name := transactionName(s.Name); name != "" && !rolledBack[name] {
+				c.report(pos, Savepoint, "savepoint "+name+" is never rolled back to")
+			}
+		default:
+			continue
+		}
+		switch {
+		case in == nil || !in[""]:
+		case len(in) == 1:
+			c.report(pos, NoTransaction, what+" runs with no transaction open")
+		default:
+			c.report(pos, NoTransaction, what+" may run with no transaction open")
+		}
+	}
+}
+
+// checkExit reports the transactions that may be open at the exit.
+func (c *checker) checkExit() {
+	open := map[int]bool{}
+	for s := range c.in[c.g.Exit] {
+		if s != "" {
+			open[s.outermost()] = true
+		}
+	}
+	for _, n := range c.g.Nodes {
+		if open[n.ID] {
+			c.report(n.Stmt.(*ast.BeginTransactionStatement).Pos, Open, "transaction may still be open when the procedure ends")
+		}
+	}
+}
+
+// checkCatch reports a CATCH block that may be entered with a transaction
+// open and neither rolls back nor re-throws.
+func (c *checker) checkCatch(tc *ast.TryCatchStatement) {
+	handled := false
+	for _, stmt := range statements(tc.CatchStatements) {
+		ast.Inspect(stmt, func(n ast.Node) bool {
+			switch s := n.(type) {
+			case *ast.RollbackTransactionStatement:
+				handled = handled || c.rollsBack(s)
+			case *ast.ThrowStatement:
+				handled = true
+			case *ast.RaiseErrorStatement:
+				// A severity below 11 is informational and does not
+				// re-throw.
+				sev := cfg.Severity(s)
+				handled = handled || sev < 0 || sev >= 11
+			}
+			return !handled
+		})
+	}
+	if handled {
+		return
+	}
+	// The CATCH block is entered by the error edges that leave the TRY
+	// block's statements.
+	inTry := map[ast.Node]bool{}
+	for _, stmt := range statements(tc.TryStatements) {
+		ast.Inspect(stmt, func(n ast.Node) bool {
+			inTry[n] = true
+			return true
+		})
+	}
+	for _, n := range c.g.Nodes {
+		if n.Stmt == nil || !inTry[n.Stmt] {
+			continue
+		}
+		for _, e := range n.Succs {
+			if e.Kind != cfg.Error || (e.To.Stmt != nil && inTry[e.To.Stmt]) {
+				continue
+			}
+			for s := range c.out(e) {
+				if s != "" {
+					c.report(tc.CatchPos, Catch, "CATCH block neither rolls back nor re-throws the error while a transaction may be open")
+					return
+				}
+			}
+		}
+	}
+}
+
+func statements(list *ast.StatementList) []ast.Statement {
+	if list == nil {
+		return nil
+	}
+	return list.Statements
+}
@@ -0,0 +1,172 @@
+package transactions
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sqlc-dev/teesql/parser"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want []string
+	}{
+		{
+			name: "balanced",
+			sql: `CREATE PROCEDURE dbo.Transfer @from int, @to int, @amount money AS
+BEGIN
+	SET XACT_ABORT ON;
+	BEGIN TRY
+		BEGIN TRANSACTION;
+		UPDATE dbo.Accounts SET Balance -= @amount WHERE Id = @from;
+		UPDATE dbo.Accounts SET Balance += @amount WHERE Id = @to;
+		COMMIT TRANSACTION;
+	END TRY
+	BEGIN CATCH
+		IF @@TRANCOUNT > 0
+			ROLLBACK TRANSACTION;
+		THROW;
+	END CATCH
+END`,
+		},
+		{
+			name: "early return",
+			sql: `CREATE PROCEDURE dbo.P @id int AS
+BEGIN
+	BEGIN TRAN;
+	IF @id IS NULL
+		RETURN 1;
+	DELETE FROM dbo.T WHERE Id = @id;
+	COMMIT;
+END`,
+			want: []string{"3:2: dbo.P: transaction may still be open when the procedure ends"},
+		},
+		{
+			name: "commit without transaction",
+			sql: `CREATE PROCEDURE dbo.P @flag bit AS
+BEGIN
+	IF @flag = 1
+		BEGIN TRANSACTION;
+	UPDATE dbo.T SET x = 1;
+	COMMIT;
+	ROLLBACK;
+END`,
+			want: []string{
+				"6:2: dbo.P: COMMIT may run with no transaction open",
+				"7:2: dbo.P: ROLLBACK runs with no transaction open",
+			},
+		},
+		{
+			name: "guarded by xact_state",
+			sql: `CREATE PROCEDURE dbo.P AS
+BEGIN
+	BEGIN TRANSACTION;
+	IF XACT_STATE() = -1
+		ROLLBACK;
+	IF XACT_STATE() = 1
+		COMMIT;
+	IF 0 < @@TRANCOUNT
+		COMMIT;
+END`,
+		},
+		{
+			name: "catch swallows error",
+			sql: `CREATE PROCEDURE dbo.P AS
+BEGIN
+	BEGIN TRY
+		BEGIN TRANSACTION;
+		INSERT INTO dbo.T VALUES (1);
+		COMMIT;
+	END TRY
+	BEGIN CATCH
+		PRINT ERROR_MESSAGE();
+	END CATCH
+END`,
+			want: []string{
+				"4:3: dbo.P: transaction may still be open when the procedure ends",
+				"8:2: dbo.P: CATCH block neither rolls back nor re-throws the error while a transaction may be open",
+			},
+		},
+		{
+			name: "catch raises informational message",
+			sql: `CREATE PROCEDURE dbo.P AS
+BEGIN
+	BEGIN TRY
+		BEGIN TRANSACTION;
+		UPDATE dbo.T SET A = 1;
+		COMMIT;
+	END TRY
+	BEGIN CATCH
+		RAISERROR('note', 10, 1);
+	END CATCH
+END`,
+			want: []string{
+				"4:3: dbo.P: transaction may still be open when the procedure ends",
+				"8:2: dbo.P: CATCH block neither rolls back nor re-throws the error while a transaction may be open",
+			},
+		},
+		{
+			name: "failed commit",
+			sql: `CREATE PROCEDURE dbo.P AS
+BEGIN
+	BEGIN TRANSACTION;
+	BEGIN TRY
+		UPDATE dbo.T SET A = 1;
+		COMMIT;
+	END TRY
+	BEGIN CATCH
+		ROLLBACK;
+		THROW;
+	END CATCH
+END`,
+		},
+		{
+			name: "catch without transaction",
+			sql: `CREATE PROCEDURE dbo.P AS
+BEGIN TRY
+	INSERT INTO dbo.T VALUES (1);
+END TRY
+BEGIN CATCH
+	PRINT ERROR_MESSAGE();
+END CATCH`,
+		},
+		{
+			name: "savepoints",
+			sql: `CREATE PROCEDURE dbo.P AS
+BEGIN
+	BEGIN TRANSACTION Outer_Tran;
+	SAVE TRANSACTION BeforeInsert;
+	INSERT INTO dbo.T VALUES (1);
+	IF @@ERROR <> 0
+		ROLLBACK TRANSACTION BeforeInsrt;
+	SAVE TRANSACTION BeforeDelete;
+	DELETE FROM dbo.T;
+	IF @@ERROR <> 0
+		ROLLBACK TRANSACTION BeforeDelete;
+	COMMIT TRANSACTION Outer_Tran;
+END`,
+			want: []string{
+				"4:2: dbo.P: savepoint beforeinsert is never rolled back to",
+				"7:3: dbo.P: ROLLBACK TRANSACTION beforeinsrt names no savepoint or transaction",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script, err := parser.Parse(context.Background(), strings.NewReader(tt.sql))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, f := range Check(script) {
+				got = append(got, f.String())
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
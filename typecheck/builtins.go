@@ -0,0 +1,462 @@
+package typecheck
+
+import (
+	"strings"
+
+	"github.com/sqlc-dev/teesql/ast"
+	"github.com/sqlc-dev/teesql/catalog"
+	"github.com/sqlc-dev/teesql/internal/astutil"
+)
+
+// Nullability of a built-in function's result.
+const (
+	// nullIfArgs: null when any argument is null.
+	nullIfArgs = iota
+	// alwaysNull: may be null regardless of the arguments, as aggregates
+	// over no rows are.
+	alwaysNull
+	// neverNull: never null.
+	neverNull
+)
+
+// builtin describes the result of a built-in function given the types of
+// its arguments.
+type builtin struct {
+	result func(args []Type) catalog.Type
+	null   int
+}
+
+func fixed(t catalog.Type) func([]Type) catalog.Type {
+	return func([]Type) catalog.Type { return t }
+}
+
+// arg returns the type of the i'th argument.
+func arg(i int) func([]Type) catalog.Type {
+	return func(args []Type) catalog.Type {
+		if i < len(args) {
+			return args[i].Type
+		}
+		return catalog.Type{}
+	}
+}
+
+// varying returns the varying-length form of the i'th argument's string or
+// binary type, as returned by SUBSTRING, LEFT and LTRIM.
+func varying(i int) func([]Type) catalog.Type {
+	return func(args []Type) catalog.Type {
+		if i >= len(args) {
+			return catalog.Type{}
+		}
+		t := args[i].Type
+		t.Alias = ""
+		switch t.Name {
+		case "char":
+			t.Name = "varchar"
+		case "nchar":
+			t.Name = "nvarchar"
+		case "binary":
+			t.Name = "varbinary"
+		case "varchar", "nvarchar", "varbinary":
+		case "text":
+			t = catalog.Type{Name: "varchar", Length: catalog.MaxLength}
+		case "ntext":
+			t = catalog.Type{Name: "nvarchar", Length: catalog.MaxLength}
+		case "":
+			return t
+		default:
+			// Other types are converted to varchar first.
+			t = catalog.Type{Name: "varchar", Length: 30}
+		}
+		return t
+	}
+}
+
+// widened returns the i'th argument's string type at its longest
+// non-max length, as returned by REPLACE and REPLICATE.
+func widened(i int) func([]Type) catalog.Type {
+	return func(args []Type) catalog.Type {
+		t := varying(i)(args)
+		if t.IsZero() || t.Length == catalog.MaxLength {
+			return t
+		}
+		if isUnicode(t) {
+			t.Length = 4000
+		} else {
+			t.Length = 8000
+		}
+		return t
+	}
+}
+
+// length returns int, or bigint for max types, as LEN and CHARINDEX do.
+func length(i int) func([]Type) catalog.Type {
+	return func(args []Type) catalog.Type {
+		if i < len(args) && args[i].Length == catalog.MaxLength {
+			return catalog.Type{Name: "bigint"}
+		}
+		return catalog.Type{Name: "int"}
+	}
+}
+
+// sum returns the type of SUM: integers widen to int, decimals to the
+// maximum precision.
+func sum(args []Type) catalog.Type {
+	t := arg(0)(args)
+	switch t.Name {
+	case "bit", "tinyint", "smallint", "int":
+		return catalog.Type{Name: "int"}
+	case "decimal", "numeric":
+		return catalog.Type{Name: t.Name, Precision: maxPrecision, Scale: t.Scale}
+	case "smallmoney":
+		return catalog.Type{Name: "money"}
+	case "real":
+		return catalog.Type{Name: "float", Precision: 53}
+	}
+	return t
+}
+
+func avg(args []Type) catalog.Type {
+	t := sum(args)
+	if isDecimal(t) {
+		t.Scale = max(t.Scale, 6)
+	}
+	return t
+}
+
+// rounded returns the argument type with no fractional digits, as
+// CEILING and FLOOR return for decimals.
+func rounded(args []Type) catalog.Type {
+	t := arg(0)(args)
+	if isDecimal(t) {
+		t.Scale = 0
+	}
+	return t
+}
+
+// power returns the type of POWER, which widens decimals like SUM.
+func power(args []Type) catalog.Type {
+	t := arg(0)(args)
+	if isDecimal(t) {
+		t.Precision = maxPrecision
+	}
+	return t
+}
+
+// dateArg returns the type of the date argument of DATEADD and similar
+// functions; a string is converted to datetime.
+func dateArg(i int) func([]Type) catalog.Type {
+	return func(args []Type) catalog.Type {
+		t := arg(i)(args)
+		if isString(t) {
+			return catalog.Type{Name: "datetime"}
+		}
+		return t
+	}
+}
+
+// unifyArgs returns the common type of the arguments from i on, as for
+// CHOOSE, GREATEST and LEAST.
+func unifyArgs(i int) func([]Type) catalog.Type {
+	return func(args []Type) catalog.Type {
+		if i >= len(args) {
+			return catalog.Type{}
+		}
+		return unify(args[i:]).Type
+	}
+}
+
+// concatArgs returns the type of CONCAT: the sum of the arguments'
+// lengths, nvarchar if any argument is Unicode.
+func concatArgs(args []Type) catalog.Type {
+	t := catalog.Type{Name: "varchar"}
+	for _, a := range args {
+		if isUnicode(a.Type) {
+			t.Name = "nvarchar"
+		}
+		switch {
+		case a.Length == catalog.MaxLength || a.Name == "text" || a.Name == "ntext":
+			t.Length = catalog.MaxLength
+		case t.Length != catalog.MaxLength:
+			n := a.Length
+			if !isString(a.Type) {
+				n = 30
+			}
+			t.Length += n
+		}
+	}
+	limit := 8000
+	if t.Name == "nvarchar" {
+		limit = 4000
+	}
+	if t.Length > limit {
+		t.Length = catalog.MaxLength
+	}
+	return t
+}
+
+func sysname() catalog.Type {
+	return catalog.Type{Name: "nvarchar", Length: 128}
+}
+
+var (
+	intType      = catalog.Type{Name: "int"}
+	bigintType   = catalog.Type{Name: "bigint"}
+	floatType    = catalog.Type{Name: "float", Precision: 53}
+	datetimeType = catalog.Type{Name: "datetime"}
+	identityType = catalog.Type{Name: "numeric", Precision: maxPrecision}
+)
+
+// builtins maps lower-case function names to their result types.
+var builtins = map[string]builtin{
+	// Aggregates.
+	"count":                 {fixed(intType), neverNull},
+	"count_big":             {fixed(bigintType), neverNull},
+	"approx_count_distinct": {fixed(bigintType), neverNull},
+	"sum":                   {sum, alwaysNull},
+	"avg":                   {avg, alwaysNull},
+	"min":                   {arg(0), alwaysNull},
+	"max":                   {arg(0), alwaysNull},
+	"stdev":                 {fixed(floatType), alwaysNull},
+	"stdevp":                {fixed(floatType), alwaysNull},
+	"var":                   {fixed(floatType), alwaysNull},
+	"varp":                  {fixed(floatType), alwaysNull},
+	"checksum_agg":          {fixed(intType), alwaysNull},
+	"grouping":              {fixed(catalog.Type{Name: "tinyint"}), neverNull},
+	"grouping_id":           {fixed(intType), neverNull},
+	"string_agg":            {widened(0), alwaysNull},
+
+	// Ranking and analytic functions.
+	"row_number":      {fixed(bigintType), neverNull},
+	"rank":            {fixed(bigintType), neverNull},
+	"dense_rank":      {fixed(bigintType), neverNull},
+	"ntile":           {fixed(bigintType), neverNull},
+	"lag":             {arg(0), alwaysNull},
+	"lead":            {arg(0), alwaysNull},
+	"first_value":     {arg(0), alwaysNull},
+	"last_value":      {arg(0), alwaysNull},
+	"percent_rank":    {fixed(floatType), neverNull},
+	"cume_dist":       {fixed(floatType), neverNull},
+	"percentile_cont": {fixed(floatType), alwaysNull},
+
+	// Mathematical functions.
+	"abs":     {arg(0), nullIfArgs},
+	"sign":    {arg(0), nullIfArgs},
+	"round":   {arg(0), nullIfArgs},
+	"ceiling": {rounded, nullIfArgs},
+	"floor":   {rounded, nullIfArgs},
+	"power":   {power, nullIfArgs},
+	"degrees": {arg(0), nullIfArgs},
+	"radians": {arg(0), nullIfArgs},
+	"sqrt":    {fixed(floatType), nullIfArgs},
+	"square":  {fixed(floatType), nullIfArgs},
+	"exp":     {fixed(floatType), nullIfArgs},
+	"log":     {fixed(floatType), nullIfArgs},
+	"log10":   {fixed(floatType), nullIfArgs},
+	"sin":     {fixed(floatType), nullIfArgs},
+	"cos":     {fixed(floatType), nullIfArgs},
+	"tan":     {fixed(floatType), nullIfArgs},
+	"cot":     {fixed(floatType), nullIfArgs},
+	"asin":    {fixed(floatType), nullIfArgs},
+	"acos":    {fixed(floatType), nullIfArgs},
+	"atan":    {fixed(floatType), nullIfArgs},
+	"atn2":    {fixed(floatType), nullIfArgs},
+	"pi":      {fixed(floatType), neverNull},
+	"rand":    {fixed(floatType), neverNull},
+
+	// String functions.
+	"len":             {length(0), nullIfArgs},
+	"datalength":      {length(0), nullIfArgs},
+	"charindex":       {length(1), nullIfArgs},
+	"patindex":        {length(1), nullIfArgs},
+	"ascii":           {fixed(intType), nullIfArgs},
+	"unicode":         {fixed(intType), nullIfArgs},
+	"char":            {fixed(catalog.Type{Name: "char", Length: 1}), nullIfArgs},
+	"nchar":           {fixed(catalog.Type{Name: "nchar", Length: 1}), nullIfArgs},
+	"upper":           {arg(0), nullIfArgs},
+	"lower":           {arg(0), nullIfArgs},
+	"reverse":         {varying(0), nullIfArgs},
+	"ltrim":           {varying(0), nullIfArgs},
+	"rtrim":           {varying(0), nullIfArgs},
+	"trim":            {varying(0), nullIfArgs},
+	"left":            {varying(0), nullIfArgs},
+	"right":           {varying(0), nullIfArgs},
+	"substring":       {varying(0), nullIfArgs},
+	"stuff":           {widened(0), nullIfArgs},
+	"replace":         {widened(0), nullIfArgs},
+	"replicate":       {widened(0), nullIfArgs},
+	"translate":       {arg(0), nullIfArgs},
+	"space":           {fixed(catalog.Type{Name: "varchar", Length: 8000}), nullIfArgs},
+	"str":             {fixed(catalog.Type{Name: "varchar", Length: 10}), nullIfArgs},
+	"soundex":         {fixed(catalog.Type{Name: "varchar", Length: 4}), nullIfArgs},
+	"difference":      {fixed(intType), nullIfArgs},
+	"quotename":       {fixed(catalog.Type{Name: "nvarchar", Length: 258}), nullIfArgs},
+	"format":          {fixed(catalog.Type{Name: "nvarchar", Length: 4000}), alwaysNull},
+	"concat":          {concatArgs, neverNull},
+	"concat_ws":       {concatArgs, neverNull},
+	"string_escape":   {fixed(catalog.Type{Name: "nvarchar", Length: catalog.MaxLength}), nullIfArgs},
+	"newid":           {fixed(catalog.Type{Name: "uniqueidentifier"}), neverNull},
+	"newsequentialid": {fixed(catalog.Type{Name: "uniqueidentifier"}), neverNull},
+
+	// Date and time functions.
+	"getdate":                 {fixed(datetimeType), neverNull},
+	"getutcdate":              {fixed(datetimeType), neverNull},
+	"sysdatetime":             {fixed(catalog.Type{Name: "datetime2", Scale: 7}), neverNull},
+	"sysutcdatetime":          {fixed(catalog.Type{Name: "datetime2", Scale: 7}), neverNull},
+	"sysdatetimeoffset":       {fixed(catalog.Type{Name: "datetimeoffset", Scale: 7}), neverNull},
+	"dateadd":                 {dateArg(2), nullIfArgs},
+	"datetrunc":               {dateArg(1), nullIfArgs},
+	"date_bucket":             {dateArg(2), nullIfArgs},
+	"datediff":                {fixed(intType), nullIfArgs},
+	"datediff_big":            {fixed(bigintType), nullIfArgs},
+	"datepart":                {fixed(intType), nullIfArgs},
+	"datename":                {fixed(catalog.Type{Name: "nvarchar", Length: 30}), nullIfArgs},
+	"year":                    {fixed(intType), nullIfArgs},
+	"month":                   {fixed(intType), nullIfArgs},
+	"day":                     {fixed(intType), nullIfArgs},
+	"eomonth":                 {fixed(catalog.Type{Name: "date"}), nullIfArgs},
+	"datefromparts":           {fixed(catalog.Type{Name: "date"}), nullIfArgs},
+	"datetimefromparts":       {fixed(datetimeType), nullIfArgs},
+	"smalldatetimefromparts":  {fixed(catalog.Type{Name: "smalldatetime"}), nullIfArgs},
+	"datetime2fromparts":      {fixed(catalog.Type{Name: "datetime2", Scale: 7}), nullIfArgs},
+	"timefromparts":           {fixed(catalog.Type{Name: "time", Scale: 7}), nullIfArgs},
+	"datetimeoffsetfromparts": {fixed(catalog.Type{Name: "datetimeoffset", Scale: 7}), nullIfArgs},
+	"switchoffset":            {fixed(catalog.Type{Name: "datetimeoffset", Scale: 7}), nullIfArgs},
+	"todatetimeoffset":        {fixed(catalog.Type{Name: "datetimeoffset", Scale: 7}), nullIfArgs},
+	"isdate":                  {fixed(intType), neverNull},
+
+	// Logical and conversion functions.
+	"choose":    {unifyArgs(1), alwaysNull},
+	"greatest":  {unifyArgs(0), nullIfArgs},
+	"least":     {unifyArgs(0), nullIfArgs},
+	"isnumeric": {fixed(intType), neverNull},
+
+	// JSON functions.
+	"isjson":      {fixed(catalog.Type{Name: "bit"}), nullIfArgs},
+	"json_value":  {fixed(catalog.Type{Name: "nvarchar", Length: 4000}), alwaysNull},
+	"json_query":  {fixed(catalog.Type{Name: "nvarchar", Length: catalog.MaxLength}), alwaysNull},
+	"json_modify": {fixed(catalog.Type{Name: "nvarchar", Length: catalog.MaxLength}), nullIfArgs},
+	"json_object": {fixed(catalog.Type{Name: "nvarchar", Length: catalog.MaxLength}), neverNull},
+	"json_array":  {fixed(catalog.Type{Name: "nvarchar", Length: catalog.MaxLength}), neverNull},
+
+	// Metadata, security and system functions.
+	"object_id":          {fixed(intType), alwaysNull},
+	"db_id":              {fixed(catalog.Type{Name: "smallint"}), alwaysNull},
+	"schema_id":          {fixed(intType), alwaysNull},
+	"user_id":            {fixed(intType), alwaysNull},
+	"type_id":            {fixed(intType), alwaysNull},
+	"object_name":        {fixed(sysname()), alwaysNull},
+	"object_schema_name": {fixed(sysname()), alwaysNull},
+	"schema_name":        {fixed(sysname()), alwaysNull},
+	"db_name":            {fixed(sysname()), alwaysNull},
+	"user_name":          {fixed(sysname()), alwaysNull},
+	"suser_name":         {fixed(sysname()), alwaysNull},
+	"suser_sname":        {fixed(sysname()), alwaysNull},
+	"original_login":     {fixed(sysname()), alwaysNull},
+	"host_name":          {fixed(sysname()), alwaysNull},
+	"app_name":           {fixed(sysname()), alwaysNull},
+	"col_name":           {fixed(sysname()), alwaysNull},
+	"type_name":          {fixed(sysname()), alwaysNull},
+	"scope_identity":     {fixed(identityType), alwaysNull},
+	"ident_current":      {fixed(identityType), alwaysNull},
+	"ident_seed":         {fixed(identityType), alwaysNull},
+	"ident_incr":         {fixed(identityType), alwaysNull},
+	"error_number":       {fixed(intType), alwaysNull},
+	"error_severity":     {fixed(intType), alwaysNull},
+	"error_state":        {fixed(intType), alwaysNull},
+	"error_line":         {fixed(intType), alwaysNull},
+	"error_message":      {fixed(catalog.Type{Name: "nvarchar", Length: 4000}), alwaysNull},
+	"error_procedure":    {fixed(sysname()), alwaysNull},
+	"xact_state":         {fixed(catalog.Type{Name: "smallint"}), neverNull},
+	"checksum":           {fixed(intType), neverNull},
+	"binary_checksum":    {fixed(intType), neverNull},
+	"hashbytes":          {fixed(catalog.Type{Name: "varbinary", Length: 8000}), nullIfArgs},
+	"compress":           {fixed(catalog.Type{Name: "varbinary", Length: catalog.MaxLength}), nullIfArgs},
+	"decompress":         {fixed(catalog.Type{Name: "varbinary", Length: catalog.MaxLength}), nullIfArgs},
+	"session_context":    {fixed(catalog.Type{Name: "sql_variant"}), alwaysNull},
+	"context_info":       {fixed(catalog.Type{Name: "varbinary", Length: 128}), alwaysNull},
+}
+
+// function returns the type of a function call: a built-in, ISNULL, or a
+// scalar user-defined function in the catalog.
+func (c *checker) function(f *ast.FunctionCall) Type {
+	if f.FunctionName == nil {
+		return Type{}
+	}
+	name := f.FunctionName.Value
+	target, ok := f.CallTarget.(*ast.MultiPartIdentifierCallTarget)
+	if f.CallTarget != nil && !ok {
+		// Method calls on expressions, such as xml value().
+		return Type{Nullable: true}
+	}
+	if target == nil {
+		if strings.EqualFold(name, "isnull") {
+			return c.isnull(f.Parameters)
+		}
+		return c.builtin(name, f.Parameters)
+	}
+	for _, p := range f.Parameters {
+		c.infer(p)
+	}
+	if c.cat == nil || target.MultiPartIdentifier == nil || len(target.MultiPartIdentifier.Identifiers) == 0 {
+		return Type{Nullable: true}
+	}
+	ids := target.MultiPartIdentifier.Identifiers
+	schema := identifierValue(ids[len(ids)-1])
+	fn := c.cat.Function(catalog.ObjectName{Schema: schema, Name: name})
+	if fn == nil || fn.Kind != catalog.ScalarFunction {
+		return Type{Nullable: true}
+	}
+	return Type{Type: c.resolve(fn.ReturnType), Nullable: true}
+}
+
+func (c *checker) builtin(name string, params []ast.ScalarExpression) Type {
+	args := make([]Type, len(params))
+	nullable := false
+	for i, p := range params {
+		args[i] = c.infer(p)
+		nullable = nullable || args[i].Nullable
+	}
+	if astutil.TakesDatepart(name) {
+		// The datepart argument is a keyword, not a column.
+		nullable = false
+		for _, a := range args[min(1, len(args)):] {
+			nullable = nullable || a.Nullable
+		}
+	}
+	fn, ok := builtins[strings.ToLower(name)]
+	if !ok {
+		return Type{Nullable: true}
+	}
+	t := Type{Type: fn.result(args)}
+	switch fn.null {
+	case nullIfArgs:
+		t.Nullable = nullable
+	case alwaysNull:
+		t.Nullable = true
+	}
+	if t.IsZero() {
+		t.Nullable = true
+	}
+	return t
+}
+
+// isnull returns the type of ISNULL: the type of the first argument, null
+// only if both arguments are.
+func (c *checker) isnull(params []ast.ScalarExpression) Type {
+	if len(params) != 2 {
+		return Type{Nullable: true}
+	}
+	t, alt := c.infer(params[0]), c.infer(params[1])
+	if isNullLiteral(params[0]) {
+		t.Type = alt.Type
+	}
+	t.Nullable = t.Nullable && alt.Nullable
+	return t
+}
+
+func identifierValue(id *ast.Identifier) string {
+	if id == nil {
+		return ""
+	}
+	return id.Value
+}
@@ -0,0 +1,368 @@
+package typecheck
+
+import (
+	"strings"
+
+	"github.com/sqlc-dev/teesql/ast"
+	"github.com/sqlc-dev/teesql/catalog"
+)
+
+// Parameter is a variable used by a statement.
+type Parameter struct {
+	// Name is the variable name, including the leading @, as first written.
+	Name string
+	// Type is the declared type of a local variable, or the type a query
+	// parameter must have given where it is used. It is zero if unknown.
+	Type Type
+	// Refs are the references to the variable, in source order.
+	Refs []*ast.VariableReference
+}
+
+// Parameters lists the variables that statements use.
+type Parameters struct {
+	// Params are the variables used without being declared: the query's
+	// parameters, in order of first use.
+	Params []*Parameter
+	// Locals are the variables declared with DECLARE or as procedure
+	// parameters, in order of declaration.
+	Locals []*Parameter
+	// Untyped are the parameters whose type could not be inferred.
+	Untyped []*Parameter
+}
+
+// Parameters returns the variables used under node, which may be a script,
+// batch or statement. The type of each undeclared variable is inferred from
+// its context: the column it is compared with or assigned to, the INSERT
+// column it supplies, the expression it is matched against with LIKE, IN or
+// BETWEEN, the procedure parameter it is passed as, or its use in TOP,
+// OFFSET and FETCH.
+func (i *Info) Parameters(node ast.Node) *Parameters {
+	ps := &Parameters{}
+	params := map[string]*Parameter{}
+	locals := map[string]*Parameter{}
+	local := func(name string, t Type) {
+		key := strings.ToLower(name)
+		if locals[key] == nil {
+			p := &Parameter{Name: name, Type: t}
+			locals[key] = p
+			ps.Locals = append(ps.Locals, p)
+		}
+	}
+	declared := map[string]bool{}
+	names := map[*ast.VariableReference]bool{}
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.Batch:
+			declared = map[string]bool{}
+		case *ast.DeclareVariableElement:
+			if n.VariableName != nil {
+				var t Type
+				if n.DataType != nil {
+					t = Type{Type: i.c.resolve(catalog.TypeOf(n.DataType)), Nullable: true}
+				}
+				declared[strings.ToLower(n.VariableName.Value)] = true
+				local(n.VariableName.Value, t)
+			}
+		case *ast.DeclareTableVariableBody:
+			if n.VariableName != nil {
+				declared[strings.ToLower(n.VariableName.Value)] = true
+				local(n.VariableName.Value, Type{})
+			}
+		case *ast.ProcedureParameter:
+			if n.VariableName != nil {
+				declared[strings.ToLower(n.VariableName.Value)] = true
+				local(n.VariableName.Value, Type{Type: i.c.resolve(catalog.TypeOf(n.DataType)), Nullable: true})
+			}
+		case *ast.ExecuteParameter:
+			// The name of a named argument is not a variable of the caller.
+			if n.Variable != nil {
+				names[n.Variable] = true
+			}
+		case *ast.VariableReference:
+			key := strings.ToLower(n.Name)
+			if names[n] {
+				break
+			}
+			if declared[key] {
+				locals[key].Refs = append(locals[key].Refs, n)
+				break
+			}
+			p := params[key]
+			if p == nil {
+				p = &Parameter{Name: n.Name}
+				params[key] = p
+				ps.Params = append(ps.Params, p)
+			}
+			p.Refs = append(p.Refs, n)
+		}
+		return true
+	})
+	if len(ps.Params) == 0 {
+		return ps
+	}
+	h := &hinter{info: i, params: params}
+	ast.Inspect(node, func(n ast.Node) bool {
+		h.node(n)
+		return true
+	})
+	for _, p := range ps.Params {
+		if p.Type.IsZero() {
+			ps.Untyped = append(ps.Untyped, p)
+		}
+	}
+	return ps
+}
+
+// QueryParameters returns the variables stmt uses, typed against the schema
+// that the DDL scripts define. See Info.Parameters.
+func QueryParameters(stmt ast.Statement, ddl ...*ast.Script) (*Parameters, error) {
+	cat, err := catalog.Build(ddl...)
+	if err != nil {
+		return nil, err
+	}
+	return CheckStatement(stmt, cat).Parameters(stmt), nil
+}
+
+// hinter infers the types of query parameters from the expressions around
+// them. The first context that determines a parameter's type wins.
+type hinter struct {
+	info   *Info
+	params map[string]*Parameter
+}
+
+var (
+	rowCount   = notNull(catalog.Type{Name: "bigint"})
+	percentage = notNull(catalog.Type{Name: "float", Precision: 53})
+)
+
+func (h *hinter) node(n ast.Node) {
+	switch n := n.(type) {
+	case *ast.BooleanComparisonExpression:
+		h.pair(n.FirstExpression, n.SecondExpression)
+	case *ast.BooleanTernaryExpression:
+		h.hint(n.FirstExpression, h.typeOf(n.SecondExpression, n.ThirdExpression))
+		h.hint(n.SecondExpression, h.typeOf(n.FirstExpression))
+		h.hint(n.ThirdExpression, h.typeOf(n.FirstExpression))
+	case *ast.BooleanLikeExpression:
+		if t := h.typeOf(n.FirstExpression); !t.IsZero() {
+			if !isString(t.Type) {
+				t = Type{Type: catalog.Type{Name: "nvarchar", Length: 4000}, Nullable: t.Nullable}
+			}
+			// A pattern is often built by concatenation, as in @p + '%'.
+			for _, e := range concatenated(n.SecondExpression) {
+				h.hint(e, t)
+			}
+		}
+		h.hint(n.FirstExpression, h.typeOf(n.SecondExpression))
+	case *ast.BooleanInExpression:
+		for _, v := range n.Values {
+			h.hint(v, h.typeOf(n.Expression))
+		}
+		h.hint(n.Expression, h.typeOf(n.Values...))
+	case *ast.BinaryExpression:
+		h.pair(n.FirstExpression, n.SecondExpression)
+	case *ast.TopRowFilter:
+		if n.Percent {
+			h.hint(n.Expression, percentage)
+		} else {
+			h.hint(n.Expression, rowCount)
+		}
+	case *ast.OffsetClause:
+		h.hint(n.OffsetExpression, rowCount)
+		h.hint(n.FetchExpression, rowCount)
+	case *ast.CastCall:
+		h.hint(n.Parameter, h.dataType(n.DataType))
+	case *ast.ConvertCall:
+		h.hint(n.Parameter, h.dataType(n.DataType))
+	case *ast.InsertSpecification:
+		h.insert(n)
+	case *ast.AssignmentSetClause:
+		if n.Column != nil {
+			h.hint(n.NewValue, h.typeOf(n.Column))
+		} else if n.Variable != nil {
+			h.hint(n.NewValue, h.typeOf(n.Variable))
+		}
+	case *ast.SetVariableStatement:
+		if n.Variable != nil {
+			h.hint(n.Expression, h.typeOf(n.Variable))
+		}
+	case *ast.SelectSetVariable:
+		if n.Variable != nil {
+			h.hint(n.Expression, h.typeOf(n.Variable))
+		}
+	case *ast.DeclareVariableElement:
+		if n.DataType != nil {
+			h.hint(n.Value, h.dataType(n.DataType))
+		}
+	case *ast.ExecutableProcedureReference:
+		h.execute(n)
+	}
+}
+
+// pair hints each operand of a binary operator with the other's type.
+func (h *hinter) pair(a, b ast.ScalarExpression) {
+	h.hint(a, h.typeOf(b))
+	h.hint(b, h.typeOf(a))
+}
+
+// hint records t as the type of e if e is a query parameter whose type is
+// not yet known.
+func (h *hinter) hint(e ast.ScalarExpression, t Type) {
+	if t.IsZero() {
+		return
+	}
+	for {
+		p, ok := e.(*ast.ParenthesisExpression)
+		if !ok {
+			break
+		}
+		e = p.Expression
+	}
+	v, ok := e.(*ast.VariableReference)
+	if !ok {
+		return
+	}
+	if p := h.params[strings.ToLower(v.Name)]; p != nil && p.Type.IsZero() && h.isParam(p, v) {
+		p.Type = t
+	}
+}
+
+func (h *hinter) isParam(p *Parameter, v *ast.VariableReference) bool {
+	for _, ref := range p.Refs {
+		if ref == v {
+			return true
+		}
+	}
+	return false
+}
+
+// typeOf returns the type of the first expression whose type is known and
+// that is not itself an untyped parameter.
+func (h *hinter) typeOf(exprs ...ast.ScalarExpression) Type {
+	for _, e := range exprs {
+		if e == nil {
+			continue
+		}
+		if v, ok := e.(*ast.VariableReference); ok {
+			if p := h.params[strings.ToLower(v.Name)]; p != nil && h.isParam(p, v) {
+				if !p.Type.IsZero() {
+					return p.Type
+				}
+				continue
+			}
+		}
+		if t := h.info.TypeOf(e); !t.IsZero() {
+			return t
+		}
+	}
+	return Type{}
+}
+
+func (h *hinter) dataType(ref ast.DataTypeReference) Type {
+	t := h.info.c.resolve(catalog.TypeOf(ref))
+	if t.IsZero() {
+		return Type{}
+	}
+	return Type{Type: t, Nullable: true}
+}
+
+// insert hints the values of an INSERT with the types of the columns they
+// supply.
+func (h *hinter) insert(spec *ast.InsertSpecification) {
+	targets := h.insertColumns(spec)
+	if targets == nil {
+		return
+	}
+	switch src := spec.InsertSource.(type) {
+	case *ast.ValuesInsertSource:
+		for _, row := range src.RowValues {
+			for j, v := range row.ColumnValues {
+				if j < len(targets) {
+					h.hint(v, targets[j])
+				}
+			}
+		}
+	case *ast.SelectInsertSource:
+		q, ok := src.Select.(*ast.QuerySpecification)
+		if !ok {
+			return
+		}
+		for j, e := range q.SelectElements {
+			if s, ok := e.(*ast.SelectScalarExpression); ok && j < len(targets) {
+				h.hint(s.Expression, targets[j])
+			}
+		}
+	}
+}
+
+// insertColumns returns the types of the columns an INSERT supplies: those
+// listed, or else the table's columns that accept values.
+func (h *hinter) insertColumns(spec *ast.InsertSpecification) []Type {
+	if len(spec.Columns) > 0 {
+		types := make([]Type, len(spec.Columns))
+		for j, col := range spec.Columns {
+			types[j] = h.info.TypeOf(col)
+		}
+		return types
+	}
+	named, ok := spec.Target.(*ast.NamedTableReference)
+	if !ok || h.info.c.cat == nil {
+		return nil
+	}
+	table := h.info.c.cat.Table(catalog.NameOf(named.SchemaObject))
+	if table == nil {
+		return nil
+	}
+	var types []Type
+	for _, col := range table.Columns {
+		if col.Computed != nil || col.Identity != nil || col.GeneratedAlways != "" || col.Type.Name == "timestamp" || col.Type.Name == "rowversion" {
+			continue
+		}
+		types = append(types, Type{Type: h.info.c.resolve(col.Type), Nullable: col.Nullable})
+	}
+	return types
+}
+
+// execute hints the arguments of a procedure call with the types of the
+// procedure's parameters.
+func (h *hinter) execute(ref *ast.ExecutableProcedureReference) {
+	cat := h.info.c.cat
+	if cat == nil || ref.ProcedureReference == nil || ref.ProcedureReference.ProcedureReference == nil {
+		return
+	}
+	proc := cat.Procedure(catalog.NameOf(ref.ProcedureReference.ProcedureReference.Name))
+	if proc == nil {
+		return
+	}
+	for j, arg := range ref.Parameters {
+		var param *catalog.Parameter
+		if arg.Variable != nil {
+			for _, p := range proc.Parameters {
+				if strings.EqualFold(p.Name, arg.Variable.Name) {
+					param = p
+				}
+			}
+		} else if j < len(proc.Parameters) {
+			param = proc.Parameters[j]
+		}
+		if param != nil {
+			h.hint(arg.ParameterValue, Type{Type: cat.ResolveType(param.Type), Nullable: true})
+		}
+	}
+}
+
+// concatenated returns the operands of a chain of + operators, or e itself.
+func concatenated(e ast.ScalarExpression) []ast.ScalarExpression {
+	for {
+		p, ok := e.(*ast.ParenthesisExpression)
+		if !ok {
+			break
+		}
+		e = p.Expression
+	}
+	b, ok := e.(*ast.BinaryExpression)
+	if !ok || b.BinaryExpressionType != "Add" {
+		return []ast.ScalarExpression{e}
+	}
+	return append(concatenated(b.FirstExpression), concatenated(b.SecondExpression)...)
+}
@@ -0,0 +1,244 @@
+package typecheck
+
+import (
+	"github.com/sqlc-dev/teesql/ast"
+	"github.com/sqlc-dev/teesql/catalog"
+)
+
+// precedence ranks system types by SQL Server's data type precedence; when
+// an operator combines two types, the operand of lower precedence is
+// converted to the other. Higher ranks take precedence.
+var precedence = map[string]int{
+	"binary":           1,
+	"varbinary":        2,
+	"char":             3,
+	"varchar":          4,
+	"nchar":            5,
+	"nvarchar":         6,
+	"uniqueidentifier": 7,
+	"rowversion":       8,
+	"timestamp":        8,
+	"image":            9,
+	"text":             10,
+	"ntext":            11,
+	"bit":              12,
+	"tinyint":          13,
+	"smallint":         14,
+	"int":              15,
+	"bigint":           16,
+	"smallmoney":       17,
+	"money":            18,
+	"decimal":          19,
+	"numeric":          19,
+	"real":             20,
+	"float":            21,
+	"time":             22,
+	"date":             23,
+	"smalldatetime":    24,
+	"datetime":         25,
+	"datetime2":        26,
+	"datetimeoffset":   27,
+	"json":             28,
+	"xml":              29,
+	"sql_variant":      30,
+	"hierarchyid":      31,
+	"geometry":         31,
+	"geography":        31,
+}
+
+// maxPrecision is the largest precision of decimal and numeric.
+const maxPrecision = 38
+
+func higher(a, b catalog.Type) catalog.Type {
+	if precedence[b.Name] > precedence[a.Name] {
+		return b
+	}
+	return a
+}
+
+func isString(t catalog.Type) bool {
+	switch t.Name {
+	case "char", "varchar", "nchar", "nvarchar":
+		return true
+	}
+	return false
+}
+
+func isUnicode(t catalog.Type) bool {
+	return t.Name == "nchar" || t.Name == "nvarchar" || t.Name == "ntext"
+}
+
+func isInteger(t catalog.Type) bool {
+	switch t.Name {
+	case "bit", "tinyint", "smallint", "int", "bigint":
+		return true
+	}
+	return false
+}
+
+func isDecimal(t catalog.Type) bool {
+	return t.Name == "decimal" || t.Name == "numeric"
+}
+
+// decimalOf returns the precision and scale of an exact numeric type as it
+// takes part in decimal arithmetic.
+func decimalOf(t catalog.Type) (p, s int, ok bool) {
+	switch t.Name {
+	case "decimal", "numeric":
+		return t.Precision, t.Scale, true
+	case "bigint":
+		return 19, 0, true
+	case "int":
+		return 10, 0, true
+	case "smallint":
+		return 5, 0, true
+	case "tinyint":
+		return 3, 0, true
+	case "bit":
+		return 1, 0, true
+	case "money":
+		return 19, 4, true
+	case "smallmoney":
+		return 10, 4, true
+	}
+	return 0, 0, false
+}
+
+// binary returns the type of an arithmetic, bitwise or concatenation
+// operator.
+func (c *checker) binary(e *ast.BinaryExpression) Type {
+	a, b := c.infer(e.FirstExpression), c.infer(e.SecondExpression)
+	nullable := a.Nullable || b.Nullable
+	if a.IsZero() || b.IsZero() {
+		return Type{Nullable: nullable}
+	}
+	if isNullLiteral(e.FirstExpression) {
+		return Type{Type: b.Type, Nullable: true}
+	}
+	if isNullLiteral(e.SecondExpression) {
+		return Type{Type: a.Type, Nullable: true}
+	}
+	if e.BinaryExpressionType == "Add" && isString(a.Type) && isString(b.Type) {
+		return Type{Type: concat(a.Type, b.Type), Nullable: nullable}
+	}
+	t := higher(a.Type, b.Type)
+	if isDecimal(t) {
+		p1, s1, ok1 := decimalOf(a.Type)
+		p2, s2, ok2 := decimalOf(b.Type)
+		if ok1 && ok2 {
+			t = decimalResult(t.Name, e.BinaryExpressionType, p1, s1, p2, s2)
+		}
+	}
+	return Type{Type: t, Nullable: nullable}
+}
+
+// decimalResult applies SQL Server's precision and scale rules for decimal
+// arithmetic, reducing the scale when the precision would exceed 38.
+func decimalResult(name, op string, p1, s1, p2, s2 int) catalog.Type {
+	var p, s int
+	additive := false
+	switch op {
+	case "Add", "Subtract":
+		s = max(s1, s2)
+		p = s + max(p1-s1, p2-s2) + 1
+		additive = true
+	case "Multiply":
+		p, s = p1+p2+1, s1+s2
+	case "Divide":
+		s = max(6, s1+p2+1)
+		p = p1 - s1 + s2 + s
+	case "Modulo":
+		s = max(s1, s2)
+		p = min(p1-s1, p2-s2) + s
+		additive = true
+	default:
+		// Bitwise operators do not apply to decimals.
+		return catalog.Type{Name: name, Precision: p1, Scale: s1}
+	}
+	if p > maxPrecision {
+		integral := p - s
+		switch {
+		case additive:
+			s = max(0, min(s, maxPrecision-integral))
+		case integral < 32:
+			s = min(s, maxPrecision-integral)
+		case s > 6:
+			s = 6
+		}
+		p = maxPrecision
+	}
+	return catalog.Type{Name: name, Precision: p, Scale: s}
+}
+
+// concat returns the type of string concatenation: the lengths add, up to
+// 8000 bytes, unless either operand is a max type.
+func concat(a, b catalog.Type) catalog.Type {
+	t := higher(a, b)
+	t.Alias = ""
+	if a.Length == catalog.MaxLength || b.Length == catalog.MaxLength {
+		t.Length = catalog.MaxLength
+		return t
+	}
+	limit := 8000
+	if isUnicode(t) {
+		limit = 4000
+	}
+	t.Length = min(a.Length+b.Length, limit)
+	return t
+}
+
+// unify returns the type that values of several types are converted to
+// when they supply one result: the type of highest precedence, wide enough
+// for every value.
+func unify(types []Type) Type {
+	var t catalog.Type
+	nullable := false
+	for _, u := range types {
+		if u.IsZero() {
+			return Type{Nullable: true}
+		}
+		nullable = nullable || u.Nullable
+		t = higher(t, u.Type)
+	}
+	t.Alias = ""
+	switch {
+	case isDecimal(t):
+		integral, scale := 0, 0
+		for _, u := range types {
+			if p, s, ok := decimalOf(u.Type); ok {
+				integral = max(integral, p-s)
+				scale = max(scale, s)
+			}
+		}
+		if integral+scale > maxPrecision {
+			scale = max(0, maxPrecision-integral)
+		}
+		t.Precision, t.Scale = min(integral+scale, maxPrecision), scale
+	case isString(t) || t.Name == "varbinary" || t.Name == "binary":
+		t.Length = 0
+		for _, u := range types {
+			n := u.Length
+			if !isString(u.Type) && u.Name != "varbinary" && u.Name != "binary" {
+				// A converted number or date; its length is not tracked.
+				continue
+			}
+			if n == catalog.MaxLength || t.Length == catalog.MaxLength {
+				t.Length = catalog.MaxLength
+				continue
+			}
+			t.Length = max(t.Length, n)
+		}
+		if t.Length == 0 {
+			t.Length = 30
+		}
+	case t.Name == "datetime2" || t.Name == "datetimeoffset" || t.Name == "time":
+		for _, u := range types {
+			if u.Name == t.Name {
+				t.Scale = max(t.Scale, u.Scale)
+			}
+		}
+	case t.Name == "float":
+		t.Precision = 53
+	}
+	return Type{Type: t, Nullable: nullable}
+}
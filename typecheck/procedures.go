@@ -0,0 +1,192 @@
+package typecheck
+
+import (
+	"strings"
+
+	"github.com/sqlc-dev/teesql/ast"
+	"github.com/sqlc-dev/teesql/binder"
+	"github.com/sqlc-dev/teesql/catalog"
+)
+
+// ProcedureResult is a result set that a procedure may return.
+type ProcedureResult struct {
+	// Statement is the statement that returns the result set. For result
+	// sets of a procedure that the body executes, it is a statement of that
+	// procedure.
+	Statement ast.Statement
+	// Columns are the result set's columns, or nil if they are not known.
+	Columns []ResultColumn
+	// Conditional is set for result sets returned only on some paths: in
+	// IF and ELSE branches, WHILE loops and CATCH blocks.
+	Conditional bool
+	// Unknown is set for EXEC statements that may return result sets that
+	// cannot be inferred, such as dynamic SQL and procedures that are not in
+	// the catalog.
+	Unknown bool
+}
+
+// ProcedureResults returns the result sets that the body of a CREATE
+// PROCEDURE, CREATE OR ALTER PROCEDURE or ALTER PROCEDURE statement may
+// return, in source order. A SELECT returns a result set unless it has an
+// INTO clause or assigns variables, and INSERT, UPDATE, DELETE and MERGE
+// return one when they have an OUTPUT clause without INTO. EXEC of a
+// procedure in the catalog returns that procedure's result sets.
+func (i *Info) ProcedureResults(stmt ast.Statement) []*ProcedureResult {
+	p := catalog.NewProcedure(stmt)
+	if p == nil || p.Body == nil {
+		return nil
+	}
+	r := &procResults{info: i, active: map[*catalog.Procedure]bool{}}
+	r.list(p.Body.Statements, false)
+	return r.results
+}
+
+// ProcedureResults returns the result sets of a procedure statement, typed
+// against the schema that the DDL scripts define. See
+// Info.ProcedureResults.
+func ProcedureResults(stmt ast.Statement, ddl ...*ast.Script) ([]*ProcedureResult, error) {
+	cat, err := catalog.Build(ddl...)
+	if err != nil {
+		return nil, err
+	}
+	return CheckStatement(stmt, cat).ProcedureResults(stmt), nil
+}
+
+type procResults struct {
+	info    *Info
+	results []*ProcedureResult
+	// active holds the procedures whose bodies are being walked, to stop
+	// at recursive calls.
+	active map[*catalog.Procedure]bool
+}
+
+// list walks a statement list. It reports whether the list always returns,
+// so that statements after it are not reached.
+func (r *procResults) list(stmts []ast.Statement, conditional bool) bool {
+	for _, stmt := range stmts {
+		if r.statement(stmt, conditional) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *procResults) statement(stmt ast.Statement, conditional bool) (returns bool) {
+	switch s := stmt.(type) {
+	case *ast.BeginEndBlockStatement:
+		if s.StatementList != nil {
+			return r.list(s.StatementList.Statements, conditional)
+		}
+	case *ast.BeginEndAtomicBlockStatement:
+		if s.StatementList != nil {
+			return r.list(s.StatementList.Statements, conditional)
+		}
+	case *ast.IfStatement:
+		then := r.statement(s.ThenStatement, true)
+		if s.ElseStatement == nil {
+			return false
+		}
+		return r.statement(s.ElseStatement, true) && then
+	case *ast.WhileStatement:
+		r.statement(s.Statement, true)
+	case *ast.TryCatchStatement:
+		var returns bool
+		if s.TryStatements != nil {
+			returns = r.list(s.TryStatements.Statements, conditional)
+		}
+		if s.CatchStatements != nil {
+			r.list(s.CatchStatements.Statements, true)
+		}
+		return returns
+	case *ast.ReturnStatement:
+		return true
+	case *ast.ExecuteStatement:
+		if s.ExecuteSpecification != nil {
+			r.execute(s, conditional)
+		}
+	default:
+		if returnsRows(stmt) {
+			r.results = append(r.results, &ProcedureResult{
+				Statement:   stmt,
+				Columns:     r.info.StatementColumns(stmt),
+				Conditional: conditional,
+			})
+		}
+	}
+	return false
+}
+
+// execute adds the result sets of an EXEC statement: those of the
+// procedure it calls if the procedure is in the catalog, or else an
+// unknown result set.
+func (r *procResults) execute(s *ast.ExecuteStatement, conditional bool) {
+	unknown := &ProcedureResult{Statement: s, Conditional: conditional, Unknown: true}
+	ref, ok := s.ExecuteSpecification.ExecutableEntity.(*ast.ExecutableProcedureReference)
+	cat := r.info.c.cat
+	if !ok || cat == nil || ref.ProcedureReference == nil || ref.ProcedureReference.ProcedureReference == nil {
+		r.results = append(r.results, unknown)
+		return
+	}
+	proc := cat.Procedure(catalog.NameOf(ref.ProcedureReference.ProcedureReference.Name))
+	if proc == nil || proc.Body == nil || r.active[proc] {
+		r.results = append(r.results, unknown)
+		return
+	}
+	r.results = append(r.results, catalogProcedure(cat, proc, r.active, conditional)...)
+}
+
+// CatalogProcedureResults returns the result sets that a procedure in the
+// catalog may return. See Info.ProcedureResults.
+func CatalogProcedureResults(cat *catalog.Catalog, proc *catalog.Procedure) []*ProcedureResult {
+	if proc.Body == nil {
+		return nil
+	}
+	return catalogProcedure(cat, proc, map[*catalog.Procedure]bool{}, false)
+}
+
+func catalogProcedure(cat *catalog.Catalog, proc *catalog.Procedure, active map[*catalog.Procedure]bool, conditional bool) []*ProcedureResult {
+	body := &ast.BeginEndBlockStatement{StatementList: proc.Body}
+	info := newInfo(binder.BindStatement(body, cat), cat)
+	for _, p := range proc.Parameters {
+		info.c.vars[strings.ToLower(p.Name)] = Type{Type: cat.ResolveType(p.Type), Nullable: true}
+	}
+	info.c.walk(body)
+	r := &procResults{info: info, active: active}
+	active[proc] = true
+	r.list(proc.Body.Statements, conditional)
+	delete(active, proc)
+	return r.results
+}
+
+// returnsRows reports whether a statement returns a result set to the
+// client.
+func returnsRows(stmt ast.Statement) bool {
+	switch s := stmt.(type) {
+	case *ast.SelectStatement:
+		return s.Into == nil && !assignsVariables(s.QueryExpression)
+	case *ast.InsertStatement:
+		return s.InsertSpecification != nil && s.InsertSpecification.OutputClause != nil
+	case *ast.UpdateStatement:
+		return s.UpdateSpecification != nil && s.UpdateSpecification.OutputClause != nil
+	case *ast.DeleteStatement:
+		return s.DeleteSpecification != nil && s.DeleteSpecification.OutputClause != nil
+	case *ast.MergeStatement:
+		return s.MergeSpecification != nil && s.MergeSpecification.OutputClause != nil
+	}
+	return false
+}
+
+// assignsVariables reports whether a query is a SELECT that assigns
+// variables, which returns no rows.
+func assignsVariables(q ast.QueryExpression) bool {
+	spec, ok := q.(*ast.QuerySpecification)
+	if !ok {
+		return false
+	}
+	for _, e := range spec.SelectElements {
+		if _, ok := e.(*ast.SelectSetVariable); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+package typecheck
+
+import (
+	"github.com/sqlc-dev/teesql/ast"
+	"github.com/sqlc-dev/teesql/catalog"
+)
+
+// ResultColumn is a column of the result set a statement returns.
+type ResultColumn struct {
+	// Name is the column name: the alias if one is given, otherwise the
+	// name of the referenced column. It is empty for unnamed columns.
+	Name string
+	Type Type
+	// Unnamed is set for expression columns without an alias, which SQL
+	// Server returns with no column name.
+	Unnamed bool
+}
+
+// ResultColumns returns the ordered output columns of a query expression,
+// with * and t.* expanded, or nil if they are not known. The columns of a
+// UNION, EXCEPT or INTERSECT take their names from the first query.
+func (i *Info) ResultColumns(q ast.QueryExpression) []ResultColumn {
+	types := i.c.query(q)
+	if types == nil {
+		return nil
+	}
+	return resultColumns(i.Bindings.Columns(q), types)
+}
+
+// OutputColumns returns the ordered columns of an OUTPUT clause, or nil if
+// they are not known.
+func (i *Info) OutputColumns(oc *ast.OutputClause) []ResultColumn {
+	cols := i.Bindings.Output(oc)
+	if cols == nil {
+		return nil
+	}
+	types := make([]Type, len(cols))
+	for j, col := range cols {
+		types[j] = i.c.columnType(nil, col)
+	}
+	return resultColumns(cols, types)
+}
+
+// StatementColumns returns the result set of a statement: the columns of a
+// SELECT without INTO or variable assignments, or of the OUTPUT clause of an INSERT, UPDATE, DELETE
+// or MERGE that returns rows to the client. It returns nil for statements
+// that return no rows and for result sets that are not known.
+func (i *Info) StatementColumns(stmt ast.Statement) []ResultColumn {
+	switch s := stmt.(type) {
+	case *ast.SelectStatement:
+		if s.Into != nil || assignsVariables(s.QueryExpression) {
+			return nil
+		}
+		return i.ResultColumns(s.QueryExpression)
+	case *ast.InsertStatement:
+		if s.InsertSpecification != nil {
+			return i.OutputColumns(s.InsertSpecification.OutputClause)
+		}
+	case *ast.UpdateStatement:
+		if s.UpdateSpecification != nil {
+			return i.OutputColumns(s.UpdateSpecification.OutputClause)
+		}
+	case *ast.DeleteStatement:
+		if s.DeleteSpecification != nil {
+			return i.OutputColumns(s.DeleteSpecification.OutputClause)
+		}
+	case *ast.MergeStatement:
+		if s.MergeSpecification != nil {
+			return i.OutputColumns(s.MergeSpecification.OutputClause)
+		}
+	}
+	return nil
+}
+
+// ResultSet returns the result set of stmt, typed against the schema that
+// the DDL scripts define. See Info.StatementColumns.
+func ResultSet(stmt ast.Statement, ddl ...*ast.Script) ([]ResultColumn, error) {
+	cat, err := catalog.Build(ddl...)
+	if err != nil {
+		return nil, err
+	}
+	return CheckStatement(stmt, cat).StatementColumns(stmt), nil
+}
+
+func resultColumns(cols []*catalog.Column, types []Type) []ResultColumn {
+	out := make([]ResultColumn, len(cols))
+	for j, col := range cols {
+		out[j] = ResultColumn{Name: col.Name, Unnamed: col.Name == ""}
+		if j < len(types) {
+			out[j].Type = types[j]
+		}
+	}
+	return out
+}
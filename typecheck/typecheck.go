@@ -0,0 +1,566 @@
+// Package typecheck infers the SQL Server data type of scalar expressions.
+//
+// Column references are resolved with the binder against a catalog, and the
+// types of literals, variables, operators, CAST and CONVERT, CASE,
+// COALESCE, IIF and built-in functions follow SQL Server's rules: data type
+// precedence decides the result of mixed operands, decimal arithmetic
+// computes precision and scale as the server does, and string concatenation
+// adds lengths. Nullability is inferred alongside the type. Expressions whose
+// type cannot be determined, such as references to unknown tables, have the
+// zero Type.
+package typecheck
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sqlc-dev/teesql/ast"
+	"github.com/sqlc-dev/teesql/binder"
+	"github.com/sqlc-dev/teesql/catalog"
+)
+
+// Type is the inferred type of an expression.
+type Type struct {
+	catalog.Type
+	Nullable bool `json:"Nullable,omitempty"`
+}
+
+// String returns the type followed by NULL or NOT NULL, or "unknown".
+func (t Type) String() string {
+	if t.IsZero() {
+		return "unknown"
+	}
+	if t.Nullable {
+		return t.Type.String() + " NULL"
+	}
+	return t.Type.String() + " NOT NULL"
+}
+
+// Info holds the inferred types of a script's expressions.
+type Info struct {
+	Types    map[ast.ScalarExpression]Type
+	Bindings *binder.Result
+
+	c *checker
+}
+
+// TypeOf returns the type of an expression, or the zero Type if it is not
+// known.
+func (i *Info) TypeOf(expr ast.ScalarExpression) Type {
+	return i.Types[expr]
+}
+
+// Columns returns the types of the output columns of a query expression, or
+// nil if its columns are not known. The column types of a UNION, EXCEPT or
+// INTERSECT combine those of both queries.
+func (i *Info) Columns(q ast.QueryExpression) []Type {
+	return i.c.query(q)
+}
+
+// Check infers the type of every scalar expression in the script. cat may
+// be nil, in which case only columns of tables created by the script have
+// known types.
+func Check(script *ast.Script, cat *catalog.Catalog) *Info {
+	info := newInfo(binder.Bind(script, cat), cat)
+	if script != nil {
+		info.c.walk(script)
+	}
+	return info
+}
+
+// CheckStatement infers the type of every scalar expression in a single
+// statement.
+func CheckStatement(stmt ast.Statement, cat *catalog.Catalog) *Info {
+	info := newInfo(binder.BindStatement(stmt, cat), cat)
+	if stmt != nil {
+		info.c.walk(stmt)
+	}
+	return info
+}
+
+func newInfo(bindings *binder.Result, cat *catalog.Catalog) *Info {
+	info := &Info{Types: map[ast.ScalarExpression]Type{}, Bindings: bindings}
+	info.c = &checker{
+		cat:      cat,
+		info:     info,
+		bindings: bindings,
+		vars:     map[string]Type{},
+		views:    map[*catalog.View]*checker{},
+	}
+	return info
+}
+
+type checker struct {
+	cat  *catalog.Catalog
+	info *Info
+	// bindings resolves the column references being checked. It differs
+	// from info.Bindings while checking a view definition.
+	bindings *binder.Result
+	// vars holds the declared types of variables and parameters in scope,
+	// keyed by lower-case name.
+	vars  map[string]Type
+	views map[*catalog.View]*checker
+}
+
+// walk infers the types of the expressions under node, tracking variable
+// declarations in order.
+func (c *checker) walk(node ast.Node) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.Batch:
+			c.vars = map[string]Type{}
+		case *ast.DeclareVariableElement:
+			if n.VariableName != nil && n.DataType != nil {
+				c.declare(n.VariableName.Value, n.DataType)
+			}
+		case *ast.ProcedureParameter:
+			if n.VariableName != nil {
+				c.declare(n.VariableName.Value, n.DataType)
+			}
+		case ast.ScalarExpression:
+			c.infer(n)
+		}
+		return true
+	})
+}
+
+func (c *checker) declare(name string, ref ast.DataTypeReference) {
+	c.vars[strings.ToLower(name)] = Type{Type: c.resolve(catalog.TypeOf(ref)), Nullable: true}
+}
+
+func (c *checker) resolve(t catalog.Type) catalog.Type {
+	if c.cat == nil {
+		return t
+	}
+	return c.cat.ResolveType(t)
+}
+
+// infer returns the type of expr, computing it once.
+func (c *checker) infer(expr ast.ScalarExpression) Type {
+	if expr == nil {
+		return Type{}
+	}
+	if t, ok := c.info.Types[expr]; ok {
+		return t
+	}
+	t := c.compute(expr)
+	c.info.Types[expr] = t
+	return t
+}
+
+func (c *checker) compute(expr ast.ScalarExpression) Type {
+	switch e := expr.(type) {
+	case *ast.ParenthesisExpression:
+		return c.infer(e.Expression)
+	case *ast.IntegerLiteral:
+		return integerLiteral(e.Value)
+	case *ast.NumericLiteral:
+		return numericLiteral(e.Value)
+	case *ast.RealLiteral:
+		return notNull(catalog.Type{Name: "float", Precision: 53})
+	case *ast.MoneyLiteral:
+		return notNull(catalog.Type{Name: "money"})
+	case *ast.StringLiteral:
+		return stringLiteral(e)
+	case *ast.BinaryLiteral:
+		n := (len(e.Value) - 2) / 2
+		if n < 1 {
+			n = 1
+		}
+		return notNull(catalog.Type{Name: "varbinary", Length: n})
+	case *ast.NullLiteral:
+		return Type{Type: catalog.Type{Name: "int"}, Nullable: true}
+	case *ast.ColumnReferenceExpression:
+		return c.column(e)
+	case *ast.VariableReference:
+		if t, ok := c.vars[strings.ToLower(e.Name)]; ok {
+			return t
+		}
+		return Type{Nullable: true}
+	case *ast.GlobalVariableExpression:
+		return globalVariables[strings.ToUpper(e.Name)]
+	case *ast.ParameterlessCall:
+		return parameterlessCalls[e.ParameterlessCallType]
+	case *ast.UnaryExpression:
+		return c.infer(e.Expression)
+	case *ast.BinaryExpression:
+		return c.binary(e)
+	case *ast.CastCall:
+		return c.cast(e.DataType, e.Parameter, false)
+	case *ast.ConvertCall:
+		return c.cast(e.DataType, e.Parameter, false)
+	case *ast.TryCastCall:
+		return c.cast(e.DataType, e.Parameter, true)
+	case *ast.TryConvertCall:
+		return c.cast(e.DataType, e.Parameter, true)
+	case *ast.ParseCall:
+		return c.cast(e.DataType, e.StringValue, false)
+	case *ast.TryParseCall:
+		return c.cast(e.DataType, e.StringValue, true)
+	case *ast.SearchedCaseExpression:
+		var results []ast.ScalarExpression
+		for _, w := range e.WhenClauses {
+			if w != nil {
+				results = append(results, w.ThenExpression)
+			}
+		}
+		return c.caseType(results, e.ElseExpression)
+	case *ast.SimpleCaseExpression:
+		var results []ast.ScalarExpression
+		for _, w := range e.WhenClauses {
+			if w != nil {
+				results = append(results, w.ThenExpression)
+			}
+		}
+		return c.caseType(results, e.ElseExpression)
+	case *ast.IIfCall:
+		return c.caseType([]ast.ScalarExpression{e.ThenExpression}, e.ElseExpression)
+	case *ast.CoalesceExpression:
+		t := c.union(e.Expressions)
+		t.Nullable = true
+		for _, arg := range e.Expressions {
+			if !c.infer(arg).Nullable {
+				t.Nullable = false
+			}
+		}
+		return t
+	case *ast.NullIfExpression:
+		t := c.infer(e.FirstExpression)
+		c.infer(e.SecondExpression)
+		t.Nullable = true
+		return t
+	case *ast.FunctionCall:
+		return c.function(e)
+	case *ast.LeftFunctionCall:
+		return c.builtin("left", e.Parameters)
+	case *ast.RightFunctionCall:
+		return c.builtin("right", e.Parameters)
+	case *ast.ScalarSubquery:
+		cols := c.query(e.QueryExpression)
+		if len(cols) == 0 {
+			return Type{Nullable: true}
+		}
+		t := cols[0]
+		// A subquery that returns no rows is null.
+		t.Nullable = true
+		return t
+	case *ast.AtTimeZoneCall:
+		d := c.infer(e.DateValue)
+		c.infer(e.TimeZone)
+		scale := 7
+		if d.Name == "datetime2" || d.Name == "datetimeoffset" || d.Name == "time" {
+			scale = d.Scale
+		}
+		return Type{Type: catalog.Type{Name: "datetimeoffset", Scale: scale}, Nullable: d.Nullable}
+	case *ast.NextValueForExpression:
+		t := catalog.Type{Name: "bigint"}
+		if c.cat != nil {
+			if seq := c.cat.Sequence(catalog.NameOf(e.SequenceName)); seq != nil && !seq.Type.IsZero() {
+				t = c.resolve(seq.Type)
+			}
+		}
+		return notNull(t)
+	case *ast.IdentityFunctionCall:
+		return notNull(c.resolve(catalog.TypeOf(e.DataType)))
+	case *ast.ExtractFromExpression:
+		return Type{Type: catalog.Type{Name: "int"}, Nullable: c.infer(e.Expression).Nullable}
+	}
+	return Type{}
+}
+
+// column returns the type of a bound column reference. Columns on the inner
+// side of an outer join are nullable.
+func (c *checker) column(ref *ast.ColumnReferenceExpression) Type {
+	if ref.ColumnType == "PseudoColumnAction" {
+		return notNull(catalog.Type{Name: "nvarchar", Length: 10})
+	}
+	binding := c.bindings.Lookup(ref)
+	if binding == nil || binding.Column == nil {
+		return Type{Nullable: true}
+	}
+	t := c.columnType(binding.Source, binding.Column)
+	if binding.Source.Nullable {
+		t.Nullable = true
+	}
+	return t
+}
+
+// columnType returns the type of a source column. Derived columns take the
+// type of the expression that computes them; view and computed columns are
+// typed from their definitions.
+func (c *checker) columnType(src *binder.Source, col *catalog.Column) Type {
+	if !col.Type.IsZero() {
+		return Type{Type: c.resolve(col.Type), Nullable: col.Nullable}
+	}
+	if expr := c.bindings.Expression(col); expr != nil {
+		return c.infer(expr)
+	}
+	if src == nil {
+		// A column expanded from * keeps the source it came from.
+		if src = c.bindings.Origin(col); src != nil {
+			t := c.columnType(src, col)
+			t.Nullable = t.Nullable || src.Nullable
+			return t
+		}
+	}
+	if src == nil || c.cat == nil {
+		return Type{Nullable: col.Nullable}
+	}
+	switch src.Kind {
+	case binder.SourceView:
+		return c.viewColumn(objectName(src.Object), col)
+	case binder.SourceTable, binder.SourceInserted, binder.SourceDeleted:
+		if col.Computed != nil {
+			return c.computed(objectName(src.Object), col)
+		}
+	}
+	return Type{Nullable: col.Nullable}
+}
+
+// viewColumn infers the type of a view column from the view's definition.
+func (c *checker) viewColumn(name catalog.ObjectName, col *catalog.Column) Type {
+	if syn := c.cat.Synonym(name); syn != nil {
+		name = catalog.NameOf(syn.Target)
+	}
+	v := c.cat.View(name)
+	if v == nil || v.Definition == nil {
+		return Type{Nullable: col.Nullable}
+	}
+	vc, ok := c.views[v]
+	if !ok {
+		vc = &checker{
+			cat:      c.cat,
+			info:     c.info,
+			bindings: binder.BindStatement(v.Definition, c.cat),
+			vars:     map[string]Type{},
+			views:    c.views,
+		}
+		c.views[v] = vc
+	}
+	cols := vc.query(v.Definition.QueryExpression)
+	for i, vcol := range v.Columns {
+		if vcol == col && i < len(cols) {
+			return cols[i]
+		}
+	}
+	return Type{Nullable: col.Nullable}
+}
+
+// computed infers the type of a computed column from its expression.
+func (c *checker) computed(table catalog.ObjectName, col *catalog.Column) Type {
+	if t, ok := c.info.Types[col.Computed]; ok {
+		return t
+	}
+	// Bind the expression as if selected from its table.
+	name := &ast.SchemaObjectName{BaseIdentifier: &ast.Identifier{Value: table.Name}}
+	if table.Schema != "" {
+		name.SchemaIdentifier = &ast.Identifier{Value: table.Schema}
+	}
+	sel := &ast.SelectStatement{QueryExpression: &ast.QuerySpecification{
+		SelectElements: []ast.SelectElement{&ast.SelectScalarExpression{Expression: col.Computed}},
+		FromClause:     &ast.FromClause{TableReferences: []ast.TableReference{&ast.NamedTableReference{SchemaObject: name}}},
+	}}
+	cc := &checker{
+		cat:      c.cat,
+		info:     c.info,
+		bindings: binder.BindStatement(sel, c.cat),
+		vars:     map[string]Type{},
+		views:    c.views,
+	}
+	return cc.infer(col.Computed)
+}
+
+// query returns the types of a query's output columns.
+func (c *checker) query(q ast.QueryExpression) []Type {
+	cols := c.queryColumns(q)
+	if cols == nil {
+		return nil
+	}
+	out := make([]Type, len(cols))
+	for i, col := range cols {
+		out[i] = col.Type
+	}
+	return out
+}
+
+// outputColumn is the type of a query output column. null is set when every
+// value comes from an untyped NULL literal.
+type outputColumn struct {
+	Type
+	null bool
+}
+
+func (c *checker) queryColumns(q ast.QueryExpression) []outputColumn {
+	switch q := q.(type) {
+	case *ast.QueryParenthesisExpression:
+		return c.queryColumns(q.QueryExpression)
+	case *ast.BinaryQueryExpression:
+		first, second := c.queryColumns(q.FirstQueryExpression), c.queryColumns(q.SecondQueryExpression)
+		if first == nil || len(first) != len(second) {
+			return first
+		}
+		out := make([]outputColumn, len(first))
+		for i, a := range first {
+			b := second[i]
+			switch {
+			case a.null:
+				out[i] = outputColumn{Type: Type{Type: b.Type.Type, Nullable: true}, null: b.null}
+			case b.null:
+				out[i] = outputColumn{Type: Type{Type: a.Type.Type, Nullable: true}}
+			default:
+				out[i].Type = unify([]Type{a.Type, b.Type})
+			}
+		}
+		return out
+	}
+	cols := c.bindings.Columns(q)
+	if cols == nil {
+		return nil
+	}
+	out := make([]outputColumn, len(cols))
+	for i, col := range cols {
+		out[i].Type = c.columnType(nil, col)
+		out[i].null = isNullLiteral(c.bindings.Expression(col))
+	}
+	return out
+}
+
+// cast returns the type of CAST, CONVERT or PARSE to ref. The TRY_ forms
+// return null when the conversion fails.
+func (c *checker) cast(ref ast.DataTypeReference, arg ast.ScalarExpression, try bool) Type {
+	t := c.resolve(catalog.TypeOf(ref))
+	if sql, ok := ref.(*ast.SqlDataTypeReference); ok && len(sql.Parameters) == 0 && isString(t) {
+		// Without a length, CAST and CONVERT use 30 rather than 1.
+		t.Length = 30
+	}
+	return Type{Type: t, Nullable: try || c.infer(arg).Nullable}
+}
+
+// caseType returns the type of CASE or IIF results. Without an ELSE the
+// result may be null.
+func (c *checker) caseType(results []ast.ScalarExpression, elseExpr ast.ScalarExpression) Type {
+	if elseExpr != nil {
+		results = append(results, elseExpr)
+	}
+	t := c.union(results)
+	if elseExpr == nil {
+		t.Nullable = true
+	}
+	return t
+}
+
+// union returns the type of expressions that supply values for the same
+// result, as in CASE and COALESCE. Untyped NULL literals do not take part
+// in choosing the type.
+func (c *checker) union(exprs []ast.ScalarExpression) Type {
+	var types []Type
+	nullable := false
+	for _, e := range exprs {
+		t := c.infer(e)
+		nullable = nullable || t.Nullable
+		if isNullLiteral(e) {
+			continue
+		}
+		types = append(types, t)
+	}
+	if len(types) == 0 {
+		return Type{Type: catalog.Type{Name: "int"}, Nullable: true}
+	}
+	t := unify(types)
+	t.Nullable = nullable
+	return t
+}
+
+func isNullLiteral(e ast.ScalarExpression) bool {
+	for {
+		switch x := e.(type) {
+		case *ast.NullLiteral:
+			return true
+		case *ast.ParenthesisExpression:
+			e = x.Expression
+		default:
+			return false
+		}
+	}
+}
+
+func objectName(s string) catalog.ObjectName {
+	if schema, name, ok := strings.Cut(s, "."); ok {
+		return catalog.ObjectName{Schema: schema, Name: name}
+	}
+	return catalog.ObjectName{Name: s}
+}
+
+func notNull(t catalog.Type) Type {
+	return Type{Type: t}
+}
+
+// integerLiteral types an integer constant: int if it fits, otherwise
+// numeric with just enough precision.
+func integerLiteral(v string) Type {
+	if n, err := strconv.ParseInt(v, 10, 64); err == nil && n <= 1<<31-1 {
+		return notNull(catalog.Type{Name: "int"})
+	}
+	digits := len(strings.TrimLeft(v, "0"))
+	if digits == 0 {
+		digits = 1
+	}
+	return notNull(catalog.Type{Name: "numeric", Precision: digits})
+}
+
+// numericLiteral types a constant with a decimal point as numeric(p,s),
+// where p and s count the digits written.
+func numericLiteral(v string) Type {
+	whole, frac, _ := strings.Cut(v, ".")
+	whole = strings.TrimLeft(whole, "0")
+	p, s := len(whole)+len(frac), len(frac)
+	if p == 0 {
+		p = 1
+	}
+	return notNull(catalog.Type{Name: "numeric", Precision: p, Scale: s})
+}
+
+func stringLiteral(e *ast.StringLiteral) Type {
+	n := utf8.RuneCountInString(e.Value)
+	t := catalog.Type{Name: "varchar", Length: n}
+	limit := 8000
+	if e.IsNational {
+		t.Name = "nvarchar"
+		limit = 4000
+	}
+	if n == 0 {
+		t.Length = 1
+	}
+	if n > limit {
+		t.Length = catalog.MaxLength
+	}
+	return notNull(t)
+}
+
+var globalVariables = map[string]Type{
+	"@@ROWCOUNT":      notNull(catalog.Type{Name: "int"}),
+	"@@ERROR":         notNull(catalog.Type{Name: "int"}),
+	"@@TRANCOUNT":     notNull(catalog.Type{Name: "int"}),
+	"@@FETCH_STATUS":  notNull(catalog.Type{Name: "int"}),
+	"@@NESTLEVEL":     notNull(catalog.Type{Name: "int"}),
+	"@@PROCID":        notNull(catalog.Type{Name: "int"}),
+	"@@SPID":          notNull(catalog.Type{Name: "smallint"}),
+	"@@DATEFIRST":     notNull(catalog.Type{Name: "tinyint"}),
+	"@@MAX_PRECISION": notNull(catalog.Type{Name: "tinyint"}),
+	"@@IDENTITY":      {Type: catalog.Type{Name: "numeric", Precision: 38}, Nullable: true},
+	"@@VERSION":       notNull(catalog.Type{Name: "nvarchar", Length: 300}),
+	"@@SERVERNAME":    {Type: catalog.Type{Name: "nvarchar", Length: 128}, Nullable: true},
+	"@@SERVICENAME":   notNull(catalog.Type{Name: "nvarchar", Length: 128}),
+	"@@LANGUAGE":      notNull(catalog.Type{Name: "nvarchar", Length: 128}),
+	"@@DBTS":          notNull(catalog.Type{Name: "varbinary", Length: 8}),
+}
+
+var parameterlessCalls = map[string]Type{
+	"CurrentTimestamp": notNull(catalog.Type{Name: "datetime"}),
+	"CurrentDate":      notNull(catalog.Type{Name: "date"}),
+	"CurrentUser":      notNull(catalog.Type{Name: "nvarchar", Length: 128}),
+	"SessionUser":      notNull(catalog.Type{Name: "nvarchar", Length: 128}),
+	"SystemUser":       notNull(catalog.Type{Name: "nvarchar", Length: 128}),
+	"User":             notNull(catalog.Type{Name: "nvarchar", Length: 128}),
+}
@@ -0,0 +1,411 @@
+package typecheck
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sqlc-dev/teesql/ast"
+	"github.com/sqlc-dev/teesql/catalog"
+	"github.com/sqlc-dev/teesql/parser"
+)
+
+const schemaSQL = `
+CREATE TYPE dbo.Email FROM nvarchar(256) NULL;
+GO
+CREATE TABLE dbo.Items (
+	Id int IDENTITY NOT NULL PRIMARY KEY,
+	Code char(4) NOT NULL,
+	Name varchar(50) NOT NULL,
+	Label nvarchar(20) NULL,
+	Notes nvarchar(max) NULL,
+	Price decimal(10,2) NOT NULL,
+	Qty smallint NOT NULL,
+	Rate float NULL,
+	Fee money NULL,
+	Created datetime2(3) NOT NULL,
+	Owner dbo.Email,
+	Total AS Price * Qty
+);
+CREATE TABLE dbo.Tags (ItemId int NOT NULL, Tag varchar(10) NOT NULL);
+GO
+CREATE VIEW dbo.ItemTotals AS SELECT Id, Price * 2 AS Doubled, UPPER(Name) AS Upper FROM dbo.Items;
+GO
+CREATE FUNCTION dbo.Tax (@amount decimal(10,2)) RETURNS decimal(12,4) AS BEGIN RETURN @amount * 0.2 END;
+GO
+CREATE SEQUENCE dbo.Seq AS int;
+`
+
+func parse(t *testing.T, sql string) *ast.Script {
+	t.Helper()
+	script, err := parser.Parse(context.Background(), strings.NewReader(sql))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	return script
+}
+
+func TestCheck(t *testing.T) {
+	cat, err := catalog.Build(parse(t, schemaSQL))
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	tests := []struct {
+		expr string
+		from string
+		want string
+	}{
+		// Literals.
+		{expr: "1", want: "int NOT NULL"},
+		{expr: "3000000000", want: "numeric(10,0) NOT NULL"},
+		{expr: "12.345", want: "numeric(5,3) NOT NULL"},
+		{expr: "1e3", want: "float NOT NULL"},
+		{expr: "$2.5", want: "money NOT NULL"},
+		{expr: "'abc'", want: "varchar(3) NOT NULL"},
+		{expr: "N'abcd'", want: "nvarchar(4) NOT NULL"},
+		{expr: "0x0A0B", want: "varbinary(2) NOT NULL"},
+		{expr: "NULL", want: "int NULL"},
+
+		// Columns and nullability.
+		{expr: "Name", want: "varchar(50) NOT NULL"},
+		{expr: "Owner", want: "nvarchar(256) NULL"},
+		{expr: "Total", want: "decimal(16,2) NOT NULL"},
+		{expr: "t.Tag", from: "dbo.Items i LEFT JOIN dbo.Tags t ON t.ItemId = i.Id", want: "varchar(10) NULL"},
+		{expr: "Doubled", from: "dbo.ItemTotals", want: "decimal(21,2) NOT NULL"},
+		{expr: "Upper", from: "dbo.ItemTotals", want: "varchar(50) NOT NULL"},
+		{expr: "d.x", from: "(SELECT Qty + 1 AS x FROM dbo.Items) d", want: "int NOT NULL"},
+		{expr: "Missing", from: "dbo.Unknown", want: "unknown"},
+
+		// Operators and precedence.
+		{expr: "Qty + 1", want: "int NOT NULL"},
+		{expr: "Qty + Rate", want: "float NULL"},
+		{expr: "Price + 1", want: "decimal(13,2) NOT NULL"},
+		{expr: "Price * Price", want: "decimal(21,4) NOT NULL"},
+		{expr: "Price / Qty", want: "decimal(16,8) NOT NULL"},
+		{expr: "Price + Fee", want: "decimal(20,4) NULL"},
+		{expr: "CAST(1 AS decimal(38,10)) * CAST(1 AS decimal(38,10))", want: "decimal(38,6) NOT NULL"},
+		{expr: "Name + '!'", want: "varchar(51) NOT NULL"},
+		{expr: "Code + Label", want: "nvarchar(24) NULL"},
+		{expr: "Name + Notes", want: "nvarchar(max) NULL"},
+		{expr: "Qty + '1'", want: "smallint NOT NULL"},
+		{expr: "Created + 1", want: "datetime2(3) NOT NULL"},
+		{expr: "-Qty", want: "smallint NOT NULL"},
+
+		// Conversions and conditionals.
+		{expr: "CAST(Qty AS varchar)", want: "varchar(30) NOT NULL"},
+		{expr: "CONVERT(nvarchar(10), Created, 120)", want: "nvarchar(10) NOT NULL"},
+		{expr: "TRY_CAST(Name AS int)", want: "int NULL"},
+		{expr: "CAST(Label AS dbo.Email)", want: "nvarchar(256) NULL"},
+		{expr: "CASE WHEN Qty > 1 THEN Name ELSE Label END", want: "nvarchar(50) NULL"},
+		{expr: "CASE Qty WHEN 1 THEN Price WHEN 2 THEN Qty END", want: "decimal(10,2) NULL"},
+		{expr: "CASE WHEN Qty > 1 THEN 'a' ELSE NULL END", want: "varchar(1) NULL"},
+		{expr: "COALESCE(Label, Name)", want: "nvarchar(50) NOT NULL"},
+		{expr: "COALESCE(Rate, Fee)", want: "float NULL"},
+		{expr: "ISNULL(Label, 'x')", want: "nvarchar(20) NOT NULL"},
+		{expr: "NULLIF(Qty, 0)", want: "smallint NULL"},
+		{expr: "IIF(Qty > 1, 1.5, Qty)", want: "numeric(6,1) NOT NULL"},
+
+		// Built-in and user-defined functions.
+		{expr: "COUNT(*)", want: "int NOT NULL"},
+		{expr: "SUM(Price)", want: "decimal(38,2) NULL"},
+		{expr: "AVG(Price)", want: "decimal(38,6) NULL"},
+		{expr: "SUM(Qty)", want: "int NULL"},
+		{expr: "MAX(Created)", want: "datetime2(3) NULL"},
+		{expr: "ROW_NUMBER() OVER (ORDER BY Id)", want: "bigint NOT NULL"},
+		{expr: "LEN(Notes)", want: "bigint NULL"},
+		{expr: "SUBSTRING(Code, 1, 2)", want: "varchar(4) NOT NULL"},
+		{expr: "LEFT(Label, 3)", want: "nvarchar(20) NULL"},
+		{expr: "REPLACE(Name, 'a', 'b')", want: "varchar(8000) NOT NULL"},
+		{expr: "CONCAT(Name, Qty, Label)", want: "nvarchar(100) NOT NULL"},
+		{expr: "DATEADD(day, 1, Created)", want: "datetime2(3) NOT NULL"},
+		{expr: "DATEDIFF(day, Created, GETDATE())", want: "int NOT NULL"},
+		{expr: "SYSDATETIME()", want: "datetime2 NOT NULL"},
+		{expr: "CURRENT_TIMESTAMP", want: "datetime NOT NULL"},
+		{expr: "@@ROWCOUNT", want: "int NOT NULL"},
+		{expr: "SCOPE_IDENTITY()", want: "numeric(38,0) NULL"},
+		{expr: "dbo.Tax(Price)", want: "decimal(12,4) NULL"},
+		{expr: "NEXT VALUE FOR dbo.Seq", want: "int NOT NULL"},
+		{expr: "(SELECT MAX(Tag) FROM dbo.Tags)", want: "varchar(10) NULL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			from := tt.from
+			if from == "" {
+				from = "dbo.Items"
+			}
+			script := parse(t, "SELECT "+tt.expr+" FROM "+from)
+			sel := script.Batches[0].Statements[0].(*ast.SelectStatement)
+			spec := sel.QueryExpression.(*ast.QuerySpecification)
+			expr := spec.SelectElements[0].(*ast.SelectScalarExpression).Expression
+			info := Check(script, cat)
+			if got := info.TypeOf(expr).String(); got != tt.want {
+				t.Errorf("TypeOf(%s) = %s, want %s", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckVariables(t *testing.T) {
+	script := parse(t, `CREATE PROCEDURE dbo.p @id int, @name nvarchar(40) = NULL AS
+SELECT @id + 1, @name + N'x';
+GO
+DECLARE @d date = GETDATE();
+SELECT @d, @id;`)
+	info := Check(script, nil)
+	var got []string
+	ast.Inspect(script, func(n ast.Node) bool {
+		if s, ok := n.(*ast.SelectScalarExpression); ok {
+			got = append(got, info.TypeOf(s.Expression).String())
+		}
+		return true
+	})
+	want := []string{"int NULL", "nvarchar(41) NULL", "date NULL", "unknown"}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestColumnsOfUnion(t *testing.T) {
+	script := parse(t, "SELECT 1 AS a, 'ab' AS b UNION ALL SELECT 2.5, NULL")
+	sel := script.Batches[0].Statements[0].(*ast.SelectStatement)
+	info := Check(script, nil)
+	var got []string
+	for _, c := range info.Columns(sel.QueryExpression) {
+		got = append(got, c.String())
+	}
+	want := []string{"numeric(11,1) NOT NULL", "varchar(2) NULL"}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestResultSet(t *testing.T) {
+	ddl := parse(t, schemaSQL)
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{
+			sql:  "SELECT * FROM dbo.Tags",
+			want: "ItemId int NOT NULL, Tag varchar(10) NOT NULL",
+		},
+		{
+			sql:  "SELECT i.Id, t.* FROM dbo.Items i LEFT JOIN dbo.Tags t ON t.ItemId = i.Id",
+			want: "Id int NOT NULL, ItemId int NULL, Tag varchar(10) NULL",
+		},
+		{
+			sql:  "SELECT Name AS ItemName, Qty * 2, COUNT(*) OVER () AS n FROM dbo.Items",
+			want: "ItemName varchar(50) NOT NULL, (unnamed) int NOT NULL, n int NOT NULL",
+		},
+		{
+			sql:  "SELECT * FROM dbo.ItemTotals",
+			want: "Id int NOT NULL, Doubled decimal(21,2) NOT NULL, Upper varchar(50) NOT NULL",
+		},
+		{
+			sql:  "SELECT d.* FROM (SELECT Total, Price + 1 AS p FROM dbo.Items) d",
+			want: "Total decimal(16,2) NOT NULL, p decimal(13,2) NOT NULL",
+		},
+		{
+			sql:  "SELECT Tag FROM dbo.Tags UNION SELECT Label FROM dbo.Items",
+			want: "Tag nvarchar(20) NULL",
+		},
+		{
+			sql:  "SELECT Name INTO #copy FROM dbo.Items",
+			want: "",
+		},
+		{
+			sql:  "SELECT * FROM dbo.Missing",
+			want: "",
+		},
+		{
+			sql:  "INSERT INTO dbo.Tags (ItemId, Tag) OUTPUT inserted.*, 1 VALUES (1, 'x')",
+			want: "ItemId int NOT NULL, Tag varchar(10) NOT NULL, (unnamed) int NOT NULL",
+		},
+		{
+			sql:  "UPDATE dbo.Items SET Qty = 1 OUTPUT deleted.Qty AS OldQty, inserted.Total WHERE Id = 1",
+			want: "OldQty smallint NOT NULL, Total decimal(16,2) NOT NULL",
+		},
+		{
+			sql:  "DELETE FROM dbo.Tags OUTPUT deleted.Tag INTO dbo.Tags (Tag) WHERE ItemId = 1",
+			want: "",
+		},
+		{
+			sql:  "MERGE dbo.Tags AS t USING dbo.Items AS s ON t.ItemId = s.Id WHEN NOT MATCHED THEN INSERT (ItemId, Tag) VALUES (s.Id, s.Code) OUTPUT $action, inserted.ItemId;",
+			want: "$action nvarchar(10) NOT NULL, ItemId int NOT NULL",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			stmt := parse(t, tt.sql).Batches[0].Statements[0]
+			cols, err := ResultSet(stmt, ddl)
+			if err != nil {
+				t.Fatalf("ResultSet: %v", err)
+			}
+			var got []string
+			for _, c := range cols {
+				name := c.Name
+				if c.Unnamed {
+					name = "(unnamed)"
+				}
+				got = append(got, name+" "+c.Type.String())
+			}
+			if s := strings.Join(got, ", "); s != tt.want {
+				t.Errorf("got %q, want %q", s, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryParameters(t *testing.T) {
+	ddl := parse(t, schemaSQL+`
+GO
+CREATE PROCEDURE dbo.Tag @itemId int, @tag varchar(10) AS INSERT INTO dbo.Tags VALUES (@itemId, @tag);`)
+	tests := []struct {
+		sql     string
+		params  string
+		locals  string
+		untyped string
+	}{
+		{
+			sql:    "SELECT Name FROM dbo.Items WHERE Id = @id AND @code <> Code",
+			params: "@id int NOT NULL, @code char(4) NOT NULL",
+		},
+		{
+			sql:    "SELECT TOP (@n) Name FROM dbo.Items WHERE Label LIKE @prefix + '%' ORDER BY Id OFFSET @skip ROWS",
+			params: "@n bigint NOT NULL, @prefix nvarchar(20) NULL, @skip bigint NOT NULL",
+		},
+		{
+			sql:    "SELECT Name FROM dbo.Items WHERE Qty BETWEEN @lo AND @hi OR Id IN (@a, @b)",
+			params: "@lo smallint NOT NULL, @hi smallint NOT NULL, @a int NOT NULL, @b int NOT NULL",
+		},
+		{
+			sql:    "INSERT INTO dbo.Tags (Tag, ItemId) VALUES (@tag, @item)",
+			params: "@tag varchar(10) NOT NULL, @item int NOT NULL",
+		},
+		{
+			sql:    "INSERT INTO dbo.Tags SELECT @item, Code FROM dbo.Items",
+			params: "@item int NOT NULL",
+		},
+		{
+			sql:    "UPDATE dbo.Items SET Label = @label, Qty = Qty + @delta WHERE Id = @id",
+			params: "@label nvarchar(20) NULL, @delta smallint NOT NULL, @id int NOT NULL",
+		},
+		{
+			sql:    "EXEC dbo.Tag @tag = @t, @itemId = @i",
+			params: "@t varchar(10) NULL, @i int NULL",
+		},
+		{
+			sql:     "SELECT CAST(@when AS date), @mystery, Name FROM dbo.Items",
+			params:  "@when date NULL, @mystery unknown",
+			untyped: "@mystery",
+		},
+		{
+			sql: `CREATE PROCEDURE dbo.Find @name varchar(50) AS
+BEGIN
+	DECLARE @max int = @limit;
+	SELECT TOP (@max) Id FROM dbo.Items WHERE Name = @name AND Qty > @minQty;
+END`,
+			params: "@limit int NULL, @minQty smallint NOT NULL",
+			locals: "@name varchar(50) NULL, @max int NULL",
+		},
+	}
+	format := func(ps []*Parameter) string {
+		var out []string
+		for _, p := range ps {
+			out = append(out, p.Name+" "+p.Type.String())
+		}
+		return strings.Join(out, ", ")
+	}
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			stmt := parse(t, tt.sql).Batches[0].Statements[0]
+			ps, err := QueryParameters(stmt, ddl)
+			if err != nil {
+				t.Fatalf("QueryParameters: %v", err)
+			}
+			if got := format(ps.Params); got != tt.params {
+				t.Errorf("params = %q, want %q", got, tt.params)
+			}
+			if got := format(ps.Locals); got != tt.locals {
+				t.Errorf("locals = %q, want %q", got, tt.locals)
+			}
+			var untyped []string
+			for _, p := range ps.Untyped {
+				untyped = append(untyped, p.Name)
+			}
+			if got := strings.Join(untyped, ", "); got != tt.untyped {
+				t.Errorf("untyped = %q, want %q", got, tt.untyped)
+			}
+		})
+	}
+}
+
+func TestProcedureResults(t *testing.T) {
+	ddl := parse(t, schemaSQL+`
+GO
+CREATE PROCEDURE dbo.TagsOf @itemId int AS SELECT Tag FROM dbo.Tags WHERE ItemId = @itemId;
+GO
+CREATE PROCEDURE dbo.Loop AS EXEC dbo.Loop;`)
+	script := parse(t, `CREATE PROCEDURE dbo.Report @id int, @verbose bit = 0 AS
+BEGIN
+	SET NOCOUNT ON;
+	DECLARE @n int;
+	SELECT @n = COUNT(*) FROM dbo.Items;
+	SELECT Id, Name INTO #tmp FROM dbo.Items;
+	SELECT @id AS Id, @n AS Total;
+	IF @verbose = 1
+		SELECT * FROM dbo.Tags WHERE ItemId = @id;
+	ELSE
+	BEGIN
+		SELECT Name FROM dbo.Items WHERE Id = @id;
+	END
+	BEGIN TRY
+		UPDATE dbo.Items SET Qty = Qty + 1 OUTPUT inserted.Qty WHERE Id = @id;
+	END TRY
+	BEGIN CATCH
+		SELECT ERROR_MESSAGE() AS Message;
+	END CATCH
+	EXEC dbo.TagsOf @itemId = @id;
+	EXEC dbo.Loop;
+	EXEC ('SELECT 1');
+	RETURN;
+	SELECT 'unreachable';
+END`)
+	results, err := ProcedureResults(script.Batches[0].Statements[0], ddl)
+	if err != nil {
+		t.Fatalf("ProcedureResults: %v", err)
+	}
+	var got []string
+	for _, r := range results {
+		var line string
+		switch {
+		case r.Unknown:
+			line = "unknown"
+		case r.Columns == nil:
+			line = "columns unknown"
+		default:
+			var cols []string
+			for _, c := range r.Columns {
+				cols = append(cols, c.Name+" "+c.Type.String())
+			}
+			line = strings.Join(cols, ", ")
+		}
+		if r.Conditional {
+			line += " (conditional)"
+		}
+		got = append(got, line)
+	}
+	want := []string{
+		"Id int NULL, Total int NULL",
+		"ItemId int NOT NULL, Tag varchar(10) NOT NULL (conditional)",
+		"Name varchar(50) NOT NULL (conditional)",
+		"Qty smallint NOT NULL",
+		"Message nvarchar(4000) NULL (conditional)",
+		"Tag varchar(10) NOT NULL",
+		"unknown",
+		"unknown",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,431 @@
+// Package variables reports mistakes in the use of local variables, which
+// SQL Server reports only when a batch or module is compiled or run.
+//
+// Variables are scoped to the batch that declares them, or to the body of
+// the procedure, function or trigger, and a declaration must precede its
+// uses in the text. Check reports variables used without a declaration in
+// their batch, including those declared in an earlier batch, before a GO;
+// variables declared twice; variables that are never read; and reads of a
+// variable that can happen, along some path of the control flow, before
+// any assignment, while the variable is still NULL.
+package variables
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/sqlc-dev/teesql/ast"
+	"github.com/sqlc-dev/teesql/cfg"
+)
+
+// Diagnostic kinds.
+const (
+	// Undeclared is a variable used without a declaration in its batch.
+	Undeclared = "undeclared"
+	// Redeclared is a second declaration of a variable.
+	Redeclared = "redeclared"
+	// Unused is a variable that is declared but never read.
+	Unused = "unused"
+	// Unassigned is a read of a variable before any assignment to it on
+	// every path, or on some path if the message says it may be.
+	Unassigned = "unassigned"
+)
+
+// Diagnostic is a problem with a variable.
+type Diagnostic struct {
+	// Pos is the position of the declaration or reference.
+	Pos  ast.Position `json:"Pos"`
+	Kind string       `json:"Kind"`
+	// Variable is the name of the variable including its @, as written.
+	Variable string `json:"Variable"`
+	Message  string `json:"Message"`
+}
+
+// String describes the diagnostic, e.g. "4:8: @id is not declared".
+func (d *Diagnostic) String() string {
+	return d.Pos.String() + ": " + d.Message
+}
+
+// Check returns the diagnostics for the batches of script and the modules
+// it creates or alters, in source order.
+func Check(script *ast.Script) []*Diagnostic {
+	var diags []*Diagnostic
+	// earlier holds the variables declared by earlier batches.
+	earlier := map[string]ast.Position{}
+	for _, batch := range script.Batches {
+		var stmts []ast.Statement
+		for _, stmt := range batch.Statements {
+			if params, ret, body, ok := module(stmt); ok {
+				u := newUnit(nil)
+				for _, p := range params {
+					u.declare(p.VariableName, param)
+				}
+				if r, ok := ret.(*ast.TableValuedFunctionReturnType); ok && r.DeclareTableVariableBody != nil {
+					u.declare(r.DeclareTableVariableBody.VariableName, table|param)
+				}
+				if body != nil {
+					diags = append(diags, u.check(body.Statements)...)
+				}
+				continue
+			}
+			stmts = append(stmts, stmt)
+		}
+		u := newUnit(earlier)
+		diags = append(diags, u.check(stmts)...)
+		for name, d := range u.decls {
+			earlier[name] = d.pos
+		}
+	}
+	sort.SliceStable(diags, func(i, j int) bool { return diags[i].Pos.Offset < diags[j].Pos.Offset })
+	return diags
+}
+
+// module returns the parameters, return type and body of a procedure,
+// function or trigger definition.
+func module(stmt ast.Statement) ([]*ast.ProcedureParameter, ast.FunctionReturnType, *ast.StatementList, bool) {
+	switch s := stmt.(type) {
+	case *ast.CreateProcedureStatement:
+		return s.Parameters, nil, s.StatementList, true
+	case *ast.CreateOrAlterProcedureStatement:
+		return s.Parameters, nil, s.StatementList, true
+	case *ast.AlterProcedureStatement:
+		return s.Parameters, nil, s.StatementList, true
+	case *ast.CreateFunctionStatement:
+		return s.Parameters, s.ReturnType, s.StatementList, true
+	case *ast.CreateOrAlterFunctionStatement:
+		return s.Parameters, s.ReturnType, s.StatementList, true
+	case *ast.AlterFunctionStatement:
+		return s.Parameters, s.ReturnType, s.StatementList, true
+	case *ast.CreateTriggerStatement:
+		return nil, nil, s.StatementList, true
+	case *ast.CreateOrAlterTriggerStatement:
+		return nil, nil, s.StatementList, true
+	case *ast.AlterTriggerStatement:
+		return nil, nil, s.StatementList, true
+	}
+	return nil, nil, nil, false
+}
+
+// Declaration flags.
+const (
+	// param is set for parameters, which are assigned by the caller and
+	// need not be read.
+	param = 1 << iota
+	// table is set for table variables, which hold an empty table until
+	// rows are inserted.
+	table
+)
+
+type declaration struct {
+	name  string
+	pos   ast.Position
+	flags int
+	read  bool
+}
+
+// role is the way a statement uses a variable reference.
+type role int
+
+const (
+	read role = iota
+	write
+	readWrite
+	// name is the parameter name of an EXEC argument, which refers to a
+	// parameter of the procedure called.
+	name
+)
+
+// unit checks a batch or a module body.
+type unit struct {
+	decls   map[string]*declaration
+	order   []*declaration
+	roles   map[*ast.VariableReference]role
+	earlier map[string]ast.Position
+	diags   []*Diagnostic
+}
+
+func newUnit(earlier map[string]ast.Position) *unit {
+	return &unit{decls: map[string]*declaration{}, roles: map[*ast.VariableReference]role{}, earlier: earlier}
+}
+
+func (u *unit) report(pos ast.Position, kind, variable, message string) {
+	u.diags = append(u.diags, &Diagnostic{Pos: pos, Kind: kind, Variable: variable, Message: message})
+}
+
+func (u *unit) declare(id *ast.Identifier, flags int) {
+	if id == nil {
+		return
+	}
+	key := strings.ToLower(id.Value)
+	if d := u.decls[key]; d != nil {
+		u.report(id.Pos, Redeclared, id.Value, id.Value+" is already declared at "+d.pos.String())
+		return
+	}
+	d := &declaration{name: id.Value, pos: id.Pos, flags: flags}
+	u.decls[key] = d
+	u.order = append(u.order, d)
+}
+
+func (u *unit) check(stmts []ast.Statement) []*Diagnostic {
+	for _, stmt := range stmts {
+		inspect(stmt, u.assignRoles)
+	}
+	reported := map[string]bool{}
+	for _, stmt := range stmts {
+		inspect(stmt, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.DeclareVariableElement:
+				u.declare(n.VariableName, 0)
+			case *ast.DeclareTableVariableBody:
+				u.declare(n.VariableName, table)
+			case *ast.VariableReference:
+				r := u.roles[n]
+				key := strings.ToLower(n.Name)
+				d := u.decls[key]
+				switch {
+				case r == name:
+				case d == nil && !reported[key]:
+					reported[key] = true
+					if pos, ok := u.earlier[key]; ok {
+						u.report(n.Pos, Undeclared, n.Name, n.Name+" is not declared in this batch; it was declared at "+pos.String()+" before GO")
+					} else {
+						u.report(n.Pos, Undeclared, n.Name, n.Name+" is not declared")
+					}
+				case d != nil && r != write:
+					d.read = true
+				}
+			}
+			return true
+		})
+	}
+	for _, d := range u.order {
+		if d.flags&param == 0 && !d.read {
+			u.report(d.pos, Unused, d.name, d.name+" is declared but never read")
+		}
+	}
+	u.checkAssigned(stmts)
+	return u.diags
+}
+
+// inspect calls f for the nodes of stmt, except dynamic SQL, which runs in
+// a scope of its own.
+func inspect(stmt ast.Node, f func(ast.Node) bool) {
+	ast.Inspect(stmt, func(n ast.Node) bool {
+		if _, ok := n.(*ast.DynamicSQL); ok {
+			return false
+		}
+		return f(n)
+	})
+}
+
+// assignRoles records the references that a node assigns.
+func (u *unit) assignRoles(n ast.Node) bool {
+	switch n := n.(type) {
+	case *ast.SetVariableStatement:
+		u.assign(n.Variable, n.AssignmentKind)
+	case *ast.SelectSetVariable:
+		u.assign(n.Variable, n.AssignmentKind)
+	case *ast.AssignmentSetClause:
+		u.assign(n.Variable, n.AssignmentKind)
+	case *ast.FetchCursorStatement:
+		for _, e := range n.IntoVariables {
+			if v, ok := e.(*ast.VariableReference); ok {
+				u.roles[v] = write
+			}
+		}
+	case *ast.ExecuteSpecification:
+		if n.Variable != nil {
+			u.roles[n.Variable] = write
+		}
+	case *ast.ExecuteParameter:
+		if n.Variable != nil {
+			u.roles[n.Variable] = name
+		}
+		// Variables passed for OUTPUT parameters are usually assigned by
+		// the call rather than read.
+		if v, ok := n.ParameterValue.(*ast.VariableReference); ok && n.IsOutput {
+			u.roles[v] = write
+		}
+	case *ast.InsertSpecification:
+		u.assignTable(n.Target)
+	case *ast.UpdateSpecification:
+		u.assignTable(n.Target)
+	case *ast.DeleteSpecification:
+		u.assignTable(n.Target)
+	case *ast.MergeSpecification:
+		u.assignTable(n.Target)
+	case *ast.OutputIntoClause:
+		u.assignTable(n.IntoTable)
+	}
+	return true
+}
+
+func (u *unit) assign(v *ast.VariableReference, kind string) {
+	if v == nil {
+		return
+	}
+	if kind == "" || kind == "Equals" {
+		u.roles[v] = write
+	} else {
+		// Compound assignments such as += read the old value.
+		u.roles[v] = readWrite
+	}
+}
+
+func (u *unit) assignTable(t ast.TableReference) {
+	if v, ok := t.(*ast.VariableTableReference); ok && v.Variable != nil {
+		u.roles[v.Variable] = write
+	}
+}
+
+// event is a read or assignment of a variable by a statement.
+type event struct {
+	ref   *ast.VariableReference
+	key   string
+	write bool
+}
+
+// events returns the reads and assignments of a node's statement in the
+// order they happen: a statement's reads come before its assignments,
+// except that the variables of a DECLARE are initialized in turn.
+func (u *unit) events(n *cfg.Node) []event {
+	var events []event
+	switch s := n.Stmt.(type) {
+	case *ast.IfStatement:
+		return u.reads(s.Predicate)
+	case *ast.WhileStatement:
+		return u.reads(s.Predicate)
+	case *ast.DeclareVariableStatement:
+		for _, d := range s.Declarations {
+			if d.Value == nil || d.VariableName == nil {
+				continue
+			}
+			events = append(events, u.reads(d.Value)...)
+			events = append(events, event{key: strings.ToLower(d.VariableName.Value), write: true})
+		}
+		return events
+	case nil:
+		return nil
+	}
+	events = u.reads(n.Stmt)
+	inspect(n.Stmt, func(node ast.Node) bool {
+		if v, ok := node.(*ast.VariableReference); ok && (u.roles[v] == write || u.roles[v] == readWrite) {
+			events = append(events, event{ref: v, key: strings.ToLower(v.Name), write: true})
+		}
+		return true
+	})
+	return events
+}
+
+// reads returns the variables that a node reads.
+func (u *unit) reads(node ast.Node) []event {
+	var events []event
+	inspect(node, func(n ast.Node) bool {
+		if v, ok := n.(*ast.VariableReference); ok && (u.roles[v] == read || u.roles[v] == readWrite) {
+			events = append(events, event{ref: v, key: strings.ToLower(v.Name)})
+		}
+		return true
+	})
+	return events
+}
+
+// checkAssigned reports reads of declared variables that an assignment
+// does not reach along every path. The variables that may be assigned, on
+// some path, and must be assigned, on every path, on entry to each node
+// are found by iterating to a fixed point over the control-flow graph.
+func (u *unit) checkAssigned(stmts []ast.Statement) {
+	g, _ := cfg.New(stmts)
+	reachable := g.Reachable()
+	events := map[*cfg.Node][]event{}
+	for _, n := range g.Nodes {
+		events[n] = u.events(n)
+	}
+	// A node's must set is nil until a path to it is known.
+	may := map[*cfg.Node]set{g.Entry: {}}
+	must := map[*cfg.Node]set{g.Entry: {}}
+	for changed := true; changed; {
+		changed = false
+		for _, n := range g.Nodes {
+			if n == g.Entry || !reachable[n] {
+				continue
+			}
+			newMay, newMust := set{}, set(nil)
+			for _, e := range n.Preds {
+				if must[e.From] == nil {
+					continue
+				}
+				newMay.union(may[e.From].assign(events[e.From]))
+				if out := must[e.From].assign(events[e.From]); newMust == nil {
+					newMust = out
+				} else {
+					newMust.intersect(out)
+				}
+			}
+			if newMust != nil && (!newMay.equal(may[n]) || !newMust.equal(must[n])) {
+				may[n], must[n] = newMay, newMust
+				changed = true
+			}
+		}
+	}
+	for _, n := range g.Nodes {
+		if must[n] == nil {
+			continue
+		}
+		mayAssigned, mustAssigned := may[n].assign(nil), must[n].assign(nil)
+		for _, e := range events[n] {
+			d := u.decls[e.key]
+			switch {
+			case e.write:
+				mayAssigned[e.key], mustAssigned[e.key] = true, true
+			case d == nil || d.flags != 0 || mustAssigned[e.key] || e.ref.Pos.Offset < d.pos.Offset:
+			case mayAssigned[e.key]:
+				u.report(e.ref.Pos, Unassigned, e.ref.Name, e.ref.Name+" may be read before it is assigned")
+			default:
+				u.report(e.ref.Pos, Unassigned, e.ref.Name, e.ref.Name+" is read before it is assigned")
+			}
+		}
+	}
+}
+
+// set is a set of lower-case variable names.
+type set map[string]bool
+
+// assign returns a copy of s with the variables that events assign.
+func (s set) assign(events []event) set {
+	t := set{}
+	for k := range s {
+		t[k] = true
+	}
+	for _, e := range events {
+		if e.write {
+			t[e.key] = true
+		}
+	}
+	return t
+}
+
+func (s set) union(t set) {
+	for k := range t {
+		s[k] = true
+	}
+}
+
+func (s set) intersect(t set) {
+	for k := range s {
+		if !t[k] {
+			delete(s, k)
+		}
+	}
+}
+
+func (s set) equal(t set) bool {
+	if t == nil || len(s) != len(t) {
+		return false
+	}
+	for k := range s {
+		if !t[k] {
+			return false
+		}
+	}
+	return true
+}
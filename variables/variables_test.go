@@ -0,0 +1,123 @@
+package variables
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sqlc-dev/teesql/parser"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want []string
+	}{
+		{
+			name: "clean",
+			sql: `DECLARE @id int = 1, @name nvarchar(50);
+SELECT @name = Name FROM dbo.Users WHERE Id = @id;
+PRINT @name;`,
+		},
+		{
+			name: "undeclared",
+			sql: `DECLARE @id int = 1;
+SELECT * FROM dbo.Users WHERE Id = @id OR Id = @other;
+SET @other = 2;`,
+			want: []string{"2:48: @other is not declared"},
+		},
+		{
+			name: "after go",
+			sql: `DECLARE @id int = 1;
+PRINT @id;
+GO
+PRINT @id;`,
+			want: []string{"4:7: @id is not declared in this batch; it was declared at 1:9 before GO"},
+		},
+		{
+			name: "redeclared",
+			sql: `DECLARE @id int = 1;
+DECLARE @ID bigint = 2;
+PRINT @id;`,
+			want: []string{"2:9: @ID is already declared at 1:9"},
+		},
+		{
+			name: "unused",
+			sql: `DECLARE @a int, @b int;
+DECLARE @t TABLE (x int);
+SET @a = 1;
+SELECT @b = COUNT(*) FROM dbo.Users;
+INSERT INTO @t VALUES (@b);`,
+			want: []string{
+				"1:9: @a is declared but never read",
+				"2:9: @t is declared but never read",
+			},
+		},
+		{
+			name: "unassigned",
+			sql: `DECLARE @i int, @total int, @n int, @x int;
+PRINT @x;
+WHILE @i < 10
+BEGIN
+	SET @total += @i;
+	SET @i += 1;
+END
+IF @@ROWCOUNT > 0
+	SET @n = 1;
+PRINT @n;
+PRINT @total;`,
+			want: []string{
+				"2:7: @x is read before it is assigned",
+				"3:7: @i may be read before it is assigned",
+				"5:6: @total may be read before it is assigned",
+				"5:16: @i may be read before it is assigned",
+				"6:6: @i may be read before it is assigned",
+				"10:7: @n may be read before it is assigned",
+				"11:7: @total may be read before it is assigned",
+			},
+		},
+		{
+			name: "assigned by fetch and output",
+			sql: `DECLARE @name sysname, @rc int, @out int;
+DECLARE c CURSOR FOR SELECT name FROM sys.tables;
+OPEN c;
+FETCH NEXT FROM c INTO @name;
+EXEC @rc = dbo.Count @table = @name, @count = @out OUTPUT;
+PRINT @rc + @out;`,
+		},
+		{
+			name: "procedure",
+			sql: `CREATE PROCEDURE dbo.P @id int, @unused int AS
+BEGIN
+	DECLARE @sql nvarchar(max) = N'SELECT @x';
+	EXEC sp_executesql @sql, N'@x int', @x = @id;
+	RETURN @missing;
+END`,
+			want: []string{"5:9: @missing is not declared"},
+		},
+		{
+			name: "function",
+			sql: `CREATE FUNCTION dbo.F (@n int) RETURNS @result TABLE (x int) AS
+BEGIN
+	INSERT INTO @result VALUES (@n);
+	RETURN;
+END`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script, err := parser.ParseWithOptions(context.Background(), strings.NewReader(tt.sql), parser.Options{DynamicSQL: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, d := range Check(script) {
+				got = append(got, d.String())
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}